@@ -0,0 +1,119 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/amshithnair/zcrypt/crypto"
+)
+
+// Move old local entries into a compressed archive segment
+func handleChainArchive() {
+	flags := flag.NewFlagSet("chain-archive", flag.ExitOnError)
+	before := flags.Int("before", -1, "archive entries before this index")
+	keep := flags.Int("keep", 0, "or keep this many newest entries in the chain file")
+	compression := flags.String("compression", crypto.DefaultCompression, "zstd or gzip")
+	flags.Parse(os.Args[2:])
+
+	if *before < 0 && *keep <= 0 {
+		fmt.Println("Usage: zcrypt chain-archive (--before <index> | --keep N) [--compression zstd|gzip]")
+		return
+	}
+
+	chain, err := crypto.NewLogChain(crypto.GetChainPath())
+	if err != nil {
+		fmt.Println("Error loading chain:", err)
+		return
+	}
+	if *before < 0 {
+		*before = chain.Length() - *keep
+	}
+
+	segment, err := chain.Archive(*before, *compression)
+	if errors.Is(err, crypto.ErrNothingToArchive) {
+		fmt.Println("Nothing to archive")
+		return
+	}
+	if err != nil {
+		fmt.Println("Error archiving entries:", err)
+		return
+	}
+
+	fmt.Printf("✓ Archived entries %d-%d (%s)\n", segment.StartIndex, segment.StartIndex+segment.Count-1, segment.Compression)
+	fmt.Printf("  Segment: %s\n", segment.Path)
+	fmt.Printf("  SHA-256: %s\n", segment.SHA256[:32]+"...")
+}
+
+// Move archived local entries back into the chain file
+func handleChainRestore() {
+	flags := flag.NewFlagSet("chain-restore", flag.ExitOnError)
+	from := flags.Int("from", 0, "restore segments holding this index and later")
+	flags.Parse(os.Args[2:])
+
+	chain, err := crypto.NewLogChain(crypto.GetChainPath())
+	if err != nil {
+		fmt.Println("Error loading chain:", err)
+		return
+	}
+
+	restored, err := chain.Restore(*from)
+	if err != nil {
+		fmt.Println("Error restoring entries:", err)
+		return
+	}
+	fmt.Printf("✓ Restored %d entries (%d segments remain)\n", restored, len(chain.Segments()))
+}
+
+// Archive old server entries; needs ZCRYPT_ADMIN_TOKEN
+func handleServerArchive() {
+	flags := flag.NewFlagSet("server-archive", flag.ExitOnError)
+	keep := flags.Int("keep", 0, "keep this many newest entries in the server chain file")
+	compression := flags.String("compression", crypto.DefaultCompression, "zstd or gzip")
+	flags.Parse(os.Args[2:])
+
+	if *keep <= 0 {
+		fmt.Println("Usage: zcrypt server-archive --keep N [--compression zstd|gzip]")
+		return
+	}
+
+	client := newServerClient()
+	client.Token = os.Getenv("ZCRYPT_ADMIN_TOKEN")
+	if client.Token == "" {
+		fmt.Println("Error: ZCRYPT_ADMIN_TOKEN is required to archive the server chain")
+		return
+	}
+
+	segment, err := client.ArchiveChain(*keep, *compression)
+	if err != nil {
+		fmt.Println("Error archiving server entries:", err)
+		return
+	}
+	if segment == nil {
+		fmt.Println("Nothing to archive on the server")
+		return
+	}
+	fmt.Printf("✓ Server archived entries %d-%d (%s)\n", segment.StartIndex, segment.StartIndex+segment.Count-1, segment.Compression)
+}
+
+// Restore archived server entries; needs ZCRYPT_ADMIN_TOKEN
+func handleServerRestore() {
+	flags := flag.NewFlagSet("server-restore", flag.ExitOnError)
+	from := flags.Int("from", 0, "restore segments holding this index and later")
+	flags.Parse(os.Args[2:])
+
+	client := newServerClient()
+	client.Token = os.Getenv("ZCRYPT_ADMIN_TOKEN")
+	if client.Token == "" {
+		fmt.Println("Error: ZCRYPT_ADMIN_TOKEN is required to restore the server chain")
+		return
+	}
+
+	restored, err := client.RestoreChain(*from)
+	if err != nil {
+		fmt.Println("Error restoring server entries:", err)
+		return
+	}
+	fmt.Printf("✓ Server restored %d entries\n", restored)
+}
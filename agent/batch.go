@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/amshithnair/zcrypt/utils"
+)
+
+// Send every line of a file, or of stdin, to the server in batches
+func handleSendBatch() {
+	flags := flag.NewFlagSet("send-batch", flag.ExitOnError)
+	size := flags.Int("size", utils.DefaultBatchSize, "submissions per request")
+	interval := flags.Duration("interval", time.Second, "send a partial batch after this long")
+	flags.Parse(os.Args[2:])
+
+	var input io.Reader = os.Stdin
+	if flags.NArg() > 0 && flags.Arg(0) != "-" {
+		file, err := os.Open(flags.Arg(0))
+		if err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+		defer file.Close()
+		input = file
+	}
+
+	signer, err := loadSigner()
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	client := newServerClient()
+	if healthy, err := client.HealthCheck(); err != nil || !healthy {
+		fmt.Printf("Error: Cannot reach server at %s\n", client.BaseURL)
+		return
+	}
+
+	// Callbacks run on the submitter's goroutines one batch at a time
+	accepted, rejected := 0, 0
+	batches := client.NewBatchSubmitter(*size, *interval, func(batch []utils.LogSubmission, results []utils.BatchResult, err error) {
+		if err != nil {
+			fmt.Printf("  ✗ Batch of %d failed: %v\n", len(batch), err)
+			rejected += len(batch)
+			return
+		}
+		for i, result := range results {
+			if result.Accepted() {
+				accepted++
+				if result.Receipt == nil {
+					fmt.Printf("  ⚠️  %q: no valid receipt: %s\n", batch[i].Message, result.Error)
+				} else if err := saveReceipt(client.BaseURL, result.Receipt, batch[i], result.Entry); err != nil {
+					fmt.Println("  Warning: receipt not saved:", err)
+				}
+				continue
+			}
+			rejected++
+			fmt.Printf("  ✗ %q: %s (%d)\n", batch[i].Message, result.Error, result.Status)
+		}
+	})
+
+	scanner := bufio.NewScanner(input)
+	for scanner.Scan() {
+		message := strings.TrimSpace(scanner.Text())
+		if message == "" {
+			continue
+		}
+		submission, err := client.SignSubmission(signer, agentID(), message, agentMetadata())
+		if err != nil {
+			fmt.Println("Error signing message:", err)
+			break
+		}
+		batches.Submit(submission)
+	}
+	if err := scanner.Err(); err != nil {
+		fmt.Println("Error reading input:", err)
+	}
+	batches.Close()
+
+	fmt.Printf("✓ Sent %d log(s) to %s\n", accepted, client.BaseURL)
+	if rejected > 0 {
+		fmt.Printf("  %d rejected\n", rejected)
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/amshithnair/zcrypt/crypto"
+	"github.com/amshithnair/zcrypt/utils"
+)
+
+// Local metadata keys linking a confidential message to its server entry
+const (
+	metaServerIndex    = "server_index"
+	metaServerHash     = "server_hash"
+	metaDisclosureSalt = "disclosure_salt"
+)
+
+// recordConfidential keeps the plaintext and salt of a confidential
+// submission in the local chain so it can be disclosed later
+func recordConfidential(signer crypto.Signer, message, salt string, resp *utils.ServerResponse) {
+	serverHash := ""
+	if entry, ok := resp.Entry.(map[string]interface{}); ok {
+		serverHash, _ = entry["current_hash"].(string)
+	}
+
+	metadata := agentMetadata()
+	metadata[metaServerIndex] = resp.Index
+	metadata[metaServerHash] = serverHash
+	metadata[metaDisclosureSalt] = salt
+
+	_, entry, err := appendLocal(signer, message, metadata)
+	if err != nil {
+		fmt.Println("Warning: message sent, but the local record failed:", err)
+		fmt.Printf("  Keep this salt to disclose later: %s\n", salt)
+		return
+	}
+
+	fmt.Println("  Only a commitment was sent; the message stays in the local chain")
+	fmt.Printf("  Local record: %s\n", entry.CurrentHash[:32]+"...")
+	fmt.Printf("  Disclose later with: zcrypt disclose %d\n", resp.Index)
+}
+
+// findConfidential looks up the local record of a confidential server entry
+func findConfidential(chain *crypto.LogChain, serverIndex int) (message, salt string, ok bool) {
+	for i := len(chain.Entries) - 1; i >= 0; i-- {
+		entry := chain.Entries[i]
+		index, isNumber := entry.Metadata[metaServerIndex].(float64)
+		salt, hasSalt := entry.Metadata[metaDisclosureSalt].(string)
+		if isNumber && hasSalt && int(index) == serverIndex {
+			return entry.Message, salt, true
+		}
+	}
+	return "", "", false
+}
+
+// Reveal the message of a confidential server entry
+func handleDisclose() {
+	if len(os.Args) < 3 {
+		fmt.Println("Usage: zcrypt disclose <server_index>")
+		return
+	}
+
+	index, err := strconv.Atoi(os.Args[2])
+	if err != nil {
+		fmt.Println("Error: index must be a number")
+		return
+	}
+
+	chain, err := crypto.NewLogChain(crypto.GetChainPath())
+	if err != nil {
+		fmt.Println("Error loading chain:", err)
+		return
+	}
+	message, salt, ok := findConfidential(chain, index)
+	if !ok {
+		fmt.Printf("Error: no local record of confidential server entry %d\n", index)
+		return
+	}
+
+	signer, err := loadSigner()
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	entry, err := newServerClient().Disclose(signer, index, message, salt)
+	if err != nil {
+		fmt.Println("Error disclosing entry:", err)
+		return
+	}
+
+	fmt.Printf("✓ Server entry %d disclosed\n", index)
+	fmt.Printf("  Message: %s\n", message)
+	fmt.Printf("  Salt: %s\n", salt)
+	fmt.Printf("  Disclosure recorded as: %s\n", entry.CurrentHash[:32]+"...")
+}
+
+// printWithheldReport lists confidential entries not yet disclosed
+func printWithheldReport(indexes []int) {
+	if len(indexes) == 0 {
+		return
+	}
+	fmt.Printf("\nConfidential entries not yet disclosed: %v\n", indexes)
+}
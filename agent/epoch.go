@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/amshithnair/zcrypt/crypto"
+)
+
+// Seal the current local epoch with the agent key and open the next
+func handleChainSeal() {
+	hashAlg := ""
+	if len(os.Args) > 2 {
+		hashAlg = os.Args[2]
+	}
+
+	chain, err := crypto.NewLogChain(crypto.GetChainPath())
+	if err != nil {
+		fmt.Println("Error loading chain:", err)
+		return
+	}
+	signer, err := loadSigner()
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	seal, err := chain.SealEpoch(signer, hashAlg)
+	if errors.Is(err, crypto.ErrEmptyEpoch) {
+		fmt.Println("Nothing to seal - the current epoch has no entries")
+		return
+	}
+	if err != nil {
+		fmt.Println("Error sealing epoch:", err)
+		return
+	}
+	printSeal(seal)
+}
+
+// printSeal shows what a seal entry commits to
+func printSeal(seal *crypto.LogEntry) {
+	c := seal.Closes
+	fmt.Printf("✓ Epoch %d sealed\n", c.Epoch)
+	fmt.Printf("  Entries: %d-%d (%d)\n", c.StartIndex, c.StartIndex+c.Count-1, c.Count)
+	fmt.Printf("  Merkle root: %s\n", c.MerkleRoot[:32]+"...")
+	fmt.Printf("  Final hash: %s\n", c.FinalHash[:min(len(c.FinalHash), 32)])
+	fmt.Printf("  Seal hash: %s\n", seal.CurrentHash[:32]+"...")
+	fmt.Printf("  Epoch %d opens at entry %d\n", c.Epoch+1, c.StartIndex+c.Count+1)
+}
+
+// printEpochs lists epochs with their range and seal state
+func printEpochs(epochs []crypto.EpochSummary) {
+	for _, epoch := range epochs {
+		state := "open"
+		if epoch.SealHash != "" {
+			state = fmt.Sprintf("sealed by entry %d", epoch.SealIndex)
+		}
+		entries := "no entries"
+		if epoch.Count > 0 {
+			entries = fmt.Sprintf("entries %d-%d (%d)", epoch.StartIndex, epoch.StartIndex+epoch.Count-1, epoch.Count)
+		}
+		fmt.Printf("  [%d] %s, %s, %s\n", epoch.Number, entries, epoch.HashAlgorithm, state)
+	}
+}
+
+// List the local chain's epochs
+func handleChainEpochs() {
+	chain, err := crypto.NewLogChain(crypto.GetChainPath())
+	if err != nil {
+		fmt.Println("Error loading chain:", err)
+		return
+	}
+	fmt.Println("Local Chain Epochs:")
+	printEpochs(chain.Epochs())
+}
+
+// printEpochReport shows the result of verifying one epoch
+func printEpochReport(report *crypto.EpochReport) {
+	if report.Valid {
+		fmt.Printf("✓ Epoch %d verified\n", report.Epoch.Number)
+		fmt.Printf("  Entries checked: %d\n", report.Total)
+		if report.Pruned > 0 {
+			fmt.Printf("  Pruned entries: %d (covered by signed anchors)\n", report.Pruned)
+		}
+		if report.Epoch.SealHash != "" {
+			fmt.Printf("  Sealed by entry %d\n", report.Epoch.SealIndex)
+		} else {
+			fmt.Println("  Epoch is still open")
+		}
+	} else {
+		fmt.Printf("✗ Epoch %d integrity COMPROMISED!\n", report.Epoch.Number)
+		fmt.Println("  Errors found:")
+		for _, e := range report.Errors {
+			fmt.Printf("    - %s\n", e)
+		}
+	}
+}
+
+// writeEpochExport saves an epoch's entries as JSON
+func writeEpochExport(number int, entries []crypto.LogEntry) {
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		fmt.Println("Error exporting epoch:", err)
+		return
+	}
+	exportPath := fmt.Sprintf("zcrypt_epoch_%d_export.json", number)
+	if err := os.WriteFile(exportPath, data, 0644); err != nil {
+		fmt.Println("Error saving export:", err)
+		return
+	}
+	fmt.Printf("✓ Epoch %d exported to: %s\n", number, exportPath)
+	fmt.Printf("  Total entries: %d\n", len(entries))
+}
+
+// List the server chain's epochs
+func handleServerEpochs() {
+	epochs, err := newServerClient().Epochs()
+	if err != nil {
+		fmt.Println("Error listing server epochs:", err)
+		return
+	}
+	fmt.Println("Server Chain Epochs:")
+	printEpochs(epochs)
+}
+
+// Seal the server's current epoch; needs ZCRYPT_ADMIN_TOKEN
+func handleServerSeal() {
+	hashAlg := ""
+	if len(os.Args) > 2 {
+		hashAlg = os.Args[2]
+	}
+
+	client := newServerClient()
+	client.Token = os.Getenv("ZCRYPT_ADMIN_TOKEN")
+	if client.Token == "" {
+		fmt.Println("Error: ZCRYPT_ADMIN_TOKEN is required to seal a server epoch")
+		return
+	}
+
+	seal, err := client.SealEpoch(hashAlg)
+	if err != nil {
+		fmt.Println("Error sealing server epoch:", err)
+		return
+	}
+	printSeal(seal)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/amshithnair/zcrypt/crypto"
+	"github.com/amshithnair/zcrypt/utils"
+)
+
+// knownServersPath returns the file server identity pins are kept in
+func knownServersPath() string {
+	return filepath.Join(filepath.Dir(crypto.GetChainPath()), "known_servers.json")
+}
+
+// pinServer checks the server's identity against its pin, pinning it on first
+// use, and sets client.ServerKey. An identity change stops the agent. Notes
+// go to stderr so commands writing data to stdout are unaffected.
+func pinServer(client *utils.LogClient) {
+	known, err := utils.LoadKnownServers(knownServersPath())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Warning: server identity not checked:", err)
+		return
+	}
+	_, firstUse := known.Lookup(client.BaseURL)
+	firstUse = !firstUse && client.ServerKey == ""
+
+	doc, err := client.PinIdentity(known)
+	var changed *utils.IdentityChangedError
+	switch {
+	case errors.As(err, &changed):
+		warnIdentityChanged(changed)
+		os.Exit(1)
+	case err != nil:
+		// An unreachable server fails the command itself; an older one
+		// without discovery is still held to any pinned key
+		return
+	}
+	if firstUse {
+		fmt.Fprintf(os.Stderr, "Pinned server identity %s for %s on first use\n", doc.PubKey, client.BaseURL)
+	}
+	if !doc.HasChain(client.Chain) {
+		fmt.Fprintf(os.Stderr, "Warning: %s does not list chain %q\n", client.BaseURL, client.Chain)
+	}
+}
+
+// warnIdentityChanged explains an identity change as loudly as it deserves
+func warnIdentityChanged(changed *utils.IdentityChangedError) {
+	rule := strings.Repeat("@", 64)
+	fmt.Fprintln(os.Stderr, rule)
+	fmt.Fprintln(os.Stderr, "@    WARNING: ZCRYPT SERVER IDENTITY HAS CHANGED!")
+	fmt.Fprintln(os.Stderr, rule)
+	fmt.Fprintln(os.Stderr, "The server may be impersonated, or its identity key was replaced.")
+	fmt.Fprintln(os.Stderr, "Receipts and proofs it signs now cannot be tied to earlier ones.")
+	fmt.Fprintf(os.Stderr, "  Server:    %s\n", changed.Server)
+	fmt.Fprintf(os.Stderr, "  Pinned:    %s\n", changed.Pinned)
+	fmt.Fprintf(os.Stderr, "  Presented: %s\n", changed.Presented)
+	if os.Getenv("ZCRYPT_SERVER_PUBKEY") != "" {
+		fmt.Fprintln(os.Stderr, "The pinned key comes from ZCRYPT_SERVER_PUBKEY.")
+	} else {
+		fmt.Fprintf(os.Stderr, "If the change is expected, confirm the new key with the operator and run:\n  zcrypt server-identity --trust %s\n", changed.Presented)
+	}
+	fmt.Fprintln(os.Stderr, rule)
+}
+
+// Show the server's signed discovery document and its pin, or pin a new
+// identity after a confirmed key change
+func handleServerIdentity() {
+	flags := flag.NewFlagSet("server-identity", flag.ExitOnError)
+	trust := flags.String("trust", "", "pin this hex key as the server identity, replacing the old pin")
+	flags.Parse(os.Args[2:])
+
+	serverURL := os.Getenv("ZCRYPT_SERVER")
+	if serverURL == "" {
+		serverURL = DEFAULT_SERVER
+	}
+	client := utils.NewLogClient(serverURL)
+	known, err := utils.LoadKnownServers(knownServersPath())
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	doc, err := client.Discover()
+	if err != nil {
+		fmt.Println("Error fetching discovery document:", err)
+		return
+	}
+	pin, pinned := known.Lookup(serverURL)
+
+	if *trust != "" {
+		// Only the key the server presents can be trusted, so a typo or a
+		// stale key does not leave a pin that fails every command
+		if *trust != doc.PubKey {
+			fmt.Printf("Error: the server presents %s, not the given key\n", doc.PubKey)
+			return
+		}
+		if err := known.Pin(serverURL, doc.PubKey, doc.Algorithm); err != nil {
+			fmt.Println("Error saving pin:", err)
+			return
+		}
+		fmt.Printf("✓ Pinned %s for %s\n", doc.PubKey, serverURL)
+		return
+	}
+
+	fmt.Printf("Server: %s\n", serverURL)
+	fmt.Printf("  Identity key: %s (%s)\n", doc.PubKey, doc.Algorithm)
+	switch {
+	case !pinned:
+		fmt.Println("  Pin: none yet - pinned on the next server command")
+	case pin.PubKey == doc.PubKey:
+		fmt.Printf("  Pin: ✓ matches, pinned %s\n", pin.PinnedAt.Format("2006-01-02 15:04:05"))
+	default:
+		fmt.Printf("  Pin: ✗ CHANGED - pinned %s\n", pin.PubKey)
+	}
+	fmt.Printf("  API: %s at %s\n", doc.APIVersion, doc.APIBase)
+	for _, chain := range doc.Chains {
+		fmt.Printf("  Chain: %s (%s)\n", chain.Name, chain.HashAlgorithm)
+	}
+	fmt.Printf("  Signature algorithms: %s\n", strings.Join(doc.SignatureAlgorithms, ", "))
+	fmt.Printf("  Hash algorithms: %s\n", strings.Join(doc.HashAlgorithms, ", "))
+	if len(doc.Features) > 0 {
+		fmt.Printf("  Features: %s\n", strings.Join(doc.Features, ", "))
+	}
+}
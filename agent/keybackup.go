@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bufio"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/amshithnair/zcrypt/crypto"
+	"github.com/amshithnair/zcrypt/crypto/shamir"
+)
+
+func handleKey() {
+	if len(os.Args) < 3 {
+		fmt.Println("Usage: zcrypt key <split|recover> [options]")
+		return
+	}
+
+	switch os.Args[2] {
+	case "split":
+		handleKeySplit(os.Args[3:])
+	case "recover":
+		handleKeyRecover(os.Args[3:])
+	default:
+		fmt.Println("Unknown key command:", os.Args[2])
+		fmt.Println("Usage: zcrypt key <split|recover> [options]")
+	}
+}
+
+func handleKeySplit(args []string) {
+	flags := flag.NewFlagSet("key split", flag.ExitOnError)
+	keyPath := flags.String("key", crypto.PrivateKeyFile, "private key file to split")
+	total := flags.Int("shares", 5, "number of shares to produce")
+	threshold := flags.Int("threshold", 3, "number of shares required to recover")
+	outDir := flags.String("out", ".", "directory to write share files to")
+	flags.Parse(args)
+
+	secret, err := os.ReadFile(*keyPath)
+	if err != nil {
+		fmt.Println("Error reading key:", err)
+		return
+	}
+
+	// Refuse to back up something that is not a usable key
+	signer, err := crypto.ParseSigner(secret)
+	if err != nil {
+		fmt.Println("Error: not a zcrypt private key:", err)
+		return
+	}
+
+	shares, err := shamir.Split(secret, *total, *threshold)
+	if err != nil {
+		fmt.Println("Error splitting key:", err)
+		return
+	}
+
+	if err := os.MkdirAll(*outDir, 0700); err != nil {
+		fmt.Println("Error creating output directory:", err)
+		return
+	}
+
+	pubHex := hex.EncodeToString(signer.PublicKey())
+	for _, share := range shares {
+		path := filepath.Join(*outDir, fmt.Sprintf("zcrypt_share_%s_%d.txt", share.SetID, share.X))
+		content := fmt.Sprintf("# zcrypt key share %d of %d (%d required to recover)\n# key: %s %s...\n%s\n",
+			share.X, share.Total, share.Threshold, signer.Algorithm(), pubHex[:min(len(pubHex), 32)], share)
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			fmt.Println("Error writing share:", err)
+			return
+		}
+		fmt.Printf("  %s\n", path)
+	}
+
+	fmt.Printf("✓ Key split into %d shares, any %d recover it\n", *total, *threshold)
+	fmt.Println("  Distribute the shares to separate custodians and delete local copies.")
+}
+
+func handleKeyRecover(args []string) {
+	flags := flag.NewFlagSet("key recover", flag.ExitOnError)
+	outPath := flags.String("out", "zcrypt_recovered.key", "file to write the recovered private key to")
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		fmt.Println("Usage: zcrypt key recover [--out file] <share-file-or-text>...")
+		return
+	}
+
+	var shares []shamir.Share
+	for _, arg := range flags.Args() {
+		text, err := readShareText(arg)
+		if err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+		share, err := shamir.Parse(text)
+		if err != nil {
+			fmt.Printf("Error: share %s: %v\n", arg, err)
+			return
+		}
+		shares = append(shares, share)
+	}
+
+	secret, err := shamir.Combine(shares)
+	if err != nil {
+		fmt.Println("Error recovering key:", err)
+		return
+	}
+
+	signer, err := crypto.ParseSigner(secret)
+	if err != nil {
+		fmt.Println("Error: recovered data is not a private key:", err)
+		return
+	}
+
+	if _, err := os.Stat(*outPath); err == nil {
+		fmt.Printf("Error: %s already exists, refusing to overwrite\n", *outPath)
+		return
+	}
+	if err := os.WriteFile(*outPath, secret, 0600); err != nil {
+		fmt.Println("Error writing key:", err)
+		return
+	}
+
+	fmt.Println("✓ Key recovered successfully!")
+	fmt.Printf("  Private key: %s\n", *outPath)
+	fmt.Printf("  Algorithm: %s\n", signer.Algorithm())
+	fmt.Printf("  Public key hex: %s\n", hex.EncodeToString(signer.PublicKey()))
+}
+
+// readShareText returns the share line from a share file, or the argument
+// itself when it is share text rather than a path
+func readShareText(arg string) (string, error) {
+	if strings.HasPrefix(arg, shamir.SharePrefix+":") {
+		return arg, nil
+	}
+
+	f, err := os.Open(arg)
+	if err != nil {
+		return "", fmt.Errorf("cannot read share %s: %w", arg, err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			return line, nil
+		}
+	}
+	return "", fmt.Errorf("no share found in %s", arg)
+}
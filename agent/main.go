@@ -1,10 +1,13 @@
 package main
 
 import (
-	"crypto/ed25519"
 	"encoding/hex"
+	"errors"
+	"flag"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/amshithnair/zcrypt/crypto"
@@ -22,6 +25,10 @@ func main() {
 	switch os.Args[1] {
 	case "genkey":
 		handleGenKey()
+	case "enc-genkey":
+		handleEncGenKey()
+	case "decrypt":
+		handleDecrypt()
 	case "log":
 		handleLog()
 	case "verify":
@@ -32,14 +39,78 @@ func main() {
 		handleChainStats()
 	case "chain-export":
 		handleChainExport()
+	case "chain-epoch":
+		handleChainEpoch()
+	case "chain":
+		handleChain()
+	case "chain-epochs":
+		handleChainEpochs()
+	case "chain-seal":
+		handleChainSeal()
+	case "chain-retention":
+		handleChainRetention()
+	case "chain-prune":
+		handleChainPrune()
+	case "chain-hold":
+		handleChainHold()
+	case "chain-archive":
+		handleChainArchive()
+	case "chain-restore":
+		handleChainRestore()
+	case "chain-timestamp":
+		handleChainTimestamp()
+	case "chain-checkpoints":
+		handleChainCheckpoints()
+	case "key":
+		handleKey()
 	case "send-to-server":
 		handleSendToServer()
+	case "send-batch":
+		handleSendBatch()
+	case "server-identity":
+		handleServerIdentity()
 	case "server-stats":
 		handleServerStats()
+	case "server-tail":
+		handleServerTail()
+	case "receipts":
+		handleReceipts()
+	case "receipt-verify":
+		handleReceiptVerify()
+	case "prove":
+		handleProve()
+	case "verify-evidence":
+		handleVerifyEvidence()
 	case "server-verify":
 		handleServerVerify()
+	case "server-epochs":
+		handleServerEpochs()
+	case "server-seal":
+		handleServerSeal()
 	case "register-agent":
 		handleRegisterAgent()
+	case "cosign":
+		handleCoSign()
+	case "cosign-status":
+		handleCoSignStatus()
+	case "redact":
+		handleRedact()
+	case "chain-redactors":
+		handleChainRedactors()
+	case "server-redact":
+		handleServerRedact()
+	case "disclose":
+		handleDisclose()
+	case "server-prune":
+		handleServerPrune()
+	case "server-archive":
+		handleServerArchive()
+	case "server-restore":
+		handleServerRestore()
+	case "server-timestamp":
+		handleServerTimestamp()
+	case "server-checkpoints":
+		handleServerCheckpoints()
 	default:
 		fmt.Println("Unknown command:", os.Args[1])
 		printUsage()
@@ -49,44 +120,105 @@ func main() {
 func printUsage() {
 	fmt.Println("Zcrypt - Cryptographic Log Chain CLI")
 	fmt.Println("\nLocal Commands:")
-	fmt.Println("  zcrypt genkey                          - Generate a keypair")
+	fmt.Println("  zcrypt genkey [algorithm]              - Generate a keypair (ed25519, ecdsa-p256, rsa-pss)")
+	fmt.Println("  zcrypt enc-genkey                      - Generate an X25519 key for receiving encrypted messages")
 	fmt.Println("  zcrypt log \"message\"                   - Sign and store log entry locally")
 	fmt.Println("  zcrypt verify \"message\" <signature>    - Verify a log signature")
-	fmt.Println("  zcrypt chain-verify                    - Verify entire local log chain")
+	fmt.Println("  zcrypt chain-verify [--epoch N] [--authority key] [--redactors keys] [--tsa-cert file] - Verify entire local log chain, or one epoch")
 	fmt.Println("  zcrypt chain-stats                     - Show local chain statistics")
-	fmt.Println("  zcrypt chain-export                    - Export local chain as JSON")
+	fmt.Println("  zcrypt chain-export [--epoch N]        - Export local chain, or one epoch, as JSON")
+	fmt.Println("  zcrypt chain-epoch <hash_algorithm>    - Start a new hash epoch (sha256, sha512-256, sha3-256)")
+	fmt.Println("  zcrypt chain-epochs                    - List epochs and their seals")
+	fmt.Println("  zcrypt chain-seal [hash_algorithm]     - Seal the current epoch and open the next")
+	fmt.Println("  zcrypt chain migrate [--file path] [--key file] [--dry-run]")
+	fmt.Println("                                         - Rewrite an older chain file in the newest format")
+	fmt.Println("  zcrypt redact <index> --fields f1,f2 --reason \"text\"")
+	fmt.Println("                                         - Erase committed fields of a local entry")
+	fmt.Println("  zcrypt chain-redactors [add <pubkey>...] - List or add keys allowed to sign local redactions")
+	fmt.Println("  zcrypt chain-timestamp --tsa <url>     - Timestamp the local chain head at an RFC 3161 TSA")
+	fmt.Println("  zcrypt chain-checkpoints [--tsa-cert file] - List and verify local timestamped checkpoints")
+	fmt.Println("\nRetention:")
+	fmt.Println("  zcrypt chain-retention [--max-age 90d] [--max-entries N] [--max-bytes N] [--whole-epochs] [--clear]")
+	fmt.Println("                                         - Show or set the local retention policy")
+	fmt.Println("  zcrypt chain-prune [limits] [--archive dir] [--dry-run]")
+	fmt.Println("                                         - Prune old entries behind a signed anchor")
+	fmt.Println("  zcrypt chain-hold add <start> [end] --reason \"text\" | release <id> | list")
+	fmt.Println("                                         - Manage legal holds that block pruning")
+	fmt.Println("  zcrypt chain-archive (--before <index> | --keep N) [--compression zstd|gzip]")
+	fmt.Println("                                         - Move old entries into a compressed segment")
+	fmt.Println("  zcrypt chain-restore [--from index]    - Move archived entries back into the chain file")
+	fmt.Println("\nKey Backup:")
+	fmt.Println("  zcrypt key split [--key file] [--shares N] [--threshold K] [--out dir]")
+	fmt.Println("                                         - Split a private key into N shares, K needed to recover")
+	fmt.Println("  zcrypt key recover [--out file] <share>... - Rebuild a private key from K shares")
 	fmt.Println("\nServer Commands:")
 	fmt.Println("  zcrypt send-to-server \"message\"        - Send log to central server")
+	fmt.Println("      [--cosigners key1,key2 --threshold N]  - Require N co-signatures before it counts")
+	fmt.Println("      [--confidential]                   - Send only a commitment, keep the message locally")
+	fmt.Println("      [--recipients a1,a2]               - Encrypt the message to auditors' X25519 keys")
+	fmt.Println("  zcrypt send-batch [file] [--size N] [--interval 1s] - Send one log per line, batched")
+	fmt.Println("  zcrypt decrypt <index|message> [--key file] - Decrypt a message encrypted to your key")
+	fmt.Println("  zcrypt disclose <index>                - Reveal a confidential server entry's message")
+	fmt.Println("  zcrypt server-identity [--trust key]   - Show the server's signed identity, or re-pin it")
 	fmt.Println("  zcrypt server-stats                    - Get server statistics")
-	fmt.Println("  zcrypt server-verify                   - Verify server chain integrity")
+	fmt.Println("  zcrypt server-tail [--from N] [--agent id] - Follow new server entries, verifying each")
+	fmt.Println("  zcrypt receipts                        - List receipts saved for server submissions")
+	fmt.Println("  zcrypt receipt-verify [index...] [--checkpoint latest|N|head]")
+	fmt.Println("                                         - Check saved receipts with fresh inclusion proofs")
+	fmt.Println("  zcrypt prove <index|request-id> [--out file] [--offline]")
+	fmt.Println("                                         - Write evidence that the server accepted a submission")
+	fmt.Println("  zcrypt verify-evidence <file> [--server-key hex]")
+	fmt.Println("                                         - Check acceptance evidence offline")
+	fmt.Println("  zcrypt server-verify [--epoch N]       - Verify server chain integrity, or one epoch")
+	fmt.Println("  zcrypt server-epochs                   - List server epochs and their seals")
+	fmt.Println("  zcrypt server-seal [hash_algorithm]    - Seal the server's current epoch (needs ZCRYPT_ADMIN_TOKEN)")
 	fmt.Println("  zcrypt register-agent <id> <name>      - Register this agent with server")
+	fmt.Println("  zcrypt cosign <index>                  - Co-sign a server entry")
+	fmt.Println("  zcrypt cosign-status <index>           - Show co-signature status of a server entry")
+	fmt.Println("  zcrypt server-redact <index> --fields f1,f2 --reason \"text\"")
+	fmt.Println("                                         - Erase fields of a server entry (needs ZCRYPT_ADMIN_TOKEN)")
+	fmt.Println("  zcrypt server-prune [limits] [--dry-run] - Prune the server chain (needs ZCRYPT_ADMIN_TOKEN)")
+	fmt.Println("  zcrypt server-archive --keep N [--compression zstd|gzip]")
+	fmt.Println("                                         - Archive old server entries (needs ZCRYPT_ADMIN_TOKEN)")
+	fmt.Println("  zcrypt server-restore [--from index]   - Restore archived server entries (needs ZCRYPT_ADMIN_TOKEN)")
+	fmt.Println("  zcrypt server-timestamp                - Timestamp the server chain head now (needs ZCRYPT_ADMIN_TOKEN)")
+	fmt.Println("  zcrypt server-checkpoints [--tsa-cert file] - List and verify server checkpoints offline")
 }
 
 func handleGenKey() {
-	pub, priv, err := ed25519.GenerateKey(nil)
+	algorithm := crypto.DefaultAlgorithm
+	if len(os.Args) >= 3 {
+		algorithm = os.Args[2]
+	}
+
+	signer, err := crypto.GenerateSigner(algorithm)
 	if err != nil {
 		fmt.Println("Error generating key:", err)
+		fmt.Printf("Supported algorithms: %v\n", crypto.SupportedAlgorithms())
 		return
 	}
 
 	os.MkdirAll(os.Getenv("HOME")+"/.zcrypt", 0700)
-	
-	err = os.WriteFile("zcrypt_private.key", priv, 0600)
-	if err != nil {
-		fmt.Println("Error saving private key:", err)
-		return
-	}
 
-	err = os.WriteFile("zcrypt_public.key", pub, 0644)
-	if err != nil {
-		fmt.Println("Error saving public key:", err)
+	if err := crypto.SaveSigner(signer, crypto.PrivateKeyFile, crypto.PublicKeyFile); err != nil {
+		fmt.Println("Error saving keypair:", err)
 		return
 	}
 
 	fmt.Println("✓ Keypair generated successfully!")
+	fmt.Printf("Algorithm: %s\n", signer.Algorithm())
 	fmt.Println("Private key: zcrypt_private.key")
 	fmt.Println("Public key: zcrypt_public.key")
-	fmt.Printf("Public key hex: %s\n", hex.EncodeToString(pub))
+	fmt.Printf("Public key hex: %s\n", hex.EncodeToString(signer.PublicKey()))
+}
+
+// loadSigner loads the local private key, whichever algorithm it uses
+func loadSigner() (crypto.Signer, error) {
+	signer, err := crypto.LoadSigner(crypto.PrivateKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("private key not found or unreadable. Run 'zcrypt genkey' first (%v)", err)
+	}
+	return signer, nil
 }
 
 func handleLog() {
@@ -96,51 +228,63 @@ func handleLog() {
 	}
 
 	message := os.Args[2]
-	privKey, err := os.ReadFile("zcrypt_private.key")
+	signer, err := loadSigner()
 	if err != nil {
-		fmt.Println("Error: Private key not found. Run 'zcrypt genkey' first.")
+		fmt.Println("Error:", err)
 		return
 	}
 
-	pubKey, err := os.ReadFile("zcrypt_public.key")
+	chain, entry, err := appendLocal(signer, message, agentMetadata())
 	if err != nil {
-		fmt.Println("Error: Public key not found.")
+		fmt.Println("Error adding log:", err)
 		return
 	}
 
-	signature := ed25519.Sign(ed25519.PrivateKey(privKey), []byte(message))
-	sigHex := hex.EncodeToString(signature)
+	fmt.Println("✓ Log entry added to local chain")
+	fmt.Printf("  Message: %s\n", message)
+	fmt.Printf("  Signature: %s\n", entry.Signature[:32]+"...")
+	fmt.Printf("  Hash: %s\n", entry.CurrentHash[:32]+"...")
+	fmt.Printf("  Prev Hash: %s\n", entry.PrevHash[:min(len(entry.PrevHash), 32)]+"...")
+	fmt.Printf("  Chain length: %d\n", len(chain.Entries))
+}
 
-	chainPath := crypto.GetChainPath()
-	os.MkdirAll(os.Getenv("HOME")+"/.zcrypt", 0700)
-	
-	chain, err := crypto.NewLogChain(chainPath)
+// agentMetadata describes where a log was written
+func agentMetadata() map[string]interface{} {
+	hostname, _ := os.Hostname()
+	return map[string]interface{}{
+		"user":     os.Getenv("USER"),
+		"hostname": hostname,
+	}
+}
+
+// appendLocal signs message in a local-chain envelope and appends it to the local chain
+func appendLocal(signer crypto.Signer, message string, metadata map[string]interface{}) (*crypto.LogChain, *crypto.LogEntry, error) {
+	env, err := crypto.NewEnvelope(agentID(), crypto.LocalChainName, metadata)
 	if err != nil {
-		fmt.Println("Error initializing chain:", err)
-		return
+		return nil, nil, fmt.Errorf("creating envelope: %w", err)
+	}
+	sigHex, err := crypto.SignEnvelope(signer, message, env)
+	if err != nil {
+		return nil, nil, fmt.Errorf("signing message: %w", err)
 	}
 
-	hostname, _ := os.Hostname()
-	entry, err := chain.AddLog(
-		message,
-		sigHex,
-		hex.EncodeToString(pubKey),
-		map[string]interface{}{
-			"user":     os.Getenv("USER"),
-			"hostname": hostname,
-		},
-	)
+	chain, err := crypto.NewLogChain(crypto.GetChainPath())
 	if err != nil {
-		fmt.Println("Error adding log:", err)
-		return
+		return nil, nil, fmt.Errorf("initializing chain: %w", err)
 	}
 
-	fmt.Println("✓ Log entry added to local chain")
-	fmt.Printf("  Message: %s\n", message)
-	fmt.Printf("  Signature: %s\n", sigHex[:32]+"...")
-	fmt.Printf("  Hash: %s\n", entry.CurrentHash[:32]+"...")
-	fmt.Printf("  Prev Hash: %s\n", entry.PrevHash[:min(len(entry.PrevHash), 32)]+"...")
-	fmt.Printf("  Chain length: %d\n", len(chain.Entries))
+	entry, err := chain.AddEntry(crypto.LogEntry{
+		Message:   message,
+		Signature: sigHex,
+		PubKey:    hex.EncodeToString(signer.PublicKey()),
+		Algorithm: signer.Algorithm(),
+		Metadata:  metadata,
+		Envelope:  env,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return chain, entry, nil
 }
 
 func handleVerify() {
@@ -152,20 +296,25 @@ func handleVerify() {
 	message := os.Args[2]
 	sigHex := os.Args[3]
 
-	pubKey, err := os.ReadFile("zcrypt_public.key")
+	pubKey, err := os.ReadFile(crypto.PublicKeyFile)
 	if err != nil {
 		fmt.Println("Error: Public key not found.")
 		return
 	}
 
-	signature, err := hex.DecodeString(sigHex)
+	algorithm, err := crypto.PublicKeyAlgorithm(pubKey)
 	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	if _, err := hex.DecodeString(sigHex); err != nil {
 		fmt.Println("Error: Invalid signature format")
 		return
 	}
 
-	valid := ed25519.Verify(ed25519.PublicKey(pubKey), []byte(message), signature)
-	
+	valid := crypto.VerifyWithAlgorithm(algorithm, hex.EncodeToString(pubKey), []byte(message), sigHex) == nil
+
 	if valid {
 		fmt.Println("✓ Signature is VALID")
 	} else {
@@ -174,25 +323,107 @@ func handleVerify() {
 }
 
 func handleChainVerify() {
+	flags := flag.NewFlagSet("chain-verify", flag.ExitOnError)
+	epoch := flags.Int("epoch", -1, "verify only this epoch")
+	authority := flags.String("authority", "", "hex public key that must have signed the prune anchors and epoch seals (default: the key recorded in the chain)")
+	tsaCert := flags.String("tsa-cert", "", "PEM certificates trusted to sign checkpoints")
+	redactors := flags.String("redactors", "", "comma-separated hex public keys that may sign redactions (default: the keys recorded in the chain)")
+	flags.Parse(os.Args[2:])
+	trusted, err := loadTSACertificates(*tsaCert)
+	if err != nil {
+		fmt.Println("Error: Cannot read TSA certificates:", err)
+		return
+	}
+
+	var opts []crypto.ChainOption
+	if *authority != "" {
+		key, err := hex.DecodeString(*authority)
+		if err != nil {
+			fmt.Println("Error: Invalid authority key:", err)
+			return
+		}
+		opts = append(opts, crypto.WithAuthority(key))
+	}
+
 	chainPath := crypto.GetChainPath()
-	chain, err := crypto.NewLogChain(chainPath)
+	chain, err := crypto.NewLogChain(chainPath, opts...)
 	if err != nil {
 		fmt.Println("Error loading chain:", err)
 		return
 	}
+	chain.SetTrustedTSA(trusted)
+	if *redactors != "" {
+		chain.TrustRedactors(strings.Split(*redactors, ","))
+	}
 
-	valid, errors := chain.VerifyChain()
-	
-	if valid {
+	if *epoch >= 0 {
+		report, err := chain.VerifyEpoch(*epoch)
+		if err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+		printEpochReport(report)
+		return
+	}
+
+	report := chain.VerifyChainReport()
+
+	if report.Valid {
 		fmt.Println("✓ Chain integrity verified - all hashes valid!")
-		fmt.Printf("  Total entries: %d\n", len(chain.Entries))
+		fmt.Printf("  Total entries: %d\n", report.Total)
+		if report.Pruned > 0 {
+			fmt.Printf("  Pruned entries: %d (covered by signed anchors)\n", report.Pruned)
+		}
 	} else {
 		fmt.Println("✗ Chain integrity COMPROMISED!")
 		fmt.Println("  Errors found:")
-		for _, e := range errors {
+		for _, e := range report.Errors {
 			fmt.Printf("    - %s\n", e)
 		}
 	}
+	printCoSignReport(report.CoSign)
+	printRedactionReport(report.Redacted)
+	printWithheldReport(report.Withheld)
+	printClockReport(report.Clock)
+	printUntrustedCheckpoints(report.UntrustedCheckpoints, "pass --tsa-cert")
+}
+
+// printCoSignReport lists approval status for entries that require co-signers
+func printCoSignReport(statuses []crypto.CoSignStatus) {
+	if len(statuses) == 0 {
+		return
+	}
+	fmt.Println("\nCo-signed entries:")
+	for _, status := range statuses {
+		printCoSignStatus(status)
+	}
+}
+
+func printCoSignStatus(status crypto.CoSignStatus) {
+	mark := "✗"
+	if status.Met {
+		mark = "✓"
+	}
+	fmt.Printf("  %s [%d] %d/%d approvals (threshold %d)\n",
+		mark, status.Index, len(status.SignedBy), len(status.Signers), status.Threshold)
+	for _, key := range status.SignedBy {
+		fmt.Printf("      signed by %s...\n", key[:min(len(key), 32)])
+	}
+}
+
+// printClockReport lists entries flagged for clock regression or skew
+func printClockReport(statuses []crypto.ClockStatus) {
+	if len(statuses) == 0 {
+		return
+	}
+	fmt.Println("\nClock-flagged entries:")
+	for _, status := range statuses {
+		fmt.Printf("  [%d] %v received %s", status.Index, status.Flags, status.Timestamp.Format(time.RFC3339))
+		if status.ClaimedAt != nil {
+			fmt.Printf(", claimed %s (skew %s)", status.ClaimedAt.Format(time.RFC3339), status.Skew().Round(time.Second))
+		}
+		fmt.Println()
+	}
 }
 
 func handleChainStats() {
@@ -204,23 +435,34 @@ func handleChainStats() {
 	}
 
 	stats := chain.Stats()
-	
+
 	fmt.Println("Local Chain Statistics:")
 	fmt.Printf("  Total entries: %d\n", stats["total_entries"])
+	fmt.Printf("  Hash algorithm: %s (%d epochs)\n", stats["hash_algorithm"], stats["epochs"])
+	fmt.Printf("  Format version: %d\n", stats["format_version"])
 	fmt.Printf("  Last hash: %s\n", stats["last_hash"].(string)[:min(len(stats["last_hash"].(string)), 64)])
-	
+
 	if stats["first_timestamp"] != nil {
 		fmt.Printf("  First entry: %s\n", stats["first_timestamp"].(time.Time).Format("2006-01-02 15:04:05"))
 		fmt.Printf("  Last entry: %s\n", stats["last_timestamp"].(time.Time).Format("2006-01-02 15:04:05"))
 	}
 
-	if len(chain.Entries) > 0 {
+	if archived := stats["archived_entries"].(int); archived > 0 {
+		fmt.Printf("  Archived entries: %d (in %d segments)\n", archived, stats["archive_segments"])
+	}
+	if pruned := stats["pruned_entries"].(int); pruned > 0 {
+		fmt.Printf("  Pruned entries: %d (behind %d anchors)\n", pruned, len(chain.Header.Anchors))
+	}
+	if regressed, skewed := stats["clock_regressed"].(int), stats["clock_skewed"].(int); regressed+skewed > 0 {
+		fmt.Printf("  Clock flags: %d regressed, %d skewed (see chain-verify)\n", regressed, skewed)
+	}
+
+	recent, _, total := chain.GetEntriesPage(chain.Length()-5, 5)
+	if len(recent) > 0 {
 		fmt.Println("\nRecent entries (last 5):")
-		start := max(0, len(chain.Entries)-5)
-		for i := start; i < len(chain.Entries); i++ {
-			entry := chain.Entries[i]
-			fmt.Printf("  [%d] %s - %s\n", 
-				i+1, 
+		for i, entry := range recent {
+			fmt.Printf("  [%d] %s - %s\n",
+				total-len(recent)+i+1,
 				entry.Timestamp.Format("15:04:05"),
 				entry.Message)
 		}
@@ -228,6 +470,10 @@ func handleChainStats() {
 }
 
 func handleChainExport() {
+	flags := flag.NewFlagSet("chain-export", flag.ExitOnError)
+	epoch := flags.Int("epoch", -1, "export only this epoch")
+	flags.Parse(os.Args[2:])
+
 	chainPath := crypto.GetChainPath()
 	chain, err := crypto.NewLogChain(chainPath)
 	if err != nil {
@@ -235,6 +481,16 @@ func handleChainExport() {
 		return
 	}
 
+	if *epoch >= 0 {
+		entries, err := chain.EpochEntries(*epoch)
+		if err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+		writeEpochExport(*epoch, entries)
+		return
+	}
+
 	json, err := chain.ExportJSON()
 	if err != nil {
 		fmt.Println("Error exporting chain:", err)
@@ -252,14 +508,60 @@ func handleChainExport() {
 	fmt.Printf("  Total entries: %d\n", len(chain.Entries))
 }
 
+func handleChainEpoch() {
+	if len(os.Args) < 3 {
+		fmt.Println("Usage: zcrypt chain-epoch <hash_algorithm>")
+		fmt.Printf("Supported: %v\n", crypto.SupportedHashAlgorithms())
+		return
+	}
+
+	chain, err := crypto.NewLogChain(crypto.GetChainPath())
+	if err != nil {
+		fmt.Println("Error loading chain:", err)
+		return
+	}
+
+	previous := chain.HashAlgorithm()
+	epoch, err := chain.StartEpoch(os.Args[2])
+	if err != nil {
+		fmt.Println("Error starting epoch:", err)
+		return
+	}
+
+	fmt.Printf("✓ Epoch %d started\n", epoch.Number)
+	fmt.Printf("  Hash algorithm: %s -> %s\n", previous, epoch.HashAlgorithm)
+	fmt.Printf("  Starts at entry: %d\n", epoch.StartIndex)
+	fmt.Printf("  Links to head: %s\n", epoch.PrevHead[:min(len(epoch.PrevHead), 32)])
+}
+
 func handleSendToServer() {
 	if len(os.Args) < 3 {
-		fmt.Println("Usage: zcrypt send-to-server \"message\"")
+		fmt.Println("Usage: zcrypt send-to-server \"message\" [--cosigners key1,key2 --threshold N] [--confidential | --recipients a1,a2]")
 		return
 	}
 
 	message := os.Args[2]
-	
+
+	flags := flag.NewFlagSet("send-to-server", flag.ExitOnError)
+	cosigners := flags.String("cosigners", "", "comma-separated co-signer public keys (hex or key file paths)")
+	threshold := flags.Int("threshold", 0, "number of co-signatures required")
+	confidential := flags.Bool("confidential", false, "send only a commitment; keep the message in the local chain")
+	recipientList := flags.String("recipients", "", "comma-separated auditors to encrypt to (agent IDs, hex X25519 keys or key files)")
+	flags.Parse(os.Args[3:])
+
+	var opts []utils.SubmissionOption
+	if *cosigners != "" {
+		keys, err := parseCoSigners(*cosigners)
+		if err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+		if *threshold == 0 {
+			*threshold = len(keys)
+		}
+		opts = append(opts, utils.WithCoSigners(keys, *threshold))
+	}
+
 	// Get server URL from env or use default
 	serverURL := os.Getenv("ZCRYPT_SERVER")
 	if serverURL == "" {
@@ -267,29 +569,14 @@ func handleSendToServer() {
 	}
 
 	// Load keys
-	privKey, err := os.ReadFile("zcrypt_private.key")
-	if err != nil {
-		fmt.Println("Error: Private key not found. Run 'zcrypt genkey' first.")
-		return
-	}
-
-	pubKey, err := os.ReadFile("zcrypt_public.key")
+	signer, err := loadSigner()
 	if err != nil {
-		fmt.Println("Error: Public key not found.")
+		fmt.Println("Error:", err)
 		return
 	}
 
-	// Sign message
-	signature := ed25519.Sign(ed25519.PrivateKey(privKey), []byte(message))
-	sigHex := hex.EncodeToString(signature)
-	pubKeyHex := hex.EncodeToString(pubKey)
-
-	// Get agent ID
-	hostname, _ := os.Hostname()
-	agentID := fmt.Sprintf("%s-%s", os.Getenv("USER"), hostname)
-
 	// Create client
-	client := utils.NewLogClient(serverURL)
+	client := newServerClient()
 
 	// Check server health
 	healthy, err := client.HealthCheck()
@@ -298,19 +585,36 @@ func handleSendToServer() {
 		return
 	}
 
-	// Submit log
-	submission := utils.LogSubmission{
-		Message:   message,
-		Signature: sigHex,
-		PubKey:    pubKeyHex,
-		AgentID:   agentID,
-		Metadata: map[string]interface{}{
-			"user":     os.Getenv("USER"),
-			"hostname": hostname,
-		},
+	// Sign message inside an envelope bound to the server chain
+	var submission utils.LogSubmission
+	var salt string
+	if *confidential && *recipientList != "" {
+		fmt.Println("Error: --confidential and --recipients cannot be combined")
+		return
+	}
+	if *confidential {
+		submission, salt, err = client.SignConfidentialSubmission(signer, agentID(), message, agentMetadata(), opts...)
+	} else if *recipientList != "" {
+		recipients, rerr := parseRecipients(client, *recipientList)
+		if rerr != nil {
+			fmt.Println("Error:", rerr)
+			return
+		}
+		submission, err = client.SignEncryptedSubmission(signer, agentID(), message, recipients, agentMetadata(), opts...)
+	} else {
+		submission, err = client.SignSubmission(signer, agentID(), message, agentMetadata(), opts...)
+	}
+	if err != nil {
+		fmt.Println("Error signing message:", err)
+		return
 	}
 
+	// Submit log
 	resp, err := client.SubmitLog(submission)
+	receiptErr := err
+	if errors.Is(err, utils.ErrInvalidReceipt) {
+		err = nil
+	}
 	if err != nil {
 		fmt.Println("Error submitting log:", err)
 		return
@@ -318,7 +622,109 @@ func handleSendToServer() {
 
 	fmt.Println("✓ Log submitted to server successfully!")
 	fmt.Printf("  Server URL: %s\n", serverURL)
+	fmt.Printf("  Entry index: %d\n", resp.Index)
 	fmt.Printf("  Chain length on server: %d\n", resp.ChainLength)
+	if receiptErr != nil {
+		fmt.Println("  ⚠️  No valid receipt - the server's acceptance cannot be proven:", receiptErr)
+	} else if entry, err := resp.AppendedEntry(); err != nil {
+		fmt.Println("  Warning: receipt not saved:", err)
+	} else if err := saveReceipt(serverURL, resp.Receipt, submission, entry); err != nil {
+		fmt.Println("  Warning: receipt not saved:", err)
+	} else {
+		fmt.Println("  Receipt saved; prove acceptance with: zcrypt prove", resp.Index)
+	}
+	if len(opts) > 0 {
+		fmt.Printf("  Awaiting %d co-signature(s): zcrypt cosign %d\n", *threshold, resp.Index)
+	}
+	if *confidential {
+		recordConfidential(signer, message, salt, resp)
+	}
+}
+
+// parseCoSigners accepts hex public keys or paths to public key files
+func parseCoSigners(list string) ([]string, error) {
+	var keys []string
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if data, err := os.ReadFile(item); err == nil {
+			keys = append(keys, hex.EncodeToString(data))
+			continue
+		}
+		if _, err := hex.DecodeString(item); err != nil {
+			return nil, fmt.Errorf("co-signer %q is neither a key file nor a hex public key", item)
+		}
+		keys = append(keys, item)
+	}
+	return keys, nil
+}
+
+func handleCoSign() {
+	if len(os.Args) < 3 {
+		fmt.Println("Usage: zcrypt cosign <index>")
+		return
+	}
+
+	index, err := strconv.Atoi(os.Args[2])
+	if err != nil {
+		fmt.Println("Error: index must be a number")
+		return
+	}
+
+	signer, err := loadSigner()
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	client := newServerClient()
+	status, err := client.CoSign(signer, agentID(), index)
+	if err != nil {
+		fmt.Println("Error co-signing entry:", err)
+		return
+	}
+
+	fmt.Printf("✓ Entry %d co-signed\n", index)
+	printCoSignStatus(*status)
+}
+
+func handleCoSignStatus() {
+	if len(os.Args) < 3 {
+		fmt.Println("Usage: zcrypt cosign-status <index>")
+		return
+	}
+
+	index, err := strconv.Atoi(os.Args[2])
+	if err != nil {
+		fmt.Println("Error: index must be a number")
+		return
+	}
+
+	status, err := newServerClient().CoSignStatus(index)
+	if err != nil {
+		fmt.Println("Error getting co-sign status:", err)
+		return
+	}
+
+	printCoSignStatus(*status)
+}
+
+// newServerClient creates a client for ZCRYPT_SERVER and ZCRYPT_CHAIN_NAME
+func newServerClient() *utils.LogClient {
+	serverURL := os.Getenv("ZCRYPT_SERVER")
+	if serverURL == "" {
+		serverURL = DEFAULT_SERVER
+	}
+
+	client := utils.NewLogClient(serverURL)
+	if chainName := os.Getenv("ZCRYPT_CHAIN_NAME"); chainName != "" {
+		client.Chain = chainName
+	}
+	client.ServerKey = os.Getenv("ZCRYPT_SERVER_PUBKEY")
+	pinServer(client)
+	return client
 }
 
 func handleServerStats() {
@@ -347,27 +753,41 @@ func handleServerVerify() {
 	}
 
 	client := utils.NewLogClient(serverURL)
-	resp, err := client.VerifyChain()
+
+	flags := flag.NewFlagSet("server-verify", flag.ExitOnError)
+	epoch := flags.Int("epoch", -1, "verify only this epoch")
+	flags.Parse(os.Args[2:])
+	if *epoch >= 0 {
+		report, err := client.VerifyEpoch(*epoch)
+		if err != nil {
+			fmt.Println("Error verifying server epoch:", err)
+			return
+		}
+		printEpochReport(report)
+		return
+	}
+
+	report, err := client.VerifyChainReport()
 	if err != nil {
 		fmt.Println("Error verifying server chain:", err)
 		return
 	}
 
-	if resp.Data != nil {
-		valid, _ := resp.Data["valid"].(bool)
-		if valid {
-			fmt.Println("✓ Server chain integrity verified!")
-			fmt.Printf("  Total entries: %.0f\n", resp.Data["total"].(float64))
-		} else {
-			fmt.Println("✗ Server chain integrity COMPROMISED!")
-			if errors, ok := resp.Data["errors"].([]interface{}); ok {
-				fmt.Println("  Errors found:")
-				for _, e := range errors {
-					fmt.Printf("    - %s\n", e)
-				}
-			}
+	if report.Valid {
+		fmt.Println("✓ Server chain integrity verified!")
+		fmt.Printf("  Total entries: %d\n", report.Total)
+	} else {
+		fmt.Println("✗ Server chain integrity COMPROMISED!")
+		fmt.Println("  Errors found:")
+		for _, e := range report.Errors {
+			fmt.Printf("    - %s\n", e)
 		}
 	}
+	printCoSignReport(report.CoSign)
+	printRedactionReport(report.Redacted)
+	printWithheldReport(report.Withheld)
+	printClockReport(report.Clock)
+	printUntrustedCheckpoints(report.UntrustedCheckpoints, "check them with server-checkpoints --tsa-cert")
 }
 
 func handleRegisterAgent() {
@@ -384,14 +804,26 @@ func handleRegisterAgent() {
 		serverURL = DEFAULT_SERVER
 	}
 
-	pubKey, err := os.ReadFile("zcrypt_public.key")
+	signer, err := loadSigner()
 	if err != nil {
-		fmt.Println("Error: Public key not found. Run 'zcrypt genkey' first.")
+		fmt.Println("Error:", err)
 		return
 	}
 
+	// Publish the encryption key too, if this agent has one
+	encPubKey := ""
+	if key, err := crypto.LoadEncryptionKey(crypto.EncryptionKeyFile); err == nil {
+		encPubKey = hex.EncodeToString(key.PublicKey().Bytes())
+	}
+
 	client := utils.NewLogClient(serverURL)
-	err = client.RegisterAgent(agentID, hex.EncodeToString(pubKey), name)
+	err = client.RegisterAgent(utils.AgentRegistration{
+		AgentID:   agentID,
+		PubKey:    hex.EncodeToString(signer.PublicKey()),
+		Algorithm: signer.Algorithm(),
+		Name:      name,
+		EncPubKey: encPubKey,
+	})
 	if err != nil {
 		fmt.Println("Error registering agent:", err)
 		return
@@ -400,9 +832,19 @@ func handleRegisterAgent() {
 	fmt.Println("✓ Agent registered successfully!")
 	fmt.Printf("  Agent ID: %s\n", agentID)
 	fmt.Printf("  Name: %s\n", name)
+	fmt.Printf("  Algorithm: %s\n", signer.Algorithm())
+	if encPubKey != "" {
+		fmt.Printf("  Encryption key: %s\n", encPubKey)
+	}
 	fmt.Printf("  Server: %s\n", serverURL)
 }
 
+// agentID identifies this agent as user-hostname
+func agentID() string {
+	hostname, _ := os.Hostname()
+	return fmt.Sprintf("%s-%s", os.Getenv("USER"), hostname)
+}
+
 func min(a, b int) int {
 	if a < b {
 		return a
@@ -415,4 +857,4 @@ func max(a, b int) int {
 		return a
 	}
 	return b
-}
\ No newline at end of file
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/amshithnair/zcrypt/crypto"
+)
+
+func handleChain() {
+	if len(os.Args) < 3 {
+		fmt.Println("Usage: zcrypt chain migrate [--file path] [--key file] [--dry-run]")
+		return
+	}
+
+	switch os.Args[2] {
+	case "migrate":
+		handleChainMigrate(os.Args[3:])
+	default:
+		fmt.Println("Unknown chain command:", os.Args[2])
+		fmt.Println("Usage: zcrypt chain migrate [--file path] [--key file] [--dry-run]")
+	}
+}
+
+// Rewrite a chain file in the newest format with a signed migration record
+func handleChainMigrate(args []string) {
+	flags := flag.NewFlagSet("chain migrate", flag.ExitOnError)
+	path := flags.String("file", crypto.GetChainPath(), "chain file to migrate, e.g. server_logs.chain")
+	keyPath := flags.String("key", crypto.PrivateKeyFile, "private key that signs the migration record")
+	dryRun := flags.Bool("dry-run", false, "only report the chain's format version")
+	flags.Parse(args)
+
+	if _, err := os.Stat(*path); err != nil {
+		fmt.Println("Error: no chain file at", *path)
+		return
+	}
+	chain, err := crypto.NewLogChain(*path)
+	if err != nil {
+		fmt.Println("Error loading chain:", err)
+		return
+	}
+
+	version := chain.FormatVersion()
+	if *dryRun {
+		fmt.Printf("Chain %s is format version %d (newest is %d)\n", *path, version, crypto.FormatVersion)
+		return
+	}
+
+	signer, err := crypto.LoadSigner(*keyPath)
+	if err != nil {
+		fmt.Println("Error loading signing key:", err)
+		return
+	}
+
+	record, err := chain.Migrate(signer)
+	if errors.Is(err, crypto.ErrUpToDate) {
+		fmt.Printf("Chain %s is already format version %d\n", *path, version)
+		return
+	}
+	if err != nil {
+		fmt.Println("Error migrating chain:", err)
+		return
+	}
+
+	ref := record.Migrates
+	fmt.Printf("✓ Migrated %s from format version %d to %d\n", *path, ref.FromVersion, ref.ToVersion)
+	fmt.Printf("  Old head: %s (%d entries)\n", ref.OldHead[:min(len(ref.OldHead), 32)], ref.OldLength)
+	fmt.Printf("  Old file SHA-256: %s\n", ref.OldSHA256[:32]+"...")
+	fmt.Printf("  Backup: %s\n", ref.Backup)
+	fmt.Printf("  Migration record: entry %d\n", ref.OldLength)
+}
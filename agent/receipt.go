@@ -0,0 +1,357 @@
+package main
+
+import (
+	"bufio"
+	"crypto/x509"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/amshithnair/zcrypt/crypto"
+	"github.com/amshithnair/zcrypt/utils"
+)
+
+// savedReceipt is a server receipt kept by the agent, one per line of the
+// receipts file, with the submission it countersigns and the entry as the
+// server stored it. Receipts saved before countersigning have neither.
+type savedReceipt struct {
+	Server     string           `json:"server"`
+	SavedAt    time.Time        `json:"saved_at"`
+	Receipt    crypto.Receipt   `json:"receipt"`
+	Submission *crypto.LogEntry `json:"submission,omitempty"`
+	Entry      *crypto.LogEntry `json:"entry,omitempty"`
+}
+
+// receiptsPath returns the file receipts are appended to
+func receiptsPath() string {
+	return filepath.Join(filepath.Dir(crypto.GetChainPath()), "receipts.jsonl")
+}
+
+// saveReceipt appends a receipt the client has verified for submission,
+// appended as entry, to the receipts file
+func saveReceipt(server string, receipt *crypto.Receipt, submission utils.LogSubmission, entry *crypto.LogEntry) error {
+	line, err := json.Marshal(savedReceipt{
+		Server:  server,
+		SavedAt: time.Now().UTC(),
+		Receipt: *receipt,
+		Submission: &crypto.LogEntry{
+			Message:   submission.Message,
+			Signature: submission.Signature,
+			PubKey:    submission.PubKey,
+			Algorithm: submission.Algorithm,
+			Envelope:  submission.Envelope,
+		},
+		Entry: entry,
+	})
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(receiptsPath(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.Write(append(line, '\n'))
+	return err
+}
+
+// loadReceipts reads every saved receipt, oldest first
+func loadReceipts() ([]savedReceipt, error) {
+	file, err := os.Open(receiptsPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var receipts []savedReceipt
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var saved savedReceipt
+		if err := json.Unmarshal(scanner.Bytes(), &saved); err != nil {
+			return nil, fmt.Errorf("malformed receipt line %d: %w", len(receipts)+1, err)
+		}
+		receipts = append(receipts, saved)
+	}
+	return receipts, scanner.Err()
+}
+
+// List saved receipts
+func handleReceipts() {
+	receipts, err := loadReceipts()
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	if len(receipts) == 0 {
+		fmt.Println("No receipts saved yet")
+		return
+	}
+	for _, saved := range receipts {
+		r := saved.Receipt
+		fmt.Printf("  [%d] %s  %s...  saved %s\n", r.Index, saved.Server, r.EntryHash[:16], saved.SavedAt.Format(time.RFC3339))
+		if r.RequestID != "" {
+			fmt.Printf("        request %s\n", r.RequestID)
+		}
+	}
+	fmt.Printf("\n%d receipt(s) in %s\n", len(receipts), receiptsPath())
+}
+
+// Re-verify saved receipts: each signature offline, then with fresh
+// inclusion proofs that the entry and the head it was appended under are
+// still in the server chain
+func handleReceiptVerify() {
+	flags := flag.NewFlagSet("receipt-verify", flag.ExitOnError)
+	checkpoint := flags.String("checkpoint", "", "prove against this checkpoint: latest, a number, or head (default: latest, or head if it does not cover the entry)")
+	tsaCert := flags.String("tsa-cert", "", "PEM certificates trusted to sign checkpoints")
+	flags.Parse(os.Args[2:])
+
+	trusted, err := loadTSACertificates(*tsaCert)
+	if err != nil {
+		fmt.Println("Error reading TSA certificates:", err)
+		return
+	}
+	receipts, err := loadReceipts()
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	// Only the given indices, when any are named
+	wanted := map[int]bool{}
+	for _, arg := range flags.Args() {
+		index, err := strconv.Atoi(arg)
+		if err != nil {
+			fmt.Println("Error: index must be a number")
+			return
+		}
+		wanted[index] = true
+	}
+
+	checked, failed := 0, 0
+	for _, saved := range receipts {
+		if len(wanted) > 0 && !wanted[saved.Receipt.Index] {
+			continue
+		}
+		checked++
+		client := utils.NewLogClient(saved.Server)
+		pinServer(client)
+		if problem := checkReceipt(client, saved.Receipt, *checkpoint, trusted); problem != "" {
+			failed++
+			fmt.Printf("  ✗ [%d] %s\n", saved.Receipt.Index, problem)
+		} else {
+			fmt.Printf("  ✓ [%d] still in the chain at %s\n", saved.Receipt.Index, saved.Server)
+		}
+	}
+
+	switch {
+	case checked == 0:
+		fmt.Println("No matching receipts")
+	case failed > 0:
+		fmt.Printf("\n✗ %d of %d receipt(s) failed\n", failed, checked)
+		os.Exit(1)
+	default:
+		fmt.Printf("\n✓ %d receipt(s) verified\n", checked)
+	}
+}
+
+// checkReceipt verifies one receipt against the server and returns what is
+// wrong with it, or "" when it holds
+func checkReceipt(client *utils.LogClient, r crypto.Receipt, checkpoint string, trusted []*x509.Certificate) string {
+	if err := r.Verify(); err != nil {
+		return fmt.Sprintf("receipt signature: %v", err)
+	}
+	if client.ServerKey != "" && r.PubKey != client.ServerKey {
+		return "receipt is signed by a key other than the pinned server identity"
+	}
+
+	// The entry, and the head the receipt names, must both be leaves of the
+	// current tree, which the same server key signs
+	for _, leaf := range []struct {
+		index int
+		hash  string
+	}{{r.Index, r.EntryHash}, {r.Head.Length - 1, r.Head.Hash}} {
+		proof, err := fetchProof(client, leaf.index, checkpoint)
+		if err != nil {
+			return fmt.Sprintf("no proof for entry %d: %v", leaf.index, err)
+		}
+		if proof.EntryHash != leaf.hash {
+			return fmt.Sprintf("entry %d has been replaced", leaf.index)
+		}
+		if err := proof.Verify(trusted); err != nil {
+			return fmt.Sprintf("proof for entry %d: %v", leaf.index, err)
+		}
+		if proof.TreeHead.PubKey != r.PubKey {
+			return "the chain is now signed by a different server key"
+		}
+		if leaf.index == r.Head.Length-1 {
+			break
+		}
+	}
+	return ""
+}
+
+// fetchProof fetches an inclusion proof for index against checkpoint, or
+// when none is named, against the latest checkpoint and failing that the head
+func fetchProof(client *utils.LogClient, index int, checkpoint string) (*crypto.InclusionProof, error) {
+	proof, err := client.InclusionProof(index, checkpoint)
+	if err != nil && checkpoint == "" {
+		proof, err = client.InclusionProof(index, "head")
+	}
+	return proof, err
+}
+
+// Write evidence that the server accepted a submission, found among the
+// saved receipts by index or request ID: the receipt, the submission it
+// countersigns, the stored entry and, unless offline, a fresh inclusion proof
+func handleProve() {
+	usage := "Usage: zcrypt prove <index|request-id> [--out file] [--offline] [--compact] [--checkpoint latest|N|head] [--tsa-cert file]"
+	if len(os.Args) < 3 {
+		fmt.Println(usage)
+		return
+	}
+	flags := flag.NewFlagSet("prove", flag.ExitOnError)
+	out := flags.String("out", "", "write the evidence to this file instead of stdout")
+	offline := flags.Bool("offline", false, "leave out the inclusion proof")
+	compact := flags.Bool("compact", false, "print only the receipt, as a single token")
+	checkpoint := flags.String("checkpoint", "", "prove against this checkpoint: latest, a number, or head (default: latest, or head if it does not cover the entry)")
+	tsaCert := flags.String("tsa-cert", "", "PEM certificates trusted to sign checkpoints")
+	flags.Parse(os.Args[3:])
+
+	receipts, err := loadReceipts()
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	// The latest receipt for a request ID wins, as retries share one
+	var saved *savedReceipt
+	wanted := os.Args[2]
+	for i := range receipts {
+		r := receipts[i].Receipt
+		if r.RequestID == wanted || strconv.Itoa(r.Index) == wanted {
+			saved = &receipts[i]
+		}
+	}
+	if saved == nil {
+		fmt.Printf("Error: no saved receipt for %s\n", wanted)
+		return
+	}
+	if saved.Submission == nil {
+		fmt.Println("Error: this receipt was saved without its submission and cannot prove it")
+		return
+	}
+
+	if *compact {
+		fmt.Println(saved.Receipt.Compact())
+		return
+	}
+
+	evidence := crypto.Evidence{Receipt: saved.Receipt, Submission: *saved.Submission, Entry: saved.Entry}
+	if !*offline {
+		client := utils.NewLogClient(saved.Server)
+		pinServer(client)
+		if client.ServerKey != "" && saved.Receipt.PubKey != client.ServerKey {
+			fmt.Println("Error: the receipt is signed by a key other than the pinned server identity")
+			return
+		}
+		proof, err := fetchProof(client, saved.Receipt.Index, *checkpoint)
+		if err != nil {
+			fmt.Println("Error fetching inclusion proof (use --offline to leave it out):", err)
+			return
+		}
+		evidence.Proof = proof
+	}
+	trusted, err := loadTSACertificates(*tsaCert)
+	if err != nil {
+		fmt.Println("Error reading TSA certificates:", err)
+		return
+	}
+	if err := evidence.Verify(trusted); err != nil {
+		fmt.Println("✗ Evidence does not hold:", err)
+		os.Exit(1)
+	}
+
+	data, err := json.MarshalIndent(evidence, "", "  ")
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	if *out == "" {
+		fmt.Println(string(data))
+		return
+	}
+	if err := os.WriteFile(*out, append(data, '\n'), 0600); err != nil {
+		fmt.Println("Error writing evidence:", err)
+		return
+	}
+	printEvidence(evidence)
+	fmt.Printf("  Evidence written to %s; check it with: zcrypt verify-evidence %s\n", *out, *out)
+}
+
+// Check acceptance evidence offline, optionally against the server key the
+// receipt must be signed with
+func handleVerifyEvidence() {
+	usage := "Usage: zcrypt verify-evidence <file> [--server-key hex] [--tsa-cert file]"
+	if len(os.Args) < 3 {
+		fmt.Println(usage)
+		return
+	}
+	flags := flag.NewFlagSet("verify-evidence", flag.ExitOnError)
+	serverKey := flags.String("server-key", "", "hex key the receipt must be signed with")
+	tsaCert := flags.String("tsa-cert", "", "PEM certificates trusted to sign checkpoints")
+	flags.Parse(os.Args[3:])
+
+	data, err := os.ReadFile(os.Args[2])
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	var evidence crypto.Evidence
+	if err := json.Unmarshal(data, &evidence); err != nil {
+		fmt.Println("Error: malformed evidence:", err)
+		return
+	}
+	trusted, err := loadTSACertificates(*tsaCert)
+	if err != nil {
+		fmt.Println("Error reading TSA certificates:", err)
+		return
+	}
+
+	if err := evidence.Verify(trusted); err != nil {
+		fmt.Println("✗ Evidence does not hold:", err)
+		os.Exit(1)
+	}
+	if *serverKey != "" && evidence.Receipt.PubKey != *serverKey {
+		fmt.Println("✗ Receipt is signed by another key:", evidence.Receipt.PubKey)
+		os.Exit(1)
+	}
+	printEvidence(evidence)
+	if *serverKey == "" {
+		fmt.Println("  Server key not checked - pass --server-key to compare it")
+	}
+}
+
+// printEvidence describes verified evidence
+func printEvidence(evidence crypto.Evidence) {
+	r := evidence.Receipt
+	fmt.Println("✓ The server accepted this submission")
+	fmt.Printf("  Index: %d\n", r.Index)
+	fmt.Printf("  Received: %s\n", r.ReceivedAt.Format(time.RFC3339Nano))
+	if r.RequestID != "" {
+		fmt.Printf("  Request ID: %s\n", r.RequestID)
+	}
+	fmt.Printf("  Submitted by: %s\n", r.Submitter)
+	fmt.Printf("  Server key: %s\n", r.PubKey)
+	if p := evidence.Proof; p != nil {
+		fmt.Printf("  Still in the chain at length %d", p.TreeHead.Length)
+		if p.Checkpoint != nil {
+			fmt.Print(" (timestamped checkpoint)")
+		}
+		fmt.Println()
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"crypto/ecdh"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/amshithnair/zcrypt/crypto"
+	"github.com/amshithnair/zcrypt/utils"
+)
+
+func handleEncGenKey() {
+	key, err := crypto.GenerateEncryptionKey()
+	if err != nil {
+		fmt.Println("Error generating encryption key:", err)
+		return
+	}
+	if err := crypto.SaveEncryptionKey(key, crypto.EncryptionKeyFile, crypto.EncryptionPubKeyFile); err != nil {
+		fmt.Println("Error saving encryption key:", err)
+		return
+	}
+
+	fmt.Println("✓ Encryption key generated successfully!")
+	fmt.Printf("Private key: %s\n", crypto.EncryptionKeyFile)
+	fmt.Printf("Public key: %s\n", crypto.EncryptionPubKeyFile)
+	fmt.Printf("Public key hex: %s\n", hex.EncodeToString(key.PublicKey().Bytes()))
+	fmt.Println("Run 'zcrypt register-agent' again to publish it to the server")
+}
+
+// parseRecipients resolves each item as a key file, a hex X25519 key or the
+// ID of an agent that registered an encryption key with the server
+func parseRecipients(client *utils.LogClient, list string) ([]*ecdh.PublicKey, error) {
+	var agents map[string]string
+	var keys []*ecdh.PublicKey
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if data, err := os.ReadFile(item); err == nil {
+			key, err := crypto.ParseEncryptionPublicKey(data)
+			if err != nil {
+				return nil, fmt.Errorf("recipient %s: %w", item, err)
+			}
+			keys = append(keys, key)
+			continue
+		}
+		if key, err := crypto.ParseEncryptionPublicKey([]byte(item)); err == nil {
+			keys = append(keys, key)
+			continue
+		}
+
+		if agents == nil {
+			registered, err := client.ListAgents()
+			if err != nil {
+				return nil, fmt.Errorf("looking up recipient %s: %w", item, err)
+			}
+			agents = make(map[string]string, len(registered))
+			for _, agent := range registered {
+				agents[agent.AgentID] = agent.EncPubKey
+			}
+		}
+		encPubKey, ok := agents[item]
+		if !ok || encPubKey == "" {
+			return nil, fmt.Errorf("recipient %q is not a key file, an X25519 key or an agent with an encryption key", item)
+		}
+		key, err := crypto.ParseEncryptionPublicKey([]byte(encPubKey))
+		if err != nil {
+			return nil, fmt.Errorf("recipient %s: %w", item, err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// Decrypt a server entry or an armored message encrypted to this key
+func handleDecrypt() {
+	if len(os.Args) < 3 {
+		fmt.Println("Usage: zcrypt decrypt <server_index|message> [--key file]")
+		return
+	}
+
+	flags := flag.NewFlagSet("decrypt", flag.ExitOnError)
+	keyPath := flags.String("key", crypto.EncryptionKeyFile, "X25519 private key file")
+	flags.Parse(os.Args[3:])
+
+	key, err := crypto.LoadEncryptionKey(*keyPath)
+	if err != nil {
+		fmt.Println("Error:", err, "- run 'zcrypt enc-genkey' first")
+		return
+	}
+
+	message := os.Args[2]
+	if index, err := strconv.Atoi(message); err == nil {
+		entry, err := newServerClient().GetEntry(index)
+		if err != nil {
+			fmt.Println("Error fetching entry:", err)
+			return
+		}
+		message = entry.Message
+	}
+	if !crypto.IsEncryptedMessage(message) {
+		fmt.Println("Error: message is not encrypted to recipients")
+		return
+	}
+
+	plain, err := crypto.DecryptMessage(message, key)
+	if err != nil {
+		fmt.Println("Error decrypting message:", err)
+		if ids, err := crypto.MessageRecipients(message); err == nil {
+			fmt.Printf("  Encrypted to key IDs: %s\n", strings.Join(ids, ", "))
+			fmt.Printf("  Your key ID: %s\n", crypto.RecipientKeyID(key.PublicKey()))
+		}
+		return
+	}
+
+	fmt.Println("✓ Message decrypted")
+	fmt.Printf("  Message: %s\n", plain)
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/amshithnair/zcrypt/crypto"
+)
+
+// parseRedactArgs reads "<index> --fields a,b --reason text"
+func parseRedactArgs(name string, args []string) (int, []string, string, bool) {
+	usage := fmt.Sprintf("Usage: zcrypt %s <index> --fields message,metadata.<key>,envelope --reason \"text\"", name)
+	if len(args) < 1 {
+		fmt.Println(usage)
+		return 0, nil, "", false
+	}
+
+	index, err := strconv.Atoi(args[0])
+	if err != nil {
+		fmt.Println("Error: index must be a number")
+		return 0, nil, "", false
+	}
+
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	fieldList := flags.String("fields", crypto.FieldMessage, "comma-separated fields to erase (\"metadata\" means all metadata)")
+	reason := flags.String("reason", "", "why the content is erased, recorded in the chain")
+	flags.Parse(args[1:])
+
+	if *reason == "" {
+		fmt.Println(usage)
+		return 0, nil, "", false
+	}
+
+	var fields []string
+	for _, field := range strings.Split(*fieldList, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			fields = append(fields, field)
+		}
+	}
+	return index, fields, *reason, true
+}
+
+// Redact an entry of the local chain, signed with the agent key
+func handleRedact() {
+	index, fields, reason, ok := parseRedactArgs("redact", os.Args[2:])
+	if !ok {
+		return
+	}
+
+	signer, err := loadSigner()
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	chain, err := crypto.NewLogChain(crypto.GetChainPath())
+	if err != nil {
+		fmt.Println("Error loading chain:", err)
+		return
+	}
+	target, err := chain.GetEntry(index)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	ref := crypto.RedactionRef{
+		TargetIndex: index,
+		TargetHash:  target.CurrentHash,
+		Fields:      fields,
+		Reason:      reason,
+	}
+	sigHex, err := crypto.SignRedaction(signer, ref)
+	if err != nil {
+		fmt.Println("Error signing redaction:", err)
+		return
+	}
+
+	entry, err := chain.Redact(ref, sigHex, hex.EncodeToString(signer.PublicKey()), signer.Algorithm(), map[string]interface{}{
+		"agent_id": agentID(),
+	})
+	if errors.Is(err, crypto.ErrNotRedactor) {
+		fmt.Println("Error: Your key may not sign redactions for this chain. Allow it with:")
+		fmt.Printf("  zcrypt chain-redactors add %s\n", hex.EncodeToString(signer.PublicKey()))
+		return
+	}
+	if err != nil {
+		fmt.Println("Error redacting entry:", err)
+		return
+	}
+
+	fmt.Printf("✓ Entry %d redacted\n", index)
+	if redacted, err := chain.GetEntry(index); err == nil {
+		fmt.Printf("  Fields: %s\n", strings.Join(redacted.Commitments.Redacted, ", "))
+	}
+	fmt.Printf("  Redaction recorded as: %s\n", entry.CurrentHash[:32])
+}
+
+// List the keys allowed to sign redactions of the local chain, or add keys
+func handleChainRedactors() {
+	args := os.Args[2:]
+	if len(args) > 0 && (args[0] != "add" || len(args) < 2) {
+		fmt.Println("Usage: zcrypt chain-redactors [add <hex public key>...]")
+		return
+	}
+
+	chain, err := crypto.NewLogChain(crypto.GetChainPath())
+	if err != nil {
+		fmt.Println("Error loading chain:", err)
+		return
+	}
+	if len(args) > 0 {
+		if err := chain.AllowRedactors(args[1:]); err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+		fmt.Printf("✓ Allowed %d key(s) to sign redactions\n", len(args)-1)
+	}
+
+	redactors := chain.Redactors()
+	if len(redactors) == 0 {
+		fmt.Println("No keys may sign redactions")
+		return
+	}
+	fmt.Println("Keys allowed to sign redactions:")
+	for _, key := range redactors {
+		fmt.Printf("  %s\n", key)
+	}
+}
+
+// Redact an entry of the server chain; needs ZCRYPT_ADMIN_TOKEN
+func handleServerRedact() {
+	index, fields, reason, ok := parseRedactArgs("server-redact", os.Args[2:])
+	if !ok {
+		return
+	}
+
+	signer, err := loadSigner()
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	client := newServerClient()
+	client.Token = os.Getenv("ZCRYPT_ADMIN_TOKEN")
+	if client.Token == "" {
+		fmt.Println("Error: ZCRYPT_ADMIN_TOKEN is required to redact server entries")
+		return
+	}
+
+	entry, err := client.Redact(signer, agentID(), index, fields, reason)
+	if err != nil {
+		fmt.Println("Error redacting server entry:", err)
+		return
+	}
+
+	fmt.Printf("✓ Server entry %d redacted\n", index)
+	fmt.Printf("  Redaction recorded as: %s\n", entry.CurrentHash[:32])
+}
+
+// printRedactionReport lists entries that are redacted but intact
+func printRedactionReport(statuses []crypto.RedactionStatus) {
+	if len(statuses) == 0 {
+		return
+	}
+	fmt.Println("\nRedacted entries (redacted but intact):")
+	for _, status := range statuses {
+		fmt.Printf("  [%d] %s erased by redaction entry %v\n",
+			status.Index, strings.Join(status.Fields, ", "), status.By)
+	}
+}
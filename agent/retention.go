@@ -0,0 +1,255 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/amshithnair/zcrypt/crypto"
+)
+
+// parseRetentionFlags reads --max-age, --max-entries, --max-bytes and
+// --whole-epochs plus any extra flags the caller registers. It returns nil
+// when no limit is given.
+func parseRetentionFlags(flags *flag.FlagSet, args []string) (*crypto.RetentionPolicy, error) {
+	maxAge := flags.String("max-age", "", "prune entries older than this (e.g. 720h or 90d)")
+	maxEntries := flags.Int("max-entries", 0, "keep at most this many entries")
+	maxBytes := flags.Int64("max-bytes", 0, "keep at most this many bytes of entries")
+	wholeEpochs := flags.Bool("whole-epochs", false, "prune only whole sealed epochs")
+	flags.Parse(args)
+
+	policy := crypto.RetentionPolicy{MaxEntries: *maxEntries, MaxBytes: *maxBytes, WholeEpochs: *wholeEpochs}
+	if *maxAge != "" {
+		age, err := crypto.ParseRetentionAge(*maxAge)
+		if err != nil {
+			return nil, err
+		}
+		policy.MaxAge = age
+	}
+	if policy.IsZero() {
+		return nil, nil
+	}
+	return &policy, nil
+}
+
+// describePolicy renders a retention policy for display
+func describePolicy(policy *crypto.RetentionPolicy) string {
+	if policy == nil {
+		return "keep everything"
+	}
+	desc := ""
+	if policy.MaxAge > 0 {
+		desc += fmt.Sprintf(" max-age=%s", policy.MaxAge)
+	}
+	if policy.MaxEntries > 0 {
+		desc += fmt.Sprintf(" max-entries=%d", policy.MaxEntries)
+	}
+	if policy.MaxBytes > 0 {
+		desc += fmt.Sprintf(" max-bytes=%d", policy.MaxBytes)
+	}
+	if policy.WholeEpochs {
+		desc += " whole-epochs"
+	}
+	return desc[1:]
+}
+
+// Show or set the local chain's retention policy
+func handleChainRetention() {
+	chain, err := crypto.NewLogChain(crypto.GetChainPath())
+	if err != nil {
+		fmt.Println("Error loading chain:", err)
+		return
+	}
+
+	flags := flag.NewFlagSet("chain-retention", flag.ExitOnError)
+	reset := flags.Bool("clear", false, "remove the policy and keep everything")
+	policy, err := parseRetentionFlags(flags, os.Args[2:])
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	if policy != nil || *reset {
+		if policy == nil {
+			policy = &crypto.RetentionPolicy{}
+		}
+		if err := chain.SetRetention(*policy); err != nil {
+			fmt.Println("Error setting retention policy:", err)
+			return
+		}
+		fmt.Println("✓ Retention policy saved")
+	}
+
+	fmt.Printf("  Policy: %s\n", describePolicy(chain.Retention()))
+	fmt.Printf("  Kept entries: %d-%d\n", chain.FirstIndex(), chain.Length()-1)
+	for _, hold := range chain.LegalHolds() {
+		fmt.Printf("  Legal hold %d: entries %d-%d (%s)\n", hold.ID, hold.StartIndex, hold.EndIndex, hold.Reason)
+	}
+}
+
+// Prune the local chain by the stored policy or the given limits
+func handleChainPrune() {
+	flags := flag.NewFlagSet("chain-prune", flag.ExitOnError)
+	archiveDir := flags.String("archive", "", "directory to archive pruned entries to before removal")
+	dryRun := flags.Bool("dry-run", false, "only report what would be pruned")
+	policy, err := parseRetentionFlags(flags, os.Args[2:])
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	chain, err := crypto.NewLogChain(crypto.GetChainPath())
+	if err != nil {
+		fmt.Println("Error loading chain:", err)
+		return
+	}
+	if policy == nil {
+		policy = chain.Retention()
+	}
+	if policy == nil {
+		fmt.Println("Usage: zcrypt chain-prune [--max-age 90d] [--max-entries N] [--max-bytes N] [--whole-epochs] [--archive dir] [--dry-run]")
+		fmt.Println("No limits given and no retention policy set (see zcrypt chain-retention)")
+		return
+	}
+
+	if *dryRun {
+		n, hold := chain.Prunable(*policy, time.Now().UTC())
+		fmt.Printf("Would prune %d entries (%s)\n", n, describePolicy(policy))
+		if hold != nil {
+			fmt.Printf("  Stopped by legal hold %d at entry %d: %s\n", hold.ID, hold.StartIndex, hold.Reason)
+		}
+		return
+	}
+
+	signer, err := loadSigner()
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	anchor, err := chain.Prune(*policy, signer, *archiveDir)
+	if errors.Is(err, crypto.ErrNothingToPrune) {
+		fmt.Println("Nothing to prune")
+		return
+	}
+	if err != nil {
+		fmt.Println("Error pruning chain:", err)
+		return
+	}
+
+	fmt.Printf("✓ Pruned entries %d-%d\n", anchor.StartIndex, anchor.StartIndex+anchor.Count-1)
+	fmt.Printf("  Anchor last hash: %s\n", anchor.LastHash[:32]+"...")
+	fmt.Printf("  Merkle root: %s\n", anchor.MerkleRoot[:32]+"...")
+	if anchor.Archive != "" {
+		fmt.Printf("  Archived to: %s\n", anchor.Archive)
+	}
+}
+
+// Add, release or list legal holds on the local chain
+func handleChainHold() {
+	usage := "Usage: zcrypt chain-hold add <start> [end] --reason \"text\" | release <id> | list"
+	if len(os.Args) < 3 {
+		fmt.Println(usage)
+		return
+	}
+
+	chain, err := crypto.NewLogChain(crypto.GetChainPath())
+	if err != nil {
+		fmt.Println("Error loading chain:", err)
+		return
+	}
+
+	switch os.Args[2] {
+	case "add":
+		args := os.Args[3:]
+		var indices []int
+		for len(args) > 0 {
+			n, err := strconv.Atoi(args[0])
+			if err != nil {
+				break
+			}
+			indices = append(indices, n)
+			args = args[1:]
+		}
+		flags := flag.NewFlagSet("chain-hold add", flag.ExitOnError)
+		reason := flags.String("reason", "", "why the entries must be kept")
+		flags.Parse(args)
+		if len(indices) == 0 || len(indices) > 2 || *reason == "" {
+			fmt.Println(usage)
+			return
+		}
+		end := indices[len(indices)-1]
+
+		hold, err := chain.AddLegalHold(indices[0], end, *reason)
+		if err != nil {
+			fmt.Println("Error adding legal hold:", err)
+			return
+		}
+		fmt.Printf("✓ Legal hold %d on entries %d-%d\n", hold.ID, hold.StartIndex, hold.EndIndex)
+	case "release":
+		if len(os.Args) < 4 {
+			fmt.Println(usage)
+			return
+		}
+		id, err := strconv.Atoi(os.Args[3])
+		if err != nil {
+			fmt.Println("Error: hold ID must be a number")
+			return
+		}
+		if err := chain.ReleaseLegalHold(id); err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+		fmt.Printf("✓ Legal hold %d released\n", id)
+	case "list":
+		holds := chain.LegalHolds()
+		if len(holds) == 0 {
+			fmt.Println("No legal holds")
+		}
+		for _, hold := range holds {
+			fmt.Printf("  [%d] entries %d-%d since %s: %s\n", hold.ID, hold.StartIndex, hold.EndIndex,
+				hold.CreatedAt.Format("2006-01-02"), hold.Reason)
+		}
+	default:
+		fmt.Println(usage)
+	}
+}
+
+// Prune the server chain; needs ZCRYPT_ADMIN_TOKEN
+func handleServerPrune() {
+	flags := flag.NewFlagSet("server-prune", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "only report what would be pruned")
+	policy, err := parseRetentionFlags(flags, os.Args[2:])
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	client := newServerClient()
+	client.Token = os.Getenv("ZCRYPT_ADMIN_TOKEN")
+	if client.Token == "" {
+		fmt.Println("Error: ZCRYPT_ADMIN_TOKEN is required to prune the server chain")
+		return
+	}
+
+	result, err := client.Prune(policy, *dryRun)
+	if err != nil {
+		fmt.Println("Error pruning server chain:", err)
+		return
+	}
+
+	if *dryRun {
+		fmt.Printf("Server would prune %d entries\n", result.Prunable)
+	} else if result.Anchor == nil {
+		fmt.Println("Nothing to prune on the server")
+	} else {
+		fmt.Printf("✓ Server pruned entries %d-%d\n", result.Anchor.StartIndex, result.Anchor.StartIndex+result.Anchor.Count-1)
+		fmt.Printf("  Anchor signed by server key %s...\n", result.Anchor.PubKey[:32])
+	}
+	if result.BlockedBy != nil {
+		fmt.Printf("  Stopped by legal hold %d at entry %d: %s\n", result.BlockedBy.ID, result.BlockedBy.StartIndex, result.BlockedBy.Reason)
+	}
+}
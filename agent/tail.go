@@ -0,0 +1,93 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/amshithnair/zcrypt/crypto"
+	"github.com/amshithnair/zcrypt/utils"
+)
+
+// Follow the server chain live, checking each entry's hash, signature and
+// link to the one before as it arrives
+func handleServerTail() {
+	flags := flag.NewFlagSet("server-tail", flag.ExitOnError)
+	from := flags.Int("from", -1, "start at this index instead of the next entry committed")
+	agent := flags.String("agent", "", "only entries from this agent ID")
+	kind := flags.String("kind", "", "only entries of this kind")
+	pubKey := flags.String("pubkey", "", "only entries signed by this hex public key")
+	flags.Parse(os.Args[2:])
+
+	// A read or admin token lets the server decrypt sealed entries
+	client := newServerClient()
+	client.Token = os.Getenv("ZCRYPT_READ_TOKEN")
+	if client.Token == "" {
+		client.Token = os.Getenv("ZCRYPT_ADMIN_TOKEN")
+	}
+	header, err := client.ChainHeader()
+	if err != nil {
+		fmt.Printf("Error: Cannot reach server at %s: %v\n", client.BaseURL, err)
+		return
+	}
+	verifier, err := crypto.NewStreamVerifier(*header)
+	if err != nil {
+		fmt.Println("Error: Invalid chain header:", err)
+		return
+	}
+
+	filter := utils.TailFilter{AgentID: *agent, Kind: *kind, PubKey: *pubKey}
+	if *from >= 0 {
+		fmt.Printf("Following %s from entry %d (Ctrl-C to stop)\n", client.BaseURL, *from)
+	} else {
+		fmt.Printf("Following %s (Ctrl-C to stop)\n", client.BaseURL)
+	}
+
+	err = client.Tail(*from, filter, func(event utils.TailEvent) error {
+		// A genesis entry starts a new epoch the header must describe
+		if event.Entry.Opens != nil {
+			if header, err := client.ChainHeader(); err == nil {
+				if err := verifier.SetHeader(*header); err != nil {
+					fmt.Fprintln(os.Stderr, "Warning: keeping the previous chain header:", err)
+				}
+			}
+		}
+		problems := verifier.Check(event.Index, event.Entry)
+		if event.Opened != nil {
+			problems = append(problems, verifier.CheckContent(*event.Opened)...)
+		}
+		printTailEntry(event, problems)
+		return nil
+	})
+	if err != nil {
+		fmt.Println("Error:", err)
+	}
+}
+
+// printTailEntry prints one streamed entry with the problems found in it
+func printTailEntry(event utils.TailEvent, problems []string) {
+	entry := event.Entry
+	if event.Opened != nil {
+		entry = *event.Opened
+	}
+
+	message := entry.Message
+	if entry.IsSealed() {
+		message = "(sealed)"
+	}
+	source, _ := entry.Metadata["agent_id"].(string)
+	if source == "" {
+		source = entry.PubKey[:min(len(entry.PubKey), 16)]
+	}
+
+	mark := "✓"
+	if len(problems) > 0 {
+		mark = "✗"
+	}
+	fmt.Printf("%s [%d] %s %s: %s\n", mark, event.Index, entry.Timestamp.Format(time.RFC3339), source, message)
+	if len(problems) > 0 {
+		fmt.Printf("    %s\n", strings.Join(problems, "; "))
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"crypto/x509"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/amshithnair/zcrypt/crypto"
+	"github.com/amshithnair/zcrypt/utils"
+)
+
+// loadTSACertificates reads the PEM certificates trusted to sign timestamps;
+// an empty path trusts only the certificate each token carries
+func loadTSACertificates(path string) ([]*x509.Certificate, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.ParseCertificates(data)
+}
+
+// printUntrustedCheckpoints lists checkpoints verified without a trusted TSA
+// certificate, with a hint on how to trust one
+func printUntrustedCheckpoints(indexes []int, hint string) {
+	if len(indexes) == 0 {
+		return
+	}
+	fmt.Printf("\nCheckpoints not checked against a trusted TSA (%s): %v\n", hint, indexes)
+}
+
+// printCheckpoints verifies each checkpoint's token offline and compares its
+// head with the chain entry it names, as returned by entryHash
+func printCheckpoints(checkpoints []crypto.Checkpoint, trusted []*x509.Certificate, entryHash func(index int) (string, error)) {
+	if len(checkpoints) == 0 {
+		fmt.Println("  No checkpoints")
+		return
+	}
+	for i, cp := range checkpoints {
+		info, err := cp.Verify(trusted)
+		if err != nil {
+			fmt.Printf("  ✗ [%d] entries 0-%d: %v\n", i, cp.Length-1, err)
+			continue
+		}
+		signer := info.Signer.Subject.CommonName
+		if !info.Trusted {
+			signer += " (certificate not checked - pass --tsa-cert)"
+		}
+		fmt.Printf("  ✓ [%d] entries 0-%d existed by %s\n", i, cp.Length-1, info.GenTime.Format(time.RFC3339))
+		fmt.Printf("      signed by %s\n", signer)
+		if hash, err := entryHash(cp.Length - 1); err != nil {
+			fmt.Printf("      head not compared: %v\n", err)
+		} else if hash != cp.Head {
+			fmt.Printf("      ✗ head does not match entry %d\n", cp.Length-1)
+		}
+	}
+}
+
+// Timestamp the local chain head at an RFC 3161 TSA
+func handleChainTimestamp() {
+	flags := flag.NewFlagSet("chain-timestamp", flag.ExitOnError)
+	tsaURL := flags.String("tsa", os.Getenv("ZCRYPT_TSA_URL"), "URL of the RFC 3161 time-stamp authority")
+	flags.Parse(os.Args[2:])
+	if *tsaURL == "" {
+		fmt.Println("Usage: zcrypt chain-timestamp --tsa <url>")
+		return
+	}
+
+	chain, err := crypto.NewLogChain(crypto.GetChainPath())
+	if err != nil {
+		fmt.Println("Error loading chain:", err)
+		return
+	}
+	checkpoint, err := utils.TimestampChain(chain, *tsaURL)
+	if err != nil {
+		fmt.Println("Error timestamping chain:", err)
+		return
+	}
+	fmt.Printf("✓ Entries 0-%d timestamped at %s\n", checkpoint.Length-1, checkpoint.GenTime.Format(time.RFC3339))
+	fmt.Printf("  Head: %s\n", checkpoint.Head[:min(len(checkpoint.Head), 32)]+"...")
+	fmt.Printf("  TSA: %s\n", checkpoint.TSA)
+}
+
+// List and verify the local chain's checkpoints
+func handleChainCheckpoints() {
+	flags := flag.NewFlagSet("chain-checkpoints", flag.ExitOnError)
+	certPath := flags.String("tsa-cert", "", "PEM certificates trusted to sign timestamps")
+	flags.Parse(os.Args[2:])
+
+	trusted, err := loadTSACertificates(*certPath)
+	if err != nil {
+		fmt.Println("Error loading TSA certificates:", err)
+		return
+	}
+	chain, err := crypto.NewLogChain(crypto.GetChainPath())
+	if err != nil {
+		fmt.Println("Error loading chain:", err)
+		return
+	}
+
+	fmt.Println("Local Chain Checkpoints:")
+	printCheckpoints(chain.Checkpoints(), trusted, func(index int) (string, error) {
+		entry, err := chain.GetEntry(index)
+		if err != nil {
+			return "", err
+		}
+		return entry.CurrentHash, nil
+	})
+}
+
+// Ask the server to timestamp its head now; needs ZCRYPT_ADMIN_TOKEN
+func handleServerTimestamp() {
+	client := newServerClient()
+	client.Token = os.Getenv("ZCRYPT_ADMIN_TOKEN")
+	if client.Token == "" {
+		fmt.Println("Error: ZCRYPT_ADMIN_TOKEN is required to timestamp the server chain")
+		return
+	}
+
+	checkpoint, err := client.TimestampServer()
+	if err != nil {
+		fmt.Println("Error timestamping server chain:", err)
+		return
+	}
+	fmt.Printf("✓ Server entries 0-%d timestamped at %s\n", checkpoint.Length-1, checkpoint.GenTime.Format(time.RFC3339))
+	fmt.Printf("  TSA: %s\n", checkpoint.TSA)
+}
+
+// List the server's checkpoints and verify them offline
+func handleServerCheckpoints() {
+	flags := flag.NewFlagSet("server-checkpoints", flag.ExitOnError)
+	certPath := flags.String("tsa-cert", "", "PEM certificates trusted to sign timestamps")
+	flags.Parse(os.Args[2:])
+
+	trusted, err := loadTSACertificates(*certPath)
+	if err != nil {
+		fmt.Println("Error loading TSA certificates:", err)
+		return
+	}
+	client := newServerClient()
+	checkpoints, err := client.Checkpoints()
+	if err != nil {
+		fmt.Println("Error listing server checkpoints:", err)
+		return
+	}
+
+	fmt.Println("Server Chain Checkpoints:")
+	printCheckpoints(checkpoints, trusted, func(index int) (string, error) {
+		entry, err := client.GetEntry(index)
+		if err != nil {
+			return "", err
+		}
+		return entry.CurrentHash, nil
+	})
+}
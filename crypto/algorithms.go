@@ -0,0 +1,347 @@
+package crypto
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Supported signature algorithms. Entries without an algorithm are Ed25519.
+const (
+	AlgEd25519   = "ed25519"
+	AlgECDSAP256 = "ecdsa-p256" // ECDSA over P-256 with SHA-256, ASN.1 DER signatures
+	AlgRSAPSS    = "rsa-pss"    // RSA-PSS with SHA-256 and salt length equal to the hash
+
+	DefaultAlgorithm = AlgEd25519
+	rsaKeyBits       = 3072
+)
+
+// ErrUnknownAlgorithm is returned when no verifier is registered for an algorithm
+var ErrUnknownAlgorithm = errors.New("unknown signature algorithm")
+
+// Verifier checks a signature produced by a specific algorithm
+type Verifier interface {
+	Verify(pubKey, msg, sig []byte) error
+}
+
+// KeyChecker is implemented by verifiers that can check a public key is
+// usable with their algorithm before any signature is made with it
+type KeyChecker interface {
+	CheckPublicKey(pubKey []byte) error
+}
+
+// VerifierFunc adapts a plain function to the Verifier interface
+type VerifierFunc func(pubKey, msg, sig []byte) error
+
+// Verify calls f(pubKey, msg, sig)
+func (f VerifierFunc) Verify(pubKey, msg, sig []byte) error {
+	return f(pubKey, msg, sig)
+}
+
+var (
+	verifiersMu sync.RWMutex
+	verifiers   = map[string]Verifier{
+		AlgEd25519:   keyVerifier{check: checkEd25519Key, verify: verifyEd25519},
+		AlgECDSAP256: keyVerifier{check: checkECDSAP256Key, verify: verifyECDSAP256},
+		AlgRSAPSS:    keyVerifier{check: checkRSAKey, verify: verifyRSAPSS},
+	}
+)
+
+// RegisterVerifier adds or replaces the verifier for an algorithm
+func RegisterVerifier(alg string, v Verifier) {
+	verifiersMu.Lock()
+	defer verifiersMu.Unlock()
+	verifiers[alg] = v
+}
+
+// LookupVerifier returns the verifier registered for an algorithm
+func LookupVerifier(alg string) (Verifier, error) {
+	verifiersMu.RLock()
+	defer verifiersMu.RUnlock()
+
+	v, ok := verifiers[NormalizeAlgorithm(alg)]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownAlgorithm, alg)
+	}
+	return v, nil
+}
+
+// CheckPublicKey checks that a public key is usable with an algorithm.
+// Keys for verifiers that cannot check them are accepted.
+func CheckPublicKey(alg string, pubKey []byte) error {
+	v, err := LookupVerifier(alg)
+	if err != nil {
+		return err
+	}
+	if checker, ok := v.(KeyChecker); ok {
+		return checker.CheckPublicKey(pubKey)
+	}
+	return nil
+}
+
+// SupportedAlgorithms lists the registered algorithm identifiers
+func SupportedAlgorithms() []string {
+	verifiersMu.RLock()
+	defer verifiersMu.RUnlock()
+
+	algs := make([]string, 0, len(verifiers))
+	for alg := range verifiers {
+		algs = append(algs, alg)
+	}
+	sort.Strings(algs)
+	return algs
+}
+
+// NormalizeAlgorithm maps an empty identifier to the Ed25519 default
+func NormalizeAlgorithm(alg string) string {
+	if alg == "" {
+		return DefaultAlgorithm
+	}
+	return alg
+}
+
+// VerifyWithAlgorithm verifies a hex signature against a hex public key
+func VerifyWithAlgorithm(alg, pubKeyHex string, msg []byte, sigHex string) error {
+	v, err := LookupVerifier(alg)
+	if err != nil {
+		return err
+	}
+	pubKey, err := hex.DecodeString(pubKeyHex)
+	if err != nil {
+		return fmt.Errorf("invalid public key format: %w", err)
+	}
+	sig, err := hex.DecodeString(sigHex)
+	if err != nil {
+		return fmt.Errorf("invalid signature format: %w", err)
+	}
+	return v.Verify(pubKey, msg, sig)
+}
+
+// keyVerifier is a built-in verifier that also checks keys on their own
+type keyVerifier struct {
+	check  func(pubKey []byte) error
+	verify VerifierFunc
+}
+
+func (v keyVerifier) CheckPublicKey(pubKey []byte) error   { return v.check(pubKey) }
+func (v keyVerifier) Verify(pubKey, msg, sig []byte) error { return v.verify(pubKey, msg, sig) }
+
+func checkEd25519Key(pubKey []byte) error {
+	if len(pubKey) != ed25519.PublicKeySize {
+		return fmt.Errorf("invalid ed25519 public key length %d", len(pubKey))
+	}
+	return nil
+}
+
+func verifyEd25519(pubKey, msg, sig []byte) error {
+	if err := checkEd25519Key(pubKey); err != nil {
+		return err
+	}
+	if !ed25519.Verify(ed25519.PublicKey(pubKey), msg, sig) {
+		return errors.New("ed25519 signature verification failed")
+	}
+	return nil
+}
+
+func parseECDSAP256Key(pubKey []byte) (*ecdsa.PublicKey, error) {
+	key, err := x509.ParsePKIXPublicKey(pubKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ecdsa public key: %w", err)
+	}
+	ecKey, ok := key.(*ecdsa.PublicKey)
+	if !ok || ecKey.Curve != elliptic.P256() {
+		return nil, errors.New("public key is not an ECDSA P-256 key")
+	}
+	return ecKey, nil
+}
+
+func checkECDSAP256Key(pubKey []byte) error {
+	_, err := parseECDSAP256Key(pubKey)
+	return err
+}
+
+func verifyECDSAP256(pubKey, msg, sig []byte) error {
+	ecKey, err := parseECDSAP256Key(pubKey)
+	if err != nil {
+		return err
+	}
+	digest := sha256.Sum256(msg)
+	if !ecdsa.VerifyASN1(ecKey, digest[:], sig) {
+		return errors.New("ecdsa signature verification failed")
+	}
+	return nil
+}
+
+func parseRSAKey(pubKey []byte) (*rsa.PublicKey, error) {
+	key, err := x509.ParsePKIXPublicKey(pubKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rsa public key: %w", err)
+	}
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("public key is not an RSA key")
+	}
+	return rsaKey, nil
+}
+
+func checkRSAKey(pubKey []byte) error {
+	_, err := parseRSAKey(pubKey)
+	return err
+}
+
+func verifyRSAPSS(pubKey, msg, sig []byte) error {
+	rsaKey, err := parseRSAKey(pubKey)
+	if err != nil {
+		return err
+	}
+	digest := sha256.Sum256(msg)
+	opts := &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: crypto.SHA256}
+	if err := rsa.VerifyPSS(rsaKey, crypto.SHA256, digest[:], sig, opts); err != nil {
+		return fmt.Errorf("rsa-pss signature verification failed: %w", err)
+	}
+	return nil
+}
+
+// Signer produces signatures for one algorithm
+type Signer interface {
+	Algorithm() string
+	PublicKey() []byte // Raw bytes for Ed25519, PKIX DER otherwise
+	Sign(msg []byte) ([]byte, error)
+}
+
+type ed25519Signer struct{ key ed25519.PrivateKey }
+
+func (s ed25519Signer) Algorithm() string { return AlgEd25519 }
+func (s ed25519Signer) PublicKey() []byte { return s.key.Public().(ed25519.PublicKey) }
+func (s ed25519Signer) Sign(msg []byte) ([]byte, error) {
+	return ed25519.Sign(s.key, msg), nil
+}
+
+type ecdsaSigner struct{ key *ecdsa.PrivateKey }
+
+func (s ecdsaSigner) Algorithm() string { return AlgECDSAP256 }
+func (s ecdsaSigner) PublicKey() []byte {
+	der, _ := x509.MarshalPKIXPublicKey(&s.key.PublicKey)
+	return der
+}
+func (s ecdsaSigner) Sign(msg []byte) ([]byte, error) {
+	digest := sha256.Sum256(msg)
+	return ecdsa.SignASN1(rand.Reader, s.key, digest[:])
+}
+
+type rsaPSSSigner struct{ key *rsa.PrivateKey }
+
+func (s rsaPSSSigner) Algorithm() string { return AlgRSAPSS }
+func (s rsaPSSSigner) PublicKey() []byte {
+	der, _ := x509.MarshalPKIXPublicKey(&s.key.PublicKey)
+	return der
+}
+func (s rsaPSSSigner) Sign(msg []byte) ([]byte, error) {
+	digest := sha256.Sum256(msg)
+	opts := &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: crypto.SHA256}
+	return rsa.SignPSS(rand.Reader, s.key, crypto.SHA256, digest[:], opts)
+}
+
+// NewEd25519Signer wraps an existing Ed25519 private key
+func NewEd25519Signer(priv ed25519.PrivateKey) Signer {
+	return ed25519Signer{key: priv}
+}
+
+// GenerateSigner creates a fresh keypair for the given algorithm
+func GenerateSigner(alg string) (Signer, error) {
+	switch NormalizeAlgorithm(alg) {
+	case AlgEd25519:
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		return ed25519Signer{key: priv}, nil
+	case AlgECDSAP256:
+		priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		return ecdsaSigner{key: priv}, nil
+	case AlgRSAPSS:
+		priv, err := rsa.GenerateKey(rand.Reader, rsaKeyBits)
+		if err != nil {
+			return nil, err
+		}
+		return rsaPSSSigner{key: priv}, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownAlgorithm, alg)
+	}
+}
+
+// MarshalSigner encodes a private key for storage. Ed25519 keys keep the
+// legacy raw 64-byte format; other algorithms use PKCS#8 DER.
+func MarshalSigner(s Signer) ([]byte, error) {
+	switch k := s.(type) {
+	case ed25519Signer:
+		return []byte(k.key), nil
+	case ecdsaSigner:
+		return x509.MarshalPKCS8PrivateKey(k.key)
+	case rsaPSSSigner:
+		return x509.MarshalPKCS8PrivateKey(k.key)
+	default:
+		return nil, fmt.Errorf("unsupported signer type %T", s)
+	}
+}
+
+// ParseSigner decodes a private key produced by MarshalSigner
+func ParseSigner(data []byte) (Signer, error) {
+	if key, err := x509.ParsePKCS8PrivateKey(data); err == nil {
+		switch k := key.(type) {
+		case ed25519.PrivateKey:
+			return ed25519Signer{key: k}, nil
+		case *ecdsa.PrivateKey:
+			if k.Curve != elliptic.P256() {
+				return nil, errors.New("only P-256 ECDSA keys are supported")
+			}
+			return ecdsaSigner{key: k}, nil
+		case *rsa.PrivateKey:
+			return rsaPSSSigner{key: k}, nil
+		default:
+			return nil, fmt.Errorf("unsupported private key type %T", key)
+		}
+	}
+
+	if len(data) == ed25519.PrivateKeySize {
+		return ed25519Signer{key: ed25519.PrivateKey(data)}, nil
+	}
+	return nil, errors.New("unrecognized private key format")
+}
+
+// PublicKeyAlgorithm infers the algorithm of a public key as written by SaveSigner
+func PublicKeyAlgorithm(pubKey []byte) (string, error) {
+	if len(pubKey) == ed25519.PublicKeySize {
+		return AlgEd25519, nil
+	}
+	key, err := x509.ParsePKIXPublicKey(pubKey)
+	if err != nil {
+		return "", fmt.Errorf("unrecognized public key format: %w", err)
+	}
+	switch k := key.(type) {
+	case ed25519.PublicKey:
+		return AlgEd25519, nil
+	case *ecdsa.PublicKey:
+		if k.Curve != elliptic.P256() {
+			return "", errors.New("only P-256 ECDSA keys are supported")
+		}
+		return AlgECDSAP256, nil
+	case *rsa.PublicKey:
+		return AlgRSAPSS, nil
+	default:
+		return "", fmt.Errorf("unsupported public key type %T", key)
+	}
+}
@@ -0,0 +1,101 @@
+package crypto
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"os"
+	"testing"
+)
+
+func TestSignersRoundTrip(t *testing.T) {
+	for _, alg := range []string{AlgEd25519, AlgECDSAP256, AlgRSAPSS} {
+		signer, err := GenerateSigner(alg)
+		if err != nil {
+			t.Fatalf("%s: generate: %v", alg, err)
+		}
+
+		data, err := MarshalSigner(signer)
+		if err != nil {
+			t.Fatalf("%s: marshal: %v", alg, err)
+		}
+		parsed, err := ParseSigner(data)
+		if err != nil {
+			t.Fatalf("%s: parse: %v", alg, err)
+		}
+		if parsed.Algorithm() != alg {
+			t.Errorf("Expected algorithm %s after parse, got %s", alg, parsed.Algorithm())
+		}
+
+		msg := []byte("Deployed version 1.2.3")
+		sig, err := parsed.Sign(msg)
+		if err != nil {
+			t.Fatalf("%s: sign: %v", alg, err)
+		}
+		pubHex := hex.EncodeToString(signer.PublicKey())
+		if err := VerifyWithAlgorithm(alg, pubHex, msg, hex.EncodeToString(sig)); err != nil {
+			t.Errorf("%s: valid signature rejected: %v", alg, err)
+		}
+		if err := VerifyWithAlgorithm(alg, pubHex, []byte("other"), hex.EncodeToString(sig)); err == nil {
+			t.Errorf("%s: signature over different message accepted", alg)
+		}
+	}
+}
+
+func TestLegacyEntryDefaultsToEd25519(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	entry := LogEntry{
+		Message:   "legacy",
+		Signature: SignMessage(priv, []byte("legacy")),
+		PubKey:    hex.EncodeToString(pub),
+	}
+	if err := entry.VerifySignature(); err != nil {
+		t.Errorf("Legacy entry without algorithm should verify as Ed25519: %v", err)
+	}
+}
+
+func TestMixedAlgorithmChain(t *testing.T) {
+	tempFile := os.TempDir() + "/test_chain_algs.json"
+	defer os.Remove(tempFile)
+
+	chain, _ := NewLogChain(tempFile)
+	for _, alg := range []string{AlgEd25519, AlgECDSAP256, AlgRSAPSS} {
+		signer, _ := GenerateSigner(alg)
+		addSigned(t, chain, signer, "signed with "+alg)
+	}
+
+	if valid, errors := chain.VerifyChain(); !valid {
+		t.Errorf("Mixed-algorithm chain should verify: %v", errors)
+	}
+
+	chain.Entries[1].Algorithm = AlgRSAPSS
+	if valid, _ := chain.VerifyChain(); valid {
+		t.Error("Changing an entry's algorithm should break verification")
+	}
+}
+
+func TestUnknownAlgorithm(t *testing.T) {
+	if _, err := LookupVerifier("md5-rsa"); err == nil {
+		t.Error("Expected unknown algorithm error")
+	}
+}
+
+func TestCheckPublicKey(t *testing.T) {
+	edSigner, _ := GenerateSigner(AlgEd25519)
+	ecdsaSigner, _ := GenerateSigner(AlgECDSAP256)
+	for _, tc := range []struct {
+		alg    string
+		pubKey []byte
+		valid  bool
+	}{
+		{AlgEd25519, edSigner.PublicKey(), true},
+		{AlgEd25519, make([]byte, 31), false},
+		{AlgECDSAP256, ecdsaSigner.PublicKey(), true},
+		{AlgECDSAP256, edSigner.PublicKey(), false},
+		{AlgRSAPSS, ecdsaSigner.PublicKey(), false},
+		{AlgRSAPSS, []byte("not a key"), false},
+	} {
+		if err := CheckPublicKey(tc.alg, tc.pubKey); (err == nil) != tc.valid {
+			t.Errorf("Expected %s key of %d bytes valid to be %v, got %v", tc.alg, len(tc.pubKey), tc.valid, err)
+		}
+	}
+}
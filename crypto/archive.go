@@ -0,0 +1,424 @@
+package crypto
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression formats for archive segments
+const (
+	CompressionZstd = "zstd"
+	CompressionGzip = "gzip"
+)
+
+// DefaultCompression is used when archiving without naming a format
+const DefaultCompression = CompressionZstd
+
+// ErrNothingToArchive is returned when no live entries fall in the range
+var ErrNothingToArchive = errors.New("no entries to archive")
+
+// Magic numbers that identify compressed files
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// ArchiveSegment is a contiguous range of entries moved out of the chain
+// file into a compressed, checksummed file. Archived entries are still part
+// of the chain: they are read and verified transparently.
+type ArchiveSegment struct {
+	StartIndex     int       `json:"start_index"`
+	Count          int       `json:"count"`
+	Path           string    `json:"path"` // Relative to the chain file's directory
+	Compression    string    `json:"compression"`
+	SHA256         string    `json:"sha256"`
+	FirstPrevHash  string    `json:"first_prev_hash"`
+	LastHash       string    `json:"last_hash"`
+	FirstTimestamp time.Time `json:"first_timestamp"`
+	LastTimestamp  time.Time `json:"last_timestamp"`
+	ArchivedAt     time.Time `json:"archived_at"`
+}
+
+// liveStart returns the absolute index of the first entry kept in the chain
+// file, after pruned and archived entries
+func (h *ChainHeader) liveStart() int {
+	if len(h.Segments) == 0 {
+		return h.prunedCount()
+	}
+	last := h.Segments[len(h.Segments)-1]
+	return last.StartIndex + last.Count
+}
+
+// liveHead returns the hash the first live entry links to
+func (h *ChainHeader) liveHead() string {
+	if len(h.Segments) == 0 {
+		return h.prunedHead()
+	}
+	return h.Segments[len(h.Segments)-1].LastHash
+}
+
+// segmentPath resolves a segment's file relative to the chain file
+func (lc *LogChain) segmentPath(segment ArchiveSegment) string {
+	return filepath.Join(filepath.Dir(lc.FilePath), segment.Path)
+}
+
+// archivedLocked returns every archived entry in index order, reading and
+// checking the segment files on first use. The result is cached until the
+// segments change; callers hold the lock.
+func (lc *LogChain) archivedLocked() ([]LogEntry, error) {
+	lc.archiveMu.Lock()
+	defer lc.archiveMu.Unlock()
+
+	if lc.archived != nil || len(lc.Header.Segments) == 0 {
+		return lc.archived, nil
+	}
+
+	var entries []LogEntry
+	next := lc.Header.prunedCount()
+	for i, segment := range lc.Header.Segments {
+		if segment.StartIndex != next {
+			return nil, fmt.Errorf("segment %d does not continue from entry %d", i, next)
+		}
+		loaded, err := lc.readSegment(segment)
+		if err != nil {
+			return nil, fmt.Errorf("segment %d-%d: %w", segment.StartIndex, segment.StartIndex+segment.Count-1, err)
+		}
+		entries = append(entries, loaded...)
+		next += segment.Count
+	}
+	lc.archived = entries
+	return entries, nil
+}
+
+// readSegment loads one segment and checks it against its header record
+func (lc *LogChain) readSegment(segment ArchiveSegment) ([]LogEntry, error) {
+	data, err := os.ReadFile(lc.segmentPath(segment))
+	if err != nil {
+		return nil, err
+	}
+	if sum := sha256.Sum256(data); hex.EncodeToString(sum[:]) != segment.SHA256 {
+		return nil, fmt.Errorf("checksum mismatch")
+	}
+	entries, err := decodeEntries(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) != segment.Count {
+		return nil, fmt.Errorf("holds %d entries, header records %d", len(entries), segment.Count)
+	}
+	if entries[0].PrevHash != segment.FirstPrevHash || entries[len(entries)-1].CurrentHash != segment.LastHash {
+		return nil, fmt.Errorf("does not match its recorded hashes")
+	}
+	return entries, nil
+}
+
+// keptLocked returns archived and live entries together, starting at the
+// first unpruned index; callers hold the lock
+func (lc *LogChain) keptLocked() ([]LogEntry, error) {
+	if len(lc.Header.Segments) == 0 {
+		return lc.Entries, nil
+	}
+	archived, err := lc.archivedLocked()
+	if err != nil {
+		return nil, err
+	}
+	kept := make([]LogEntry, 0, len(archived)+len(lc.Entries))
+	kept = append(kept, archived...)
+	return append(kept, lc.Entries...), nil
+}
+
+// entriesFromLocked returns the kept entries from absolute index start on,
+// reading archives only when start falls inside them; callers hold the lock
+func (lc *LogChain) entriesFromLocked(start int) []LogEntry {
+	if live := lc.Header.liveStart(); start >= live {
+		return lc.Entries[min(start-live, len(lc.Entries)):]
+	}
+	kept, err := lc.keptLocked()
+	if err != nil {
+		return lc.Entries
+	}
+	return kept[max(start-lc.Header.prunedCount(), 0):]
+}
+
+// livePos returns the position in lc.Entries of an entry that may be
+// modified in place; archived entries must be restored first
+func (lc *LogChain) livePos(index int) (int, error) {
+	if lc.entryLocked(index) == nil {
+		return 0, fmt.Errorf("index out of range")
+	}
+	pos := index - lc.Header.liveStart()
+	if pos < 0 {
+		return 0, fmt.Errorf("entry %d is archived; restore it first", index)
+	}
+	return pos, nil
+}
+
+// invalidateArchive drops cached archived entries after segments change
+func (lc *LogChain) invalidateArchive() {
+	lc.archiveMu.Lock()
+	lc.archived = nil
+	lc.archiveMu.Unlock()
+}
+
+// Archive moves the live entries before index out of the chain file into a
+// new segment compressed with zstd or gzip. Reads and verification continue
+// to see them; only the storage changes.
+func (lc *LogChain) Archive(before int, compression string) (*ArchiveSegment, error) {
+	unlock, err := lc.lockWrite()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if compression == "" {
+		compression = DefaultCompression
+	}
+	ext, err := compressionExt(compression)
+	if err != nil {
+		return nil, err
+	}
+
+	start := lc.Header.liveStart()
+	n := min(before-start, len(lc.Entries))
+	if n <= 0 {
+		return nil, ErrNothingToArchive
+	}
+	entries := lc.Entries[:n]
+
+	segment := ArchiveSegment{
+		StartIndex:     start,
+		Count:          n,
+		Compression:    compression,
+		FirstPrevHash:  entries[0].PrevHash,
+		LastHash:       entries[n-1].CurrentHash,
+		FirstTimestamp: entries[0].Timestamp,
+		LastTimestamp:  entries[n-1].Timestamp,
+		ArchivedAt:     lc.now(),
+	}
+	if err := lc.writeSegment(&segment, entries, ext); err != nil {
+		return nil, fmt.Errorf("failed to write segment: %w", err)
+	}
+
+	live, segments := lc.Entries, lc.Header.Segments
+	lc.Entries = append([]LogEntry{}, lc.Entries[n:]...)
+	lc.Header.Segments = append(append([]ArchiveSegment{}, segments...), segment)
+	if err := lc.Save(); err != nil {
+		lc.Entries, lc.Header.Segments = live, segments
+		os.Remove(lc.segmentPath(segment))
+		return nil, fmt.Errorf("failed to save chain: %w", err)
+	}
+	lc.invalidateArchive()
+	return &segment, nil
+}
+
+// writeSegment compresses entries into a new segment file named after its
+// range and digest, so rewriting a segment never overwrites the old file
+func (lc *LogChain) writeSegment(segment *ArchiveSegment, entries []LogEntry, ext string) error {
+	data, digest, err := encodeEntries(entries, segment.Compression)
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%d-%d.%s.json%s", segment.StartIndex, segment.StartIndex+segment.Count-1, digest[:12], ext)
+	segment.Path = filepath.Join(filepath.Base(lc.FilePath)+".segments", name)
+	segment.SHA256 = digest
+	return writeFileAtomic(lc.segmentPath(*segment), data)
+}
+
+// rekeySegmentsLocked rewraps the data keys of sealed archived entries and
+// rewrites the segments that changed. Replaced files are returned so the
+// caller can remove them once the header is saved; callers hold the lock.
+func (lc *LogChain) rekeySegmentsLocked() (int, []string, error) {
+	rewrapped := 0
+	var replaced []string
+	for i, segment := range lc.Header.Segments {
+		entries, err := lc.readSegment(segment)
+		if err != nil {
+			return rewrapped, replaced, fmt.Errorf("segment %d-%d: %w", segment.StartIndex, segment.StartIndex+segment.Count-1, err)
+		}
+		changed := 0
+		for j := range entries {
+			if entries[j].Sealed == nil {
+				continue
+			}
+			ok, err := lc.keyring.rewrap(entries[j].Sealed, lc.random)
+			if err != nil {
+				return rewrapped, replaced, fmt.Errorf("entry %d: %w", segment.StartIndex+j, err)
+			}
+			if ok {
+				changed++
+			}
+		}
+		if changed == 0 {
+			continue
+		}
+		ext, _ := compressionExt(segment.Compression)
+		if err := lc.writeSegment(&segment, entries, ext); err != nil {
+			return rewrapped, replaced, fmt.Errorf("failed to rewrite segment: %w", err)
+		}
+		replaced = append(replaced, lc.segmentPath(lc.Header.Segments[i]))
+		lc.Header.Segments[i] = segment
+		rewrapped += changed
+	}
+	if rewrapped > 0 {
+		lc.invalidateArchive()
+	}
+	return rewrapped, replaced, nil
+}
+
+// Restore moves archived entries from index from onward back into the
+// chain file. Whole segments are restored, newest first, so the segment
+// containing from is restored completely. It returns the number of entries
+// restored.
+func (lc *LogChain) Restore(from int) (int, error) {
+	unlock, err := lc.lockWrite()
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	segments := lc.Header.Segments
+	keep := len(segments)
+	for keep > 0 && segments[keep-1].StartIndex+segments[keep-1].Count > from {
+		keep--
+	}
+	if keep == len(segments) {
+		return 0, nil
+	}
+
+	var restored []LogEntry
+	for _, segment := range segments[keep:] {
+		entries, err := lc.readSegment(segment)
+		if err != nil {
+			return 0, fmt.Errorf("segment %d-%d: %w", segment.StartIndex, segment.StartIndex+segment.Count-1, err)
+		}
+		restored = append(restored, entries...)
+	}
+
+	live := lc.Entries
+	lc.Entries = append(restored, live...)
+	lc.Header.Segments = append([]ArchiveSegment{}, segments[:keep]...)
+	if err := lc.Save(); err != nil {
+		lc.Entries, lc.Header.Segments = live, segments
+		return 0, fmt.Errorf("failed to save chain: %w", err)
+	}
+	for _, segment := range segments[keep:] {
+		os.Remove(lc.segmentPath(segment))
+	}
+	lc.invalidateArchive()
+	return len(restored), nil
+}
+
+// Segments returns the chain's archive segments, oldest first
+func (lc *LogChain) Segments() []ArchiveSegment {
+	lc.mu.RLock()
+	defer lc.mu.RUnlock()
+	return append([]ArchiveSegment{}, lc.Header.Segments...)
+}
+
+// compressionExt returns the file extension for a compression format
+func compressionExt(compression string) (string, error) {
+	switch compression {
+	case CompressionZstd:
+		return ".zst", nil
+	case CompressionGzip:
+		return ".gz", nil
+	default:
+		return "", fmt.Errorf("unsupported compression %q (use %s or %s)", compression, CompressionZstd, CompressionGzip)
+	}
+}
+
+// encodeEntries returns entries as compressed JSON and its hex SHA-256
+func encodeEntries(entries []LogEntry, compression string) ([]byte, string, error) {
+	var buf bytes.Buffer
+	var zw io.WriteCloser
+	switch compression {
+	case CompressionGzip:
+		zw = gzip.NewWriter(&buf)
+	case CompressionZstd:
+		enc, err := zstd.NewWriter(&buf)
+		if err != nil {
+			return nil, "", err
+		}
+		zw = enc
+	default:
+		return nil, "", fmt.Errorf("unsupported compression %q", compression)
+	}
+	if err := json.NewEncoder(zw).Encode(entries); err != nil {
+		return nil, "", err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, "", err
+	}
+
+	sum := sha256.Sum256(buf.Bytes())
+	return buf.Bytes(), hex.EncodeToString(sum[:]), nil
+}
+
+// writeFileAtomic writes data through a temporary file and a rename
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// writeEntries writes entries as compressed JSON and returns the hex SHA-256
+// of the file
+func writeEntries(path string, entries []LogEntry, compression string) (string, error) {
+	data, digest, err := encodeEntries(entries, compression)
+	if err != nil {
+		return "", err
+	}
+	if err := writeFileAtomic(path, data); err != nil {
+		return "", err
+	}
+	return digest, nil
+}
+
+// decodeEntries reads compressed JSON entries, detecting gzip or zstd
+func decodeEntries(data []byte) ([]LogEntry, error) {
+	var r io.Reader
+	switch {
+	case bytes.HasPrefix(data, gzipMagic):
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip data: %w", err)
+		}
+		defer zr.Close()
+		r = zr
+	case bytes.HasPrefix(data, zstdMagic):
+		zr, err := zstd.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("invalid zstd data: %w", err)
+		}
+		defer zr.Close()
+		r = zr
+	default:
+		return nil, fmt.Errorf("unknown compression format")
+	}
+
+	var entries []LogEntry
+	if err := json.NewDecoder(r).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("invalid archive: %w", err)
+	}
+	return entries, nil
+}
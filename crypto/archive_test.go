@@ -0,0 +1,200 @@
+package crypto
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestArchiveReadsTransparently(t *testing.T) {
+	for _, compression := range []string{CompressionZstd, CompressionGzip} {
+		t.Run(compression, func(t *testing.T) {
+			tempFile := os.TempDir() + "/test_chain_archive.json"
+			defer os.Remove(tempFile)
+			defer os.RemoveAll(tempFile + ".segments")
+
+			chain, _ := NewLogChain(tempFile)
+			signer, _ := GenerateSigner(AlgEd25519)
+			for i := 0; i < 6; i++ {
+				addSigned(t, chain, signer, fmt.Sprintf("Log %d", i))
+			}
+			hash := chain.Entries[1].CurrentHash
+
+			segment, err := chain.Archive(4, compression)
+			if err != nil {
+				t.Fatalf("Failed to archive: %v", err)
+			}
+			if segment.StartIndex != 0 || segment.Count != 4 || len(chain.Entries) != 2 {
+				t.Fatalf("Expected entries 0-3 archived, got %+v", segment)
+			}
+			if _, err := os.Stat(chain.segmentPath(*segment)); err != nil {
+				t.Fatalf("Expected segment file: %v", err)
+			}
+
+			reloaded, _ := NewLogChain(tempFile)
+			if entry, err := reloaded.GetEntry(1); err != nil || entry.CurrentHash != hash {
+				t.Errorf("Expected archived entry 1 to be readable, got %v", err)
+			}
+			if index := reloaded.IndexOf(hash); index != 1 {
+				t.Errorf("Expected archived hash at index 1, got %d", index)
+			}
+			all := reloaded.GetEntriesRange(time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+			if len(all) != 6 {
+				t.Errorf("Expected range query across archive, got %d entries", len(all))
+			}
+			page, first, total := reloaded.GetEntriesPage(2, 3)
+			if len(page) != 3 || first != 0 || total != 6 || page[0].Message != "Log 2" {
+				t.Errorf("Unexpected page across archive: %d entries from %d of %d", len(page), first, total)
+			}
+			exported, _ := reloaded.ExportJSON()
+			var entries []LogEntry
+			json.Unmarshal([]byte(exported), &entries)
+			if len(entries) != 6 {
+				t.Errorf("Expected export to include archived entries, got %d", len(entries))
+			}
+
+			addSigned(t, reloaded, signer, "Log 6")
+			report := reloaded.VerifyChainReport()
+			if !report.Valid || report.Total != 7 {
+				t.Errorf("Expected chain to verify across archive, got %+v", report)
+			}
+			if stats := reloaded.Stats(); stats["archived_entries"] != 4 || stats["total_entries"] != 7 {
+				t.Errorf("Unexpected stats %v", stats)
+			}
+		})
+	}
+}
+
+func TestRestoreArchivedEntries(t *testing.T) {
+	tempFile := os.TempDir() + "/test_chain_restore.json"
+	defer os.Remove(tempFile)
+	defer os.RemoveAll(tempFile + ".segments")
+
+	chain, _ := NewLogChain(tempFile)
+	signer, _ := GenerateSigner(AlgEd25519)
+	for i := 0; i < 6; i++ {
+		addSigned(t, chain, signer, fmt.Sprintf("Log %d", i))
+	}
+	first, _ := chain.Archive(2, CompressionGzip)
+	second, _ := chain.Archive(4, CompressionZstd)
+
+	// Restoring from inside a segment restores that whole segment
+	restored, err := chain.Restore(3)
+	if err != nil || restored != 2 {
+		t.Fatalf("Expected 2 entries restored, got %d (%v)", restored, err)
+	}
+	if len(chain.Header.Segments) != 1 || len(chain.Entries) != 4 || chain.Entries[0].Message != "Log 2" {
+		t.Errorf("Expected only the newer segment restored, got %d live entries", len(chain.Entries))
+	}
+	if _, err := os.Stat(chain.segmentPath(*second)); !os.IsNotExist(err) {
+		t.Error("Expected restored segment file to be removed")
+	}
+
+	if restored, _ := chain.Restore(0); restored != 2 || len(chain.Entries) != 6 {
+		t.Errorf("Expected everything restored, got %d", restored)
+	}
+	if _, err := os.Stat(chain.segmentPath(*first)); !os.IsNotExist(err) {
+		t.Error("Expected restored segment file to be removed")
+	}
+	if valid, errors := chain.VerifyChain(); !valid {
+		t.Errorf("Expected restored chain to verify: %v", errors)
+	}
+}
+
+func TestTamperedSegmentIsDetected(t *testing.T) {
+	tempFile := os.TempDir() + "/test_chain_segment_tamper.json"
+	defer os.Remove(tempFile)
+	defer os.RemoveAll(tempFile + ".segments")
+
+	chain, _ := NewLogChain(tempFile)
+	signer, _ := GenerateSigner(AlgEd25519)
+	for i := 0; i < 3; i++ {
+		addSigned(t, chain, signer, fmt.Sprintf("Log %d", i))
+	}
+	segment, _ := chain.Archive(2, CompressionZstd)
+
+	// A segment rewritten with altered entries no longer matches its checksum
+	entries, _ := ReadArchive(chain.segmentPath(*segment))
+	entries[0].Message = "Log X"
+	writeEntries(chain.segmentPath(*segment), entries, CompressionZstd)
+
+	reloaded, _ := NewLogChain(tempFile)
+	if valid, _ := reloaded.VerifyChain(); valid {
+		t.Error("Expected tampered segment to be detected")
+	}
+	if _, err := reloaded.GetEntry(0); err == nil {
+		t.Error("Expected tampered segment not to be read")
+	}
+}
+
+func TestArchivedEntriesWithPruneAndRedact(t *testing.T) {
+	tempFile := os.TempDir() + "/test_chain_archive_prune.json"
+	defer os.Remove(tempFile)
+	defer os.RemoveAll(tempFile + ".segments")
+
+	chain, _ := NewLogChain(tempFile)
+	signer, _ := GenerateSigner(AlgEd25519)
+	for i := 0; i < 6; i++ {
+		addSigned(t, chain, signer, fmt.Sprintf("Log %d", i))
+	}
+	chain.Archive(2, CompressionGzip)
+	segment, _ := chain.Archive(4, CompressionZstd)
+
+	target, _ := chain.GetEntry(2)
+	ref := RedactionRef{TargetIndex: 2, TargetHash: target.CurrentHash, Fields: []string{FieldMessage}}
+	sig, _ := SignRedaction(signer, ref)
+	allowRedactor(t, chain, signer)
+	if _, err := chain.Redact(ref, sig, target.PubKey, AlgEd25519, nil); err == nil {
+		t.Error("Expected error redacting an archived entry")
+	}
+
+	// Pruning stops at segment boundaries
+	if n, _ := chain.Prunable(RetentionPolicy{MaxEntries: 3}, time.Now()); n != 2 {
+		t.Errorf("Expected pruning rounded down to the first segment, got %d", n)
+	}
+	if _, err := chain.Prune(RetentionPolicy{MaxEntries: 2}, signer, ""); err != nil {
+		t.Fatalf("Failed to prune: %v", err)
+	}
+	if chain.FirstIndex() != 4 || len(chain.Header.Segments) != 0 || len(chain.Entries) != 2 {
+		t.Errorf("Expected both segments pruned, first index %d", chain.FirstIndex())
+	}
+	if _, err := os.Stat(chain.segmentPath(*segment)); !os.IsNotExist(err) {
+		t.Error("Expected pruned segment file to be removed")
+	}
+	if valid, errors := chain.VerifyChain(); !valid {
+		t.Errorf("Expected chain to verify: %v", errors)
+	}
+}
+
+func TestRekeyArchivedEntries(t *testing.T) {
+	tempFile := os.TempDir() + "/test_chain_archive_rekey.json"
+	defer os.Remove(tempFile)
+	defer os.RemoveAll(tempFile + ".segments")
+
+	oldMaster, _ := GenerateMasterKey()
+	oldKr, _ := NewKeyring(oldMaster)
+	chain := newSealedChain(t, tempFile, oldKr)
+	signer, _ := GenerateSigner(AlgEd25519)
+	addSigned(t, chain, signer, "Log 0")
+	addSigned(t, chain, signer, "Log 1")
+	chain.Archive(1, CompressionZstd)
+
+	newMaster, _ := GenerateMasterKey()
+	newKr, _ := NewKeyring(newMaster, oldMaster)
+	chain.SetKeyring(newKr)
+	if n, err := chain.Rekey(); err != nil || n != 2 {
+		t.Fatalf("Expected live and archived entries rewrapped, got %d (%v)", n, err)
+	}
+
+	onlyNew, _ := NewKeyring(newMaster)
+	reloaded := newSealedChain(t, tempFile, onlyNew)
+	entry, _ := reloaded.GetEntry(0)
+	if opened, err := reloaded.Open(*entry); err != nil || opened.Message != "Log 0" {
+		t.Errorf("Expected archived entry to open with the new key, got %v", err)
+	}
+	if report := reloaded.VerifyChainReport(); !report.Valid || report.SignaturesSkipped != 0 {
+		t.Errorf("Expected chain to verify with the new key, got %+v", report)
+	}
+}
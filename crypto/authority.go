@@ -0,0 +1,97 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// ErrNotAuthority is returned when a chain's anchors or seals would be
+// signed by a key other than the one recorded for the chain
+var ErrNotAuthority = errors.New("signer is not the chain's authority")
+
+// ChainAuthority is the key a chain's prune anchors and epoch seals are
+// signed with. The first prune or seal records it, and verification rejects
+// anchors, seals and genesis entries signed by any other key.
+type ChainAuthority struct {
+	PubKey    string `json:"pubkey"`
+	Algorithm string `json:"algorithm"`
+}
+
+// SetAuthority sets the key trusted to sign the chain's anchors and seals.
+// It takes precedence over the key recorded in the header, which anyone
+// able to rewrite the chain file could replace together with the anchors.
+func (lc *LogChain) SetAuthority(pubKey []byte) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	lc.authority = pubKey
+}
+
+// authorityLocked returns the trusted key, or the recorded one when no key
+// is trusted, or nil when there is neither; callers hold the lock
+func (lc *LogChain) authorityLocked() []byte {
+	if lc.authority != nil {
+		return lc.authority
+	}
+	if a := lc.Header.Authority; a != nil {
+		if key, err := hex.DecodeString(a.PubKey); err == nil {
+			return key
+		}
+	}
+	return nil
+}
+
+// checkSignerLocked returns ErrNotAuthority when the chain has an authority
+// and signer is another key; callers hold the lock
+func (lc *LogChain) checkSignerLocked(signer Signer) error {
+	if expected := lc.authorityLocked(); expected != nil && !bytes.Equal(signer.PublicKey(), expected) {
+		return fmt.Errorf("%w: anchors and seals are signed by %s", ErrNotAuthority, hex.EncodeToString(expected))
+	}
+	return nil
+}
+
+// recordAuthorityLocked records signer as the chain's authority if none is
+// recorded yet, and returns the previous value for rollback; callers hold
+// the lock
+func (lc *LogChain) recordAuthorityLocked(signer Signer) *ChainAuthority {
+	previous := lc.Header.Authority
+	if previous == nil {
+		lc.Header.Authority = &ChainAuthority{
+			PubKey:    hex.EncodeToString(signer.PublicKey()),
+			Algorithm: signer.Algorithm(),
+		}
+	}
+	return previous
+}
+
+// checkAuthorityLocked returns a problem when pubKeyHex is not the chain's
+// authority, or an empty string; callers hold the lock
+func (lc *LogChain) checkAuthorityLocked(pubKeyHex string) string {
+	expected := lc.authorityLocked()
+	if expected == nil {
+		return "no authority is recorded for the chain"
+	}
+	key, err := hex.DecodeString(pubKeyHex)
+	if err != nil || !bytes.Equal(key, expected) {
+		return "signed by a key other than the chain's authority"
+	}
+	return ""
+}
+
+// verifyAuthorityLocked checks that the recorded authority is the trusted
+// key, when one is set; callers hold the lock
+func (lc *LogChain) verifyAuthorityLocked() []string {
+	a := lc.Header.Authority
+	if a == nil {
+		return nil
+	}
+	key, err := hex.DecodeString(a.PubKey)
+	if err != nil {
+		return []string{fmt.Sprintf("Header: invalid authority key: %v", err)}
+	}
+	if lc.authority != nil && !bytes.Equal(key, lc.authority) {
+		return []string{"Header: recorded authority is not the trusted key"}
+	}
+	return nil
+}
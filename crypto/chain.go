@@ -2,12 +2,13 @@
 package crypto
 
 import (
-	"crypto/sha256"
-	"encoding/hex"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"sync"
 	"time"
 )
@@ -21,21 +22,98 @@ type LogEntry struct {
 	PrevHash    string                 `json:"prev_hash"`
 	CurrentHash string                 `json:"current_hash"`
 	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+	Algorithm   string                 `json:"algorithm,omitempty"` // Empty means Ed25519
+	Envelope    *Envelope              `json:"envelope,omitempty"`
+	Kind        string                 `json:"kind,omitempty"`        // Empty for ordinary logs
+	CoSigns     *CoSignRef             `json:"cosigns,omitempty"`     // Target of a co-signature entry
+	Sealed      *SealedContent         `json:"sealed,omitempty"`      // Encrypted message, metadata and envelope
+	Commitments *Commitments           `json:"commitments,omitempty"` // Salted hashes standing in for content
+	Redacts     *RedactionRef          `json:"redacts,omitempty"`     // Target of a redaction entry
+	Discloses   *DisclosureRef         `json:"discloses,omitempty"`   // Target of a disclosure entry
+	Closes      *EpochSeal             `json:"closes,omitempty"`      // Epoch closed by a seal entry
+	Opens       *EpochGenesis          `json:"opens,omitempty"`       // Epoch started by a genesis entry
+	Migrates    *MigrationRef          `json:"migrates,omitempty"`    // Format migration recorded by the entry
+	Sequence    int                    `json:"sequence,omitempty"`    // One more than the entry's index; zero before format version 4
+	ClaimedAt   *time.Time             `json:"claimed_at,omitempty"`  // Submitter's own time, separate from the received Timestamp
+	ClockFlags  []string               `json:"clock_flags,omitempty"` // Clock problems noticed when the entry was received
+}
+
+// SigningPayload returns the bytes covered by the entry's signature
+func (e *LogEntry) SigningPayload() []byte {
+	if e.CoSigns != nil {
+		return CoSignBytes(e.CoSigns.TargetIndex, e.CoSigns.TargetHash)
+	}
+	if e.Redacts != nil {
+		return RedactionBytes(*e.Redacts)
+	}
+	if e.Discloses != nil {
+		return DisclosureBytes(*e.Discloses)
+	}
+	if e.Closes != nil {
+		return EpochSealBytes(*e.Closes)
+	}
+	if e.Opens != nil {
+		return EpochGenesisBytes(*e.Opens)
+	}
+	if e.Migrates != nil {
+		return MigrationBytes(*e.Migrates)
+	}
+	if e.Envelope != nil && e.Envelope.Commitment != "" {
+		// Confidential submissions sign the commitment, never the message
+		return e.Envelope.SigningBytes("")
+	}
+	if e.Envelope != nil {
+		return e.Envelope.SigningBytes(e.Message)
+	}
+	return []byte(e.Message)
+}
+
+// VerifySignature checks the entry signature using its declared algorithm
+func (e *LogEntry) VerifySignature() error {
+	return VerifyWithAlgorithm(e.Algorithm, e.PubKey, e.SigningPayload(), e.Signature)
 }
 
 // LogChain manages the immutable log ledger
 type LogChain struct {
-	Entries  []LogEntry `json:"entries"`
-	FilePath string     `json:"-"`
-	mu       sync.RWMutex
+	Header    *ChainHeader `json:"header,omitempty"`
+	Entries   []LogEntry   `json:"entries"`
+	FilePath  string       `json:"-"`
+	mu        sync.RWMutex
+	keyring   *Keyring            // Encrypts new entries at rest when set
+	authority []byte              // Key trusted to sign anchors and seals, overriding the recorded one
+	tsaCerts  []*x509.Certificate // Certificates checkpoint tokens must chain to
+	redactors []string            // Keys trusted to sign redactions, overriding the recorded ones
+
+	lockTimeout  time.Duration    // How long writes wait for the cross-process lock
+	maxClockSkew time.Duration    // Claimed times further than this from received times are flagged
+	fileInfo     os.FileInfo      // The chain file as last read or written
+	clock        func() time.Time // Source of every time the chain records
+	random       io.Reader        // Source of salts, data keys and nonces
+	hashAlg      string           // Hash algorithm of a new chain's first epoch
+
+	archiveMu sync.Mutex
+	archived  []LogEntry // Entries read from archive segments, loaded on demand
+
+	subsMu    sync.Mutex
+	subs      map[*Subscription]struct{}
+	published int // Length of the chain when subscribers were last sent entries
 }
 
-// NewLogChain initializes or loads existing chain
-func NewLogChain(filePath string) (*LogChain, error) {
+// NewLogChain initializes or loads existing chain. With the same options and
+// inputs, including a fixed clock and random source, it builds byte-identical
+// chain files.
+func NewLogChain(filePath string, opts ...ChainOption) (*LogChain, error) {
 	lc := &LogChain{
 		FilePath: filePath,
 		Entries:  []LogEntry{},
 	}
+	defaultChainOptions(lc)
+	for _, opt := range opts {
+		opt(lc)
+	}
+	if _, err := NewHash(lc.hashAlg); err != nil {
+		return nil, err
+	}
 
 	// Ensure directory exists
 	dir := filepath.Dir(filePath)
@@ -50,86 +128,410 @@ func NewLogChain(filePath string) (*LogChain, error) {
 		}
 	}
 
+	if lc.Header == nil {
+		lc.Header = newChainHeader(lc.hashAlg, lc.now())
+	}
+	lc.published = lc.lengthLocked()
+
 	return lc, nil
 }
 
-// AddLog adds a new log entry to the chain
+// AddLog adds a new Ed25519-signed log entry to the chain
 func (lc *LogChain) AddLog(message, signature, pubKey string, metadata map[string]interface{}) (*LogEntry, error) {
-	lc.mu.Lock()
-	defer lc.mu.Unlock()
-
-	// Get previous hash
-	prevHash := "0" // Genesis block
-	if len(lc.Entries) > 0 {
-		prevHash = lc.Entries[len(lc.Entries)-1].CurrentHash
-	}
-
-	// Create new entry
-	entry := LogEntry{
-		Timestamp: time.Now().UTC(),
+	return lc.AddEntry(LogEntry{
 		Message:   message,
 		Signature: signature,
 		PubKey:    pubKey,
-		PrevHash:  prevHash,
+		Algorithm: AlgEd25519,
 		Metadata:  metadata,
+	})
+}
+
+// AddEntry appends an entry carrying the caller's message, signature, key,
+// algorithm and metadata. Timestamp, sequence number and hashes are assigned
+// by the chain; a ClaimedAt set by the caller is kept as the submitter's time.
+func (lc *LogChain) AddEntry(entry LogEntry) (*LogEntry, error) {
+	unlock, err := lc.lockWrite()
+	if err != nil {
+		return nil, err
 	}
+	defer unlock()
 
-	// Calculate current hash
-	entry.CurrentHash = lc.calculateHash(entry)
+	return lc.appendLocked(entry)
+}
 
-	// Add to chain
-	lc.Entries = append(lc.Entries, entry)
+// AddLogs appends entries atomically, in order, with a single write of the
+// chain file: either all are linked, hashed and saved, or the chain is left
+// as it was. Each entry is treated as by AddEntry.
+func (lc *LogChain) AddLogs(entries []LogEntry) ([]*LogEntry, error) {
+	unlock, err := lc.lockWrite()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	keepEntries, keepAlgorithms := lc.Entries, lc.Header.SignatureAlgorithms
+	rollback := func() {
+		lc.Entries, lc.Header.SignatureAlgorithms = keepEntries, keepAlgorithms
+	}
+	appended := make([]*LogEntry, 0, len(entries))
+	for i, entry := range entries {
+		staged, err := lc.stageLocked(entry)
+		if err != nil {
+			rollback()
+			return nil, fmt.Errorf("entry %d of batch: %w", i, err)
+		}
+		appended = append(appended, staged)
+	}
+
+	if err := lc.Save(); err != nil {
+		rollback()
+		return nil, fmt.Errorf("failed to save chain: %w", err)
+	}
+	return appended, nil
+}
 
-	// Persist to disk
+// appendLocked links, hashes, appends and persists an entry; callers hold the write lock
+func (lc *LogChain) appendLocked(entry LogEntry) (*LogEntry, error) {
+	keepEntries, keepAlgorithms := lc.Entries, lc.Header.SignatureAlgorithms
+	appended, err := lc.stageLocked(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	// Persist to disk, leaving the chain as it was if that fails
 	if err := lc.Save(); err != nil {
+		lc.Entries, lc.Header.SignatureAlgorithms = keepEntries, keepAlgorithms
 		return nil, fmt.Errorf("failed to save chain: %w", err)
 	}
 
+	return appended, nil
+}
+
+// stageLocked links, hashes and appends an entry without saving the chain
+func (lc *LogChain) stageLocked(entry LogEntry) (*LogEntry, error) {
+	lc.stampLocked(&entry, lc.now())
+	entry.PrevHash = lc.headLocked()
+
+	// Commit to the content of ordinary logs so it can be redacted later
+	if entry.Kind == "" && entry.Commitments == nil {
+		if err := entry.commit(lc.random); err != nil {
+			return nil, fmt.Errorf("failed to commit entry content: %w", err)
+		}
+	}
+
+	// Encrypt content at rest before hashing, so the hash covers ciphertext
+	if lc.keyring != nil && entry.Sealed == nil {
+		if err := lc.keyring.seal(&entry, lc.random); err != nil {
+			return nil, fmt.Errorf("failed to seal entry: %w", err)
+		}
+	}
+
+	// Calculate current hash with the current epoch's algorithm
+	entry.CurrentHash = calculateHash(entry, lc.Header.currentEpoch().HashAlgorithm)
+	lc.Header.noteAlgorithm(entry.Algorithm)
+
+	// Add to chain
+	lc.Entries = append(lc.Entries, entry)
 	return &entry, nil
 }
 
-// calculateHash computes SHA-256 hash of log entry
-func (lc *LogChain) calculateHash(entry LogEntry) string {
+// calculateHash computes the hash of a log entry with the given algorithm
+func calculateHash(entry LogEntry, hashAlg string) string {
+	// Committed content is hashed through its commitments
+	message := entry.Message
+	if entry.Commitments != nil {
+		message = ""
+	}
+
 	// Create deterministic string representation
 	data := fmt.Sprintf("%s|%s|%s|%s|%s",
 		entry.Timestamp.Format(time.RFC3339Nano),
-		entry.Message,
+		message,
 		entry.Signature,
 		entry.PubKey,
 		entry.PrevHash,
 	)
+	if ext := hashExtensions(entry); ext != "" {
+		data += "|" + ext
+	}
+
+	return hashHex(hashAlg, []byte(data))
+}
+
+// hashExtensions canonically encodes entry fields added after the original
+// hash format. Legacy entries have none, so their hashes are unchanged.
+func hashExtensions(entry LogEntry) string {
+	ext := map[string]interface{}{}
+	if entry.Algorithm != "" {
+		ext["algorithm"] = entry.Algorithm
+	}
+	if entry.Envelope != nil && entry.Commitments == nil {
+		ext["envelope"] = entry.Envelope
+	}
+	if entry.Kind != "" {
+		ext["kind"] = entry.Kind
+	}
+	if entry.CoSigns != nil {
+		ext["cosigns"] = entry.CoSigns
+	}
+	if entry.Sealed != nil && entry.Sealed.Digest != "" {
+		// Redaction reseals committed entries, so the original digest is hashed
+		ext["sealed"] = map[string]string{"digest": entry.Sealed.Digest}
+	} else if entry.Sealed != nil {
+		// Key ID and wrapped key are excluded so rekeying keeps hashes stable
+		ext["sealed"] = map[string]string{
+			"nonce":      entry.Sealed.Nonce,
+			"ciphertext": entry.Sealed.Ciphertext,
+		}
+	}
+	if entry.Commitments != nil {
+		ext["commitments"] = entry.Commitments.Fields
+	}
+	if entry.Commitments != nil && len(entry.Commitments.Withheld) > 0 {
+		ext["withheld"] = entry.Commitments.Withheld
+	}
+	if entry.Redacts != nil {
+		ext["redacts"] = entry.Redacts
+	}
+	if entry.Discloses != nil {
+		ext["discloses"] = entry.Discloses
+	}
+	if entry.Closes != nil {
+		ext["closes"] = entry.Closes
+	}
+	if entry.Opens != nil {
+		ext["opens"] = entry.Opens
+	}
+	if entry.Migrates != nil {
+		ext["migrates"] = entry.Migrates
+	}
+	if entry.Sequence != 0 {
+		ext["sequence"] = entry.Sequence
+	}
+	if entry.ClaimedAt != nil {
+		ext["claimed_at"] = entry.ClaimedAt.UTC().Format(time.RFC3339Nano)
+	}
+	if len(entry.ClockFlags) > 0 {
+		ext["clock_flags"] = entry.ClockFlags
+	}
 
-	hash := sha256.Sum256([]byte(data))
-	return hex.EncodeToString(hash[:])
+	if len(ext) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(ext)
+	return string(data)
+}
+
+// VerifyReport is the detailed result of verifying a chain
+type VerifyReport struct {
+	Valid  bool           `json:"valid"`
+	Errors []string       `json:"errors"`
+	Total  int            `json:"total"`
+	Pruned int            `json:"pruned,omitempty"` // Entries before the first kept one, covered by anchors
+	CoSign []CoSignStatus `json:"cosign,omitempty"` // Entries that declare co-signers
+
+	// Sealed entries whose signatures could not be checked without the master key
+	SignaturesSkipped int `json:"signatures_skipped,omitempty"`
+
+	// Entries redacted but intact: erased by authorized redactions, hashes unchanged
+	Redacted []RedactionStatus `json:"redacted,omitempty"`
+
+	// Confidential entries whose message is committed but not yet disclosed
+	Withheld []int `json:"withheld,omitempty"`
+
+	// Entries flagged for clock regression or skew when they were received
+	Clock []ClockStatus `json:"clock,omitempty"`
+
+	// Checkpoints whose TSA could not be checked because no TSA certificates
+	// are trusted. Their tokens are intact, but anyone could have made them.
+	UntrustedCheckpoints []int `json:"untrusted_checkpoints,omitempty"`
 }
 
 // VerifyChain checks integrity of entire chain
 func (lc *LogChain) VerifyChain() (bool, []string) {
+	report := lc.VerifyChainReport()
+	return report.Valid, report.Errors
+}
+
+// VerifyChainReport checks integrity of the entire chain and reports the
+// co-signature status of every entry that requires approvals
+func (lc *LogChain) VerifyChainReport() VerifyReport {
 	lc.mu.RLock()
 	defer lc.mu.RUnlock()
 
+	report := lc.verifyRangeLocked(0, lc.lengthLocked())
+	report.Errors = append(append(lc.verifyAuthorityLocked(), lc.verifyAnchorsLocked()...), report.Errors...)
+	checkpointErrors, untrusted := lc.verifyCheckpointsLocked()
+	report.Errors = append(report.Errors, checkpointErrors...)
+	report.UntrustedCheckpoints = untrusted
+	report.Valid = len(report.Errors) == 0
+	return report
+}
+
+// verifyRangeLocked checks the kept entries with indices in [from, to). The
+// first checked entry must link to the head before it: the prune anchor, the
+// previous epoch's head, or the preceding entry. Callers hold the lock.
+func (lc *LogChain) verifyRangeLocked(from, to int) VerifyReport {
 	var errors []string
+	var cosign []CoSignStatus
+	var redacted []RedactionStatus
+	var withheld []int
+	var clock []ClockStatus
+	skipped := 0
+	redactions, resealed := lc.redactionsLocked()
+	base := lc.Header.prunedCount()
+	prevHead := lc.Header.prunedHead()
+
+	// Archived entries are verified like live ones. If a segment cannot be
+	// read, only the live entries are checked and the failure is reported.
+	kept, err := lc.keptLocked()
+	if err != nil {
+		errors = append(errors, fmt.Sprintf("Archive: %v", err))
+		kept, base, prevHead = lc.Entries, lc.Header.liveStart(), lc.Header.liveHead()
+	}
+	pruned := max(min(base, to)-from, 0)
+	start := max(from, base)
+	if epoch := lc.Header.epochAt(start); start > base && start == epoch.StartIndex {
+		prevHead = epoch.PrevHead
+	} else if start > base {
+		prevHead = kept[start-base-1].CurrentHash
+	}
+	var prevTime time.Time
+	sequenced := false
+	if start > base {
+		prevTime = kept[start-base-1].Timestamp
+		sequenced = kept[start-base-1].Sequence != 0
+	}
+	kept = kept[start-base : max(to, start)-base]
+
+	for k, entry := range kept {
+		i := start + k
 
-	for i, entry := range lc.Entries {
-		// Check hash
-		expectedHash := lc.calculateHash(entry)
+		// Check hash with the algorithm of the entry's epoch
+		epoch := lc.Header.epochAt(i)
+		expectedHash := calculateHash(entry, epoch.HashAlgorithm)
 		if entry.CurrentHash != expectedHash {
 			errors = append(errors, fmt.Sprintf("Entry %d: hash mismatch", i))
 		}
 
-		// Check chain linkage
-		if i > 0 {
-			if entry.PrevHash != lc.Entries[i-1].CurrentHash {
-				errors = append(errors, fmt.Sprintf("Entry %d: broken chain link", i))
+		// Check committed content and any redactions. Sealed content is only
+		// compared with its commitments when it can be opened.
+		plain, openErr := lc.openLocked(entry)
+		signed := true
+		if c := entry.Commitments; c != nil {
+			problems := lc.checkRedactionLocked(entry, redactions[i], resealed[i])
+			if openErr == nil {
+				problems = append(problems, plain.checkContent()...)
+			}
+			for _, problem := range problems {
+				errors = append(errors, fmt.Sprintf("Entry %d: %s", i, problem))
+			}
+			if len(c.Redacted) > 0 && len(problems) == 0 {
+				redacted = append(redacted, RedactionStatus{Index: i, Fields: c.Redacted, By: redactions[i].By})
 			}
-		} else {
-			if entry.PrevHash != "0" {
+			if openErr == nil && c.isWithheld(FieldMessage) && plain.Commitments.Salts[FieldMessage] == "" && !c.isRedacted(FieldMessage) {
+				withheld = append(withheld, i)
+			}
+			signed = !c.isRedacted(FieldMessage) && !c.isRedacted(FieldEnvelope)
+		}
+
+		// Check signature with the entry's algorithm. Sealed entries need the
+		// master key; their hashes and links are still checked above. Erased
+		// content can no longer be checked against its signature.
+		if openErr == ErrNoKey {
+			skipped++
+		} else if openErr != nil {
+			errors = append(errors, fmt.Sprintf("Entry %d: cannot decrypt: %v", i, openErr))
+		} else if !signed {
+			// Redacted but intact
+		} else if err := plain.VerifySignature(); err != nil {
+			errors = append(errors, fmt.Sprintf("Entry %d: invalid signature (%s): %v", i, NormalizeAlgorithm(entry.Algorithm), err))
+		}
+
+		// Check chain linkage. The first kept entry after pruning links to
+		// the last anchor instead of its pruned predecessor.
+		if entry.PrevHash != prevHead {
+			switch {
+			case i == 0:
 				errors = append(errors, "Entry 0: invalid genesis prev_hash")
+			case k == 0 && i == base:
+				errors = append(errors, fmt.Sprintf("Entry %d: does not link to prune anchor", i))
+			default:
+				errors = append(errors, fmt.Sprintf("Entry %d: broken chain link", i))
+			}
+		}
+		if i > 0 && epoch.StartIndex == i && epoch.PrevHead != prevHead {
+			errors = append(errors, fmt.Sprintf("Epoch %d: does not link to previous head", epoch.Number))
+		}
+		prevHead = entry.CurrentHash
+
+		// Check sequence numbers and that received times never go backwards
+		for _, problem := range checkClock(i, entry, prevTime, sequenced) {
+			errors = append(errors, fmt.Sprintf("Entry %d: %s", i, problem))
+		}
+		if status := clockStatus(i, entry); status != nil {
+			clock = append(clock, *status)
+		}
+		prevTime, sequenced = entry.Timestamp, sequenced || entry.Sequence != 0
+
+		// Check epoch seals and the genesis entries that follow them
+		for _, problem := range lc.checkEpochLocked(i, entry) {
+			errors = append(errors, fmt.Sprintf("Entry %d: %s", i, problem))
+		}
+		if ref := entry.Migrates; ref != nil && (ref.OldHead != entry.PrevHash || ref.OldLength != i || ref.ToVersion > lc.Header.FormatVersion) {
+			errors = append(errors, fmt.Sprintf("Entry %d: migration record does not match the chain", i))
+		}
+		if i > 0 && epoch.StartIndex == i && epoch.Number > 0 && lc.Header.Epochs[epoch.Number-1].SealHash != "" && entry.Opens == nil {
+			errors = append(errors, fmt.Sprintf("Epoch %d: does not start with a genesis entry", epoch.Number))
+		}
+
+		// Check co-signature, redaction and disclosure references. Targets
+		// that have since been pruned are covered by their anchor.
+		if ref := entry.CoSigns; ref != nil && ref.TargetIndex >= base {
+			if !lc.refersLocked(i, ref.TargetIndex, ref.TargetHash) {
+				errors = append(errors, fmt.Sprintf("Entry %d: co-signature references unknown entry", i))
 			}
 		}
+		if ref := entry.Redacts; ref != nil && ref.TargetIndex >= base {
+			if !lc.refersLocked(i, ref.TargetIndex, ref.TargetHash) {
+				errors = append(errors, fmt.Sprintf("Entry %d: redaction references unknown entry", i))
+			}
+		}
+		if entry.Kind == KindRedaction && !lc.isRedactorLocked(entry.PubKey) {
+			errors = append(errors, fmt.Sprintf("Entry %d: redaction signed by a key that may not redact", i))
+		}
+		if ref := entry.Discloses; ref != nil && ref.TargetIndex >= base {
+			if !lc.refersLocked(i, ref.TargetIndex, ref.TargetHash) {
+				errors = append(errors, fmt.Sprintf("Entry %d: disclosure references unknown entry", i))
+			} else if lc.entryLocked(ref.TargetIndex).PubKey != entry.PubKey {
+				errors = append(errors, fmt.Sprintf("Entry %d: disclosure not signed by the entry's key", i))
+			}
+		}
+		if status := lc.coSignStatus(i); status != nil {
+			cosign = append(cosign, *status)
+		}
 	}
 
-	return len(errors) == 0, errors
+	return VerifyReport{
+		Valid:  len(errors) == 0,
+		Errors: errors,
+		Total:  len(kept),
+		Pruned: pruned,
+		CoSign: cosign,
+
+		SignaturesSkipped: skipped,
+		Redacted:          redacted,
+		Withheld:          withheld,
+		Clock:             clock,
+	}
+}
+
+// refersLocked reports whether the entry at index may refer to target: an
+// earlier kept entry with the given hash; callers hold the lock
+func (lc *LogChain) refersLocked(index, target int, targetHash string) bool {
+	entry := lc.entryLocked(target)
+	return target < index && entry != nil && entry.CurrentHash == targetHash
 }
 
 // GetLastHash returns the hash of the last entry
@@ -137,30 +539,72 @@ func (lc *LogChain) GetLastHash() string {
 	lc.mu.RLock()
 	defer lc.mu.RUnlock()
 
+	return lc.headLocked()
+}
+
+// headLocked returns the hash the next entry links to: the last entry, the
+// last archived entry, the last prune anchor, or "0" for genesis; callers
+// hold the lock
+func (lc *LogChain) headLocked() string {
 	if len(lc.Entries) == 0 {
-		return "0"
+		return lc.Header.liveHead()
 	}
 	return lc.Entries[len(lc.Entries)-1].CurrentHash
 }
 
-// GetEntry retrieves a specific log entry by index
+// GetEntry retrieves a specific log entry by index. Indices count every
+// entry ever appended, so they stay stable when older entries are pruned.
 func (lc *LogChain) GetEntry(index int) (*LogEntry, error) {
 	lc.mu.RLock()
 	defer lc.mu.RUnlock()
 
-	if index < 0 || index >= len(lc.Entries) {
+	if index >= 0 && index < lc.Header.prunedCount() {
+		return nil, fmt.Errorf("entry %d has been pruned", index)
+	}
+	entry := lc.entryLocked(index)
+	if entry == nil {
 		return nil, fmt.Errorf("index out of range")
 	}
-	return &lc.Entries[index], nil
+	return entry, nil
+}
+
+// IndexOf returns the index of the entry with the given hash, or -1.
+// It scans from the head, where recently appended entries are found quickly.
+func (lc *LogChain) IndexOf(hash string) int {
+	lc.mu.RLock()
+	defer lc.mu.RUnlock()
+
+	for i := len(lc.Entries) - 1; i >= 0; i-- {
+		if lc.Entries[i].CurrentHash == hash {
+			return lc.Header.liveStart() + i
+		}
+	}
+	archived, _ := lc.archivedLocked()
+	for i := len(archived) - 1; i >= 0; i-- {
+		if archived[i].CurrentHash == hash {
+			return lc.Header.prunedCount() + i
+		}
+	}
+	return -1
 }
 
-// GetEntriesRange retrieves logs within a time range
+// GetEntriesRange retrieves logs received within a time range. Received
+// timestamps never decrease, so segments outside the range are not read.
 func (lc *LogChain) GetEntriesRange(start, end time.Time) []LogEntry {
 	lc.mu.RLock()
 	defer lc.mu.RUnlock()
 
+	// Archive segments are only read when their time span overlaps
+	entries := lc.Entries
+	for _, segment := range lc.Header.Segments {
+		if !segment.FirstTimestamp.After(end) && !segment.LastTimestamp.Before(start) {
+			entries = lc.entriesFromLocked(segment.StartIndex)
+			break
+		}
+	}
+
 	var result []LogEntry
-	for _, entry := range lc.Entries {
+	for _, entry := range entries {
 		if (entry.Timestamp.Equal(start) || entry.Timestamp.After(start)) &&
 			(entry.Timestamp.Equal(end) || entry.Timestamp.Before(end)) {
 			result = append(result, entry)
@@ -182,25 +626,59 @@ func (lc *LogChain) Save() error {
 		return fmt.Errorf("marshal error: %w", err)
 	}
 
-	if err := os.WriteFile(lc.FilePath, data, 0600); err != nil {
+	// Replace the file atomically, so readers never see a partial chain
+	if err := writeFileAtomic(lc.FilePath, data); err != nil {
 		return fmt.Errorf("write error: %w", err)
 	}
+	lc.fileInfo, _ = os.Stat(lc.FilePath)
+	lc.publishLocked()
 
 	return nil
 }
 
 // Load reads the chain from disk
 func (lc *LogChain) Load() error {
-	data, err := os.ReadFile(lc.FilePath)
+	// Saves replace the file, so the open file is exactly the one read
+	file, err := os.Open(lc.FilePath)
+	if err != nil {
+		return fmt.Errorf("read error: %w", err)
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return fmt.Errorf("read error: %w", err)
+	}
+	info, err := file.Stat()
 	if err != nil {
 		return fmt.Errorf("read error: %w", err)
 	}
 
+	lc.Header, lc.Entries = nil, nil
 	if err := json.Unmarshal(data, lc); err != nil {
 		return fmt.Errorf("unmarshal error: %w", err)
 	}
+	if lc.Entries == nil {
+		lc.Entries = []LogEntry{}
+	}
+	lc.fileInfo = info
+	lc.invalidateArchive()
+
+	// Chains written before headers existed are a single SHA-256 epoch.
+	// Older formats stay at their version until migrated.
+	switch {
+	case lc.Header == nil:
+		lc.Header = &ChainHeader{
+			FormatVersion: FormatVersionLegacy,
+			Epochs:        newChainHeader(DefaultHashAlgorithm, time.Time{}).Epochs,
+		}
+		if len(lc.Entries) > 0 {
+			lc.Header.Epochs[0].StartedAt = lc.Entries[0].Timestamp
+		}
+	case lc.Header.FormatVersion == 0:
+		lc.Header.FormatVersion = FormatVersionEpochs
+	}
 
-	return nil
+	return lc.Header.validate()
 }
 
 // ExportJSON exports chain to JSON string
@@ -208,7 +686,11 @@ func (lc *LogChain) ExportJSON() (string, error) {
 	lc.mu.RLock()
 	defer lc.mu.RUnlock()
 
-	data, err := json.MarshalIndent(lc.Entries, "", "  ")
+	entries, err := lc.keptLocked()
+	if err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
 	if err != nil {
 		return "", err
 	}
@@ -220,13 +702,44 @@ func (lc *LogChain) Stats() map[string]interface{} {
 	lc.mu.RLock()
 	defer lc.mu.RUnlock()
 
+	archived := lc.Header.liveStart() - lc.Header.prunedCount()
 	stats := map[string]interface{}{
-		"total_entries": len(lc.Entries),
-		"last_hash":     lc.GetLastHash(),
+		"total_entries":    archived + len(lc.Entries),
+		"last_hash":        lc.headLocked(),
+		"hash_algorithm":   lc.Header.currentEpoch().HashAlgorithm,
+		"epochs":           len(lc.Header.Epochs),
+		"pruned_entries":   lc.Header.prunedCount(),
+		"legal_holds":      len(lc.Header.LegalHolds),
+		"archived_entries": archived,
+		"archive_segments": len(lc.Header.Segments),
+		"format_version":   lc.Header.FormatVersion,
+		"checkpoints":      len(lc.Header.Checkpoints),
 	}
 
-	if len(lc.Entries) > 0 {
+	// Clock flags are counted across archives when they can be read
+	kept, err := lc.keptLocked()
+	if err != nil {
+		kept = lc.Entries
+	}
+	regressed, skewed := 0, 0
+	for _, entry := range kept {
+		if slices.Contains(entry.ClockFlags, ClockRegressed) {
+			regressed++
+		}
+		if slices.Contains(entry.ClockFlags, ClockSkewed) {
+			skewed++
+		}
+	}
+	stats["clock_regressed"] = regressed
+	stats["clock_skewed"] = skewed
+
+	if len(lc.Header.Segments) > 0 {
+		stats["first_timestamp"] = lc.Header.Segments[0].FirstTimestamp
+		stats["last_timestamp"] = lc.Header.Segments[len(lc.Header.Segments)-1].LastTimestamp
+	} else if len(lc.Entries) > 0 {
 		stats["first_timestamp"] = lc.Entries[0].Timestamp
+	}
+	if len(lc.Entries) > 0 {
 		stats["last_timestamp"] = lc.Entries[len(lc.Entries)-1].Timestamp
 	}
 
@@ -237,10 +750,10 @@ func (lc *LogChain) Stats() map[string]interface{} {
 func GetChainPath() string {
 	homeDir, _ := os.UserHomeDir()
 	chainPath := filepath.Join(homeDir, ".zcrypt", "logs.chain")
-	
+
 	// Ensure .zcrypt directory exists
 	zcryptDir := filepath.Join(homeDir, ".zcrypt")
 	os.MkdirAll(zcryptDir, 0700)
-	
+
 	return chainPath
-}
\ No newline at end of file
+}
@@ -1,30 +1,84 @@
 package crypto
 
 import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/hex"
+	"math/rand/v2"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 )
 
-func TestNewLogChain(t *testing.T) {
-	tempFile := os.TempDir() + "/test_chain.json"
-	defer os.Remove(tempFile)
+// testEpoch is when deterministic test chains start their clock
+var testEpoch = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// testClock returns a clock that reads testEpoch and then one second later
+// on every call
+func testClock() func() time.Time {
+	now := testEpoch.Add(-time.Second)
+	return func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+}
 
-	chain, err := NewLogChain(tempFile)
+// newTestChain opens a chain at path with a stepping clock and a seeded
+// random source, so the same calls always write the same file
+func newTestChain(t *testing.T, path string, opts ...ChainOption) *LogChain {
+	t.Helper()
+	opts = append([]ChainOption{WithClock(testClock()), WithRandom(rand.NewChaCha8([32]byte{}))}, opts...)
+	chain, err := NewLogChain(path, opts...)
 	if err != nil {
 		t.Fatalf("Failed to create chain: %v", err)
 	}
+	return chain
+}
+
+// testSigner returns an Ed25519 signer derived from seed
+func testSigner(seed byte) Signer {
+	return NewEd25519Signer(ed25519.NewKeyFromSeed(bytes.Repeat([]byte{seed}, ed25519.SeedSize)))
+}
+
+// addSigned appends a message signed by the given signer
+func addSigned(t *testing.T, chain *LogChain, signer Signer, message string) *LogEntry {
+	t.Helper()
+	sig, err := signer.Sign([]byte(message))
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+	entry, err := chain.AddEntry(LogEntry{
+		Message:   message,
+		Signature: hex.EncodeToString(sig),
+		PubKey:    hex.EncodeToString(signer.PublicKey()),
+		Algorithm: signer.Algorithm(),
+	})
+	if err != nil {
+		t.Fatalf("Failed to add log: %v", err)
+	}
+	return entry
+}
+
+func TestNewLogChain(t *testing.T) {
+	tempFile := os.TempDir() + "/test_chain.json"
+	defer os.Remove(tempFile)
+
+	chain := newTestChain(t, tempFile)
 
 	if len(chain.Entries) != 0 {
 		t.Errorf("Expected empty chain, got %d entries", len(chain.Entries))
 	}
+	if !chain.Header.CreatedAt.Equal(testEpoch) {
+		t.Errorf("Expected header created at %s, got %s", testEpoch, chain.Header.CreatedAt)
+	}
 }
 
 func TestAddLog(t *testing.T) {
 	tempFile := os.TempDir() + "/test_chain.json"
 	defer os.Remove(tempFile)
 
-	chain, _ := NewLogChain(tempFile)
+	chain := newTestChain(t, tempFile)
 
 	entry, err := chain.AddLog(
 		"Test message",
@@ -45,6 +99,10 @@ func TestAddLog(t *testing.T) {
 		t.Error("Expected current_hash to be set")
 	}
 
+	if !entry.Timestamp.Equal(testEpoch.Add(time.Second)) {
+		t.Errorf("Expected timestamp from the chain clock, got %s", entry.Timestamp)
+	}
+
 	if len(chain.Entries) != 1 {
 		t.Errorf("Expected 1 entry, got %d", len(chain.Entries))
 	}
@@ -54,7 +112,7 @@ func TestChainLinking(t *testing.T) {
 	tempFile := os.TempDir() + "/test_chain.json"
 	defer os.Remove(tempFile)
 
-	chain, _ := NewLogChain(tempFile)
+	chain := newTestChain(t, tempFile)
 
 	entry1, _ := chain.AddLog("Log 1", "sig1", "key1", nil)
 	entry2, _ := chain.AddLog("Log 2", "sig2", "key2", nil)
@@ -73,10 +131,11 @@ func TestVerifyChain(t *testing.T) {
 	tempFile := os.TempDir() + "/test_chain.json"
 	defer os.Remove(tempFile)
 
-	chain, _ := NewLogChain(tempFile)
+	chain := newTestChain(t, tempFile)
+	signer := testSigner(1)
 
-	chain.AddLog("Log 1", "sig1", "key1", nil)
-	chain.AddLog("Log 2", "sig2", "key2", nil)
+	addSigned(t, chain, signer, "Log 1")
+	addSigned(t, chain, signer, "Log 2")
 
 	valid, errors := chain.VerifyChain()
 	if !valid {
@@ -84,11 +143,24 @@ func TestVerifyChain(t *testing.T) {
 	}
 }
 
+func TestVerifyChainRejectsBadSignature(t *testing.T) {
+	tempFile := os.TempDir() + "/test_chain.json"
+	defer os.Remove(tempFile)
+
+	chain := newTestChain(t, tempFile)
+	chain.AddLog("Log 1", "sig1", "key1", nil)
+
+	valid, errors := chain.VerifyChain()
+	if valid || len(errors) != 1 {
+		t.Errorf("Expected a single signature error, got %v", errors)
+	}
+}
+
 func TestTamperedChain(t *testing.T) {
 	tempFile := os.TempDir() + "/test_chain.json"
 	defer os.Remove(tempFile)
 
-	chain, _ := NewLogChain(tempFile)
+	chain := newTestChain(t, tempFile)
 
 	chain.AddLog("Log 1", "sig1", "key1", nil)
 	chain.AddLog("Log 2", "sig2", "key2", nil)
@@ -110,7 +182,7 @@ func TestPersistence(t *testing.T) {
 	tempFile := os.TempDir() + "/test_chain.json"
 	defer os.Remove(tempFile)
 
-	chain1, _ := NewLogChain(tempFile)
+	chain1 := newTestChain(t, tempFile)
 	chain1.AddLog("Log 1", "sig1", "key1", nil)
 	chain1.AddLog("Log 2", "sig2", "key2", nil)
 
@@ -132,17 +204,165 @@ func TestGetEntriesRange(t *testing.T) {
 	tempFile := os.TempDir() + "/test_chain.json"
 	defer os.Remove(tempFile)
 
-	chain, _ := NewLogChain(tempFile)
+	chain := newTestChain(t, tempFile)
 
-	now := time.Now()
 	chain.AddLog("Log 1", "sig1", "key1", nil)
-	time.Sleep(10 * time.Millisecond)
 	chain.AddLog("Log 2", "sig2", "key2", nil)
-	time.Sleep(10 * time.Millisecond)
 	chain.AddLog("Log 3", "sig3", "key3", nil)
 
-	entries := chain.GetEntriesRange(now.Add(-1*time.Hour), now.Add(1*time.Hour))
+	entries := chain.GetEntriesRange(testEpoch, testEpoch.Add(time.Hour))
 	if len(entries) != 3 {
 		t.Errorf("Expected 3 entries, got %d", len(entries))
 	}
-}
\ No newline at end of file
+
+	// Entries were received at one-second steps after the header
+	entries = chain.GetEntriesRange(testEpoch.Add(2*time.Second), testEpoch.Add(3*time.Second))
+	if len(entries) != 2 || entries[0].Message != "Log 2" {
+		t.Errorf("Expected Log 2 and Log 3, got %v", entries)
+	}
+}
+
+func TestDeterministicChain(t *testing.T) {
+	first := os.TempDir() + "/test_chain_deterministic_a.json"
+	second := os.TempDir() + "/test_chain_deterministic_b.json"
+	defer os.Remove(first)
+	defer os.Remove(second)
+
+	build := func(path string) []byte {
+		chain := newTestChain(t, path, WithHashAlgorithm(HashSHA3_256))
+		signer := testSigner(1)
+		addSigned(t, chain, signer, "Log 1")
+		sig, _ := signer.Sign([]byte("Log 2"))
+		chain.AddEntry(LogEntry{
+			Message:   "Log 2",
+			Signature: hex.EncodeToString(sig),
+			PubKey:    hex.EncodeToString(signer.PublicKey()),
+			Algorithm: signer.Algorithm(),
+			Metadata:  map[string]interface{}{"user": "alice", "id": 7},
+		})
+		if _, err := chain.SealEpoch(signer, HashSHA512_256); err != nil {
+			t.Fatalf("Failed to seal epoch: %v", err)
+		}
+		addSigned(t, chain, signer, "Log 3")
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Failed to read chain: %v", err)
+		}
+		return data
+	}
+
+	a, b := build(first), build(second)
+	if !bytes.Equal(a, b) {
+		t.Fatal("Expected the same inputs to build byte-identical chains")
+	}
+
+	reloaded, _ := NewLogChain(first)
+	if valid, errors := reloaded.VerifyChain(); !valid {
+		t.Errorf("Expected deterministic chain to verify: %v", errors)
+	}
+	if reloaded.Header.Epochs[0].HashAlgorithm != HashSHA3_256 {
+		t.Errorf("Expected first epoch hashed with sha3-256, got %s", reloaded.Header.Epochs[0].HashAlgorithm)
+	}
+}
+
+func TestChainOptions(t *testing.T) {
+	tempFile := os.TempDir() + "/test_chain_options.json"
+	defer os.Remove(tempFile)
+
+	if _, err := NewLogChain(tempFile, WithHashAlgorithm("md5")); err == nil {
+		t.Error("Expected unknown hash algorithm to be rejected")
+	}
+
+	// A historical import keeps its original times
+	imported := time.Date(2019, 6, 1, 12, 0, 0, 0, time.FixedZone("CEST", 2*60*60))
+	chain := newTestChain(t, tempFile, WithClock(func() time.Time { return imported }))
+	entry, _ := chain.AddLog("Imported", "sig", "key", nil)
+	if !entry.Timestamp.Equal(imported) || entry.Timestamp.Location() != time.UTC {
+		t.Errorf("Expected imported time in UTC, got %s", entry.Timestamp)
+	}
+
+	// The hash algorithm only applies to new chains
+	reloaded, err := NewLogChain(tempFile, WithHashAlgorithm(HashSHA3_256))
+	if err != nil {
+		t.Fatalf("Failed to reload chain: %v", err)
+	}
+	if reloaded.HashAlgorithm() != DefaultHashAlgorithm {
+		t.Errorf("Expected existing chain to keep %s, got %s", DefaultHashAlgorithm, reloaded.HashAlgorithm())
+	}
+}
+
+func TestAddLogs(t *testing.T) {
+	tempFile := os.TempDir() + "/test_chain_batch.json"
+	defer os.Remove(tempFile)
+
+	chain := newTestChain(t, tempFile)
+	signer := testSigner(1)
+	addSigned(t, chain, signer, "Log 0")
+
+	var batch []LogEntry
+	for _, message := range []string{"Log 1", "Log 2", "Log 3"} {
+		sig, _ := signer.Sign([]byte(message))
+		batch = append(batch, LogEntry{
+			Message:   message,
+			Signature: hex.EncodeToString(sig),
+			PubKey:    hex.EncodeToString(signer.PublicKey()),
+			Algorithm: signer.Algorithm(),
+		})
+	}
+	appended, err := chain.AddLogs(batch)
+	if err != nil {
+		t.Fatalf("Failed to add batch: %v", err)
+	}
+	if len(appended) != 3 || appended[0].PrevHash != chain.Entries[0].CurrentHash || appended[2].Sequence != 4 {
+		t.Fatalf("Expected batch linked after the existing entry, got %+v", appended)
+	}
+
+	// One bad entry fails the whole batch
+	bad := LogEntry{Message: "leaked", Envelope: &Envelope{Commitment: "00"}}
+	if _, err := chain.AddLogs([]LogEntry{batch[0], bad}); err == nil {
+		t.Error("Expected batch with an invalid entry to fail")
+	}
+	if len(chain.Entries) != 4 {
+		t.Errorf("Expected failed batch to leave 4 entries, got %d", len(chain.Entries))
+	}
+
+	reloaded, _ := NewLogChain(tempFile)
+	if valid, errors := reloaded.VerifyChain(); !valid || len(reloaded.Entries) != 4 {
+		t.Errorf("Expected 4 valid entries after reload, got %d: %v", len(reloaded.Entries), errors)
+	}
+}
+
+func TestFailedSaveLeavesChain(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "chain.json")
+	chain := newTestChain(t, path)
+	signer := testSigner(1)
+	sig, _ := signer.Sign([]byte("Log 0"))
+	entry := LogEntry{
+		Message:   "Log 0",
+		Signature: hex.EncodeToString(sig),
+		PubKey:    hex.EncodeToString(signer.PublicKey()),
+		Algorithm: signer.Algorithm(),
+	}
+
+	// A directory where the temporary file goes fails the save after the
+	// entry is staged
+	if err := os.Mkdir(path+".tmp", 0700); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if _, err := chain.AddEntry(entry); err == nil {
+		t.Fatal("Expected the save to fail")
+	}
+	if len(chain.Entries) != 0 || len(chain.Header.SignatureAlgorithms) != 0 {
+		t.Errorf("Expected the failed append to be rolled back, got %d entries and algorithms %v", len(chain.Entries), chain.Header.SignatureAlgorithms)
+	}
+
+	// The retry appends the entry once
+	os.Remove(path + ".tmp")
+	if _, err := chain.AddEntry(entry); err != nil {
+		t.Fatalf("Failed to retry: %v", err)
+	}
+	reloaded, _ := NewLogChain(path)
+	if valid, errors := reloaded.VerifyChain(); !valid || len(reloaded.Entries) != 1 {
+		t.Errorf("Expected one valid entry after the retry, got %d: %v", len(reloaded.Entries), errors)
+	}
+}
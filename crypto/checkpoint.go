@@ -0,0 +1,119 @@
+package crypto
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// CheckpointDomain separates checkpoint statements from other timestamped data
+const CheckpointDomain = "zcrypt-checkpoint-v1"
+
+// Checkpoint is an RFC 3161 timestamp over the chain head at a given length.
+// It lets a third party's clock vouch that entries [0, Length) existed no
+// later than GenTime, without trusting the chain's own timestamps.
+type Checkpoint struct {
+	Length  int       `json:"length"`        // Entries covered by the checkpoint
+	Head    string    `json:"head"`          // Hash of entry Length-1
+	TSA     string    `json:"tsa,omitempty"` // URL of the time-stamp authority
+	Token   []byte    `json:"token"`         // DER TimeStampToken over CheckpointBytes
+	GenTime time.Time `json:"gen_time"`      // Time asserted by the token
+}
+
+// CheckpointBytes returns the domain-separated statement a TSA timestamps
+func CheckpointBytes(length int, head string) []byte {
+	data, _ := json.Marshal(struct {
+		Length int    `json:"length"`
+		Head   string `json:"head"`
+	}{length, head})
+	return append([]byte(CheckpointDomain+"\x00"), data...)
+}
+
+// Verify checks offline that the checkpoint's token covers its length and
+// head and asserts its GenTime. See VerifyTimestamp for trusted.
+func (cp Checkpoint) Verify(trusted []*x509.Certificate) (*TimestampInfo, error) {
+	info, err := VerifyTimestamp(cp.Token, CheckpointBytes(cp.Length, cp.Head), trusted)
+	if err != nil {
+		return nil, err
+	}
+	if !info.GenTime.Equal(cp.GenTime) {
+		return nil, fmt.Errorf("token time %s does not match checkpoint time %s", info.GenTime.Format(time.RFC3339), cp.GenTime.Format(time.RFC3339))
+	}
+	return info, nil
+}
+
+// CheckpointHead returns the chain length and head hash to timestamp
+func (lc *LogChain) CheckpointHead() (int, string) {
+	lc.mu.RLock()
+	defer lc.mu.RUnlock()
+	return lc.lengthLocked(), lc.headLocked()
+}
+
+// AddCheckpoint stores a timestamped checkpoint after checking that its
+// token covers it and that its head is the chain's entry at Length-1. The
+// chain may have grown since the head was timestamped. GenTime is taken
+// from the token.
+func (lc *LogChain) AddCheckpoint(cp Checkpoint) (*Checkpoint, error) {
+	unlock, err := lc.lockWrite()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if cp.Length <= 0 || cp.Length > lc.lengthLocked() {
+		return nil, fmt.Errorf("checkpoint length %d is outside the chain", cp.Length)
+	}
+	if entry := lc.entryLocked(cp.Length - 1); entry == nil || entry.CurrentHash != cp.Head {
+		return nil, fmt.Errorf("checkpoint head does not match entry %d", cp.Length-1)
+	}
+	info, err := VerifyTimestamp(cp.Token, CheckpointBytes(cp.Length, cp.Head), nil)
+	if err != nil {
+		return nil, err
+	}
+	cp.GenTime = info.GenTime
+
+	lc.Header.Checkpoints = append(lc.Header.Checkpoints, cp)
+	if err := lc.Save(); err != nil {
+		lc.Header.Checkpoints = lc.Header.Checkpoints[:len(lc.Header.Checkpoints)-1]
+		return nil, fmt.Errorf("failed to save chain: %w", err)
+	}
+	return &cp, nil
+}
+
+// Checkpoints returns the chain's timestamped checkpoints, oldest first
+func (lc *LogChain) Checkpoints() []Checkpoint {
+	lc.mu.RLock()
+	defer lc.mu.RUnlock()
+	return append([]Checkpoint(nil), lc.Header.Checkpoints...)
+}
+
+// SetTrustedTSA sets the certificates checkpoint tokens must chain to.
+// Without them verification can only check a token against the certificate
+// it carries, and reports the checkpoint as untrusted.
+func (lc *LogChain) SetTrustedTSA(certs []*x509.Certificate) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	lc.tsaCerts = certs
+}
+
+// verifyCheckpointsLocked checks every checkpoint's token against the
+// trusted TSA certificates and its head against the chain. Heads of pruned
+// entries are covered by their anchors. Without trusted certificates each
+// token is checked against its own certificate and its index returned as
+// untrusted. Callers hold the lock.
+func (lc *LogChain) verifyCheckpointsLocked() (errors []string, untrusted []int) {
+	for i, cp := range lc.Header.Checkpoints {
+		if _, err := cp.Verify(lc.tsaCerts); err != nil {
+			errors = append(errors, fmt.Sprintf("Checkpoint %d: %v", i, err))
+		} else if len(lc.tsaCerts) == 0 {
+			untrusted = append(untrusted, i)
+		}
+		if cp.Length > lc.lengthLocked() {
+			errors = append(errors, fmt.Sprintf("Checkpoint %d: covers entries beyond the chain", i))
+		} else if entry := lc.entryLocked(cp.Length - 1); entry != nil && entry.CurrentHash != cp.Head {
+			errors = append(errors, fmt.Sprintf("Checkpoint %d: head does not match entry %d", i, cp.Length-1))
+		}
+	}
+	return errors, untrusted
+}
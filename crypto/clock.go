@@ -0,0 +1,130 @@
+package crypto
+
+import (
+	"os"
+	"slices"
+	"time"
+)
+
+// DefaultMaxClockSkew is how far an agent's claimed time may differ from the
+// time the chain received the entry before the entry is flagged
+const DefaultMaxClockSkew = time.Minute
+
+// Clock flags recorded on entries whose times need a second look
+const (
+	// ClockRegressed marks an entry received while the chain's clock read
+	// earlier than the previous entry; its timestamp was held at the previous one
+	ClockRegressed = "clock-regressed"
+
+	// ClockSkewed marks an entry whose claimed time differs from its received
+	// time by more than the chain's maximum skew
+	ClockSkewed = "clock-skewed"
+)
+
+// ClockStatus describes an entry carrying clock flags
+type ClockStatus struct {
+	Index     int        `json:"index"`
+	Flags     []string   `json:"flags"`
+	Timestamp time.Time  `json:"timestamp"`            // When the chain received the entry
+	ClaimedAt *time.Time `json:"claimed_at,omitempty"` // When the submitter says it was written
+}
+
+// Skew returns how far the received time is ahead of the claimed time
+func (s ClockStatus) Skew() time.Duration {
+	if s.ClaimedAt == nil {
+		return 0
+	}
+	return s.Timestamp.Sub(*s.ClaimedAt)
+}
+
+// SetMaxClockSkew sets how far claimed times may differ from received times
+// before new entries are flagged. Zero or less disables the check.
+func (lc *LogChain) SetMaxClockSkew(skew time.Duration) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	lc.maxClockSkew = skew
+}
+
+// maxClockSkewFromEnv reads ZCRYPT_MAX_CLOCK_SKEW, falling back to DefaultMaxClockSkew
+func maxClockSkewFromEnv() time.Duration {
+	if value := os.Getenv("ZCRYPT_MAX_CLOCK_SKEW"); value != "" {
+		if skew, err := time.ParseDuration(value); err == nil {
+			return skew
+		}
+	}
+	return DefaultMaxClockSkew
+}
+
+// lastTimestampLocked returns the received time of the last kept entry, or
+// the zero time when there is none; callers hold the lock
+func (lc *LogChain) lastTimestampLocked() time.Time {
+	if len(lc.Entries) > 0 {
+		return lc.Entries[len(lc.Entries)-1].Timestamp
+	}
+	if segments := lc.Header.Segments; len(segments) > 0 {
+		return segments[len(segments)-1].LastTimestamp
+	}
+	return time.Time{}
+}
+
+// stampLocked sets the entry's received time, never earlier than the
+// previous entry's. Current-format chains also number the entry, keep the
+// envelope's time as its claimed time and record clock flags. Callers hold
+// the write lock.
+func (lc *LogChain) stampLocked(entry *LogEntry, now time.Time) {
+	var flags []string
+	entry.Timestamp = now
+	if last := lc.lastTimestampLocked(); now.Before(last) {
+		entry.Timestamp = last
+		flags = append(flags, ClockRegressed)
+	}
+	if lc.Header.FormatVersion < FormatVersionSequenced {
+		return
+	}
+
+	entry.Sequence = lc.lengthLocked() + 1
+	if entry.ClaimedAt == nil && entry.Envelope != nil {
+		claimed := entry.Envelope.Timestamp.UTC()
+		entry.ClaimedAt = &claimed
+	}
+	if entry.ClaimedAt != nil && lc.maxClockSkew > 0 {
+		if skew := entry.Timestamp.Sub(*entry.ClaimedAt).Abs(); skew > lc.maxClockSkew {
+			flags = append(flags, ClockSkewed)
+		}
+	}
+	entry.ClockFlags = flags
+}
+
+// checkClock checks an entry's sequence number and received time against
+// the entry before it. Entries written before sequencing are not checked.
+func checkClock(i int, entry LogEntry, prevTime time.Time, sequenced bool) []string {
+	var problems []string
+	switch {
+	case entry.Sequence == 0 && sequenced:
+		problems = append(problems, "missing sequence number")
+	case entry.Sequence != 0 && entry.Sequence != i+1:
+		problems = append(problems, "sequence number out of order")
+	}
+	if entry.Sequence != 0 && entry.Timestamp.Before(prevTime) {
+		problems = append(problems, "timestamp earlier than the previous entry")
+	}
+	for _, flag := range entry.ClockFlags {
+		if flag != ClockRegressed && flag != ClockSkewed {
+			problems = append(problems, "unknown clock flag "+flag)
+		}
+	}
+	return problems
+}
+
+// clockStatus returns the clock flags of an entry, or nil if it has none
+func clockStatus(i int, entry LogEntry) *ClockStatus {
+	if len(entry.ClockFlags) == 0 {
+		return nil
+	}
+	return &ClockStatus{
+		Index:     i,
+		Flags:     slices.Clone(entry.ClockFlags),
+		Timestamp: entry.Timestamp,
+		ClaimedAt: entry.ClaimedAt,
+	}
+}
@@ -0,0 +1,98 @@
+package crypto
+
+import (
+	"encoding/hex"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestEntriesAreSequenced(t *testing.T) {
+	tempFile := os.TempDir() + "/test_chain_sequence.json"
+	defer os.Remove(tempFile)
+
+	chain, _ := NewLogChain(tempFile)
+	signer, _ := GenerateSigner(AlgEd25519)
+	for i := 0; i < 3; i++ {
+		if entry := addSigned(t, chain, signer, "Log"); entry.Sequence != i+1 {
+			t.Errorf("Expected sequence %d, got %d", i+1, entry.Sequence)
+		}
+	}
+	if valid, errors := chain.VerifyChain(); !valid {
+		t.Fatalf("Expected chain to verify: %v", errors)
+	}
+
+	// Sequence numbers are covered by the entry hash
+	chain.Entries[1].Sequence = 3
+	if valid, _ := chain.VerifyChain(); valid {
+		t.Error("Expected altered sequence number to be detected")
+	}
+	chain.Entries[1].CurrentHash = calculateHash(chain.Entries[1], DefaultHashAlgorithm)
+	chain.Entries[2].PrevHash = chain.Entries[1].CurrentHash
+	chain.Entries[2].CurrentHash = calculateHash(chain.Entries[2], DefaultHashAlgorithm)
+	report := chain.VerifyChainReport()
+	if report.Valid || report.Errors[0] != "Entry 1: sequence number out of order" {
+		t.Errorf("Expected rehashed sequence to be out of order, got %v", report.Errors)
+	}
+}
+
+func TestClockRegressionIsClamped(t *testing.T) {
+	tempFile := os.TempDir() + "/test_chain_regress.json"
+	defer os.Remove(tempFile)
+
+	chain, _ := NewLogChain(tempFile)
+	signer, _ := GenerateSigner(AlgEd25519)
+	first := addSigned(t, chain, signer, "Log 0")
+
+	// A clock stepped back an hour cannot move the chain backwards
+	entry := LogEntry{Message: "Log 1", PubKey: hex.EncodeToString(signer.PublicKey()), Algorithm: AlgEd25519}
+	chain.stampLocked(&entry, first.Timestamp.Add(-time.Hour))
+	if !entry.Timestamp.Equal(first.Timestamp) || len(entry.ClockFlags) != 1 || entry.ClockFlags[0] != ClockRegressed {
+		t.Errorf("Expected timestamp held at the previous entry and flagged, got %s %v", entry.Timestamp, entry.ClockFlags)
+	}
+	if entry.Sequence != 2 {
+		t.Errorf("Expected sequence 2, got %d", entry.Sequence)
+	}
+}
+
+func TestClaimedTimeSkew(t *testing.T) {
+	tempFile := os.TempDir() + "/test_chain_skew.json"
+	defer os.Remove(tempFile)
+
+	chain, _ := NewLogChain(tempFile)
+	chain.SetMaxClockSkew(time.Minute)
+	signer, _ := GenerateSigner(AlgEd25519)
+
+	env, _ := NewEnvelope("agent-1", "test", nil)
+	env.Timestamp = time.Now().UTC().Add(-10 * time.Minute)
+	sig, _ := SignEnvelope(signer, "Late log", env)
+	late, err := chain.AddEntry(LogEntry{
+		Message:   "Late log",
+		Signature: sig,
+		PubKey:    hex.EncodeToString(signer.PublicKey()),
+		Algorithm: AlgEd25519,
+		Envelope:  env,
+	})
+	if err != nil {
+		t.Fatalf("Failed to add entry: %v", err)
+	}
+	if late.ClaimedAt == nil || !late.ClaimedAt.Equal(env.Timestamp) {
+		t.Errorf("Expected envelope time kept as the claimed time, got %v", late.ClaimedAt)
+	}
+	if len(late.ClockFlags) != 1 || late.ClockFlags[0] != ClockSkewed {
+		t.Errorf("Expected skew flag, got %v", late.ClockFlags)
+	}
+	addSigned(t, chain, signer, "On time")
+
+	reloaded, _ := NewLogChain(tempFile)
+	report := reloaded.VerifyChainReport()
+	if !report.Valid || len(report.Clock) != 1 || report.Clock[0].Index != 0 {
+		t.Fatalf("Expected one flagged entry in a valid chain, got %+v", report)
+	}
+	if skew := report.Clock[0].Skew(); skew < 10*time.Minute {
+		t.Errorf("Expected about 10m of skew, got %s", skew)
+	}
+	if stats := reloaded.Stats(); stats["clock_skewed"] != 1 || stats["clock_regressed"] != 0 {
+		t.Errorf("Unexpected stats %v", stats)
+	}
+}
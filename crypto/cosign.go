@@ -0,0 +1,195 @@
+package crypto
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Entry kinds. Ordinary log entries leave Kind empty.
+const (
+	KindCoSign = "cosign"
+)
+
+// ErrNoEntry is returned when a co-signature targets an entry the chain
+// does not hold, because it was never appended or has been pruned
+var ErrNoEntry = errors.New("no entry at index")
+
+// ErrCoSignRejected is returned when a co-signature is invalid or the
+// target's policy does not accept it
+var ErrCoSignRejected = errors.New("co-signature rejected")
+
+// CoSignDomain separates co-signatures from envelope and message signatures
+const CoSignDomain = "zcrypt-cosign-v1"
+
+// CoSignPolicy declares who must approve an entry before it counts.
+// Signers are hex public keys; Threshold of them must co-sign.
+type CoSignPolicy struct {
+	Signers   []string `json:"signers"`
+	Threshold int      `json:"threshold"`
+}
+
+// Validate checks the threshold fits the signer set
+func (p *CoSignPolicy) Validate() error {
+	if p.Threshold < 1 {
+		return errors.New("co-sign threshold must be at least 1")
+	}
+	if p.Threshold > len(p.Signers) {
+		return fmt.Errorf("co-sign threshold %d exceeds %d signers", p.Threshold, len(p.Signers))
+	}
+	seen := make(map[string]bool, len(p.Signers))
+	for _, signer := range p.Signers {
+		if _, err := hex.DecodeString(signer); err != nil || signer == "" {
+			return fmt.Errorf("invalid co-signer key %q", signer)
+		}
+		if seen[signer] {
+			return fmt.Errorf("duplicate co-signer key %s", signer)
+		}
+		seen[signer] = true
+	}
+	return nil
+}
+
+// allows reports whether pubKey is in the signer set
+func (p *CoSignPolicy) allows(pubKey string) bool {
+	for _, signer := range p.Signers {
+		if signer == pubKey {
+			return true
+		}
+	}
+	return false
+}
+
+// CoSignRef points a co-signature entry at the entry it approves
+type CoSignRef struct {
+	TargetIndex int    `json:"target_index"`
+	TargetHash  string `json:"target_hash"`
+}
+
+// CoSignBytes returns the domain-separated statement a co-signer signs
+func CoSignBytes(targetIndex int, targetHash string) []byte {
+	data, _ := json.Marshal(CoSignRef{TargetIndex: targetIndex, TargetHash: targetHash})
+	return append([]byte(CoSignDomain+"\x00"), data...)
+}
+
+// SignCoSignature signs approval of the entry at targetIndex with the given hash
+func SignCoSignature(signer Signer, targetIndex int, targetHash string) (string, error) {
+	sig, err := signer.Sign(CoSignBytes(targetIndex, targetHash))
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(sig), nil
+}
+
+// CoSignPolicy returns the policy declared in the entry's signed envelope
+func (e *LogEntry) CoSignPolicy() *CoSignPolicy {
+	if e.Envelope == nil {
+		return nil
+	}
+	return e.Envelope.CoSign
+}
+
+// CoSignStatus reports how far an entry is from meeting its threshold
+type CoSignStatus struct {
+	Index     int      `json:"index"`
+	Hash      string   `json:"hash"`
+	Threshold int      `json:"threshold"`
+	Signers   []string `json:"signers"`
+	SignedBy  []string `json:"signed_by"`
+	Met       bool     `json:"met"`
+}
+
+// AddCoSignature verifies a co-signature over the entry at targetIndex and
+// records it as a new entry linked to the target
+func (lc *LogChain) AddCoSignature(targetIndex int, signature, pubKey, algorithm string, metadata map[string]interface{}) (*LogEntry, error) {
+	unlock, err := lc.lockWrite()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	original := lc.entryLocked(targetIndex)
+	if original == nil {
+		return nil, fmt.Errorf("%w %d", ErrNoEntry, targetIndex)
+	}
+	target := lc.plainLocked(*original)
+
+	policy := target.CoSignPolicy()
+	if policy == nil {
+		return nil, fmt.Errorf("%w: entry %d does not require co-signatures", ErrCoSignRejected, targetIndex)
+	}
+	if !policy.allows(pubKey) {
+		return nil, fmt.Errorf("%w: key is not an authorized co-signer for entry %d", ErrCoSignRejected, targetIndex)
+	}
+	for _, signed := range lc.coSignStatus(targetIndex).SignedBy {
+		if signed == pubKey {
+			return nil, fmt.Errorf("%w: entry %d already co-signed by this key", ErrCoSignRejected, targetIndex)
+		}
+	}
+
+	ref := &CoSignRef{TargetIndex: targetIndex, TargetHash: target.CurrentHash}
+	if err := VerifyWithAlgorithm(algorithm, pubKey, CoSignBytes(ref.TargetIndex, ref.TargetHash), signature); err != nil {
+		return nil, fmt.Errorf("%w: invalid signature: %v", ErrCoSignRejected, err)
+	}
+
+	return lc.appendLocked(LogEntry{
+		Kind:      KindCoSign,
+		Message:   fmt.Sprintf("Co-signature for entry %d", targetIndex),
+		Signature: signature,
+		PubKey:    pubKey,
+		Algorithm: NormalizeAlgorithm(algorithm),
+		Metadata:  metadata,
+		CoSigns:   ref,
+	})
+}
+
+// CoSignStatus collects the valid co-signatures recorded for an entry
+func (lc *LogChain) CoSignStatus(index int) (*CoSignStatus, error) {
+	lc.mu.RLock()
+	defer lc.mu.RUnlock()
+
+	if lc.entryLocked(index) == nil {
+		return nil, fmt.Errorf("index out of range")
+	}
+	status := lc.coSignStatus(index)
+	if status == nil {
+		return nil, fmt.Errorf("entry %d does not require co-signatures", index)
+	}
+	return status, nil
+}
+
+// coSignStatus scans entries after index for co-signatures; callers hold the lock
+func (lc *LogChain) coSignStatus(index int) *CoSignStatus {
+	target := lc.plainLocked(*lc.entryLocked(index))
+	policy := target.CoSignPolicy()
+	if policy == nil {
+		return nil
+	}
+
+	status := &CoSignStatus{
+		Index:     index,
+		Hash:      target.CurrentHash,
+		Threshold: policy.Threshold,
+		Signers:   policy.Signers,
+		SignedBy:  []string{},
+	}
+
+	seen := make(map[string]bool)
+	for _, entry := range lc.entriesFromLocked(index + 1) {
+		if entry.Kind != KindCoSign || entry.CoSigns == nil || entry.CoSigns.TargetIndex != index {
+			continue
+		}
+		if entry.CoSigns.TargetHash != target.CurrentHash || !policy.allows(entry.PubKey) || seen[entry.PubKey] {
+			continue
+		}
+		if plain := lc.plainLocked(entry); plain.VerifySignature() != nil {
+			continue
+		}
+		seen[entry.PubKey] = true
+		status.SignedBy = append(status.SignedBy, entry.PubKey)
+	}
+
+	status.Met = len(status.SignedBy) >= policy.Threshold
+	return status
+}
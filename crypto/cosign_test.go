@@ -0,0 +1,83 @@
+package crypto
+
+import (
+	"encoding/hex"
+	"os"
+	"testing"
+)
+
+func TestCoSignThreshold(t *testing.T) {
+	tempFile := os.TempDir() + "/test_chain_cosign.json"
+	defer os.Remove(tempFile)
+
+	chain, _ := NewLogChain(tempFile)
+	author, _ := GenerateSigner(AlgEd25519)
+	reviewers := make([]Signer, 3)
+	policy := &CoSignPolicy{Threshold: 2}
+	for i := range reviewers {
+		reviewers[i], _ = GenerateSigner(AlgECDSAP256)
+		policy.Signers = append(policy.Signers, hex.EncodeToString(reviewers[i].PublicKey()))
+	}
+	if err := policy.Validate(); err != nil {
+		t.Fatalf("Policy should be valid: %v", err)
+	}
+
+	env, _ := NewEnvelope("deployer", DefaultServerChain, nil)
+	env.CoSign = policy
+	sig, _ := SignEnvelope(author, "Deploy v2 to production", env)
+	target, err := chain.AddEntry(LogEntry{
+		Message:   "Deploy v2 to production",
+		Signature: sig,
+		PubKey:    hex.EncodeToString(author.PublicKey()),
+		Algorithm: author.Algorithm(),
+		Envelope:  env,
+	})
+	if err != nil {
+		t.Fatalf("Failed to add entry: %v", err)
+	}
+
+	cosign := func(s Signer) error {
+		sig, _ := SignCoSignature(s, 0, target.CurrentHash)
+		_, err := chain.AddCoSignature(0, sig, hex.EncodeToString(s.PublicKey()), s.Algorithm(), nil)
+		return err
+	}
+
+	if err := cosign(reviewers[0]); err != nil {
+		t.Fatalf("First co-signature rejected: %v", err)
+	}
+	if status, _ := chain.CoSignStatus(0); status.Met {
+		t.Error("Threshold should not be met with one co-signature")
+	}
+	if err := cosign(reviewers[0]); err == nil {
+		t.Error("Duplicate co-signature accepted")
+	}
+	if err := cosign(author); err == nil {
+		t.Error("Co-signature from outside the signer set accepted")
+	}
+	if err := cosign(reviewers[2]); err != nil {
+		t.Fatalf("Second co-signature rejected: %v", err)
+	}
+
+	report := chain.VerifyChainReport()
+	if !report.Valid {
+		t.Fatalf("Chain should verify: %v", report.Errors)
+	}
+	if len(report.CoSign) != 1 || !report.CoSign[0].Met || len(report.CoSign[0].SignedBy) != 2 {
+		t.Errorf("Expected threshold met by two reviewers, got %+v", report.CoSign)
+	}
+
+	// Weakening the signed policy invalidates the author's signature
+	chain.Entries[0].Envelope.CoSign.Threshold = 1
+	if valid, _ := chain.VerifyChain(); valid {
+		t.Error("Chain should be invalid after changing the co-sign policy")
+	}
+}
+
+func TestCoSignPolicyValidate(t *testing.T) {
+	if err := (&CoSignPolicy{Signers: []string{"aa"}, Threshold: 2}).Validate(); err == nil {
+		t.Error("Threshold above signer count accepted")
+	}
+	if err := (&CoSignPolicy{Signers: []string{"aa", "aa"}, Threshold: 1}).Validate(); err == nil {
+		t.Error("Duplicate signers accepted")
+	}
+}
@@ -0,0 +1,139 @@
+package crypto
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// KindDisclosure marks an entry that reveals a withheld message
+const KindDisclosure = "disclosure"
+
+// DisclosureDomain separates disclosure consent from other signatures
+const DisclosureDomain = "zcrypt-disclosure-v1"
+
+// CommitMessage returns a salted commitment to a message that will be
+// withheld from the server. The agent keeps the salt to disclose later.
+func CommitMessage(message string) (commitment, salt string, err error) {
+	salt, err = NewSalt()
+	if err != nil {
+		return "", "", err
+	}
+	return CommitValue(FieldMessage, salt, []byte(message)), salt, nil
+}
+
+// CheckDisclosure reports whether message and salt open the entry's
+// message commitment. Anyone holding the entry can run it.
+func CheckDisclosure(entry *LogEntry, message, salt string) error {
+	if entry.Commitments == nil || entry.Commitments.Fields[FieldMessage] == "" {
+		return errors.New("entry has no message commitment")
+	}
+	if CommitValue(FieldMessage, salt, []byte(message)) != entry.Commitments.Fields[FieldMessage] {
+		return errors.New("message and salt do not match the commitment")
+	}
+	return nil
+}
+
+// DisclosureRef names the entry whose withheld message is revealed
+type DisclosureRef struct {
+	TargetIndex int    `json:"target_index"`
+	TargetHash  string `json:"target_hash"`
+
+	// Digest of a sealed target after resealing; set by the chain, not signed
+	Resealed string `json:"resealed,omitempty"`
+}
+
+// DisclosureBytes returns the domain-separated statement the original
+// signer signs to consent to publishing the message
+func DisclosureBytes(ref DisclosureRef) []byte {
+	ref.Resealed = ""
+	data, _ := json.Marshal(ref)
+	return append([]byte(DisclosureDomain+"\x00"), data...)
+}
+
+// SignDisclosure signs consent to disclose the entry's message
+func SignDisclosure(signer Signer, ref DisclosureRef) (string, error) {
+	sig, err := signer.Sign(DisclosureBytes(ref))
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(sig), nil
+}
+
+// Disclose fills in the withheld message of the entry at ref.TargetIndex
+// with its salt and records the disclosure as a new entry. The signature
+// must come from the key that signed the target. The target's hash does not
+// change because it covers the commitment, not the message.
+func (lc *LogChain) Disclose(ref DisclosureRef, message, salt, signature string, metadata map[string]interface{}) (*LogEntry, error) {
+	unlock, err := lc.lockWrite()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	pos, err := lc.livePos(ref.TargetIndex)
+	if err != nil {
+		return nil, err
+	}
+	original := lc.Entries[pos]
+	if original.CurrentHash != ref.TargetHash {
+		return nil, fmt.Errorf("entry %d does not have hash %s", ref.TargetIndex, ref.TargetHash)
+	}
+	c := original.Commitments
+	if c == nil || !c.isWithheld(FieldMessage) {
+		return nil, fmt.Errorf("entry %d has no withheld message", ref.TargetIndex)
+	}
+	if c.isRedacted(FieldMessage) {
+		return nil, fmt.Errorf("entry %d message was redacted", ref.TargetIndex)
+	}
+	if err := CheckDisclosure(&original, message, salt); err != nil {
+		return nil, err
+	}
+	ref.Resealed = ""
+	if err := VerifyWithAlgorithm(original.Algorithm, original.PubKey, DisclosureBytes(ref), signature); err != nil {
+		return nil, fmt.Errorf("disclosure must be signed by the entry's key: %w", err)
+	}
+
+	target, err := lc.openLocked(original)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open sealed entry %d: %w", ref.TargetIndex, err)
+	}
+	if _, ok := target.Commitments.Salts[FieldMessage]; ok {
+		return nil, fmt.Errorf("entry %d message is already disclosed", ref.TargetIndex)
+	}
+	commitments := *target.Commitments
+	commitments.Salts = map[string]string{FieldMessage: salt}
+	for field, s := range target.Commitments.Salts {
+		commitments.Salts[field] = s
+	}
+	target.Commitments = &commitments
+	target.Message = message
+
+	if original.Sealed != nil {
+		if err := lc.keyring.seal(&target, lc.random); err != nil {
+			return nil, fmt.Errorf("failed to reseal entry: %w", err)
+		}
+		ref.Resealed = target.Sealed.Digest
+		target.Sealed.Digest = original.Sealed.Digest
+	}
+	if calculateHash(target, lc.Header.epochAt(ref.TargetIndex).HashAlgorithm) != original.CurrentHash {
+		return nil, fmt.Errorf("disclosure would change the hash of entry %d", ref.TargetIndex)
+	}
+
+	lc.Entries[pos] = target
+	entry, err := lc.appendLocked(LogEntry{
+		Kind:      KindDisclosure,
+		Message:   fmt.Sprintf("Disclosure of entry %d", ref.TargetIndex),
+		Signature: signature,
+		PubKey:    original.PubKey,
+		Algorithm: NormalizeAlgorithm(original.Algorithm),
+		Metadata:  metadata,
+		Discloses: &ref,
+	})
+	if err != nil {
+		lc.Entries[pos] = original
+		return nil, err
+	}
+	return entry, nil
+}
@@ -0,0 +1,125 @@
+package crypto
+
+import (
+	"encoding/hex"
+	"os"
+	"testing"
+)
+
+// addConfidential appends an entry that carries only a commitment to message
+func addConfidential(t *testing.T, chain *LogChain, signer Signer, message string) (*LogEntry, string) {
+	t.Helper()
+	commitment, salt, err := CommitMessage(message)
+	if err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+	env, _ := NewEnvelope("agent-1", DefaultServerChain, nil)
+	env.Commitment = commitment
+	sig, err := SignEnvelope(signer, "", env)
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+	entry, err := chain.AddEntry(LogEntry{
+		Signature: sig,
+		PubKey:    hex.EncodeToString(signer.PublicKey()),
+		Algorithm: signer.Algorithm(),
+		Envelope:  env,
+	})
+	if err != nil {
+		t.Fatalf("Failed to add confidential entry: %v", err)
+	}
+	return entry, salt
+}
+
+// disclose signs and applies a disclosure of the entry at index
+func disclose(chain *LogChain, signer Signer, index int, message, salt string) (*LogEntry, error) {
+	ref := DisclosureRef{TargetIndex: index, TargetHash: chain.Entries[index].CurrentHash}
+	sig, err := SignDisclosure(signer, ref)
+	if err != nil {
+		return nil, err
+	}
+	return chain.Disclose(ref, message, salt, sig, nil)
+}
+
+func TestConfidentialSubmissionAndDisclosure(t *testing.T) {
+	tempFile := os.TempDir() + "/test_chain_disclose.json"
+	defer os.Remove(tempFile)
+
+	chain, _ := NewLogChain(tempFile)
+	agent, _ := GenerateSigner(AlgEd25519)
+	other, _ := GenerateSigner(AlgEd25519)
+
+	entry, salt := addConfidential(t, chain, agent, "merger talks with ACME started")
+	if entry.Message != "" || !entry.Commitments.isWithheld(FieldMessage) {
+		t.Fatalf("Expected withheld message, got %+v", entry)
+	}
+	report := chain.VerifyChainReport()
+	if !report.Valid || len(report.Withheld) != 1 {
+		t.Fatalf("Expected valid chain with one withheld entry, got %+v", report)
+	}
+	head := chain.GetLastHash()
+
+	if _, err := disclose(chain, agent, 0, "merger talks with ACME cancelled", salt); err == nil {
+		t.Error("Expected error for a message that does not match the commitment")
+	}
+	if _, err := disclose(chain, other, 0, "merger talks with ACME started", salt); err == nil {
+		t.Error("Expected error for disclosure signed by another key")
+	}
+	if _, err := disclose(chain, agent, 0, "merger talks with ACME started", salt); err != nil {
+		t.Fatalf("Failed to disclose: %v", err)
+	}
+	if _, err := disclose(chain, agent, 0, "merger talks with ACME started", salt); err == nil {
+		t.Error("Expected error disclosing twice")
+	}
+
+	reloaded, _ := NewLogChain(tempFile)
+	disclosed := reloaded.Entries[0]
+	if disclosed.Message != "merger talks with ACME started" || reloaded.Entries[1].PrevHash != head {
+		t.Errorf("Expected disclosed message with unchanged hash, got %+v", disclosed)
+	}
+	if err := CheckDisclosure(&disclosed, disclosed.Message, disclosed.Commitments.Salts[FieldMessage]); err != nil {
+		t.Errorf("Disclosed entry should check against its commitment: %v", err)
+	}
+	report = reloaded.VerifyChainReport()
+	if !report.Valid || len(report.Withheld) != 0 {
+		t.Errorf("Expected valid chain with nothing withheld, got %+v", report)
+	}
+}
+
+func TestWithheldMessageCannotBeFilledIn(t *testing.T) {
+	tempFile := os.TempDir() + "/test_chain_withheld.json"
+	defer os.Remove(tempFile)
+
+	chain, _ := NewLogChain(tempFile)
+	signer, _ := GenerateSigner(AlgEd25519)
+	addConfidential(t, chain, signer, "secret")
+
+	// Inserting a message without a matching disclosure is tampering
+	chain.Entries[0].Message = "not the secret"
+	if valid, _ := chain.VerifyChain(); valid {
+		t.Error("Expected inserted message to be detected")
+	}
+}
+
+func TestDiscloseSealedEntry(t *testing.T) {
+	tempFile := os.TempDir() + "/test_chain_disclose_sealed.json"
+	defer os.Remove(tempFile)
+
+	master, _ := GenerateMasterKey()
+	kr, _ := NewKeyring(master)
+	chain := newSealedChain(t, tempFile, kr)
+	signer, _ := GenerateSigner(AlgEd25519)
+	_, salt := addConfidential(t, chain, signer, "sealed secret")
+
+	if _, err := disclose(chain, signer, 0, "sealed secret", salt); err != nil {
+		t.Fatalf("Failed to disclose sealed entry: %v", err)
+	}
+	if opened, _ := chain.Open(chain.Entries[0]); opened.Message != "sealed secret" {
+		t.Errorf("Expected disclosed message inside the seal, got %q", opened.Message)
+	}
+
+	reloaded, _ := NewLogChain(tempFile)
+	if report := reloaded.VerifyChainReport(); !report.Valid {
+		t.Errorf("Expected resealed chain to verify without key, got %v", report.Errors)
+	}
+}
@@ -0,0 +1,71 @@
+package crypto
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// DiscoveryDomain separates discovery document signatures from other signed data
+const DiscoveryDomain = "zcrypt-discovery-v1"
+
+// DiscoveryPath is where a server publishes its discovery document
+const DiscoveryPath = "/.well-known/zcrypt"
+
+// Discovery describes a server to its clients: the API it speaks, the chains
+// it keeps and the algorithms it accepts. It is signed by the server identity
+// key it names, so a client that pins the key can tell when it changes.
+type Discovery struct {
+	APIVersion          string           `json:"api_version"`
+	APIBase             string           `json:"api_base"` // Path prefix of the API
+	Chains              []DiscoveryChain `json:"chains"`
+	SignatureAlgorithms []string         `json:"signature_algorithms"`
+	HashAlgorithms      []string         `json:"hash_algorithms"`
+	Features            []string         `json:"features,omitempty"` // Optional capabilities enabled on this server
+	IssuedAt            time.Time        `json:"issued_at"`
+	PubKey              string           `json:"pubkey"`
+	Algorithm           string           `json:"algorithm"`
+	Signature           string           `json:"signature"`
+}
+
+// DiscoveryChain is a chain a server keeps, by the name agents sign for
+type DiscoveryChain struct {
+	Name          string `json:"name"`
+	HashAlgorithm string `json:"hash_algorithm"` // Of the current epoch
+}
+
+// SigningBytes returns the domain-separated document covered by its signature
+func (d Discovery) SigningBytes() []byte {
+	d.Signature = ""
+	data, _ := json.Marshal(d)
+	return append([]byte(DiscoveryDomain+"\x00"), data...)
+}
+
+// Sign signs the document with signer, recording its key and algorithm
+func (d *Discovery) Sign(signer Signer) error {
+	d.PubKey = hex.EncodeToString(signer.PublicKey())
+	d.Algorithm = signer.Algorithm()
+	sig, err := signer.Sign(d.SigningBytes())
+	if err != nil {
+		return fmt.Errorf("failed to sign discovery document: %w", err)
+	}
+	d.Signature = hex.EncodeToString(sig)
+	return nil
+}
+
+// Verify checks the document signature against its declared key. It does
+// not check whose key signed it.
+func (d Discovery) Verify() error {
+	return VerifyWithAlgorithm(d.Algorithm, d.PubKey, d.SigningBytes(), d.Signature)
+}
+
+// HasChain reports whether the server keeps a chain named name
+func (d Discovery) HasChain(name string) bool {
+	for _, chain := range d.Chains {
+		if chain.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,43 @@
+package crypto
+
+import (
+	"encoding/hex"
+	"testing"
+	"time"
+)
+
+func TestDiscoverySignature(t *testing.T) {
+	server := testSigner(2)
+	doc := Discovery{
+		APIVersion:          "v1",
+		APIBase:             "/api/v1",
+		Chains:              []DiscoveryChain{{Name: DefaultServerChain, HashAlgorithm: DefaultHashAlgorithm}},
+		SignatureAlgorithms: SupportedAlgorithms(),
+		HashAlgorithms:      SupportedHashAlgorithms(),
+		IssuedAt:            time.Date(2025, 1, 15, 10, 30, 0, 0, time.UTC),
+	}
+	if err := doc.Sign(server); err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+	if doc.PubKey != hex.EncodeToString(server.PublicKey()) {
+		t.Error("Expected document to name the signing key")
+	}
+	if err := doc.Verify(); err != nil {
+		t.Fatalf("Expected document to verify: %v", err)
+	}
+	if !doc.HasChain(DefaultServerChain) || doc.HasChain("other") {
+		t.Error("Expected only the listed chain")
+	}
+
+	// Another key or altered content fails
+	tampered := doc
+	tampered.PubKey = hex.EncodeToString(testSigner(3).PublicKey())
+	if tampered.Verify() == nil {
+		t.Error("Expected document under another key to fail")
+	}
+	tampered = doc
+	tampered.HashAlgorithms = []string{"sha256"}
+	if tampered.Verify() == nil {
+		t.Error("Expected altered document to fail")
+	}
+}
@@ -0,0 +1,103 @@
+package crypto
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// EnvelopeDomain separates log envelope signatures from any other use of a key
+const EnvelopeDomain = "zcrypt-log-envelope-v1"
+
+// Well-known chain names used as envelope targets
+const (
+	LocalChainName     = "local"
+	DefaultServerChain = "server"
+)
+
+// Envelope binds a signed message to its agent, time, nonce and target chain,
+// so a signature cannot be replayed later or against a different chain.
+type Envelope struct {
+	AgentID   string                 `json:"agent_id"`
+	Timestamp time.Time              `json:"timestamp"`
+	Nonce     string                 `json:"nonce"`
+	Chain     string                 `json:"chain"`
+	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+	CoSign    *CoSignPolicy          `json:"cosign,omitempty"` // Required approvals, if any
+
+	// Commitment to a message withheld from the server; the message itself is not signed
+	Commitment string `json:"commitment,omitempty"`
+}
+
+// NewEnvelope creates an envelope stamped with the current time and a random nonce
+func NewEnvelope(agentID, chain string, metadata map[string]interface{}) (*Envelope, error) {
+	nonce, err := NewNonce()
+	if err != nil {
+		return nil, err
+	}
+	return &Envelope{
+		AgentID:   agentID,
+		Timestamp: time.Now().UTC(),
+		Nonce:     nonce,
+		Chain:     chain,
+		Metadata:  metadata,
+	}, nil
+}
+
+// NewNonce returns 128 random bits, hex encoded
+func NewNonce() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// SigningBytes returns the domain-separated canonical encoding of the envelope
+// and message. Field order is fixed and metadata keys are sorted by encoding/json.
+func (env *Envelope) SigningBytes(message string) []byte {
+	canonical := struct {
+		Message   string                 `json:"message"`
+		AgentID   string                 `json:"agent_id"`
+		Timestamp string                 `json:"timestamp"`
+		Nonce     string                 `json:"nonce"`
+		Chain     string                 `json:"chain"`
+		Metadata  map[string]interface{} `json:"metadata"`
+		CoSign    *CoSignPolicy          `json:"cosign,omitempty"`
+
+		Commitment string `json:"commitment,omitempty"`
+	}{
+		Message:   message,
+		AgentID:   env.AgentID,
+		Timestamp: env.Timestamp.UTC().Format(time.RFC3339Nano),
+		Nonce:     env.Nonce,
+		Chain:     env.Chain,
+		Metadata:  env.Metadata,
+		CoSign:    env.CoSign,
+
+		Commitment: env.Commitment,
+	}
+
+	data, _ := json.Marshal(canonical)
+	return append([]byte(EnvelopeDomain+"\x00"), data...)
+}
+
+// SignEnvelope signs the envelope and message, returning a hex signature
+func SignEnvelope(signer Signer, message string, env *Envelope) (string, error) {
+	sig, err := signer.Sign(env.SigningBytes(message))
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(sig), nil
+}
+
+// CheckFreshness rejects envelopes stamped further than window from now
+func (env *Envelope) CheckFreshness(now time.Time, window time.Duration) error {
+	skew := now.Sub(env.Timestamp)
+	if skew > window || skew < -window {
+		return fmt.Errorf("envelope timestamp %s is outside the %s freshness window", env.Timestamp.Format(time.RFC3339), window)
+	}
+	return nil
+}
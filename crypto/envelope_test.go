@@ -0,0 +1,74 @@
+package crypto
+
+import (
+	"encoding/hex"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestEnvelopeEntryVerifies(t *testing.T) {
+	tempFile := os.TempDir() + "/test_chain_envelope.json"
+	defer os.Remove(tempFile)
+
+	chain, _ := NewLogChain(tempFile)
+	signer, _ := GenerateSigner(AlgEd25519)
+
+	env, err := NewEnvelope("agent-1", DefaultServerChain, map[string]interface{}{"hostname": "web01"})
+	if err != nil {
+		t.Fatalf("Failed to create envelope: %v", err)
+	}
+	sig, err := SignEnvelope(signer, "User logged in", env)
+	if err != nil {
+		t.Fatalf("Failed to sign envelope: %v", err)
+	}
+
+	_, err = chain.AddEntry(LogEntry{
+		Message:   "User logged in",
+		Signature: sig,
+		PubKey:    hex.EncodeToString(signer.PublicKey()),
+		Algorithm: signer.Algorithm(),
+		Envelope:  env,
+	})
+	if err != nil {
+		t.Fatalf("Failed to add entry: %v", err)
+	}
+
+	reloaded, _ := NewLogChain(tempFile)
+	if valid, errors := reloaded.VerifyChain(); !valid {
+		t.Fatalf("Envelope chain should verify after reload: %v", errors)
+	}
+
+	// Moving the signature to another chain or nonce must fail
+	reloaded.Entries[0].Envelope.Chain = "other"
+	if err := reloaded.Entries[0].VerifySignature(); err == nil {
+		t.Error("Signature should not verify for a different target chain")
+	}
+}
+
+func TestEnvelopeDomainSeparation(t *testing.T) {
+	signer, _ := GenerateSigner(AlgEd25519)
+	env, _ := NewEnvelope("agent-1", DefaultServerChain, nil)
+	sig, _ := SignEnvelope(signer, "User logged in", env)
+
+	// An envelope signature is not a signature over the raw message
+	err := VerifyWithAlgorithm(AlgEd25519, hex.EncodeToString(signer.PublicKey()), []byte("User logged in"), sig)
+	if err == nil {
+		t.Error("Envelope signature should not verify as a raw message signature")
+	}
+}
+
+func TestEnvelopeFreshness(t *testing.T) {
+	env, _ := NewEnvelope("agent-1", DefaultServerChain, nil)
+	now := env.Timestamp
+
+	if err := env.CheckFreshness(now.Add(time.Minute), 5*time.Minute); err != nil {
+		t.Errorf("Envelope within window rejected: %v", err)
+	}
+	if err := env.CheckFreshness(now.Add(10*time.Minute), 5*time.Minute); err == nil {
+		t.Error("Stale envelope accepted")
+	}
+	if err := env.CheckFreshness(now.Add(-10*time.Minute), 5*time.Minute); err == nil {
+		t.Error("Envelope from the future accepted")
+	}
+}
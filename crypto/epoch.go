@@ -0,0 +1,281 @@
+package crypto
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Entry kinds that close one epoch and open the next
+const (
+	KindEpochSeal    = "epoch-seal"
+	KindEpochGenesis = "epoch-genesis"
+)
+
+// Domain-separation prefixes for epoch seal and genesis statements
+const (
+	EpochSealDomain    = "zcrypt-epoch-seal-v1"
+	EpochGenesisDomain = "zcrypt-epoch-genesis-v1"
+)
+
+// ErrEmptyEpoch is returned when sealing an epoch that has no entries
+var ErrEmptyEpoch = errors.New("epoch has no entries to seal")
+
+// EpochSeal is the signed statement closing an epoch. It commits to every
+// entry hash of the epoch before the seal through a Merkle root.
+type EpochSeal struct {
+	Epoch      int    `json:"epoch"`
+	StartIndex int    `json:"start_index"`
+	Count      int    `json:"count"` // Entries before the seal, including the genesis
+	MerkleRoot string `json:"merkle_root"`
+	FinalHash  string `json:"final_hash"` // Hash of the last entry before the seal
+}
+
+// EpochGenesis is the signed statement opening an epoch after a seal
+type EpochGenesis struct {
+	Epoch         int    `json:"epoch"`
+	HashAlgorithm string `json:"hash_algorithm"`
+	SealHash      string `json:"seal_hash"` // Hash of the previous epoch's seal entry
+}
+
+// EpochSealBytes returns the domain-separated statement a sealer signs
+func EpochSealBytes(seal EpochSeal) []byte {
+	data, _ := json.Marshal(seal)
+	return append([]byte(EpochSealDomain+"\x00"), data...)
+}
+
+// EpochGenesisBytes returns the domain-separated statement a sealer signs
+func EpochGenesisBytes(genesis EpochGenesis) []byte {
+	data, _ := json.Marshal(genesis)
+	return append([]byte(EpochGenesisDomain+"\x00"), data...)
+}
+
+// EpochSummary describes an epoch and how many entries it holds
+type EpochSummary struct {
+	ChainEpoch
+	Count int `json:"count"` // Entries in the epoch, including pruned ones
+}
+
+// EpochReport is the result of verifying a single epoch
+type EpochReport struct {
+	Epoch EpochSummary `json:"epoch"`
+	VerifyReport
+}
+
+// epochEnd returns the index after the last entry of the given epoch
+func (lc *LogChain) epochEnd(number int) int {
+	if number+1 < len(lc.Header.Epochs) {
+		return lc.Header.Epochs[number+1].StartIndex
+	}
+	return lc.lengthLocked()
+}
+
+// SealEpoch closes the current epoch with a seal entry signed by signer and
+// opens the next epoch with a signed genesis entry linked to the seal. The
+// new epoch uses hashAlg, or the current algorithm when it is empty. The
+// signer must be the chain's authority, and becomes it if none is recorded.
+func (lc *LogChain) SealEpoch(signer Signer, hashAlg string) (*LogEntry, error) {
+	unlock, err := lc.lockWrite()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	current := *lc.Header.currentEpoch()
+	if hashAlg == "" {
+		hashAlg = current.HashAlgorithm
+	}
+	if _, err := NewHash(hashAlg); err != nil {
+		return nil, err
+	}
+	length := lc.lengthLocked()
+	if length == current.StartIndex {
+		return nil, ErrEmptyEpoch
+	}
+	if current.StartIndex < lc.Header.prunedCount() {
+		return nil, fmt.Errorf("epoch %d is partly pruned and cannot be sealed", current.Number)
+	}
+	if err := lc.checkSignerLocked(signer); err != nil {
+		return nil, err
+	}
+
+	entries := lc.entriesFromLocked(current.StartIndex)
+	hashes := make([]string, len(entries))
+	for i, entry := range entries {
+		hashes[i] = entry.CurrentHash
+	}
+	seal := EpochSeal{
+		Epoch:      current.Number,
+		StartIndex: current.StartIndex,
+		Count:      len(entries),
+		MerkleRoot: MerkleRoot(hashes),
+		FinalHash:  lc.headLocked(),
+	}
+	sig, err := signer.Sign(EpochSealBytes(seal))
+	if err != nil {
+		return nil, err
+	}
+
+	// Both entries are written before the chain is saved, so a failure
+	// never leaves a seal without the epoch it opens
+	keepEntries, keepEpochs := lc.Entries, lc.Header.Epochs
+	keepAuthority := lc.recordAuthorityLocked(signer)
+	rollback := func() {
+		lc.Entries, lc.Header.Epochs, lc.Header.Authority = keepEntries, keepEpochs, keepAuthority
+	}
+	sealEntry, err := lc.stageLocked(LogEntry{
+		Kind:      KindEpochSeal,
+		Message:   fmt.Sprintf("Seal of epoch %d", current.Number),
+		Signature: hex.EncodeToString(sig),
+		PubKey:    hex.EncodeToString(signer.PublicKey()),
+		Algorithm: signer.Algorithm(),
+		Closes:    &seal,
+	})
+	if err != nil {
+		rollback()
+		return nil, err
+	}
+
+	now := lc.now()
+	epochs := append([]ChainEpoch{}, lc.Header.Epochs...)
+	epochs[len(epochs)-1].SealIndex = length
+	epochs[len(epochs)-1].SealHash = sealEntry.CurrentHash
+	lc.Header.Epochs = append(epochs, ChainEpoch{
+		Number:        current.Number + 1,
+		StartIndex:    length + 1,
+		HashAlgorithm: hashAlg,
+		PrevHead:      sealEntry.CurrentHash,
+		StartedAt:     now,
+	})
+
+	genesis := EpochGenesis{Epoch: current.Number + 1, HashAlgorithm: hashAlg, SealHash: sealEntry.CurrentHash}
+	sig, err = signer.Sign(EpochGenesisBytes(genesis))
+	if err != nil {
+		rollback()
+		return nil, err
+	}
+	if _, err := lc.stageLocked(LogEntry{
+		Kind:      KindEpochGenesis,
+		Message:   fmt.Sprintf("Genesis of epoch %d", genesis.Epoch),
+		Signature: hex.EncodeToString(sig),
+		PubKey:    hex.EncodeToString(signer.PublicKey()),
+		Algorithm: signer.Algorithm(),
+		Opens:     &genesis,
+	}); err != nil {
+		rollback()
+		return nil, err
+	}
+
+	if err := lc.Save(); err != nil {
+		rollback()
+		return nil, fmt.Errorf("failed to save chain: %w", err)
+	}
+	return sealEntry, nil
+}
+
+// Epochs lists every epoch with its entry count
+func (lc *LogChain) Epochs() []EpochSummary {
+	lc.mu.RLock()
+	defer lc.mu.RUnlock()
+
+	summaries := make([]EpochSummary, len(lc.Header.Epochs))
+	for i, epoch := range lc.Header.Epochs {
+		summaries[i] = EpochSummary{ChainEpoch: epoch, Count: lc.epochEnd(i) - epoch.StartIndex}
+	}
+	return summaries
+}
+
+// VerifyEpoch checks one epoch on its own: its entries' hashes, signatures
+// and links from the epoch's previous head, and its seal if it has one.
+// Entries pruned from the epoch are counted but not checked.
+func (lc *LogChain) VerifyEpoch(number int) (*EpochReport, error) {
+	lc.mu.RLock()
+	defer lc.mu.RUnlock()
+
+	if number < 0 || number >= len(lc.Header.Epochs) {
+		return nil, fmt.Errorf("epoch %d does not exist", number)
+	}
+	epoch := lc.Header.Epochs[number]
+	end := lc.epochEnd(number)
+
+	report := &EpochReport{
+		Epoch:        EpochSummary{ChainEpoch: epoch, Count: end - epoch.StartIndex},
+		VerifyReport: lc.verifyRangeLocked(epoch.StartIndex, end),
+	}
+	if epoch.SealHash != "" {
+		seal := lc.entryLocked(epoch.SealIndex)
+		if epoch.SealIndex != end-1 || (seal != nil && (seal.CurrentHash != epoch.SealHash || seal.Closes == nil)) {
+			report.Errors = append(report.Errors, fmt.Sprintf("Epoch %d: seal entry does not match the header", number))
+		}
+	}
+	report.Valid = len(report.Errors) == 0
+	return report, nil
+}
+
+// EpochEntries returns the kept entries of an epoch
+func (lc *LogChain) EpochEntries(number int) ([]LogEntry, error) {
+	lc.mu.RLock()
+	defer lc.mu.RUnlock()
+
+	if number < 0 || number >= len(lc.Header.Epochs) {
+		return nil, fmt.Errorf("epoch %d does not exist", number)
+	}
+	start := max(lc.Header.Epochs[number].StartIndex, lc.Header.prunedCount())
+	end := lc.epochEnd(number)
+	if start >= end {
+		return []LogEntry{}, nil
+	}
+	entries := lc.entriesFromLocked(start)
+	return append([]LogEntry{}, entries[:min(end-start, len(entries))]...), nil
+}
+
+// checkEpochLocked checks a seal or genesis entry at index against the epoch
+// it belongs to; callers hold the lock
+func (lc *LogChain) checkEpochLocked(index int, entry LogEntry) []string {
+	epoch := lc.Header.epochAt(index)
+	var problems []string
+	if entry.Closes != nil || entry.Opens != nil {
+		if problem := lc.checkAuthorityLocked(entry.PubKey); problem != "" {
+			problems = append(problems, problem)
+		}
+	}
+
+	if seal := entry.Closes; seal != nil {
+		if seal.Epoch != epoch.Number || seal.StartIndex != epoch.StartIndex || seal.Count != index-epoch.StartIndex {
+			problems = append(problems, fmt.Sprintf("seal does not match epoch %d", epoch.Number))
+		}
+		if seal.FinalHash != entry.PrevHash {
+			problems = append(problems, "seal final hash does not match the previous entry")
+		}
+		if index+1 != lc.epochEnd(epoch.Number) || epoch.Number+1 >= len(lc.Header.Epochs) {
+			problems = append(problems, fmt.Sprintf("seal is not the last entry of epoch %d", epoch.Number))
+		}
+
+		// Epochs partly pruned are covered by their prune anchor instead
+		if epoch.StartIndex >= lc.Header.prunedCount() {
+			entries := lc.entriesFromLocked(epoch.StartIndex)
+			hashes := make([]string, 0, seal.Count)
+			for _, e := range entries[:min(max(seal.Count, 0), len(entries))] {
+				hashes = append(hashes, e.CurrentHash)
+			}
+			if len(hashes) == 0 || MerkleRoot(hashes) != seal.MerkleRoot {
+				problems = append(problems, "seal Merkle root does not match the epoch's entries")
+			}
+		}
+	}
+
+	if genesis := entry.Opens; genesis != nil {
+		if genesis.Epoch != epoch.Number || index != epoch.StartIndex {
+			problems = append(problems, fmt.Sprintf("genesis does not start epoch %d", genesis.Epoch))
+		}
+		if genesis.HashAlgorithm != epoch.HashAlgorithm {
+			problems = append(problems, "genesis hash algorithm does not match its epoch")
+		}
+		prev := lc.entryLocked(index - 1)
+		if genesis.SealHash != entry.PrevHash || (prev != nil && prev.Closes == nil) {
+			problems = append(problems, "genesis does not link to a seal")
+		}
+	}
+	return problems
+}
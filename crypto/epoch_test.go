@@ -0,0 +1,182 @@
+package crypto
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSealEpoch(t *testing.T) {
+	tempFile := os.TempDir() + "/test_chain_epoch_seal.json"
+	defer os.Remove(tempFile)
+
+	chain, _ := NewLogChain(tempFile)
+	signer, _ := GenerateSigner(AlgEd25519)
+	var hashes []string
+	for i := 0; i < 3; i++ {
+		hashes = append(hashes, addSigned(t, chain, signer, fmt.Sprintf("Log %d", i)).CurrentHash)
+	}
+
+	seal, err := chain.SealEpoch(signer, HashSHA3_256)
+	if err != nil {
+		t.Fatalf("Failed to seal epoch: %v", err)
+	}
+	if c := seal.Closes; c.Epoch != 0 || c.Count != 3 || c.FinalHash != hashes[2] || c.MerkleRoot != MerkleRoot(hashes) {
+		t.Errorf("Unexpected seal %+v", c)
+	}
+	if chain.Length() != 5 || len(chain.Header.Epochs) != 2 {
+		t.Fatalf("Expected seal and genesis entries, got %d entries", chain.Length())
+	}
+	epoch := chain.Header.Epochs[1]
+	genesis, _ := chain.GetEntry(4)
+	if epoch.StartIndex != 4 || epoch.PrevHead != seal.CurrentHash || genesis.Opens == nil || genesis.PrevHash != seal.CurrentHash {
+		t.Errorf("Expected epoch 1 to open with a genesis linked to the seal, got %+v", epoch)
+	}
+	if chain.Header.Epochs[0].SealIndex != 3 || chain.Header.Epochs[0].SealHash != seal.CurrentHash {
+		t.Errorf("Expected epoch 0 to record its seal, got %+v", chain.Header.Epochs[0])
+	}
+	addSigned(t, chain, signer, "Log 3")
+
+	reloaded, _ := NewLogChain(tempFile)
+	if valid, errors := reloaded.VerifyChain(); !valid {
+		t.Fatalf("Expected sealed chain to verify: %v", errors)
+	}
+	for number, total := range []int{4, 2} {
+		report, err := reloaded.VerifyEpoch(number)
+		if err != nil || !report.Valid || report.Total != total || report.Epoch.Count != total {
+			t.Errorf("Expected epoch %d to verify with %d entries, got %+v (%v)", number, total, report, err)
+		}
+	}
+	if entries, _ := reloaded.EpochEntries(1); len(entries) != 2 || entries[0].Kind != KindEpochGenesis {
+		t.Errorf("Expected epoch 1 entries from its genesis, got %d", len(entries))
+	}
+	if _, err := reloaded.VerifyEpoch(2); err == nil {
+		t.Error("Expected error verifying an unknown epoch")
+	}
+}
+
+func TestTamperedSealIsDetected(t *testing.T) {
+	tempFile := os.TempDir() + "/test_chain_epoch_tamper.json"
+	defer os.Remove(tempFile)
+
+	chain, _ := NewLogChain(tempFile)
+	signer, _ := GenerateSigner(AlgEd25519)
+	for i := 0; i < 3; i++ {
+		addSigned(t, chain, signer, fmt.Sprintf("Log %d", i))
+	}
+	chain.SealEpoch(signer, "")
+	addSigned(t, chain, signer, "Log 3")
+
+	// Dropping an entry from the seal breaks its hash, signature and root
+	chain.Entries[3].Closes.Count = 2
+	if valid, _ := chain.VerifyChain(); valid {
+		t.Error("Expected modified seal to be detected")
+	}
+	if report, _ := chain.VerifyEpoch(0); report.Valid {
+		t.Error("Expected sealed epoch to fail verification")
+	}
+
+	// The next epoch verifies on its own from its recorded previous head
+	if report, _ := chain.VerifyEpoch(1); !report.Valid {
+		t.Errorf("Expected epoch 1 to verify on its own: %v", report.Errors)
+	}
+}
+
+func TestSealsSignedByAuthority(t *testing.T) {
+	dir := t.TempDir()
+	agent, _ := GenerateSigner(AlgEd25519)
+	server, _ := GenerateSigner(AlgEd25519)
+	attacker, _ := GenerateSigner(AlgEd25519)
+
+	chain, _ := NewLogChain(filepath.Join(dir, "chain.json"))
+	addSigned(t, chain, agent, "Log 0")
+	if _, err := chain.SealEpoch(server, ""); err != nil {
+		t.Fatalf("Failed to seal: %v", err)
+	}
+	addSigned(t, chain, agent, "Log 1")
+	if _, err := chain.SealEpoch(attacker, ""); !errors.Is(err, ErrNotAuthority) {
+		t.Errorf("Expected ErrNotAuthority, got %v", err)
+	}
+	if valid, errs := chain.VerifyChain(); !valid {
+		t.Fatalf("Expected chain to verify, got %v", errs)
+	}
+
+	// A chain rebuilt with seals validly signed by another key
+	forged, _ := NewLogChain(filepath.Join(dir, "forged.json"))
+	addSigned(t, forged, agent, "Log 0")
+	if _, err := forged.SealEpoch(attacker, ""); err != nil {
+		t.Fatalf("Failed to seal: %v", err)
+	}
+	forged.SetAuthority(server.PublicKey())
+	if valid, _ := forged.VerifyChain(); valid {
+		t.Error("Expected seals signed by another key to be rejected")
+	}
+	if report, _ := forged.VerifyEpoch(0); report.Valid {
+		t.Error("Expected the forged seal to fail epoch verification")
+	}
+	if report, _ := forged.VerifyEpoch(1); report.Valid {
+		t.Error("Expected the forged genesis to fail epoch verification")
+	}
+
+	// The recorded key is checked when no key is trusted
+	forged.SetAuthority(nil)
+	forged.Header.Authority = chain.GetHeader().Authority
+	if valid, _ := forged.VerifyChain(); valid {
+		t.Error("Expected seals not signed by the recorded key to be rejected")
+	}
+}
+
+func TestSealEmptyEpoch(t *testing.T) {
+	tempFile := os.TempDir() + "/test_chain_epoch_empty.json"
+	defer os.Remove(tempFile)
+
+	chain, _ := NewLogChain(tempFile)
+	signer, _ := GenerateSigner(AlgEd25519)
+	if _, err := chain.SealEpoch(signer, ""); err != ErrEmptyEpoch {
+		t.Errorf("Expected ErrEmptyEpoch, got %v", err)
+	}
+
+	// An epoch holding only its genesis can be sealed again
+	addSigned(t, chain, signer, "Log 0")
+	chain.SealEpoch(signer, "")
+	if _, err := chain.SealEpoch(signer, ""); err != nil {
+		t.Fatalf("Failed to seal genesis-only epoch: %v", err)
+	}
+	if valid, errors := chain.VerifyChain(); !valid || len(chain.Epochs()) != 3 {
+		t.Errorf("Expected three verified epochs: %v", errors)
+	}
+}
+
+func TestPruneWholeEpochs(t *testing.T) {
+	tempFile := os.TempDir() + "/test_chain_epoch_prune.json"
+	defer os.Remove(tempFile)
+
+	chain, _ := NewLogChain(tempFile)
+	signer, _ := GenerateSigner(AlgEd25519)
+	for i := 0; i < 3; i++ {
+		addSigned(t, chain, signer, fmt.Sprintf("Log %d", i))
+	}
+	chain.SealEpoch(signer, "")
+	addSigned(t, chain, signer, "Log 3")
+	addSigned(t, chain, signer, "Log 4")
+
+	policy := RetentionPolicy{MaxEntries: 1, WholeEpochs: true}
+	if n, _ := chain.Prunable(policy, time.Now()); n != 4 {
+		t.Errorf("Expected pruning rounded down to the sealed epoch, got %d", n)
+	}
+	if _, err := chain.Prune(policy, signer, ""); err != nil {
+		t.Fatalf("Failed to prune: %v", err)
+	}
+	if valid, errors := chain.VerifyChain(); !valid {
+		t.Errorf("Expected chain to verify: %v", errors)
+	}
+	if report, _ := chain.VerifyEpoch(0); !report.Valid || report.Pruned != 4 || report.Total != 0 {
+		t.Errorf("Expected epoch 0 to be fully pruned, got %+v", report)
+	}
+	if report, _ := chain.VerifyEpoch(1); !report.Valid || report.Total != 3 {
+		t.Errorf("Expected epoch 1 to stay complete, got %+v", report)
+	}
+}
@@ -0,0 +1,56 @@
+package crypto
+
+import (
+	"crypto/sha256"
+	"crypto/sha3"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"sort"
+)
+
+// Supported chain hash algorithms
+const (
+	HashSHA256     = "sha256"
+	HashSHA512_256 = "sha512-256"
+	HashSHA3_256   = "sha3-256"
+
+	DefaultHashAlgorithm = HashSHA256
+)
+
+var hashConstructors = map[string]func() hash.Hash{
+	HashSHA256:     sha256.New,
+	HashSHA512_256: sha512.New512_256,
+	HashSHA3_256:   func() hash.Hash { return sha3.New256() },
+}
+
+// NewHash returns a fresh hash.Hash for a chain hash algorithm
+func NewHash(alg string) (hash.Hash, error) {
+	newHash, ok := hashConstructors[alg]
+	if !ok {
+		return nil, fmt.Errorf("unknown hash algorithm: %s", alg)
+	}
+	return newHash(), nil
+}
+
+// SupportedHashAlgorithms lists the chain hash algorithm identifiers
+func SupportedHashAlgorithms() []string {
+	algs := make([]string, 0, len(hashConstructors))
+	for alg := range hashConstructors {
+		algs = append(algs, alg)
+	}
+	sort.Strings(algs)
+	return algs
+}
+
+// hashHex hashes data with a known algorithm and hex encodes the digest
+func hashHex(alg string, data []byte) string {
+	h, err := NewHash(alg)
+	if err != nil {
+		// Algorithms are validated when a chain is loaded or an epoch starts
+		panic(err)
+	}
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil))
+}
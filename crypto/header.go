@@ -0,0 +1,156 @@
+package crypto
+
+import (
+	"fmt"
+	"time"
+)
+
+// ChainHeader records chain-wide parameters that entries do not carry themselves
+type ChainHeader struct {
+	FormatVersion       int       `json:"format_version,omitempty"` // Absent before version 3
+	CreatedAt           time.Time `json:"created_at,omitzero"`
+	CreatedBy           string    `json:"created_by,omitempty"`           // Program and host that created the chain
+	SignatureAlgorithms []string  `json:"signature_algorithms,omitempty"` // Every algorithm entries are signed with
+
+	Epochs     []ChainEpoch     `json:"epochs"`
+	Anchors    []PruneAnchor    `json:"anchors,omitempty"`     // Signed stand-ins for pruned prefixes, oldest first
+	Authority  *ChainAuthority  `json:"authority,omitempty"`   // Key the anchors and seals are signed with
+	Redactors  []string         `json:"redactors,omitempty"`   // Hex keys allowed to sign redactions
+	Segments   []ArchiveSegment `json:"segments,omitempty"`    // Compressed files holding archived entries, oldest first
+	Retention  *RetentionPolicy `json:"retention,omitempty"`   // Nil keeps entries forever
+	LegalHolds []LegalHold      `json:"legal_holds,omitempty"` // Ranges exempt from pruning
+
+	Checkpoints []Checkpoint `json:"checkpoints,omitempty"` // Heads timestamped by a TSA, oldest first
+}
+
+// ChainEpoch is a contiguous run of entries hashed with one algorithm. The
+// first entry of a later epoch links to the head of the previous epoch.
+type ChainEpoch struct {
+	Number        int       `json:"number"`
+	StartIndex    int       `json:"start_index"`
+	HashAlgorithm string    `json:"hash_algorithm"`
+	PrevHead      string    `json:"prev_head"`
+	StartedAt     time.Time `json:"started_at"`
+	SealIndex     int       `json:"seal_index,omitempty"` // Index of the seal entry closing the epoch
+	SealHash      string    `json:"seal_hash,omitempty"`  // Empty while the epoch is open
+}
+
+// newChainHeader starts a current-format header with a single epoch at index 0
+func newChainHeader(hashAlg string, now time.Time) *ChainHeader {
+	return &ChainHeader{
+		FormatVersion: FormatVersion,
+		CreatedAt:     now,
+		CreatedBy:     creator(),
+		Epochs: []ChainEpoch{{
+			Number:        0,
+			StartIndex:    0,
+			HashAlgorithm: hashAlg,
+			PrevHead:      "0",
+			StartedAt:     now,
+		}},
+	}
+}
+
+// validate checks the format version, and that epochs are ordered and use
+// known hash algorithms
+func (h *ChainHeader) validate() error {
+	if h.FormatVersion > FormatVersion {
+		return fmt.Errorf("chain format version %d is newer than supported version %d", h.FormatVersion, FormatVersion)
+	}
+	if len(h.Epochs) == 0 {
+		return fmt.Errorf("chain header has no epochs")
+	}
+	for i, epoch := range h.Epochs {
+		if _, err := NewHash(epoch.HashAlgorithm); err != nil {
+			return fmt.Errorf("epoch %d: %w", epoch.Number, err)
+		}
+		if i > 0 && epoch.StartIndex < h.Epochs[i-1].StartIndex {
+			return fmt.Errorf("epoch %d starts before epoch %d", epoch.Number, h.Epochs[i-1].Number)
+		}
+	}
+	return nil
+}
+
+// currentEpoch returns the epoch new entries are appended to
+func (h *ChainHeader) currentEpoch() *ChainEpoch {
+	return &h.Epochs[len(h.Epochs)-1]
+}
+
+// epochAt returns the epoch containing the entry at index
+func (h *ChainHeader) epochAt(index int) *ChainEpoch {
+	for i := len(h.Epochs) - 1; i > 0; i-- {
+		if index >= h.Epochs[i].StartIndex {
+			return &h.Epochs[i]
+		}
+	}
+	return &h.Epochs[0]
+}
+
+// HashAlgorithm returns the hash algorithm of the current epoch
+func (lc *LogChain) HashAlgorithm() string {
+	lc.mu.RLock()
+	defer lc.mu.RUnlock()
+	return lc.Header.currentEpoch().HashAlgorithm
+}
+
+// GetHeader returns a copy of the chain header
+func (lc *LogChain) GetHeader() ChainHeader {
+	lc.mu.RLock()
+	defer lc.mu.RUnlock()
+
+	header := *lc.Header
+	header.SignatureAlgorithms = append([]string(nil), lc.Header.SignatureAlgorithms...)
+	header.Redactors = append([]string(nil), lc.Header.Redactors...)
+	header.Epochs = append([]ChainEpoch(nil), lc.Header.Epochs...)
+	header.Anchors = append([]PruneAnchor(nil), lc.Header.Anchors...)
+	header.Segments = append([]ArchiveSegment(nil), lc.Header.Segments...)
+	header.LegalHolds = append([]LegalHold(nil), lc.Header.LegalHolds...)
+	header.Checkpoints = append([]Checkpoint(nil), lc.Header.Checkpoints...)
+	if lc.Header.Retention != nil {
+		policy := *lc.Header.Retention
+		header.Retention = &policy
+	}
+	if lc.Header.Authority != nil {
+		authority := *lc.Header.Authority
+		header.Authority = &authority
+	}
+	return header
+}
+
+// StartEpoch switches the chain to a new hash algorithm. Entries appended from
+// now on are hashed with hashAlg, and the first of them links to the current
+// head, so history keeps verifying under the algorithm it was written with.
+// On an empty chain the initial epoch is simply re-parameterised.
+func (lc *LogChain) StartEpoch(hashAlg string) (*ChainEpoch, error) {
+	unlock, err := lc.lockWrite()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if _, err := NewHash(hashAlg); err != nil {
+		return nil, err
+	}
+
+	current := lc.Header.currentEpoch()
+	length := lc.lengthLocked()
+	if current.StartIndex == length {
+		// Nothing hashed under the current epoch yet
+		current.HashAlgorithm = hashAlg
+	} else {
+		lc.Header.Epochs = append(lc.Header.Epochs, ChainEpoch{
+			Number:        current.Number + 1,
+			StartIndex:    length,
+			HashAlgorithm: hashAlg,
+			PrevHead:      lc.headLocked(),
+			StartedAt:     lc.now(),
+		})
+	}
+
+	if err := lc.Save(); err != nil {
+		return nil, fmt.Errorf("failed to save chain: %w", err)
+	}
+
+	epoch := *lc.Header.currentEpoch()
+	return &epoch, nil
+}
@@ -0,0 +1,78 @@
+package crypto
+
+import (
+	"os"
+	"testing"
+)
+
+func TestHashAlgorithmOnEmptyChain(t *testing.T) {
+	tempFile := os.TempDir() + "/test_chain_hash.json"
+	defer os.Remove(tempFile)
+
+	chain, _ := NewLogChain(tempFile)
+	if _, err := chain.StartEpoch(HashSHA3_256); err != nil {
+		t.Fatalf("Failed to set hash algorithm: %v", err)
+	}
+	signer, _ := GenerateSigner(AlgEd25519)
+	addSigned(t, chain, signer, "Log 1")
+
+	if len(chain.Header.Epochs) != 1 {
+		t.Errorf("Expected empty chain to be re-parameterised, got %d epochs", len(chain.Header.Epochs))
+	}
+
+	reloaded, err := NewLogChain(tempFile)
+	if err != nil {
+		t.Fatalf("Failed to reload chain: %v", err)
+	}
+	if reloaded.HashAlgorithm() != HashSHA3_256 {
+		t.Errorf("Expected sha3-256 after reload, got %s", reloaded.HashAlgorithm())
+	}
+	if valid, errors := reloaded.VerifyChain(); !valid {
+		t.Errorf("Reloaded chain should verify: %v", errors)
+	}
+}
+
+func TestStartEpochLinksToOldHead(t *testing.T) {
+	tempFile := os.TempDir() + "/test_chain_epoch.json"
+	defer os.Remove(tempFile)
+
+	chain, _ := NewLogChain(tempFile)
+	signer, _ := GenerateSigner(AlgEd25519)
+	addSigned(t, chain, signer, "Log 1")
+	old := addSigned(t, chain, signer, "Log 2")
+
+	epoch, err := chain.StartEpoch(HashSHA512_256)
+	if err != nil {
+		t.Fatalf("Failed to start epoch: %v", err)
+	}
+	if epoch.Number != 1 || epoch.StartIndex != 2 || epoch.PrevHead != old.CurrentHash {
+		t.Errorf("Unexpected epoch: %+v", epoch)
+	}
+
+	next := addSigned(t, chain, signer, "Log 3")
+	if next.PrevHash != old.CurrentHash {
+		t.Error("First entry of new epoch should link to the old head")
+	}
+	if next.CurrentHash != calculateHash(*next, HashSHA512_256) {
+		t.Error("New epoch entry should be hashed with sha512-256")
+	}
+	if valid, errors := chain.VerifyChain(); !valid {
+		t.Errorf("Chain spanning epochs should verify: %v", errors)
+	}
+
+	// Claiming the old epoch used the new algorithm must be detected
+	chain.Header.Epochs[0].HashAlgorithm = HashSHA3_256
+	if valid, _ := chain.VerifyChain(); valid {
+		t.Error("Chain should be invalid after rewriting epoch algorithm")
+	}
+}
+
+func TestStartEpochRejectsUnknownHash(t *testing.T) {
+	tempFile := os.TempDir() + "/test_chain_epoch.json"
+	defer os.Remove(tempFile)
+
+	chain, _ := NewLogChain(tempFile)
+	if _, err := chain.StartEpoch("md5"); err == nil {
+		t.Error("Expected unknown hash algorithm error")
+	}
+}
@@ -8,6 +8,12 @@ import (
 	"os"
 )
 
+// Default key file locations, relative to the working directory
+const (
+	PrivateKeyFile = "zcrypt_private.key"
+	PublicKeyFile  = "zcrypt_public.key"
+)
+
 // GenerateKeyPair creates and saves a new Ed25519 keypair
 func GenerateKeyPair() (ed25519.PublicKey, ed25519.PrivateKey, error) {
 	pub, priv, err := ed25519.GenerateKey(rand.Reader)
@@ -44,3 +50,27 @@ func VerifySignature(pub ed25519.PublicKey, msg []byte, sigHex string) bool {
 	sig, _ := hex.DecodeString(sigHex)
 	return ed25519.Verify(pub, msg, sig)
 }
+
+// SaveSigner writes the private and public key files for any supported algorithm
+func SaveSigner(s Signer, privPath, pubPath string) error {
+	priv, err := MarshalSigner(s)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(privPath, priv, 0600); err != nil {
+		return fmt.Errorf("failed to save private key: %w", err)
+	}
+	if err := os.WriteFile(pubPath, s.PublicKey(), 0644); err != nil {
+		return fmt.Errorf("failed to save public key: %w", err)
+	}
+	return nil
+}
+
+// LoadSigner loads a private key file and detects its algorithm
+func LoadSigner(privPath string) (Signer, error) {
+	data, err := os.ReadFile(privPath)
+	if err != nil {
+		return nil, fmt.Errorf("private key not found: %w", err)
+	}
+	return ParseSigner(data)
+}
@@ -0,0 +1,129 @@
+package crypto
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultLockTimeout is how long a write waits for another process to
+// release the chain lock
+const DefaultLockTimeout = 10 * time.Second
+
+// ErrLockTimeout is returned when the chain lock could not be acquired in time
+var ErrLockTimeout = errors.New("timed out waiting for chain lock")
+
+// lockPollInterval is how often a held lock is retried
+const lockPollInterval = 10 * time.Millisecond
+
+// lockPath returns the advisory lock file guarding the chain file. The chain
+// file itself is replaced on every save, so it cannot carry the lock.
+func (lc *LogChain) lockPath() string {
+	return lc.FilePath + ".lock"
+}
+
+// SetLockTimeout sets how long writes wait for another process holding the
+// chain lock. Zero or less fails at once if the lock is held.
+func (lc *LogChain) SetLockTimeout(timeout time.Duration) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	lc.lockTimeout = timeout
+}
+
+// lockTimeoutFromEnv reads ZCRYPT_LOCK_TIMEOUT, falling back to DefaultLockTimeout
+func lockTimeoutFromEnv() time.Duration {
+	if value := os.Getenv("ZCRYPT_LOCK_TIMEOUT"); value != "" {
+		if timeout, err := time.ParseDuration(value); err == nil {
+			return timeout
+		}
+	}
+	return DefaultLockTimeout
+}
+
+// lockWrite takes the in-process write lock and the cross-process file lock,
+// and reloads the chain if another process saved it since it was read. The
+// returned function releases both locks.
+func (lc *LogChain) lockWrite() (func(), error) {
+	lc.mu.Lock()
+
+	file, err := lc.acquireFileLock()
+	if err != nil {
+		lc.mu.Unlock()
+		return nil, err
+	}
+	unlock := func() {
+		unlockFile(file)
+		file.Close()
+		lc.mu.Unlock()
+	}
+
+	if err := lc.refreshLocked(); err != nil {
+		unlock()
+		return nil, err
+	}
+	return unlock, nil
+}
+
+// acquireFileLock opens the lock file and polls for an exclusive lock until
+// the timeout passes. The holder's PID is written to the file for errors.
+func (lc *LogChain) acquireFileLock() (*os.File, error) {
+	file, err := os.OpenFile(lc.lockPath(), os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open chain lock: %w", err)
+	}
+
+	deadline := time.Now().Add(lc.lockTimeout)
+	for {
+		locked, err := tryLockFile(file)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to lock chain: %w", err)
+		}
+		if locked {
+			break
+		}
+		if time.Now().After(deadline) {
+			holder := lockHolder(file)
+			file.Close()
+			return nil, fmt.Errorf("%w after %s: %s is held by %s", ErrLockTimeout, lc.lockTimeout, lc.lockPath(), holder)
+		}
+		time.Sleep(lockPollInterval)
+	}
+
+	file.Truncate(0)
+	file.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	return file, nil
+}
+
+// lockHolder describes the process that last took the lock
+func lockHolder(file *os.File) string {
+	data := make([]byte, 32)
+	n, _ := file.ReadAt(data, 0)
+	if pid := strings.TrimSpace(string(data[:n])); pid != "" {
+		return "process " + pid
+	}
+	return "another process"
+}
+
+// refreshLocked reloads the chain when the file on disk is not the one it
+// last read or wrote; callers hold both locks
+func (lc *LogChain) refreshLocked() error {
+	info, err := os.Stat(lc.FilePath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to stat chain: %w", err)
+	}
+	if lc.fileInfo != nil && os.SameFile(lc.fileInfo, info) &&
+		lc.fileInfo.ModTime().Equal(info.ModTime()) && lc.fileInfo.Size() == info.Size() {
+		return nil
+	}
+	if err := lc.Load(); err != nil {
+		return fmt.Errorf("failed to reload chain: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package crypto
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLockTimeout(t *testing.T) {
+	tempFile := os.TempDir() + "/test_chain_lock.json"
+	defer os.Remove(tempFile)
+	defer os.Remove(tempFile + ".lock")
+
+	chain, _ := NewLogChain(tempFile)
+	signer, _ := GenerateSigner(AlgEd25519)
+	addSigned(t, chain, signer, "Log 0")
+
+	// Another open file description holding the lock blocks writers
+	holder, _ := os.OpenFile(chain.lockPath(), os.O_RDWR, 0600)
+	defer holder.Close()
+	if locked, err := tryLockFile(holder); !locked || err != nil {
+		t.Fatalf("Failed to take lock: %v", err)
+	}
+	chain.SetLockTimeout(50 * time.Millisecond)
+	start := time.Now()
+	if _, err := chain.AddLog("Log 1", "", "", nil); !errors.Is(err, ErrLockTimeout) {
+		t.Errorf("Expected ErrLockTimeout, got %v", err)
+	}
+	if waited := time.Since(start); waited < 50*time.Millisecond {
+		t.Errorf("Expected to wait for the lock, gave up after %s", waited)
+	}
+
+	unlockFile(holder)
+	addSigned(t, chain, signer, "Log 1")
+	if chain.Length() != 2 {
+		t.Errorf("Expected 2 entries after the lock was released, got %d", chain.Length())
+	}
+}
+
+func TestWriteReloadsChangedChain(t *testing.T) {
+	tempFile := os.TempDir() + "/test_chain_lock_reload.json"
+	defer os.Remove(tempFile)
+	defer os.Remove(tempFile + ".lock")
+
+	first, _ := NewLogChain(tempFile)
+	second, _ := NewLogChain(tempFile)
+	signer, _ := GenerateSigner(AlgEd25519)
+	addSigned(t, first, signer, "Log 0")
+	entry := addSigned(t, second, signer, "Log 1")
+	addSigned(t, first, signer, "Log 2")
+
+	if entry.PrevHash == "0" {
+		t.Error("Expected the second writer to link to the first writer's entry")
+	}
+	reloaded, _ := NewLogChain(tempFile)
+	if reloaded.Length() != 3 {
+		t.Errorf("Expected no lost entries, got %d", reloaded.Length())
+	}
+	if valid, errors := reloaded.VerifyChain(); !valid {
+		t.Errorf("Expected chain to verify: %v", errors)
+	}
+}
+
+// TestConcurrentProcessesLoseNoEntries runs several copies of the test binary
+// that each append to the same chain, like overlapping zcrypt log commands
+func TestConcurrentProcessesLoseNoEntries(t *testing.T) {
+	const processes, perProcess = 4, 25
+
+	if path := os.Getenv("ZCRYPT_LOCK_TEST_CHAIN"); path != "" {
+		signer, _ := GenerateSigner(AlgEd25519)
+		for i := 0; i < perProcess; i++ {
+			// A fresh chain per entry, like separate CLI invocations
+			chain, err := NewLogChain(path)
+			if err != nil {
+				t.Fatalf("Failed to load chain: %v", err)
+			}
+			addSigned(t, chain, signer, fmt.Sprintf("%s-%d", os.Getenv("ZCRYPT_LOCK_TEST_WORKER"), i))
+		}
+		return
+	}
+
+	tempFile := os.TempDir() + "/test_chain_lock_stress.json"
+	os.Remove(tempFile)
+	defer os.Remove(tempFile)
+	defer os.Remove(tempFile + ".lock")
+
+	var wg sync.WaitGroup
+	failures := make(chan string, processes)
+	for p := 0; p < processes; p++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			cmd := exec.Command(os.Args[0], "-test.run=^TestConcurrentProcessesLoseNoEntries$")
+			cmd.Env = append(os.Environ(), "ZCRYPT_LOCK_TEST_CHAIN="+tempFile, "ZCRYPT_LOCK_TEST_WORKER="+strconv.Itoa(worker))
+			if out, err := cmd.CombinedOutput(); err != nil {
+				failures <- fmt.Sprintf("worker %d: %v\n%s", worker, err, out)
+			}
+		}(p)
+	}
+	wg.Wait()
+	close(failures)
+	for failure := range failures {
+		t.Error(failure)
+	}
+
+	chain, _ := NewLogChain(tempFile)
+	if chain.Length() != processes*perProcess {
+		t.Fatalf("Expected %d entries, got %d", processes*perProcess, chain.Length())
+	}
+	seen := map[string]bool{}
+	for _, entry := range chain.Entries {
+		seen[entry.Message] = true
+	}
+	if len(seen) != processes*perProcess {
+		t.Errorf("Expected every message once, got %d distinct", len(seen))
+	}
+	if valid, errors := chain.VerifyChain(); !valid {
+		t.Errorf("Expected chain to verify: %v", errors)
+	}
+}
@@ -0,0 +1,108 @@
+package crypto
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// Domain prefixes for Merkle tree hashing, as in RFC 6962, so a leaf can
+// never be confused with an interior node
+const (
+	merkleLeafPrefix = 0x00
+	merkleNodePrefix = 0x01
+)
+
+// MerkleRoot returns the hex SHA-256 Merkle tree root over the given entry
+// hashes in order. The tree is built as in RFC 6962: the left subtree holds
+// the largest power of two smaller than the number of leaves.
+func MerkleRoot(hashes []string) string {
+	leaves := make([][]byte, len(hashes))
+	for i, h := range hashes {
+		leaves[i] = merkleLeaf(h)
+	}
+	return hex.EncodeToString(merkleTreeHash(leaves))
+}
+
+// merkleLeaf hashes an entry hash as a tree leaf
+func merkleLeaf(hash string) []byte {
+	sum := sha256.Sum256(append([]byte{merkleLeafPrefix}, hash...))
+	return sum[:]
+}
+
+// merkleNode hashes two child nodes
+func merkleNode(left, right []byte) []byte {
+	data := make([]byte, 0, 1+len(left)+len(right))
+	data = append(data, merkleNodePrefix)
+	data = append(data, left...)
+	data = append(data, right...)
+	sum := sha256.Sum256(data)
+	return sum[:]
+}
+
+// merkleTreeHash computes the root over already hashed leaves
+func merkleTreeHash(leaves [][]byte) []byte {
+	switch len(leaves) {
+	case 0:
+		sum := sha256.Sum256(nil)
+		return sum[:]
+	case 1:
+		return leaves[0]
+	}
+	split := merkleSplit(len(leaves))
+	return merkleNode(merkleTreeHash(leaves[:split]), merkleTreeHash(leaves[split:]))
+}
+
+// merkleSplit returns the largest power of two smaller than n
+func merkleSplit(n int) int {
+	split := 1
+	for split*2 < n {
+		split *= 2
+	}
+	return split
+}
+
+// merklePath returns the audit path for leaf m: the sibling subtree hashes
+// from the leaf up to the root, as in RFC 6962
+func merklePath(m int, leaves [][]byte) [][]byte {
+	if len(leaves) <= 1 {
+		return nil
+	}
+	split := merkleSplit(len(leaves))
+	if m < split {
+		return append(merklePath(m, leaves[:split]), merkleTreeHash(leaves[split:]))
+	}
+	return append(merklePath(m-split, leaves[split:]), merkleTreeHash(leaves[:split]))
+}
+
+// VerifyInclusion checks that the entry hash is leaf index of a tree of size
+// leaves with the given hex root, using the hex audit path (RFC 9162,
+// section 2.1.3.2)
+func VerifyInclusion(hash string, index, size int, path []string, root string) error {
+	if index < 0 || index >= size {
+		return fmt.Errorf("leaf %d is outside a tree of %d", index, size)
+	}
+
+	fn, sn := index, size-1
+	node := merkleLeaf(hash)
+	for _, p := range path {
+		sibling, err := hex.DecodeString(p)
+		if err != nil || sn == 0 {
+			return errors.New("malformed audit path")
+		}
+		if fn&1 == 1 || fn == sn {
+			node = merkleNode(sibling, node)
+			for fn&1 == 0 && fn != 0 {
+				fn, sn = fn>>1, sn>>1
+			}
+		} else {
+			node = merkleNode(node, sibling)
+		}
+		fn, sn = fn>>1, sn>>1
+	}
+	if sn != 0 || hex.EncodeToString(node) != root {
+		return errors.New("audit path does not lead to the root")
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package crypto
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// Chain file format versions. Each version is read by every later loader.
+const (
+	FormatVersionLegacy    = 1                      // Entries only, no header; a single SHA-256 epoch
+	FormatVersionEpochs    = 2                      // Header with hash epochs, anchors and segments
+	FormatVersionHeader    = 3                      // Header with format version, creation info and signature algorithms
+	FormatVersionSequenced = 4                      // Entries with sequence numbers, claimed times and clock flags
+	FormatVersion          = FormatVersionSequenced // Newest version, used for new chains
+)
+
+// KindMigration marks an entry recording a chain format migration
+const KindMigration = "migration"
+
+// MigrationDomain separates migration record signatures from other signatures
+const MigrationDomain = "zcrypt-migration-v1"
+
+// ErrUpToDate is returned when migrating a chain already in the newest format
+var ErrUpToDate = errors.New("chain is already in the newest format")
+
+// MigrationRef is the signed record of a format migration. It links the head
+// and file contents before the migration to the migrated chain, whose head
+// is the record itself.
+type MigrationRef struct {
+	FromVersion int    `json:"from_version"`
+	ToVersion   int    `json:"to_version"`
+	OldHead     string `json:"old_head"`
+	OldLength   int    `json:"old_length"`
+	OldSHA256   string `json:"old_sha256"`       // Digest of the chain file before migration
+	Backup      string `json:"backup,omitempty"` // Copy of the old file, relative to the chain's directory
+}
+
+// MigrationBytes returns the domain-separated record a migrator signs
+func MigrationBytes(ref MigrationRef) []byte {
+	data, _ := json.Marshal(ref)
+	return append([]byte(MigrationDomain+"\x00"), data...)
+}
+
+// creator names the program and host creating a chain
+func creator() string {
+	host, _ := os.Hostname()
+	return filepath.Base(os.Args[0]) + "@" + host
+}
+
+// noteAlgorithm records a signature algorithm in current-format headers
+func (h *ChainHeader) noteAlgorithm(algorithm string) {
+	algorithm = NormalizeAlgorithm(algorithm)
+	if h.FormatVersion < FormatVersionHeader || slices.Contains(h.SignatureAlgorithms, algorithm) {
+		return
+	}
+	h.SignatureAlgorithms = append(h.SignatureAlgorithms, algorithm)
+}
+
+// FormatVersion returns the on-disk format version the chain was loaded or created with
+func (lc *LogChain) FormatVersion() int {
+	lc.mu.RLock()
+	defer lc.mu.RUnlock()
+	return lc.Header.FormatVersion
+}
+
+// Migrate rewrites a chain loaded from an older format into the newest one.
+// The old file is first copied to "<file>.v<version>.bak". The header gains
+// the current format fields, and a migration record signed by signer is
+// appended, linking the old head and file digest to the migrated chain.
+// Entry hashes are unchanged.
+func (lc *LogChain) Migrate(signer Signer) (*LogEntry, error) {
+	unlock, err := lc.lockWrite()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	from := lc.Header.FormatVersion
+	if from >= FormatVersion {
+		return nil, ErrUpToDate
+	}
+
+	data, err := os.ReadFile(lc.FilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chain file: %w", err)
+	}
+	digest := sha256.Sum256(data)
+	backup := fmt.Sprintf("%s.v%d.bak", lc.FilePath, from)
+	if err := writeFileAtomic(backup, data); err != nil {
+		return nil, fmt.Errorf("failed to back up chain file: %w", err)
+	}
+
+	kept, err := lc.keptLocked()
+	if err != nil {
+		return nil, err
+	}
+	original, live := *lc.Header, len(lc.Entries)
+	lc.Header.FormatVersion = FormatVersion
+	if lc.Header.CreatedAt.IsZero() {
+		lc.Header.CreatedAt = lc.Header.Epochs[0].StartedAt
+	}
+	if lc.Header.CreatedBy == "" {
+		lc.Header.CreatedBy = "unknown (migrated by " + creator() + ")"
+	}
+	for _, entry := range kept {
+		lc.Header.noteAlgorithm(entry.Algorithm)
+	}
+
+	ref := MigrationRef{
+		FromVersion: from,
+		ToVersion:   FormatVersion,
+		OldHead:     lc.headLocked(),
+		OldLength:   lc.lengthLocked(),
+		OldSHA256:   hex.EncodeToString(digest[:]),
+		Backup:      filepath.Base(backup),
+	}
+	sig, err := signer.Sign(MigrationBytes(ref))
+	if err != nil {
+		*lc.Header = original
+		return nil, err
+	}
+
+	entry, err := lc.appendLocked(LogEntry{
+		Kind:      KindMigration,
+		Message:   fmt.Sprintf("Migration from format version %d to %d", from, FormatVersion),
+		Signature: hex.EncodeToString(sig),
+		PubKey:    hex.EncodeToString(signer.PublicKey()),
+		Algorithm: signer.Algorithm(),
+		Migrates:  &ref,
+	})
+	if err != nil {
+		*lc.Header, lc.Entries = original, lc.Entries[:live]
+		return nil, err
+	}
+	return entry, nil
+}
@@ -0,0 +1,132 @@
+package crypto
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+)
+
+// writeOldChain writes a chain with signed entries in an older file format
+func writeOldChain(t *testing.T, path string, version int) {
+	t.Helper()
+	chain, _ := NewLogChain(path)
+	chain.Header.FormatVersion = version
+	signer, _ := GenerateSigner(AlgEd25519)
+	for i := 0; i < 3; i++ {
+		addSigned(t, chain, signer, fmt.Sprintf("Log %d", i))
+	}
+
+	file := map[string]interface{}{"entries": chain.Entries}
+	if version == FormatVersionEpochs {
+		file["header"] = map[string]interface{}{"epochs": chain.Header.Epochs}
+	} else if version > FormatVersionEpochs {
+		file["header"] = chain.Header
+	}
+	data, _ := json.Marshal(file)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("Failed to write old chain: %v", err)
+	}
+}
+
+func TestNewChainUsesCurrentFormat(t *testing.T) {
+	tempFile := os.TempDir() + "/test_chain_format.json"
+	defer os.Remove(tempFile)
+
+	chain, _ := NewLogChain(tempFile)
+	signer, _ := GenerateSigner(AlgECDSAP256)
+	addSigned(t, chain, signer, "Log 0")
+
+	reloaded, _ := NewLogChain(tempFile)
+	header := reloaded.GetHeader()
+	if header.FormatVersion != FormatVersion || header.CreatedAt.IsZero() || header.CreatedBy == "" {
+		t.Errorf("Expected current format with creation info, got %+v", header)
+	}
+	if len(header.SignatureAlgorithms) != 1 || header.SignatureAlgorithms[0] != AlgECDSAP256 {
+		t.Errorf("Expected signature algorithms to be recorded, got %v", header.SignatureAlgorithms)
+	}
+	if _, err := reloaded.Migrate(signer); err != ErrUpToDate {
+		t.Errorf("Expected ErrUpToDate, got %v", err)
+	}
+}
+
+func TestMigrateOldFormats(t *testing.T) {
+	for _, version := range []int{FormatVersionLegacy, FormatVersionEpochs, FormatVersionHeader} {
+		t.Run(fmt.Sprintf("v%d", version), func(t *testing.T) {
+			tempFile := os.TempDir() + "/test_chain_migrate.json"
+			backup := fmt.Sprintf("%s.v%d.bak", tempFile, version)
+			defer os.Remove(tempFile)
+			defer os.Remove(backup)
+			writeOldChain(t, tempFile, version)
+			old, _ := os.ReadFile(tempFile)
+
+			chain, err := NewLogChain(tempFile)
+			if err != nil {
+				t.Fatalf("Failed to load old chain: %v", err)
+			}
+			if chain.FormatVersion() != version {
+				t.Errorf("Expected format version %d, got %d", version, chain.FormatVersion())
+			}
+			if valid, errors := chain.VerifyChain(); !valid {
+				t.Fatalf("Expected old chain to verify: %v", errors)
+			}
+			head := chain.GetLastHash()
+
+			signer, _ := GenerateSigner(AlgEd25519)
+			record, err := chain.Migrate(signer)
+			if err != nil {
+				t.Fatalf("Failed to migrate: %v", err)
+			}
+			if ref := record.Migrates; ref.FromVersion != version || ref.OldHead != head || ref.OldLength != 3 || record.PrevHash != head {
+				t.Errorf("Expected record linking the old head, got %+v", ref)
+			}
+			if saved, _ := os.ReadFile(backup); string(saved) != string(old) {
+				t.Error("Expected backup of the old chain file")
+			}
+
+			reloaded, _ := NewLogChain(tempFile)
+			if reloaded.FormatVersion() != FormatVersion || reloaded.GetHeader().CreatedAt.IsZero() {
+				t.Errorf("Expected migrated header, got %+v", reloaded.GetHeader())
+			}
+			if valid, errors := reloaded.VerifyChain(); !valid {
+				t.Errorf("Expected migrated chain to verify: %v", errors)
+			}
+
+			// Only entries written after the migration are sequenced
+			if old, _ := reloaded.GetEntry(2); old.Sequence != 0 {
+				t.Errorf("Expected old entries unsequenced, got %d", old.Sequence)
+			}
+			if record.Sequence != 4 {
+				t.Errorf("Expected migration record to be entry 4 in sequence, got %d", record.Sequence)
+			}
+		})
+	}
+}
+
+func TestRejectNewerFormat(t *testing.T) {
+	tempFile := os.TempDir() + "/test_chain_format_newer.json"
+	defer os.Remove(tempFile)
+
+	data := fmt.Sprintf(`{"header":{"format_version":%d,"epochs":[{"number":0,"start_index":0,"hash_algorithm":"sha256","prev_head":"0"}]},"entries":[]}`, FormatVersion+1)
+	os.WriteFile(tempFile, []byte(data), 0600)
+	if _, err := NewLogChain(tempFile); err == nil {
+		t.Error("Expected error loading a newer format")
+	}
+}
+
+func TestTamperedMigrationRecord(t *testing.T) {
+	tempFile := os.TempDir() + "/test_chain_migrate_tamper.json"
+	defer os.Remove(tempFile)
+	defer os.Remove(tempFile + ".v1.bak")
+	writeOldChain(t, tempFile, FormatVersionLegacy)
+
+	chain, _ := NewLogChain(tempFile)
+	signer, _ := GenerateSigner(AlgEd25519)
+	chain.Migrate(signer)
+
+	chain.Entries[3].Migrates.OldLength = 2
+	chain.Entries[3].CurrentHash = calculateHash(chain.Entries[3], DefaultHashAlgorithm)
+	if valid, _ := chain.VerifyChain(); valid {
+		t.Error("Expected modified migration record to be detected")
+	}
+}
@@ -0,0 +1,74 @@
+package crypto
+
+import (
+	"crypto/rand"
+	"io"
+	"time"
+)
+
+// ChainOption configures a LogChain when it is created or loaded
+type ChainOption func(*LogChain)
+
+// WithClock sets the time source for entry timestamps, epochs, seals,
+// anchors, holds, archive segments and the header of a new chain. Defaults
+// to time.Now. Times are stored in UTC.
+func WithClock(now func() time.Time) ChainOption {
+	return func(lc *LogChain) {
+		lc.clock = now
+	}
+}
+
+// WithRandom sets the source of commitment salts, and of data keys and
+// nonces when entries are encrypted at rest. Defaults to crypto/rand.
+func WithRandom(r io.Reader) ChainOption {
+	return func(lc *LogChain) {
+		lc.random = r
+	}
+}
+
+// WithHashAlgorithm sets the hash algorithm of a new chain's first epoch.
+// It has no effect on an existing chain file, whose epochs name their own
+// algorithms; use StartEpoch to switch those.
+func WithHashAlgorithm(hashAlg string) ChainOption {
+	return func(lc *LogChain) {
+		lc.hashAlg = hashAlg
+	}
+}
+
+// WithLockTimeout sets how long writes wait for the cross-process lock,
+// overriding ZCRYPT_LOCK_TIMEOUT
+func WithLockTimeout(timeout time.Duration) ChainOption {
+	return func(lc *LogChain) {
+		lc.lockTimeout = timeout
+	}
+}
+
+// WithMaxClockSkew sets how far claimed times may differ from received
+// times before entries are flagged, overriding ZCRYPT_MAX_CLOCK_SKEW
+func WithMaxClockSkew(skew time.Duration) ChainOption {
+	return func(lc *LogChain) {
+		lc.maxClockSkew = skew
+	}
+}
+
+// WithAuthority sets the key trusted to sign the chain's prune anchors and
+// epoch seals, in place of the key recorded by the first of them
+func WithAuthority(pubKey []byte) ChainOption {
+	return func(lc *LogChain) {
+		lc.authority = pubKey
+	}
+}
+
+// now returns the chain clock's current time in UTC
+func (lc *LogChain) now() time.Time {
+	return lc.clock().UTC()
+}
+
+// defaultChainOptions are applied before the caller's options
+func defaultChainOptions(lc *LogChain) {
+	lc.clock = time.Now
+	lc.random = rand.Reader
+	lc.hashAlg = DefaultHashAlgorithm
+	lc.lockTimeout = lockTimeoutFromEnv()
+	lc.maxClockSkew = maxClockSkewFromEnv()
+}
@@ -0,0 +1,113 @@
+package crypto
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrUnknownHead is returned for a head that does not name a state of the
+// chain, such as one from another chain or one past its end
+var ErrUnknownHead = errors.New("head is not a state of this chain")
+
+// ChainHead names a state of the chain: its length and the hash of its last
+// entry. The chain only grows, so a head that still matches identifies the
+// same entries [0, Length) for as long as they are kept.
+type ChainHead struct {
+	Length int    `json:"length"`
+	Hash   string `json:"hash"`
+}
+
+// Page is a run of kept entries read against a fixed head
+type Page struct {
+	Entries []LogEntry `json:"entries"`
+	Start   int        `json:"start"`       // Index of the first entry returned
+	First   int        `json:"first_index"` // Oldest kept index
+	Head    ChainHead  `json:"head"`        // State the page was read from
+}
+
+// End returns the index just past the page's last entry
+func (p *Page) End() int {
+	return p.Start + len(p.Entries)
+}
+
+// Head returns the chain's current state
+func (lc *LogChain) Head() ChainHead {
+	lc.mu.RLock()
+	defer lc.mu.RUnlock()
+	return ChainHead{Length: lc.lengthLocked(), Hash: lc.headLocked()}
+}
+
+// ReadPage returns up to limit kept entries from index start, stopping at
+// the end of head, so pages read against the same head never see entries
+// appended since. A zero head reads against the current one. Starts before
+// the oldest kept entry begin at it.
+func (lc *LogChain) ReadPage(head ChainHead, start, limit int) (*Page, error) {
+	lc.mu.RLock()
+	defer lc.mu.RUnlock()
+
+	head, err := lc.pageHeadLocked(head)
+	if err != nil {
+		return nil, err
+	}
+	return lc.readPageLocked(head, start, start+max(limit, 0)), nil
+}
+
+// ReadPageBefore returns up to limit kept entries ending just before index
+// end, for paging backwards. An end past head stops at it.
+func (lc *LogChain) ReadPageBefore(head ChainHead, end, limit int) (*Page, error) {
+	lc.mu.RLock()
+	defer lc.mu.RUnlock()
+
+	head, err := lc.pageHeadLocked(head)
+	if err != nil {
+		return nil, err
+	}
+	end = min(end, head.Length)
+	return lc.readPageLocked(head, end-max(limit, 0), end), nil
+}
+
+// pageHeadLocked checks a page's head, or returns the current one for a
+// zero head. Callers hold the lock.
+func (lc *LogChain) pageHeadLocked(head ChainHead) (ChainHead, error) {
+	if head == (ChainHead{}) {
+		return ChainHead{Length: lc.lengthLocked(), Hash: lc.headLocked()}, nil
+	}
+	return head, lc.checkHeadLocked(head)
+}
+
+// readPageLocked returns the kept entries in [start, end) of head. Callers
+// hold the lock.
+func (lc *LogChain) readPageLocked(head ChainHead, start, end int) *Page {
+	first := lc.Header.prunedCount()
+	start = min(max(start, first), max(head.Length, first))
+	end = min(end, head.Length)
+
+	page := &Page{Entries: []LogEntry{}, Start: start, First: first, Head: head}
+	if start < end {
+		entries := lc.entriesFromLocked(start)
+		page.Entries = append(page.Entries, entries[:min(end-start, len(entries))]...)
+	}
+	return page
+}
+
+// checkHeadLocked confirms that head is a state of the chain. A head whose
+// last entry was pruned can only be confirmed through the prune anchor.
+// Callers hold the lock.
+func (lc *LogChain) checkHeadLocked(head ChainHead) error {
+	if head.Length < 0 || head.Length > lc.lengthLocked() {
+		return ErrUnknownHead
+	}
+	if head.Length == lc.Header.prunedCount() {
+		if head.Hash != lc.Header.prunedHead() {
+			return ErrUnknownHead
+		}
+		return nil
+	}
+	if head.Length < lc.Header.prunedCount() {
+		return fmt.Errorf("head at length %d has been pruned", head.Length)
+	}
+	if entry := lc.entryLocked(head.Length - 1); entry == nil || entry.CurrentHash != head.Hash {
+		return ErrUnknownHead
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package crypto
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestReadPageAgainstHead(t *testing.T) {
+	tempFile := os.TempDir() + "/test_chain_page.json"
+	defer os.Remove(tempFile)
+
+	chain := newTestChain(t, tempFile)
+	signer := testSigner(1)
+	for i := 0; i < 5; i++ {
+		addSigned(t, chain, signer, fmt.Sprintf("Log %d", i))
+	}
+
+	first, err := chain.ReadPage(ChainHead{}, 0, 3)
+	if err != nil {
+		t.Fatalf("Failed to read page: %v", err)
+	}
+	if len(first.Entries) != 3 || first.Head.Length != 5 || first.Head.Hash != chain.Entries[4].CurrentHash {
+		t.Fatalf("Expected 3 entries of a 5-entry head, got %d of %+v", len(first.Entries), first.Head)
+	}
+
+	// Entries appended while paging stay out of the listing
+	addSigned(t, chain, signer, "Log 5")
+	next, err := chain.ReadPage(first.Head, first.End(), 3)
+	if err != nil {
+		t.Fatalf("Failed to read next page: %v", err)
+	}
+	if next.Start != 3 || len(next.Entries) != 2 || next.Entries[1].Message != "Log 4" {
+		t.Errorf("Expected entries 3-4, got %d from %d", len(next.Entries), next.Start)
+	}
+
+	// Backward pages end just before the given index
+	back, err := chain.ReadPageBefore(first.Head, 3, 2)
+	if err != nil {
+		t.Fatalf("Failed to read backward: %v", err)
+	}
+	if back.Start != 1 || len(back.Entries) != 2 || back.Entries[0].Message != "Log 1" {
+		t.Errorf("Expected entries 1-2, got %d from %d", len(back.Entries), back.Start)
+	}
+	if back, _ := chain.ReadPageBefore(first.Head, 1, 5); back.Start != 0 || len(back.Entries) != 1 {
+		t.Errorf("Expected only entry 0 before 1, got %d from %d", len(back.Entries), back.Start)
+	}
+
+	// A head from elsewhere is refused
+	forged := ChainHead{Length: 4, Hash: chain.Entries[4].CurrentHash}
+	if _, err := chain.ReadPage(forged, 0, 3); !errors.Is(err, ErrUnknownHead) {
+		t.Errorf("Expected unknown head, got %v", err)
+	}
+	if _, err := chain.ReadPage(ChainHead{Length: 9, Hash: "x"}, 0, 3); !errors.Is(err, ErrUnknownHead) {
+		t.Errorf("Expected head past the chain to be unknown, got %v", err)
+	}
+}
+
+func TestReadPageAfterPrune(t *testing.T) {
+	tempFile := os.TempDir() + "/test_chain_page_prune.json"
+	defer os.Remove(tempFile)
+
+	chain := newTestChain(t, tempFile)
+	signer := testSigner(1)
+	for i := 0; i < 5; i++ {
+		addSigned(t, chain, signer, fmt.Sprintf("Log %d", i))
+	}
+	early := ChainHead{Length: 2, Hash: chain.Entries[1].CurrentHash}
+	if _, err := chain.Prune(RetentionPolicy{MaxEntries: 3}, testSigner(2), ""); err != nil {
+		t.Fatalf("Failed to prune: %v", err)
+	}
+
+	// Pages start at the oldest kept entry
+	page, err := chain.ReadPage(ChainHead{}, 0, 10)
+	if err != nil {
+		t.Fatalf("Failed to read page: %v", err)
+	}
+	if page.Start != 2 || page.First != 2 || len(page.Entries) != 3 {
+		t.Errorf("Expected entries 2-4, got %d from %d", len(page.Entries), page.Start)
+	}
+
+	// The pruned boundary is still a known head; heads before it are not
+	if _, err := chain.ReadPage(early, 0, 10); err != nil {
+		t.Errorf("Expected head at the prune anchor to be accepted, got %v", err)
+	}
+	early.Length, early.Hash = 1, "x"
+	if _, err := chain.ReadPage(early, 0, 10); err == nil || errors.Is(err, ErrUnknownHead) {
+		t.Errorf("Expected pruned head error, got %v", err)
+	}
+}
@@ -0,0 +1,125 @@
+package crypto
+
+import (
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// TreeHeadDomain separates tree head signatures from other signed data
+const TreeHeadDomain = "zcrypt-tree-head-v1"
+
+// TreeHead commits to the entries [Start, Length) of a chain state through
+// the Merkle root of their hashes. Start is the oldest entry kept when the
+// tree was built, since pruned entries can no longer be leaves. The server
+// signs it, binding the root to the head hash that checkpoints timestamp.
+type TreeHead struct {
+	Start     int    `json:"start"`
+	Length    int    `json:"length"`
+	Head      string `json:"head"` // Hash of entry Length-1
+	Root      string `json:"root"`
+	PubKey    string `json:"pubkey"`
+	Algorithm string `json:"algorithm"`
+	Signature string `json:"signature"`
+}
+
+// SigningBytes returns the domain-separated tree head covered by its signature
+func (th TreeHead) SigningBytes() []byte {
+	th.Signature = ""
+	data, _ := json.Marshal(th)
+	return append([]byte(TreeHeadDomain+"\x00"), data...)
+}
+
+// Sign signs the tree head with signer, recording its key and algorithm
+func (th *TreeHead) Sign(signer Signer) error {
+	th.PubKey = hex.EncodeToString(signer.PublicKey())
+	th.Algorithm = signer.Algorithm()
+	sig, err := signer.Sign(th.SigningBytes())
+	if err != nil {
+		return fmt.Errorf("failed to sign tree head: %w", err)
+	}
+	th.Signature = hex.EncodeToString(sig)
+	return nil
+}
+
+// Verify checks the tree head signature against its declared key
+func (th TreeHead) Verify() error {
+	return VerifyWithAlgorithm(th.Algorithm, th.PubKey, th.SigningBytes(), th.Signature)
+}
+
+// InclusionProof shows that an entry hash is part of a chain state: an audit
+// path from the entry to a signed tree head, and optionally the timestamped
+// checkpoint of the same state.
+type InclusionProof struct {
+	Index      int         `json:"index"`
+	EntryHash  string      `json:"entry_hash"`
+	Path       []string    `json:"path"`
+	TreeHead   TreeHead    `json:"tree_head"`
+	Checkpoint *Checkpoint `json:"checkpoint,omitempty"`
+}
+
+// Verify checks the proof offline: the audit path leads to the tree head's
+// root, the tree head is signed by its key, and any checkpoint covers the
+// same state with a valid token (see Checkpoint.Verify for trusted). It does
+// not check whose key signed the tree head.
+func (p InclusionProof) Verify(trusted []*x509.Certificate) error {
+	th := p.TreeHead
+	if err := VerifyInclusion(p.EntryHash, p.Index-th.Start, th.Length-th.Start, p.Path, th.Root); err != nil {
+		return err
+	}
+	if err := th.Verify(); err != nil {
+		return fmt.Errorf("tree head: %w", err)
+	}
+	if cp := p.Checkpoint; cp != nil {
+		if cp.Length != th.Length || cp.Head != th.Head {
+			return fmt.Errorf("checkpoint does not cover the tree head")
+		}
+		if _, err := cp.Verify(trusted); err != nil {
+			return fmt.Errorf("checkpoint: %w", err)
+		}
+	}
+	return nil
+}
+
+// InclusionProof builds an unsigned proof that the entry at index is part of
+// the chain state head. The tree covers the entries kept now, so the proof
+// is for an entry that has not been pruned.
+func (lc *LogChain) InclusionProof(index int, head ChainHead) (*InclusionProof, error) {
+	lc.mu.RLock()
+	defer lc.mu.RUnlock()
+
+	if err := lc.checkHeadLocked(head); err != nil {
+		return nil, err
+	}
+	first := lc.Header.prunedCount()
+	if index < first || index >= head.Length {
+		return nil, fmt.Errorf("entry %d is not a kept entry of a chain of %d", index, head.Length)
+	}
+
+	kept, err := lc.keptLocked()
+	if err != nil {
+		return nil, err
+	}
+	leaves := make([][]byte, head.Length-first)
+	for i := range leaves {
+		leaves[i] = merkleLeaf(kept[i].CurrentHash)
+	}
+	path := merklePath(index-first, leaves)
+
+	proof := &InclusionProof{
+		Index:     index,
+		EntryHash: kept[index-first].CurrentHash,
+		Path:      make([]string, len(path)),
+		TreeHead: TreeHead{
+			Start:  first,
+			Length: head.Length,
+			Head:   head.Hash,
+			Root:   hex.EncodeToString(merkleTreeHash(leaves)),
+		},
+	}
+	for i, node := range path {
+		proof.Path[i] = hex.EncodeToString(node)
+	}
+	return proof, nil
+}
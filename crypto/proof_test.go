@@ -0,0 +1,172 @@
+package crypto
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestMerkleInclusion(t *testing.T) {
+	for size := 1; size <= 17; size++ {
+		hashes := make([]string, size)
+		leaves := make([][]byte, size)
+		for i := range hashes {
+			hashes[i] = fmt.Sprintf("%064x", i)
+			leaves[i] = merkleLeaf(hashes[i])
+		}
+		root := MerkleRoot(hashes)
+
+		for index := 0; index < size; index++ {
+			var path []string
+			for _, node := range merklePath(index, leaves) {
+				path = append(path, fmt.Sprintf("%x", node))
+			}
+			if err := VerifyInclusion(hashes[index], index, size, path, root); err != nil {
+				t.Fatalf("Leaf %d of %d: %v", index, size, err)
+			}
+			if size > 1 && VerifyInclusion(hashes[index], (index+1)%size, size, path, root) == nil {
+				t.Fatalf("Leaf %d of %d verified at the wrong index", index, size)
+			}
+			if VerifyInclusion("other", index, size, path, root) == nil {
+				t.Fatalf("Wrong hash verified as leaf %d of %d", index, size)
+			}
+		}
+	}
+}
+
+func TestInclusionProofAndReceipt(t *testing.T) {
+	tempFile := os.TempDir() + "/test_chain_proof.json"
+	defer os.Remove(tempFile)
+
+	chain := newTestChain(t, tempFile)
+	signer, server := testSigner(1), testSigner(2)
+	var receipt *Receipt
+	for i := 0; i < 6; i++ {
+		entry := addSigned(t, chain, signer, fmt.Sprintf("Log %d", i))
+		if i == 2 {
+			var err error
+			receipt, err = NewReceipt(server, i, entry, ChainHead{Length: i + 1, Hash: entry.CurrentHash}, "")
+			if err != nil {
+				t.Fatalf("Failed to sign receipt: %v", err)
+			}
+		}
+	}
+	if err := receipt.Verify(); err != nil {
+		t.Fatalf("Expected receipt to verify: %v", err)
+	}
+
+	// The entry a receipt names is proven against a later head
+	proof, err := chain.InclusionProof(receipt.Index, chain.Head())
+	if err != nil {
+		t.Fatalf("Failed to build proof: %v", err)
+	}
+	if err := proof.TreeHead.Sign(server); err != nil {
+		t.Fatalf("Failed to sign tree head: %v", err)
+	}
+	if proof.EntryHash != receipt.EntryHash || proof.TreeHead.PubKey != receipt.PubKey {
+		t.Error("Expected proof for the receipt's entry under the same key")
+	}
+	if err := proof.Verify(nil); err != nil {
+		t.Fatalf("Expected proof to verify: %v", err)
+	}
+	hashes := make([]string, len(chain.Entries))
+	for i, entry := range chain.Entries {
+		hashes[i] = entry.CurrentHash
+	}
+	if proof.TreeHead.Root != MerkleRoot(hashes) {
+		t.Error("Expected tree head root over every entry hash")
+	}
+
+	// Altering the proof or the receipt breaks them
+	tampered := *proof
+	tampered.EntryHash = chain.Entries[3].CurrentHash
+	if tampered.Verify(nil) == nil {
+		t.Error("Expected proof for another entry to fail")
+	}
+	tampered = *proof
+	tampered.TreeHead.Root = MerkleRoot(hashes[:5])
+	if tampered.Verify(nil) == nil {
+		t.Error("Expected proof with another root to fail")
+	}
+	forged := *receipt
+	forged.Index = 3
+	if forged.Verify() == nil {
+		t.Error("Expected altered receipt to fail")
+	}
+
+	if _, err := chain.InclusionProof(0, ChainHead{Length: 3, Hash: hashes[4]}); !errors.Is(err, ErrUnknownHead) {
+		t.Errorf("Expected unknown head, got %v", err)
+	}
+	if _, err := chain.InclusionProof(4, ChainHead{Length: 3, Hash: hashes[2]}); err == nil {
+		t.Error("Expected entry past the head to be refused")
+	}
+}
+
+func TestReceiptEvidence(t *testing.T) {
+	tempFile := os.TempDir() + "/test_chain_evidence.json"
+	defer os.Remove(tempFile)
+
+	chain := newTestChain(t, tempFile)
+	signer, server := testSigner(1), testSigner(2)
+	addSigned(t, chain, signer, "Log 0")
+	entry := addSigned(t, chain, signer, "Log 1")
+	addSigned(t, chain, signer, "Log 2")
+
+	receipt, err := NewReceipt(server, 1, entry, ChainHead{Length: 2, Hash: entry.CurrentHash}, "req-1")
+	if err != nil {
+		t.Fatalf("Failed to sign receipt: %v", err)
+	}
+	if receipt.Countersigns != entry.Signature || !receipt.ReceivedAt.Equal(entry.Timestamp) {
+		t.Error("Expected receipt to countersign the entry and record its time")
+	}
+
+	// The compact form round-trips and still verifies
+	parsed, err := ParseReceipt(receipt.Compact())
+	if err != nil {
+		t.Fatalf("Failed to parse compact receipt: %v", err)
+	}
+	if err := parsed.Verify(); err != nil {
+		t.Fatalf("Expected parsed receipt to verify: %v", err)
+	}
+
+	proof, err := chain.InclusionProof(1, chain.Head())
+	if err != nil {
+		t.Fatalf("Failed to build proof: %v", err)
+	}
+	if err := proof.TreeHead.Sign(server); err != nil {
+		t.Fatalf("Failed to sign tree head: %v", err)
+	}
+	submission := LogEntry{Message: entry.Message, Signature: entry.Signature, PubKey: entry.PubKey, Algorithm: entry.Algorithm}
+	evidence := Evidence{Receipt: *parsed, Submission: submission, Entry: entry, Proof: proof}
+	if err := evidence.Verify(nil); err != nil {
+		t.Fatalf("Expected evidence to verify: %v", err)
+	}
+
+	// Evidence for another message, entry or server key fails
+	tampered := evidence
+	tampered.Submission.Message = "Log 9"
+	if tampered.Verify(nil) == nil {
+		t.Error("Expected evidence for an altered message to fail")
+	}
+	tampered = evidence
+	tampered.Entry = &chain.Entries[2]
+	if tampered.Verify(nil) == nil {
+		t.Error("Expected evidence with another entry to fail")
+	}
+	other := *proof
+	if err := other.TreeHead.Sign(testSigner(3)); err != nil {
+		t.Fatalf("Failed to sign tree head: %v", err)
+	}
+	tampered = evidence
+	tampered.Proof = &other
+	if tampered.Verify(nil) == nil {
+		t.Error("Expected proof under another server key to fail")
+	}
+	tampered = evidence
+	tampered.Receipt.ReceivedAt = entry.Timestamp.Add(time.Second)
+	if tampered.Verify(nil) == nil {
+		t.Error("Expected receipt with an altered time to fail")
+	}
+}
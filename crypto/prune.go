@@ -0,0 +1,535 @@
+package crypto
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AnchorDomain separates prune anchor signatures from other signatures
+const AnchorDomain = "zcrypt-prune-anchor-v1"
+
+// ErrNothingToPrune is returned when no entries fall outside the retention policy
+var ErrNothingToPrune = errors.New("no entries to prune")
+
+// RetentionPolicy limits how much history a chain keeps. Each rule that is
+// set marks the oldest entries beyond it for pruning; zero values are unset.
+type RetentionPolicy struct {
+	MaxAge     time.Duration `json:"-"`
+	MaxEntries int           `json:"max_entries,omitempty"`
+	MaxBytes   int64         `json:"max_bytes,omitempty"` // Serialized size of the kept entries
+
+	// Prune only whole sealed epochs, so every kept epoch stays complete
+	WholeEpochs bool `json:"whole_epochs,omitempty"`
+}
+
+// retentionPolicyJSON encodes MaxAge as a readable duration
+type retentionPolicyJSON struct {
+	MaxAge     string `json:"max_age,omitempty"`
+	MaxEntries int    `json:"max_entries,omitempty"`
+	MaxBytes   int64  `json:"max_bytes,omitempty"`
+
+	WholeEpochs bool `json:"whole_epochs,omitempty"`
+}
+
+// MarshalJSON writes max_age as a duration string such as "720h0m0s"
+func (p RetentionPolicy) MarshalJSON() ([]byte, error) {
+	out := retentionPolicyJSON{MaxEntries: p.MaxEntries, MaxBytes: p.MaxBytes, WholeEpochs: p.WholeEpochs}
+	if p.MaxAge > 0 {
+		out.MaxAge = p.MaxAge.String()
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON accepts max_age as a Go duration or a number of days ("90d")
+func (p *RetentionPolicy) UnmarshalJSON(data []byte) error {
+	var in retentionPolicyJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	*p = RetentionPolicy{MaxEntries: in.MaxEntries, MaxBytes: in.MaxBytes, WholeEpochs: in.WholeEpochs}
+	if in.MaxAge != "" {
+		age, err := ParseRetentionAge(in.MaxAge)
+		if err != nil {
+			return err
+		}
+		p.MaxAge = age
+	}
+	return nil
+}
+
+// ParseRetentionAge parses a Go duration or a whole number of days such as "90d"
+func ParseRetentionAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid retention age %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid retention age %q", s)
+	}
+	return d, nil
+}
+
+// IsZero reports whether the policy sets no limits. WholeEpochs only
+// narrows what the limits prune.
+func (p RetentionPolicy) IsZero() bool {
+	return p.MaxAge == 0 && p.MaxEntries == 0 && p.MaxBytes == 0
+}
+
+// LegalHold exempts a range of entries from pruning. Pruning removes a
+// prefix, so a hold also protects every entry after its start.
+type LegalHold struct {
+	ID         int       `json:"id"`
+	StartIndex int       `json:"start_index"`
+	EndIndex   int       `json:"end_index"` // Inclusive
+	Reason     string    `json:"reason"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// PruneAnchor stands in for a pruned prefix of the chain. It records the
+// link into the removed entries and the hash the first kept entry links to,
+// and commits to every removed entry hash through a Merkle root.
+type PruneAnchor struct {
+	StartIndex    int       `json:"start_index"`
+	Count         int       `json:"count"`
+	FirstPrevHash string    `json:"first_prev_hash"`
+	LastHash      string    `json:"last_hash"`
+	MerkleRoot    string    `json:"merkle_root"`
+	PrunedAt      time.Time `json:"pruned_at"`
+	Archive       string    `json:"archive,omitempty"`        // Compressed copy of the pruned entries
+	ArchiveSHA256 string    `json:"archive_sha256,omitempty"` // Digest of the archive file
+	PubKey        string    `json:"pubkey"`
+	Algorithm     string    `json:"algorithm"`
+	Signature     string    `json:"signature"`
+}
+
+// SigningBytes returns the domain-separated anchor content covered by its signature
+func (a PruneAnchor) SigningBytes() []byte {
+	a.Signature = ""
+	data, _ := json.Marshal(a)
+	return append([]byte(AnchorDomain+"\x00"), data...)
+}
+
+// Verify checks the anchor signature against its declared key
+func (a PruneAnchor) Verify() error {
+	return VerifyWithAlgorithm(a.Algorithm, a.PubKey, a.SigningBytes(), a.Signature)
+}
+
+// prunedCount returns how many entries have been pruned, which is also the
+// absolute index of the first kept entry
+func (h *ChainHeader) prunedCount() int {
+	if len(h.Anchors) == 0 {
+		return 0
+	}
+	last := h.Anchors[len(h.Anchors)-1]
+	return last.StartIndex + last.Count
+}
+
+// prunedHead returns the hash the first kept entry links to
+func (h *ChainHeader) prunedHead() string {
+	if len(h.Anchors) == 0 {
+		return "0"
+	}
+	return h.Anchors[len(h.Anchors)-1].LastHash
+}
+
+// entryLocked returns the kept entry at an absolute index, reading archive
+// segments if needed, or nil if the index was pruned or is past the head;
+// callers hold the lock
+func (lc *LogChain) entryLocked(index int) *LogEntry {
+	if i := index - lc.Header.liveStart(); i >= 0 {
+		if i >= len(lc.Entries) {
+			return nil
+		}
+		return &lc.Entries[i]
+	}
+	i := index - lc.Header.prunedCount()
+	if i < 0 {
+		return nil
+	}
+	archived, err := lc.archivedLocked()
+	if err != nil || i >= len(archived) {
+		return nil
+	}
+	return &archived[i]
+}
+
+// lengthLocked returns the absolute length of the chain; callers hold the lock
+func (lc *LogChain) lengthLocked() int {
+	return lc.Header.liveStart() + len(lc.Entries)
+}
+
+// FirstIndex returns the absolute index of the oldest kept entry
+func (lc *LogChain) FirstIndex() int {
+	lc.mu.RLock()
+	defer lc.mu.RUnlock()
+	return lc.Header.prunedCount()
+}
+
+// Length returns the number of entries ever appended, including pruned ones
+func (lc *LogChain) Length() int {
+	lc.mu.RLock()
+	defer lc.mu.RUnlock()
+	return lc.lengthLocked()
+}
+
+// GetEntriesPage returns up to limit kept entries starting at index offset,
+// along with the first kept index and the chain length. Offsets before the
+// first kept entry start at it.
+func (lc *LogChain) GetEntriesPage(offset, limit int) ([]LogEntry, int, int) {
+	lc.mu.RLock()
+	defer lc.mu.RUnlock()
+
+	first, total := lc.Header.prunedCount(), lc.lengthLocked()
+	start := max(offset, first)
+	end := min(start+max(limit, 0), total)
+	if start >= end {
+		return []LogEntry{}, first, total
+	}
+	entries := lc.entriesFromLocked(start)
+	return append([]LogEntry{}, entries[:min(end-start, len(entries))]...), first, total
+}
+
+// SetRetention stores the chain's retention policy in its header
+func (lc *LogChain) SetRetention(policy RetentionPolicy) error {
+	unlock, err := lc.lockWrite()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if policy.MaxEntries < 0 || policy.MaxBytes < 0 || policy.MaxAge < 0 {
+		return fmt.Errorf("retention limits must not be negative")
+	}
+	if policy.IsZero() {
+		lc.Header.Retention = nil
+	} else {
+		lc.Header.Retention = &policy
+	}
+	return lc.Save()
+}
+
+// Retention returns the chain's retention policy, if any
+func (lc *LogChain) Retention() *RetentionPolicy {
+	lc.mu.RLock()
+	defer lc.mu.RUnlock()
+
+	if lc.Header.Retention == nil {
+		return nil
+	}
+	policy := *lc.Header.Retention
+	return &policy
+}
+
+// AddLegalHold exempts entries start through end from pruning
+func (lc *LogChain) AddLegalHold(start, end int, reason string) (*LegalHold, error) {
+	unlock, err := lc.lockWrite()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if start > end {
+		return nil, fmt.Errorf("hold start %d is after end %d", start, end)
+	}
+	if start < lc.Header.prunedCount() {
+		return nil, fmt.Errorf("entry %d has already been pruned", start)
+	}
+	if reason == "" {
+		return nil, fmt.Errorf("a legal hold needs a reason")
+	}
+
+	hold := LegalHold{
+		ID:         1,
+		StartIndex: start,
+		EndIndex:   end,
+		Reason:     reason,
+		CreatedAt:  lc.now(),
+	}
+	for _, existing := range lc.Header.LegalHolds {
+		hold.ID = max(hold.ID, existing.ID+1)
+	}
+	lc.Header.LegalHolds = append(lc.Header.LegalHolds, hold)
+	if err := lc.Save(); err != nil {
+		lc.Header.LegalHolds = lc.Header.LegalHolds[:len(lc.Header.LegalHolds)-1]
+		return nil, fmt.Errorf("failed to save chain: %w", err)
+	}
+	return &hold, nil
+}
+
+// ReleaseLegalHold removes the hold with the given ID
+func (lc *LogChain) ReleaseLegalHold(id int) error {
+	unlock, err := lc.lockWrite()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	for i, hold := range lc.Header.LegalHolds {
+		if hold.ID == id {
+			holds := lc.Header.LegalHolds
+			lc.Header.LegalHolds = append(append([]LegalHold(nil), holds[:i]...), holds[i+1:]...)
+			if err := lc.Save(); err != nil {
+				lc.Header.LegalHolds = holds
+				return fmt.Errorf("failed to save chain: %w", err)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("legal hold %d not found", id)
+}
+
+// LegalHolds returns the chain's active legal holds
+func (lc *LogChain) LegalHolds() []LegalHold {
+	lc.mu.RLock()
+	defer lc.mu.RUnlock()
+	return append([]LegalHold{}, lc.Header.LegalHolds...)
+}
+
+// Prunable reports how many of the oldest entries the policy would prune at
+// now, and the legal hold that stopped it from pruning more, if any
+func (lc *LogChain) Prunable(policy RetentionPolicy, now time.Time) (int, *LegalHold) {
+	lc.mu.RLock()
+	defer lc.mu.RUnlock()
+	return lc.prunableLocked(policy, now)
+}
+
+// prunableLocked applies each retention rule to the kept entries and caps
+// the result at the first held entry; callers hold the lock
+func (lc *LogChain) prunableLocked(policy RetentionPolicy, now time.Time) (int, *LegalHold) {
+	kept, err := lc.keptLocked()
+	if err != nil {
+		return 0, nil
+	}
+
+	n := 0
+	if policy.MaxEntries > 0 && len(kept) > policy.MaxEntries {
+		n = len(kept) - policy.MaxEntries
+	}
+	if policy.MaxAge > 0 {
+		cutoff := now.Add(-policy.MaxAge)
+		aged := 0
+		for aged < len(kept) && kept[aged].Timestamp.Before(cutoff) {
+			aged++
+		}
+		n = max(n, aged)
+	}
+	if policy.MaxBytes > 0 {
+		sizes := make([]int64, len(kept))
+		var total int64
+		for i, entry := range kept {
+			data, _ := json.Marshal(entry)
+			sizes[i] = int64(len(data))
+			total += sizes[i]
+		}
+		oversize := 0
+		for oversize < len(sizes) && total > policy.MaxBytes {
+			total -= sizes[oversize]
+			oversize++
+		}
+		n = max(n, oversize)
+	}
+
+	base := lc.Header.prunedCount()
+	var blocking *LegalHold
+	for _, hold := range lc.Header.LegalHolds {
+		if hold.EndIndex < base {
+			continue
+		}
+		if limit := hold.StartIndex - base; limit < n {
+			n = max(limit, 0)
+			blocking = &hold
+		}
+	}
+
+	// Round down to the end of the last fully prunable sealed epoch
+	if policy.WholeEpochs {
+		whole := 0
+		for _, epoch := range lc.Header.Epochs {
+			if epoch.SealHash != "" && epoch.SealIndex < base+n {
+				whole = max(whole, epoch.SealIndex+1-base)
+			}
+		}
+		n = whole
+	}
+
+	// Archive segments are pruned whole
+	for _, segment := range lc.Header.Segments {
+		if end := base + n; segment.StartIndex < end && end < segment.StartIndex+segment.Count {
+			n = segment.StartIndex - base
+		}
+	}
+	return n, blocking
+}
+
+// Prune removes the oldest entries outside the retention policy and replaces
+// them with an anchor signed by signer. The kept entries link to the anchor's
+// last hash, so the chain still verifies. When archiveDir is set the pruned
+// entries are first written there as a gzip-compressed JSON file. Archive
+// segments are pruned whole, and their files are removed.
+func (lc *LogChain) Prune(policy RetentionPolicy, signer Signer, archiveDir string) (*PruneAnchor, error) {
+	unlock, err := lc.lockWrite()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	n, _ := lc.prunableLocked(policy, lc.now())
+	if n == 0 {
+		return nil, ErrNothingToPrune
+	}
+	if err := lc.checkSignerLocked(signer); err != nil {
+		return nil, err
+	}
+	kept, err := lc.keptLocked()
+	if err != nil {
+		return nil, err
+	}
+	pruned := kept[:n]
+
+	hashes := make([]string, n)
+	for i, entry := range pruned {
+		hashes[i] = entry.CurrentHash
+	}
+	anchor := PruneAnchor{
+		StartIndex:    lc.Header.prunedCount(),
+		Count:         n,
+		FirstPrevHash: pruned[0].PrevHash,
+		LastHash:      pruned[n-1].CurrentHash,
+		MerkleRoot:    MerkleRoot(hashes),
+		PrunedAt:      lc.now(),
+		PubKey:        hex.EncodeToString(signer.PublicKey()),
+		Algorithm:     signer.Algorithm(),
+	}
+
+	if archiveDir != "" {
+		name := fmt.Sprintf("%s.%d-%d.json.gz", filepath.Base(lc.FilePath), anchor.StartIndex, anchor.StartIndex+n-1)
+		anchor.Archive = filepath.Join(archiveDir, name)
+		digest, err := writeEntries(anchor.Archive, pruned, CompressionGzip)
+		if err != nil {
+			return nil, fmt.Errorf("failed to archive pruned entries: %w", err)
+		}
+		anchor.ArchiveSHA256 = digest
+	}
+
+	sig, err := signer.Sign(anchor.SigningBytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign anchor: %w", err)
+	}
+	anchor.Signature = hex.EncodeToString(sig)
+
+	end := anchor.StartIndex + n
+	var dropped, remaining []ArchiveSegment
+	for _, segment := range lc.Header.Segments {
+		if segment.StartIndex < end {
+			dropped = append(dropped, segment)
+		} else {
+			remaining = append(remaining, segment)
+		}
+	}
+	liveCut := max(end-lc.Header.liveStart(), 0)
+
+	entries, anchors, segments := lc.Entries, lc.Header.Anchors, lc.Header.Segments
+	authority := lc.recordAuthorityLocked(signer)
+	lc.Entries = append([]LogEntry{}, lc.Entries[liveCut:]...)
+	lc.Header.Anchors = append(append([]PruneAnchor{}, anchors...), anchor)
+	lc.Header.Segments = remaining
+	if err := lc.Save(); err != nil {
+		lc.Entries, lc.Header.Anchors, lc.Header.Segments = entries, anchors, segments
+		lc.Header.Authority = authority
+		return nil, fmt.Errorf("failed to save chain: %w", err)
+	}
+	for _, segment := range dropped {
+		os.Remove(lc.segmentPath(segment))
+	}
+	lc.invalidateArchive()
+	return &anchor, nil
+}
+
+// ReadArchive loads the entries stored in a prune archive or archive segment
+func ReadArchive(path string) ([]LogEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return decodeEntries(data)
+}
+
+// VerifyArchive checks an anchor's archive file against the anchor: the file
+// digest, each entry's hash and link, and the Merkle root
+func (lc *LogChain) VerifyArchive(anchor PruneAnchor) error {
+	if anchor.Archive == "" {
+		return fmt.Errorf("pruned entries %d-%d were not archived", anchor.StartIndex, anchor.StartIndex+anchor.Count-1)
+	}
+	data, err := os.ReadFile(anchor.Archive)
+	if err != nil {
+		return fmt.Errorf("cannot read archive: %w", err)
+	}
+	if sum := sha256.Sum256(data); hex.EncodeToString(sum[:]) != anchor.ArchiveSHA256 {
+		return fmt.Errorf("archive digest does not match anchor")
+	}
+	entries, err := ReadArchive(anchor.Archive)
+	if err != nil {
+		return err
+	}
+	if len(entries) != anchor.Count {
+		return fmt.Errorf("archive holds %d entries, anchor records %d", len(entries), anchor.Count)
+	}
+
+	lc.mu.RLock()
+	defer lc.mu.RUnlock()
+
+	prev := anchor.FirstPrevHash
+	hashes := make([]string, len(entries))
+	for i, entry := range entries {
+		index := anchor.StartIndex + i
+		if entry.PrevHash != prev {
+			return fmt.Errorf("archived entry %d: broken chain link", index)
+		}
+		if calculateHash(entry, lc.Header.epochAt(index).HashAlgorithm) != entry.CurrentHash {
+			return fmt.Errorf("archived entry %d: hash mismatch", index)
+		}
+		hashes[i] = entry.CurrentHash
+		prev = entry.CurrentHash
+	}
+	if prev != anchor.LastHash {
+		return fmt.Errorf("archive does not end at the anchor's last hash")
+	}
+	if MerkleRoot(hashes) != anchor.MerkleRoot {
+		return fmt.Errorf("archive does not match the anchor's Merkle root")
+	}
+	return nil
+}
+
+// verifyAnchorsLocked checks that anchors are signed by the chain's
+// authority and cover the pruned prefix without gaps; callers hold the lock
+func (lc *LogChain) verifyAnchorsLocked() []string {
+	var errors []string
+	start, prev := 0, "0"
+	for i, anchor := range lc.Header.Anchors {
+		if err := anchor.Verify(); err != nil {
+			errors = append(errors, fmt.Sprintf("Anchor %d: invalid signature: %v", i, err))
+		} else if problem := lc.checkAuthorityLocked(anchor.PubKey); problem != "" {
+			errors = append(errors, fmt.Sprintf("Anchor %d: %s", i, problem))
+		}
+		if anchor.Count <= 0 || anchor.StartIndex != start {
+			errors = append(errors, fmt.Sprintf("Anchor %d: does not continue from entry %d", i, start))
+		}
+		if anchor.FirstPrevHash != prev {
+			errors = append(errors, fmt.Sprintf("Anchor %d: does not link to previous anchor", i))
+		}
+		start, prev = anchor.StartIndex+anchor.Count, anchor.LastHash
+	}
+	return errors
+}
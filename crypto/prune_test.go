@@ -0,0 +1,271 @@
+package crypto
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMerkleRoot(t *testing.T) {
+	leaves := [][]byte{merkleLeaf("a"), merkleLeaf("b"), merkleLeaf("c")}
+	expected := hex.EncodeToString(merkleNode(merkleNode(leaves[0], leaves[1]), leaves[2]))
+	if root := MerkleRoot([]string{"a", "b", "c"}); root != expected {
+		t.Errorf("Expected RFC 6962 tree shape, got %s", root)
+	}
+	if MerkleRoot([]string{"a"}) != hex.EncodeToString(leaves[0]) {
+		t.Error("Root of a single leaf should be the leaf hash")
+	}
+	if MerkleRoot([]string{"a", "b"}) == MerkleRoot([]string{"b", "a"}) {
+		t.Error("Root should depend on leaf order")
+	}
+}
+
+func TestPruneKeepsChainVerifiable(t *testing.T) {
+	tempFile := os.TempDir() + "/test_chain_prune.json"
+	archiveDir := os.TempDir() + "/test_chain_prune_archive"
+	defer os.Remove(tempFile)
+	defer os.RemoveAll(archiveDir)
+
+	chain, _ := NewLogChain(tempFile)
+	signer, _ := GenerateSigner(AlgEd25519)
+	server, _ := GenerateSigner(AlgEd25519)
+	for i := 0; i < 5; i++ {
+		addSigned(t, chain, signer, fmt.Sprintf("Log %d", i))
+	}
+	prunedHead := chain.Entries[2].CurrentHash
+
+	anchor, err := chain.Prune(RetentionPolicy{MaxEntries: 2}, server, archiveDir)
+	if err != nil {
+		t.Fatalf("Failed to prune: %v", err)
+	}
+	if anchor.StartIndex != 0 || anchor.Count != 3 || anchor.LastHash != prunedHead {
+		t.Errorf("Unexpected anchor %+v", anchor)
+	}
+	if len(chain.Entries) != 2 || chain.FirstIndex() != 3 || chain.Length() != 5 {
+		t.Fatalf("Expected entries 3-4 kept, got %d from %d", len(chain.Entries), chain.FirstIndex())
+	}
+
+	// Indices stay stable across pruning
+	if _, err := chain.GetEntry(1); err == nil {
+		t.Error("Expected error reading a pruned entry")
+	}
+	if entry, err := chain.GetEntry(3); err != nil || entry.Message != "Log 3" {
+		t.Errorf("Expected entry 3 to be kept, got %+v (%v)", entry, err)
+	}
+	addSigned(t, chain, signer, "Log 5")
+	if index := chain.IndexOf(chain.GetLastHash()); index != 5 {
+		t.Errorf("Expected new entry at index 5, got %d", index)
+	}
+
+	reloaded, _ := NewLogChain(tempFile)
+	report := reloaded.VerifyChainReport()
+	if !report.Valid || report.Pruned != 3 || report.Total != 3 {
+		t.Fatalf("Expected pruned chain to verify, got %+v", report)
+	}
+	if err := reloaded.VerifyArchive(reloaded.Header.Anchors[0]); err != nil {
+		t.Errorf("Archive should match its anchor: %v", err)
+	}
+	if _, err := reloaded.Prune(RetentionPolicy{MaxEntries: 10}, server, ""); err != ErrNothingToPrune {
+		t.Errorf("Expected nothing to prune, got %v", err)
+	}
+
+	// Anchors are signed
+	reloaded.Header.Anchors[0].Count = 2
+	if valid, _ := reloaded.VerifyChain(); valid {
+		t.Error("Expected modified anchor to be detected")
+	}
+}
+
+func TestAnchorsSignedByAuthority(t *testing.T) {
+	chain, _ := NewLogChain(filepath.Join(t.TempDir(), "chain.json"))
+	agent, _ := GenerateSigner(AlgEd25519)
+	server, _ := GenerateSigner(AlgEd25519)
+	attacker, _ := GenerateSigner(AlgEd25519)
+	for i := 0; i < 4; i++ {
+		addSigned(t, chain, agent, fmt.Sprintf("Log %d", i))
+	}
+
+	// The first prune records its signer, and later prunes must use it
+	if _, err := chain.Prune(RetentionPolicy{MaxEntries: 3}, server, ""); err != nil {
+		t.Fatalf("Failed to prune: %v", err)
+	}
+	if a := chain.GetHeader().Authority; a == nil || a.PubKey != hex.EncodeToString(server.PublicKey()) {
+		t.Fatalf("Expected the server key to be recorded, got %+v", a)
+	}
+	if _, err := chain.Prune(RetentionPolicy{MaxEntries: 2}, attacker, ""); !errors.Is(err, ErrNotAuthority) {
+		t.Errorf("Expected ErrNotAuthority, got %v", err)
+	}
+
+	// An anchor validly signed by another key
+	forged := chain.Header.Anchors[0]
+	forged.PubKey, forged.Algorithm = hex.EncodeToString(attacker.PublicKey()), attacker.Algorithm()
+	sig, _ := attacker.Sign(forged.SigningBytes())
+	forged.Signature = hex.EncodeToString(sig)
+	chain.Header.Anchors[0] = forged
+	if valid, _ := chain.VerifyChain(); valid {
+		t.Error("Expected an anchor signed by another key to be rejected")
+	}
+
+	// Replacing the recorded key too is only caught with a trusted key
+	recorded := chain.Header.Authority
+	chain.Header.Authority = &ChainAuthority{PubKey: forged.PubKey, Algorithm: forged.Algorithm}
+	if valid, errs := chain.VerifyChain(); !valid {
+		t.Fatalf("Expected the rewritten chain to be consistent, got %v", errs)
+	}
+	chain.SetAuthority(server.PublicKey())
+	if valid, _ := chain.VerifyChain(); valid {
+		t.Error("Expected the trusted key to reject the rewritten anchors")
+	}
+
+	// Anchors without a recorded key are not trusted
+	chain.SetAuthority(nil)
+	chain.Header.Authority = nil
+	if valid, _ := chain.VerifyChain(); valid {
+		t.Error("Expected anchors without an authority to be rejected")
+	}
+	chain.Header.Authority = recorded
+
+	// A key trusted from the start also holds for the first prune
+	trusted, _ := NewLogChain(filepath.Join(t.TempDir(), "trusted.json"))
+	trusted.SetAuthority(server.PublicKey())
+	addSigned(t, trusted, agent, "Log 0")
+	if _, err := trusted.Prune(RetentionPolicy{MaxAge: time.Nanosecond}, attacker, ""); !errors.Is(err, ErrNotAuthority) {
+		t.Errorf("Expected ErrNotAuthority, got %v", err)
+	}
+}
+
+func TestPruneTwiceAndEmpty(t *testing.T) {
+	tempFile := os.TempDir() + "/test_chain_prune_twice.json"
+	defer os.Remove(tempFile)
+
+	chain, _ := NewLogChain(tempFile)
+	signer, _ := GenerateSigner(AlgEd25519)
+	for i := 0; i < 4; i++ {
+		addSigned(t, chain, signer, fmt.Sprintf("Log %d", i))
+	}
+
+	if _, err := chain.Prune(RetentionPolicy{MaxEntries: 3}, signer, ""); err != nil {
+		t.Fatalf("Failed to prune: %v", err)
+	}
+	head := chain.GetLastHash()
+	anchor, err := chain.Prune(RetentionPolicy{MaxAge: time.Nanosecond}, signer, "")
+	if err != nil {
+		t.Fatalf("Failed to prune remaining entries: %v", err)
+	}
+	if anchor.StartIndex != 1 || anchor.Count != 3 || len(chain.Entries) != 0 {
+		t.Fatalf("Expected every entry pruned, got %+v", anchor)
+	}
+
+	// An empty chain links new entries to the last anchor
+	if chain.GetLastHash() != head {
+		t.Error("Expected head to be the last pruned hash")
+	}
+	entry := addSigned(t, chain, signer, "Log 4")
+	if entry.PrevHash != head {
+		t.Error("Expected new entry to link to the anchor")
+	}
+	if valid, errors := chain.VerifyChain(); !valid {
+		t.Errorf("Expected chain to verify: %v", errors)
+	}
+}
+
+func TestLegalHoldStopsPruning(t *testing.T) {
+	tempFile := os.TempDir() + "/test_chain_hold.json"
+	defer os.Remove(tempFile)
+
+	chain, _ := NewLogChain(tempFile)
+	signer, _ := GenerateSigner(AlgEd25519)
+	for i := 0; i < 6; i++ {
+		addSigned(t, chain, signer, fmt.Sprintf("Log %d", i))
+	}
+
+	hold, err := chain.AddLegalHold(2, 3, "litigation 2026-17")
+	if err != nil {
+		t.Fatalf("Failed to add hold: %v", err)
+	}
+	n, blocking := chain.Prunable(RetentionPolicy{MaxEntries: 1}, time.Now())
+	if n != 2 || blocking == nil || blocking.ID != hold.ID {
+		t.Errorf("Expected hold to cap pruning at 2 entries, got %d (%+v)", n, blocking)
+	}
+	if _, err := chain.Prune(RetentionPolicy{MaxEntries: 1}, signer, ""); err != nil {
+		t.Fatalf("Failed to prune: %v", err)
+	}
+	if chain.FirstIndex() != 2 {
+		t.Errorf("Expected held entries kept, first index %d", chain.FirstIndex())
+	}
+	if _, err := chain.Prune(RetentionPolicy{MaxEntries: 1}, signer, ""); err != ErrNothingToPrune {
+		t.Errorf("Expected hold to block pruning, got %v", err)
+	}
+
+	if err := chain.ReleaseLegalHold(hold.ID); err != nil {
+		t.Fatalf("Failed to release hold: %v", err)
+	}
+	if n, _ := chain.Prunable(RetentionPolicy{MaxEntries: 1}, time.Now()); n != 3 {
+		t.Errorf("Expected 3 prunable entries after release, got %d", n)
+	}
+	if _, err := chain.AddLegalHold(0, 1, "too late"); err == nil {
+		t.Error("Expected error holding pruned entries")
+	}
+}
+
+func TestPruneBySize(t *testing.T) {
+	tempFile := os.TempDir() + "/test_chain_prune_size.json"
+	defer os.Remove(tempFile)
+
+	chain, _ := NewLogChain(tempFile)
+	signer, _ := GenerateSigner(AlgEd25519)
+	for i := 0; i < 4; i++ {
+		addSigned(t, chain, signer, fmt.Sprintf("Log %d", i))
+	}
+	data, _ := json.Marshal(chain.Entries[3])
+
+	if n, _ := chain.Prunable(RetentionPolicy{MaxBytes: int64(len(data))}, time.Now()); n != 3 {
+		t.Errorf("Expected size limit to keep one entry, got %d prunable", n)
+	}
+}
+
+func TestRetentionPolicyJSON(t *testing.T) {
+	var policy RetentionPolicy
+	if err := json.Unmarshal([]byte(`{"max_age":"90d","max_entries":1000}`), &policy); err != nil {
+		t.Fatalf("Failed to parse policy: %v", err)
+	}
+	if policy.MaxAge != 90*24*time.Hour || policy.MaxEntries != 1000 {
+		t.Errorf("Unexpected policy %+v", policy)
+	}
+	data, _ := json.Marshal(policy)
+	if string(data) != `{"max_age":"2160h0m0s","max_entries":1000}` {
+		t.Errorf("Unexpected encoding %s", data)
+	}
+	if err := json.Unmarshal([]byte(`{"max_age":"soon"}`), &policy); err == nil {
+		t.Error("Expected error for invalid age")
+	}
+}
+
+func TestRedactAfterPrune(t *testing.T) {
+	tempFile := os.TempDir() + "/test_chain_prune_redact.json"
+	defer os.Remove(tempFile)
+
+	chain, _ := NewLogChain(tempFile)
+	signer, _ := GenerateSigner(AlgEd25519)
+	for i := 0; i < 3; i++ {
+		addSigned(t, chain, signer, fmt.Sprintf("Log %d", i))
+	}
+	chain.Prune(RetentionPolicy{MaxEntries: 1}, signer, "")
+
+	target, _ := chain.GetEntry(2)
+	ref := RedactionRef{TargetIndex: 2, TargetHash: target.CurrentHash, Fields: []string{FieldMessage}}
+	sig, _ := SignRedaction(signer, ref)
+	allowRedactor(t, chain, signer)
+	if _, err := chain.Redact(ref, sig, hex.EncodeToString(signer.PublicKey()), AlgEd25519, nil); err != nil {
+		t.Fatalf("Failed to redact by absolute index: %v", err)
+	}
+	report := chain.VerifyChainReport()
+	if !report.Valid || len(report.Redacted) != 1 || report.Redacted[0].Index != 2 || report.Redacted[0].By[0] != 3 {
+		t.Errorf("Expected entry 2 redacted by entry 3, got %+v", report)
+	}
+}
@@ -0,0 +1,168 @@
+package crypto
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ReceiptDomain separates receipt signatures from other signed data
+const ReceiptDomain = "zcrypt-receipt-v1"
+
+// compactReceiptPrefix marks the single-token form of a receipt
+const compactReceiptPrefix = "zcr1."
+
+// Receipt is the server's signed statement that it appended an entry: the
+// entry's hash at Index, in the chain state Head. It countersigns the
+// submitter's own signature and records when the entry was received, so an
+// agent holding its submission can show later that the server accepted it,
+// and check with an inclusion proof that it is still in the chain.
+type Receipt struct {
+	Index        int       `json:"index"`
+	EntryHash    string    `json:"entry_hash"`
+	Head         ChainHead `json:"head"`
+	ReceivedAt   time.Time `json:"received_at,omitzero"`   // The entry's Timestamp
+	Submitter    string    `json:"submitter,omitempty"`    // Public key that signed the submission
+	Countersigns string    `json:"countersigns,omitempty"` // The submission's signature
+	RequestID    string    `json:"request_id,omitempty"`
+	PubKey       string    `json:"pubkey"`
+	Algorithm    string    `json:"algorithm"`
+	Signature    string    `json:"signature"`
+}
+
+// NewReceipt signs a receipt for the entry at index of the chain state head,
+// accepted under requestID when the submission carried one
+func NewReceipt(signer Signer, index int, entry *LogEntry, head ChainHead, requestID string) (*Receipt, error) {
+	receipt := &Receipt{
+		Index:        index,
+		EntryHash:    entry.CurrentHash,
+		Head:         head,
+		ReceivedAt:   entry.Timestamp,
+		Submitter:    entry.PubKey,
+		Countersigns: entry.Signature,
+		RequestID:    requestID,
+		PubKey:       hex.EncodeToString(signer.PublicKey()),
+		Algorithm:    signer.Algorithm(),
+	}
+	sig, err := signer.Sign(receipt.SigningBytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign receipt: %w", err)
+	}
+	receipt.Signature = hex.EncodeToString(sig)
+	return receipt, nil
+}
+
+// SigningBytes returns the domain-separated receipt covered by its signature
+func (r Receipt) SigningBytes() []byte {
+	r.Signature = ""
+	data, _ := json.Marshal(r)
+	return append([]byte(ReceiptDomain+"\x00"), data...)
+}
+
+// Verify checks the receipt signature against its declared key and that the
+// entry lies within the head. It does not check whose key signed it.
+func (r Receipt) Verify() error {
+	if r.Index < 0 || r.Index >= r.Head.Length {
+		return fmt.Errorf("receipt index %d is outside its head of length %d", r.Index, r.Head.Length)
+	}
+	if r.Index == r.Head.Length-1 && r.EntryHash != r.Head.Hash {
+		return fmt.Errorf("receipt entry is the head but hashes differ")
+	}
+	return VerifyWithAlgorithm(r.Algorithm, r.PubKey, r.SigningBytes(), r.Signature)
+}
+
+// Compact encodes the receipt as a single URL-safe token
+func (r Receipt) Compact() string {
+	data, _ := json.Marshal(r)
+	return compactReceiptPrefix + base64.RawURLEncoding.EncodeToString(data)
+}
+
+// ParseReceipt decodes a receipt from its compact token or its JSON form.
+// The receipt is not verified.
+func ParseReceipt(s string) (*Receipt, error) {
+	s = strings.TrimSpace(s)
+	data := []byte(s)
+	if token, ok := strings.CutPrefix(s, compactReceiptPrefix); ok {
+		var err error
+		if data, err = base64.RawURLEncoding.DecodeString(token); err != nil {
+			return nil, fmt.Errorf("malformed compact receipt: %w", err)
+		}
+	}
+	var receipt Receipt
+	if err := json.Unmarshal(data, &receipt); err != nil {
+		return nil, fmt.Errorf("malformed receipt: %w", err)
+	}
+	return &receipt, nil
+}
+
+// Evidence shows that a server accepted a signed submission: the server's
+// receipt countersigning it, the submission as the agent signed it, and
+// optionally the entry as the server stored it and a later inclusion proof.
+// Anyone can check it offline.
+type Evidence struct {
+	Receipt    Receipt         `json:"receipt"`
+	Submission LogEntry        `json:"submission"` // Message, envelope, key and signature as sent
+	Entry      *LogEntry       `json:"entry,omitempty"`
+	Proof      *InclusionProof `json:"proof,omitempty"`
+}
+
+// Verify checks that the receipt is signed, that it countersigns the
+// submission's valid signature, and that any entry and proof are for the same
+// entry under the same server key. trusted is passed to the proof's
+// checkpoint. It does not check whose key signed the receipt.
+func (e Evidence) Verify(trusted []*x509.Certificate) error {
+	r := e.Receipt
+	if err := r.Verify(); err != nil {
+		return fmt.Errorf("receipt: %w", err)
+	}
+	if r.Countersigns == "" {
+		return fmt.Errorf("receipt does not countersign a submission")
+	}
+	if e.Submission.PubKey != r.Submitter || e.Submission.Signature != r.Countersigns {
+		return fmt.Errorf("receipt countersigns another submission")
+	}
+	if err := e.Submission.VerifySignature(); err != nil {
+		return fmt.Errorf("submission signature: %w", err)
+	}
+
+	if entry := e.Entry; entry != nil {
+		if entry.CurrentHash != r.EntryHash {
+			return fmt.Errorf("entry is not the one the receipt names")
+		}
+		if !entry.hashesUnderSomeAlgorithm() {
+			return fmt.Errorf("entry does not match its hash")
+		}
+		if entry.Signature != r.Countersigns || !entry.Timestamp.Equal(r.ReceivedAt) {
+			return fmt.Errorf("entry differs from the receipt")
+		}
+	}
+
+	if p := e.Proof; p != nil {
+		if p.Index != r.Index || p.EntryHash != r.EntryHash {
+			return fmt.Errorf("proof is for another entry")
+		}
+		if p.TreeHead.PubKey != r.PubKey {
+			return fmt.Errorf("proof is signed by a different server key")
+		}
+		if err := p.Verify(trusted); err != nil {
+			return fmt.Errorf("proof: %w", err)
+		}
+	}
+	return nil
+}
+
+// hashesUnderSomeAlgorithm reports whether the entry's CurrentHash is its
+// hash under any supported algorithm, for checking an entry without the
+// header of the chain it came from
+func (e *LogEntry) hashesUnderSomeAlgorithm() bool {
+	for _, alg := range SupportedHashAlgorithms() {
+		if e.Hash(alg) == e.CurrentHash {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,235 @@
+package crypto
+
+import (
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// EncryptedMessagePrefix marks a log message sealed to recipient keys
+const EncryptedMessagePrefix = "zcrypt-encrypted-v1:"
+
+// recipientWrapInfo separates recipient key-wrapping keys from other HKDF uses
+const recipientWrapInfo = "zcrypt-recipient-wrap-v1"
+
+// Default X25519 encryption key file locations
+const (
+	EncryptionKeyFile    = "zcrypt_encryption.key"
+	EncryptionPubKeyFile = "zcrypt_encryption.pub"
+)
+
+// ErrNotRecipient is returned when a message was not sealed to the given key
+var ErrNotRecipient = errors.New("message is not encrypted to this key")
+
+// encryptedMessage is the armored payload after the prefix
+type encryptedMessage struct {
+	Ephemeral  string             `json:"ephemeral"` // hex X25519 public key
+	Recipients []messageRecipient `json:"recipients"`
+	Nonce      string             `json:"nonce"`
+	Ciphertext string             `json:"ciphertext"`
+}
+
+// messageRecipient carries the content key wrapped for one recipient
+type messageRecipient struct {
+	KeyID      string `json:"key_id"`
+	WrappedKey string `json:"wrapped_key"` // base64 nonce||AES-GCM(content key)
+}
+
+// GenerateEncryptionKey creates an X25519 key for receiving sealed messages
+func GenerateEncryptionKey() (*ecdh.PrivateKey, error) {
+	return ecdh.X25519().GenerateKey(rand.Reader)
+}
+
+// ParseEncryptionPublicKey accepts a raw or hex X25519 public key
+func ParseEncryptionPublicKey(data []byte) (*ecdh.PublicKey, error) {
+	if raw, err := hex.DecodeString(strings.TrimSpace(string(data))); err == nil {
+		data = raw
+	}
+	key, err := ecdh.X25519().NewPublicKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid X25519 public key: %w", err)
+	}
+	return key, nil
+}
+
+// SaveEncryptionKey writes the private and public X25519 key files
+func SaveEncryptionKey(key *ecdh.PrivateKey, privPath, pubPath string) error {
+	if err := os.WriteFile(privPath, key.Bytes(), 0600); err != nil {
+		return fmt.Errorf("failed to save encryption key: %w", err)
+	}
+	if err := os.WriteFile(pubPath, key.PublicKey().Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to save encryption public key: %w", err)
+	}
+	return nil
+}
+
+// LoadEncryptionKey reads an X25519 private key file
+func LoadEncryptionKey(path string) (*ecdh.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("encryption key not found: %w", err)
+	}
+	key, err := ecdh.X25519().NewPrivateKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid X25519 private key: %w", err)
+	}
+	return key, nil
+}
+
+// RecipientKeyID is a short public fingerprint of an encryption key
+func RecipientKeyID(pub *ecdh.PublicKey) string {
+	sum := sha256.Sum256(pub.Bytes())
+	return hex.EncodeToString(sum[:8])
+}
+
+// IsEncryptedMessage reports whether a log message is sealed to recipients
+func IsEncryptedMessage(message string) bool {
+	return strings.HasPrefix(message, EncryptedMessagePrefix)
+}
+
+// EncryptForRecipients seals message so that only the holders of the given
+// X25519 keys can read it. A fresh content key encrypts the message; it is
+// wrapped for each recipient with a key derived from X25519 agreement with a
+// single ephemeral key.
+func EncryptForRecipients(message []byte, recipients []*ecdh.PublicKey) (string, error) {
+	if len(recipients) == 0 {
+		return "", errors.New("at least one recipient is required")
+	}
+
+	ephemeral, err := GenerateEncryptionKey()
+	if err != nil {
+		return "", err
+	}
+	msg := encryptedMessage{Ephemeral: hex.EncodeToString(ephemeral.PublicKey().Bytes())}
+
+	contentKey := make([]byte, 32)
+	if _, err := rand.Read(contentKey); err != nil {
+		return "", err
+	}
+	for _, recipient := range recipients {
+		shared, err := ephemeral.ECDH(recipient)
+		if err != nil {
+			return "", fmt.Errorf("key agreement failed: %w", err)
+		}
+		wrapKey, err := recipientWrapKey(shared, ephemeral.PublicKey(), recipient)
+		if err != nil {
+			return "", err
+		}
+		nonce, wrapped, err := aesGCMSeal(wrapKey, contentKey, rand.Reader)
+		if err != nil {
+			return "", err
+		}
+		msg.Recipients = append(msg.Recipients, messageRecipient{
+			KeyID:      RecipientKeyID(recipient),
+			WrappedKey: base64.StdEncoding.EncodeToString(append(nonce, wrapped...)),
+		})
+	}
+
+	nonce, ciphertext, err := aesGCMSeal(contentKey, message, rand.Reader)
+	if err != nil {
+		return "", err
+	}
+	msg.Nonce = base64.StdEncoding.EncodeToString(nonce)
+	msg.Ciphertext = base64.StdEncoding.EncodeToString(ciphertext)
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return "", err
+	}
+	return EncryptedMessagePrefix + base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// DecryptMessage opens a message sealed by EncryptForRecipients
+func DecryptMessage(armored string, key *ecdh.PrivateKey) ([]byte, error) {
+	msg, err := parseEncryptedMessage(armored)
+	if err != nil {
+		return nil, err
+	}
+
+	ephemeralBytes, err := hex.DecodeString(msg.Ephemeral)
+	if err != nil {
+		return nil, errors.New("malformed encrypted message")
+	}
+	ephemeral, err := ecdh.X25519().NewPublicKey(ephemeralBytes)
+	if err != nil {
+		return nil, errors.New("malformed encrypted message")
+	}
+
+	keyID := RecipientKeyID(key.PublicKey())
+	for _, recipient := range msg.Recipients {
+		if recipient.KeyID != keyID {
+			continue
+		}
+		shared, err := key.ECDH(ephemeral)
+		if err != nil {
+			return nil, fmt.Errorf("key agreement failed: %w", err)
+		}
+		wrapKey, err := recipientWrapKey(shared, ephemeral, key.PublicKey())
+		if err != nil {
+			return nil, err
+		}
+		wrapped, err := base64.StdEncoding.DecodeString(recipient.WrappedKey)
+		if err != nil || len(wrapped) < 12 {
+			return nil, errors.New("malformed wrapped key")
+		}
+		contentKey, err := aesGCMOpen(wrapKey, wrapped[:12], wrapped[12:])
+		if err != nil {
+			return nil, fmt.Errorf("failed to unwrap content key: %w", err)
+		}
+
+		nonce, err1 := base64.StdEncoding.DecodeString(msg.Nonce)
+		ciphertext, err2 := base64.StdEncoding.DecodeString(msg.Ciphertext)
+		if err := errors.Join(err1, err2); err != nil {
+			return nil, errors.New("malformed encrypted message")
+		}
+		plain, err := aesGCMOpen(contentKey, nonce, ciphertext)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt message: %w", err)
+		}
+		return plain, nil
+	}
+	return nil, ErrNotRecipient
+}
+
+// MessageRecipients lists the key IDs a message is sealed to
+func MessageRecipients(armored string) ([]string, error) {
+	msg, err := parseEncryptedMessage(armored)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(msg.Recipients))
+	for i, recipient := range msg.Recipients {
+		ids[i] = recipient.KeyID
+	}
+	return ids, nil
+}
+
+func parseEncryptedMessage(armored string) (*encryptedMessage, error) {
+	if !IsEncryptedMessage(armored) {
+		return nil, errors.New("not an encrypted message")
+	}
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(armored, EncryptedMessagePrefix))
+	if err != nil {
+		return nil, errors.New("malformed encrypted message")
+	}
+	var msg encryptedMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return nil, errors.New("malformed encrypted message")
+	}
+	return &msg, nil
+}
+
+// recipientWrapKey derives the key-wrapping key for one recipient from the
+// X25519 shared secret, bound to the ephemeral key and the recipient key
+func recipientWrapKey(shared []byte, ephemeral, recipient *ecdh.PublicKey) ([]byte, error) {
+	salt := append(append([]byte{}, ephemeral.Bytes()...), recipient.Bytes()...)
+	return hkdf.Key(sha256.New, shared, salt, recipientWrapInfo, 32)
+}
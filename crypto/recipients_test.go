@@ -0,0 +1,79 @@
+package crypto
+
+import (
+	"crypto/ecdh"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestEncryptForRecipients(t *testing.T) {
+	alice, _ := GenerateEncryptionKey()
+	bob, _ := GenerateEncryptionKey()
+	eve, _ := GenerateEncryptionKey()
+
+	armored, err := EncryptForRecipients([]byte("wire transfer approved"), []*ecdh.PublicKey{alice.PublicKey(), bob.PublicKey()})
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+	if !IsEncryptedMessage(armored) || strings.Contains(armored, "wire transfer") {
+		t.Fatalf("Expected armored ciphertext, got %q", armored)
+	}
+
+	for name, key := range map[string]*ecdh.PrivateKey{"alice": alice, "bob": bob} {
+		plain, err := DecryptMessage(armored, key)
+		if err != nil || string(plain) != "wire transfer approved" {
+			t.Errorf("%s failed to decrypt: %v", name, err)
+		}
+	}
+	if _, err := DecryptMessage(armored, eve); !errors.Is(err, ErrNotRecipient) {
+		t.Errorf("Expected ErrNotRecipient for eve, got %v", err)
+	}
+
+	ids, _ := MessageRecipients(armored)
+	if len(ids) != 2 || ids[0] != RecipientKeyID(alice.PublicKey()) {
+		t.Errorf("Unexpected recipients %v", ids)
+	}
+
+	// Swapping another message's body in fails authentication
+	other, _ := EncryptForRecipients([]byte("other"), []*ecdh.PublicKey{alice.PublicKey()})
+	a, _ := parseEncryptedMessage(armored)
+	b, _ := parseEncryptedMessage(other)
+	a.Ciphertext, a.Nonce = b.Ciphertext, b.Nonce
+	if _, err := DecryptMessage(rearmor(t, a), alice); err == nil {
+		t.Error("Expected tampered message to fail decryption")
+	}
+}
+
+// rearmor encodes a modified message payload
+func rearmor(t *testing.T, msg *encryptedMessage) string {
+	t.Helper()
+	data, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return EncryptedMessagePrefix + base64.RawURLEncoding.EncodeToString(data)
+}
+
+func TestEncryptionKeyFiles(t *testing.T) {
+	privFile := os.TempDir() + "/test_encryption.key"
+	pubFile := os.TempDir() + "/test_encryption.pub"
+	defer os.Remove(privFile)
+	defer os.Remove(pubFile)
+
+	key, _ := GenerateEncryptionKey()
+	if err := SaveEncryptionKey(key, privFile, pubFile); err != nil {
+		t.Fatalf("Failed to save: %v", err)
+	}
+	loaded, err := LoadEncryptionKey(privFile)
+	if err != nil || !loaded.Equal(key) {
+		t.Fatalf("Failed to load: %v", err)
+	}
+	pub, _ := os.ReadFile(pubFile)
+	if parsed, err := ParseEncryptionPublicKey(pub); err != nil || !parsed.Equal(key.PublicKey()) {
+		t.Errorf("Failed to parse public key file: %v", err)
+	}
+}
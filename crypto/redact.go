@@ -0,0 +1,481 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// KindRedaction marks an entry that authorizes erasing content from an earlier entry
+const KindRedaction = "redaction"
+
+// ErrNotRedactor is returned when a redaction is signed by a key the chain
+// does not allow to redact
+var ErrNotRedactor = errors.New("key may not sign redactions")
+
+// Domain-separation prefixes for commitments and redaction statements
+const (
+	CommitDomain    = "zcrypt-commit-v1"
+	RedactionDomain = "zcrypt-redaction-v1"
+)
+
+// Committed field names. Metadata keys are committed individually as "metadata.<key>".
+const (
+	FieldMessage   = "message"
+	FieldEnvelope  = "envelope"
+	FieldMetadata  = "metadata"
+	metadataPrefix = FieldMetadata + "."
+)
+
+// Commitments bind an entry's hash to salted hashes of its content instead
+// of the content itself, so fields can later be erased without breaking the
+// chain. Only Fields is hashed; salts are dropped with the content they salt.
+type Commitments struct {
+	Fields   map[string]string `json:"fields"`             // Field name -> commitment
+	Salts    map[string]string `json:"salts,omitempty"`    // Field name -> salt, for fields still present
+	Redacted []string          `json:"redacted,omitempty"` // Fields erased by redaction entries
+	Withheld []string          `json:"withheld,omitempty"` // Fields never sent, only committed; hashed
+}
+
+// NewSalt returns a random hex salt for a commitment
+func NewSalt() (string, error) {
+	return newSalt(rand.Reader)
+}
+
+// newSalt reads a hex salt from random
+func newSalt(random io.Reader) (string, error) {
+	salt := make([]byte, 16)
+	if _, err := io.ReadFull(random, salt); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(salt), nil
+}
+
+// CommitValue computes the commitment to one field's value
+func CommitValue(field, salt string, value []byte) string {
+	h := sha256.New()
+	h.Write([]byte(CommitDomain + "\x00" + field + "\x00" + salt + "\x00"))
+	h.Write(value)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// committedValues returns the canonical bytes of every committable field present
+func (e *LogEntry) committedValues() map[string][]byte {
+	values := map[string][]byte{FieldMessage: []byte(e.Message)}
+	if e.Envelope != nil {
+		values[FieldEnvelope], _ = json.Marshal(e.Envelope)
+	}
+	for key, value := range e.Metadata {
+		values[metadataPrefix+key], _ = json.Marshal(value)
+	}
+	return values
+}
+
+// commit records salted commitments to the entry's message, envelope and
+// metadata. A message withheld by a confidential envelope keeps the agent's
+// commitment and has no salt until it is disclosed. Salts are read from random.
+func (e *LogEntry) commit(random io.Reader) error {
+	commitments := &Commitments{Fields: map[string]string{}, Salts: map[string]string{}}
+	if e.Envelope != nil && e.Envelope.Commitment != "" {
+		if e.Message != "" {
+			return errors.New("confidential entries must not carry their message")
+		}
+		commitments.Fields[FieldMessage] = e.Envelope.Commitment
+		commitments.Withheld = []string{FieldMessage}
+	}
+	// Salts are drawn in field order so a fixed random source gives fixed salts
+	values := e.committedValues()
+	fields := make([]string, 0, len(values))
+	for field := range values {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		if _, ok := commitments.Fields[field]; ok {
+			continue
+		}
+		value := values[field]
+		salt, err := newSalt(random)
+		if err != nil {
+			return err
+		}
+		commitments.Salts[field] = salt
+		commitments.Fields[field] = CommitValue(field, salt, value)
+	}
+	e.Commitments = commitments
+	return nil
+}
+
+// isRedacted reports whether a field was erased
+func (c *Commitments) isRedacted(field string) bool {
+	return containsField(c.Redacted, field)
+}
+
+// isWithheld reports whether a field was committed without being sent
+func (c *Commitments) isWithheld(field string) bool {
+	return containsField(c.Withheld, field)
+}
+
+func containsField(fields []string, field string) bool {
+	for _, f := range fields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// checkContent compares an opened entry's content against its commitments.
+// Fields without a salt must be redacted or still withheld, and absent.
+func (e *LogEntry) checkContent() []string {
+	var problems []string
+	values := e.committedValues()
+	for field, commitment := range e.Commitments.Fields {
+		salt, ok := e.Commitments.Salts[field]
+		value, present := values[field]
+		switch {
+		case !ok && !e.Commitments.isRedacted(field) && !e.Commitments.isWithheld(field):
+			problems = append(problems, fmt.Sprintf("%s removed without redaction", field))
+		case !ok:
+			if present && (field != FieldMessage || e.Message != "") {
+				problems = append(problems, fmt.Sprintf("%s is present without a salt", field))
+			}
+		case !present:
+			problems = append(problems, fmt.Sprintf("%s is missing", field))
+		case CommitValue(field, salt, value) != commitment:
+			problems = append(problems, fmt.Sprintf("%s does not match its commitment", field))
+		}
+	}
+	for field := range values {
+		if _, ok := e.Commitments.Fields[field]; !ok && !(field == FieldMessage && e.Message == "") {
+			problems = append(problems, fmt.Sprintf("%s is not committed", field))
+		}
+	}
+	sort.Strings(problems)
+	return problems
+}
+
+// RedactionRef names the entry and fields a redaction erases
+type RedactionRef struct {
+	TargetIndex int      `json:"target_index"`
+	TargetHash  string   `json:"target_hash"`
+	Fields      []string `json:"fields"`
+	Reason      string   `json:"reason"`
+
+	// Digest of a sealed target after resealing; set by the chain, not signed
+	Resealed string `json:"resealed,omitempty"`
+}
+
+// RedactionBytes returns the domain-separated statement a redactor signs
+func RedactionBytes(ref RedactionRef) []byte {
+	ref.Resealed = ""
+	data, _ := json.Marshal(ref)
+	return append([]byte(RedactionDomain+"\x00"), data...)
+}
+
+// SignRedaction signs a redaction statement
+func SignRedaction(signer Signer, ref RedactionRef) (string, error) {
+	sig, err := signer.Sign(RedactionBytes(ref))
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(sig), nil
+}
+
+// expandRedactionFields resolves "metadata" to every committed metadata key
+// and checks each field is committed and not yet redacted
+func expandRedactionFields(fields []string, c *Commitments) ([]string, error) {
+	seen := map[string]bool{}
+	var result []string
+	add := func(field string) {
+		if !seen[field] && !c.isRedacted(field) {
+			seen[field] = true
+			result = append(result, field)
+		}
+	}
+
+	for _, field := range fields {
+		if field == FieldMetadata {
+			for committed := range c.Fields {
+				if strings.HasPrefix(committed, metadataPrefix) {
+					add(committed)
+				}
+			}
+			continue
+		}
+		if _, ok := c.Fields[field]; !ok {
+			return nil, fmt.Errorf("field %q is not committed", field)
+		}
+		add(field)
+	}
+
+	if len(result) == 0 {
+		return nil, errors.New("nothing left to redact")
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// RedactionStatus reports an entry whose content was erased by authorized
+// redactions. Its hash and links still verify; signatures over erased
+// content can no longer be checked.
+type RedactionStatus struct {
+	Index  int      `json:"index"`
+	Fields []string `json:"fields"`
+	By     []int    `json:"by"` // Indexes of the redaction entries
+}
+
+// Redact erases fields from the entry at ref.TargetIndex and records the
+// signed redaction as a new entry. The target keeps its commitments, so its
+// hash and every later link stay valid. Sealed targets are resealed, which
+// needs the master key.
+func (lc *LogChain) Redact(ref RedactionRef, signature, pubKey, algorithm string, metadata map[string]interface{}) (*LogEntry, error) {
+	unlock, err := lc.lockWrite()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	pos, err := lc.livePos(ref.TargetIndex)
+	if err != nil {
+		return nil, err
+	}
+	original := lc.Entries[pos]
+	if original.CurrentHash != ref.TargetHash {
+		return nil, fmt.Errorf("entry %d does not have hash %s", ref.TargetIndex, ref.TargetHash)
+	}
+	if original.Commitments == nil {
+		return nil, fmt.Errorf("entry %d has no commitments and cannot be redacted", ref.TargetIndex)
+	}
+	fields, err := expandRedactionFields(ref.Fields, original.Commitments)
+	if err != nil {
+		return nil, err
+	}
+	ref.Resealed = ""
+	if !lc.isRedactorLocked(pubKey) {
+		return nil, fmt.Errorf("%w: %s", ErrNotRedactor, pubKey)
+	}
+	if err := VerifyWithAlgorithm(algorithm, pubKey, RedactionBytes(ref), signature); err != nil {
+		return nil, fmt.Errorf("invalid redaction signature: %w", err)
+	}
+
+	target, err := lc.openLocked(original)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open sealed entry %d: %w", ref.TargetIndex, err)
+	}
+	commitments := &Commitments{
+		Fields:   target.Commitments.Fields,
+		Salts:    map[string]string{},
+		Redacted: append(append([]string{}, target.Commitments.Redacted...), fields...),
+	}
+	for field, salt := range target.Commitments.Salts {
+		commitments.Salts[field] = salt
+	}
+	remaining := map[string]interface{}{}
+	for key, value := range target.Metadata {
+		remaining[key] = value
+	}
+	for _, field := range fields {
+		delete(commitments.Salts, field)
+		switch {
+		case field == FieldMessage:
+			target.Message = ""
+		case field == FieldEnvelope:
+			target.Envelope = nil
+		case strings.HasPrefix(field, metadataPrefix):
+			delete(remaining, strings.TrimPrefix(field, metadataPrefix))
+		}
+	}
+	sort.Strings(commitments.Redacted)
+	target.Commitments = commitments
+	target.Metadata = remaining
+	if len(remaining) == 0 {
+		target.Metadata = nil
+	}
+
+	if original.Sealed != nil {
+		if err := lc.keyring.seal(&target, lc.random); err != nil {
+			return nil, fmt.Errorf("failed to reseal entry: %w", err)
+		}
+		ref.Resealed = target.Sealed.Digest
+		target.Sealed.Digest = original.Sealed.Digest
+	}
+	if calculateHash(target, lc.Header.epochAt(ref.TargetIndex).HashAlgorithm) != original.CurrentHash {
+		return nil, fmt.Errorf("redaction would change the hash of entry %d", ref.TargetIndex)
+	}
+
+	lc.Entries[pos] = target
+	entry, err := lc.appendLocked(LogEntry{
+		Kind:      KindRedaction,
+		Message:   fmt.Sprintf("Redaction of entry %d", ref.TargetIndex),
+		Signature: signature,
+		PubKey:    pubKey,
+		Algorithm: NormalizeAlgorithm(algorithm),
+		Metadata:  metadata,
+		Redacts:   &ref,
+	})
+	if err != nil {
+		lc.Entries[pos] = original
+		return nil, err
+	}
+	return entry, nil
+}
+
+// AllowRedactors records hex public keys as allowed to sign redactions.
+// Keys are never removed, so the redactions they signed keep verifying.
+func (lc *LogChain) AllowRedactors(pubKeys []string) error {
+	unlock, err := lc.lockWrite()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	keep := lc.Header.Redactors
+	for _, pubKey := range pubKeys {
+		key, err := hex.DecodeString(pubKey)
+		if err != nil {
+			return fmt.Errorf("invalid redactor key %q", pubKey)
+		}
+		if _, err := PublicKeyAlgorithm(key); err != nil {
+			return fmt.Errorf("invalid redactor key %q: %w", pubKey, err)
+		}
+		if pubKey = hex.EncodeToString(key); !slices.Contains(lc.Header.Redactors, pubKey) {
+			lc.Header.Redactors = append(lc.Header.Redactors, pubKey)
+		}
+	}
+	if len(lc.Header.Redactors) == len(keep) {
+		return nil
+	}
+	if err := lc.Save(); err != nil {
+		lc.Header.Redactors = keep
+		return fmt.Errorf("failed to save chain: %w", err)
+	}
+	return nil
+}
+
+// Redactors returns the hex public keys recorded as allowed to redact
+func (lc *LogChain) Redactors() []string {
+	lc.mu.RLock()
+	defer lc.mu.RUnlock()
+	return slices.Clone(lc.Header.Redactors)
+}
+
+// TrustRedactors sets the hex public keys trusted to sign redactions. They
+// take precedence over the keys recorded in the header, which anyone able to
+// rewrite the chain file could extend.
+func (lc *LogChain) TrustRedactors(pubKeys []string) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	lc.redactors = pubKeys
+}
+
+// isRedactorLocked reports whether pubKey may sign redactions; callers hold
+// the lock
+func (lc *LogChain) isRedactorLocked(pubKey string) bool {
+	allowed := lc.Header.Redactors
+	if lc.redactors != nil {
+		allowed = lc.redactors
+	}
+	key, err := hex.DecodeString(pubKey)
+	if err != nil {
+		return false
+	}
+	for _, redactor := range allowed {
+		if other, err := hex.DecodeString(redactor); err == nil && bytes.Equal(key, other) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactionsLocked maps each target index to the redaction entries that
+// erased its content, and to the digest its sealed content was last resealed
+// to by a redaction or disclosure. Redactions by keys that may not redact
+// are left out, so what they erased is reported as tampering.
+func (lc *LogChain) redactionsLocked() (map[int]*RedactionStatus, map[int]string) {
+	redactions := map[int]*RedactionStatus{}
+	resealed := map[int]string{}
+	base := lc.Header.prunedCount()
+	kept, err := lc.keptLocked()
+	if err != nil {
+		kept, base = lc.Entries, lc.Header.liveStart()
+	}
+	for k, entry := range kept {
+		i := base + k
+		var target int
+		var targetHash, digest string
+		switch {
+		case entry.Kind == KindRedaction && entry.Redacts != nil:
+			if !lc.isRedactorLocked(entry.PubKey) {
+				continue
+			}
+			target, targetHash, digest = entry.Redacts.TargetIndex, entry.Redacts.TargetHash, entry.Redacts.Resealed
+		case entry.Kind == KindDisclosure && entry.Discloses != nil:
+			target, targetHash, digest = entry.Discloses.TargetIndex, entry.Discloses.TargetHash, entry.Discloses.Resealed
+		default:
+			continue
+		}
+		if !lc.refersLocked(i, target, targetHash) {
+			continue
+		}
+		if digest != "" {
+			resealed[target] = digest
+		}
+		if entry.Kind != KindRedaction {
+			continue
+		}
+		status := redactions[target]
+		if status == nil {
+			status = &RedactionStatus{Index: target}
+			redactions[target] = status
+		}
+		status.By = append(status.By, i)
+	}
+	return redactions, resealed
+}
+
+// checkRedactionLocked verifies a committed entry's redactions against the
+// redaction entries that target it, and its sealed content against the last
+// reseal; callers hold the lock
+func (lc *LogChain) checkRedactionLocked(entry LogEntry, status *RedactionStatus, resealed string) []string {
+	authorized := map[string]bool{}
+	if status != nil {
+		for _, by := range status.By {
+			for _, field := range lc.entryLocked(by).Redacts.Fields {
+				if field == FieldMetadata {
+					for committed := range entry.Commitments.Fields {
+						if strings.HasPrefix(committed, metadataPrefix) {
+							authorized[committed] = true
+						}
+					}
+				}
+				authorized[field] = true
+			}
+		}
+	}
+
+	var problems []string
+	for _, field := range entry.Commitments.Redacted {
+		if !authorized[field] {
+			problems = append(problems, fmt.Sprintf("%s redacted without authorization", field))
+		}
+	}
+	if entry.Sealed != nil && entry.Sealed.Digest != "" {
+		expected := entry.Sealed.Digest
+		if resealed != "" {
+			expected = resealed
+		}
+		if sealedDigest(entry.Sealed) != expected {
+			problems = append(problems, "sealed content does not match its digest")
+		}
+	}
+	return problems
+}
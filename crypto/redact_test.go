@@ -0,0 +1,226 @@
+package crypto
+
+import (
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// addWithMetadata appends a signed message carrying metadata
+func addWithMetadata(t *testing.T, chain *LogChain, signer Signer, message string, metadata map[string]interface{}) *LogEntry {
+	t.Helper()
+	sig, _ := signer.Sign([]byte(message))
+	entry, err := chain.AddEntry(LogEntry{
+		Message:   message,
+		Signature: hex.EncodeToString(sig),
+		PubKey:    hex.EncodeToString(signer.PublicKey()),
+		Algorithm: signer.Algorithm(),
+		Metadata:  metadata,
+	})
+	if err != nil {
+		t.Fatalf("Failed to add log: %v", err)
+	}
+	return entry
+}
+
+// redact signs and applies a redaction of the entry at index
+func redact(chain *LogChain, signer Signer, index int, fields ...string) (*LogEntry, error) {
+	ref := RedactionRef{
+		TargetIndex: index,
+		TargetHash:  chain.Entries[index].CurrentHash,
+		Fields:      fields,
+		Reason:      "erasure request",
+	}
+	sig, err := SignRedaction(signer, ref)
+	if err != nil {
+		return nil, err
+	}
+	return chain.Redact(ref, sig, hex.EncodeToString(signer.PublicKey()), signer.Algorithm(), nil)
+}
+
+// allowRedactor records signer as allowed to redact entries of chain
+func allowRedactor(t *testing.T, chain *LogChain, signer Signer) {
+	t.Helper()
+	if err := chain.AllowRedactors([]string{hex.EncodeToString(signer.PublicKey())}); err != nil {
+		t.Fatalf("Failed to allow redactor: %v", err)
+	}
+}
+
+func TestRedactionKeepsChainIntact(t *testing.T) {
+	tempFile := os.TempDir() + "/test_chain_redact.json"
+	defer os.Remove(tempFile)
+
+	chain, _ := NewLogChain(tempFile)
+	agent, _ := GenerateSigner(AlgEd25519)
+	admin, _ := GenerateSigner(AlgEd25519)
+
+	addWithMetadata(t, chain, agent, "alice@example.com logged in", map[string]interface{}{"email": "alice@example.com", "host": "web-1"})
+	addSigned(t, chain, agent, "Log 2")
+	head := chain.GetLastHash()
+
+	allowRedactor(t, chain, admin)
+	if _, err := redact(chain, admin, 0, FieldMessage, "metadata.email"); err != nil {
+		t.Fatalf("Failed to redact: %v", err)
+	}
+
+	entry := chain.Entries[0]
+	if entry.Message != "" || entry.Metadata["email"] != nil || entry.Metadata["host"] != "web-1" {
+		t.Errorf("Expected message and email erased, host kept, got %+v", entry)
+	}
+	if chain.Entries[1].PrevHash != entry.CurrentHash || chain.Entries[2].PrevHash != head {
+		t.Error("Redaction must not change entry hashes")
+	}
+
+	reloaded, _ := NewLogChain(tempFile)
+	report := reloaded.VerifyChainReport()
+	if !report.Valid {
+		t.Fatalf("Expected redacted chain to verify, got %v", report.Errors)
+	}
+	if len(report.Redacted) != 1 || report.Redacted[0].Index != 0 || report.Redacted[0].By[0] != 2 {
+		t.Errorf("Expected entry 0 redacted by entry 2, got %+v", report.Redacted)
+	}
+	if strings.Join(report.Redacted[0].Fields, ",") != "message,metadata.email" {
+		t.Errorf("Unexpected redacted fields %v", report.Redacted[0].Fields)
+	}
+
+	// Nothing is left to redact twice
+	if _, err := redact(reloaded, admin, 0, FieldMessage); err == nil {
+		t.Error("Expected error redacting an already redacted field")
+	}
+}
+
+func TestUnauthorizedErasureIsTampering(t *testing.T) {
+	tempFile := os.TempDir() + "/test_chain_erase.json"
+	defer os.Remove(tempFile)
+
+	chain, _ := NewLogChain(tempFile)
+	signer, _ := GenerateSigner(AlgEd25519)
+	addWithMetadata(t, chain, signer, "Log 1", map[string]interface{}{"user": "bob"})
+
+	// Editing committed content is caught even though the hash does not cover it
+	chain.Entries[0].Metadata["user"] = "mallory"
+	if valid, _ := chain.VerifyChain(); valid {
+		t.Error("Expected edited metadata to be detected")
+	}
+
+	// Erasing content without a redaction entry is caught
+	delete(chain.Entries[0].Metadata, "user")
+	delete(chain.Entries[0].Commitments.Salts, "metadata.user")
+	chain.Entries[0].Commitments.Redacted = []string{"metadata.user"}
+	valid, errors := chain.VerifyChain()
+	if valid {
+		t.Fatal("Expected unauthorized erasure to be detected")
+	}
+	if !strings.Contains(strings.Join(errors, "\n"), "redacted without authorization") {
+		t.Errorf("Unexpected errors: %v", errors)
+	}
+}
+
+func TestRedactRejectsBadRequests(t *testing.T) {
+	tempFile := os.TempDir() + "/test_chain_redact_bad.json"
+	defer os.Remove(tempFile)
+
+	chain, _ := NewLogChain(tempFile)
+	signer, _ := GenerateSigner(AlgEd25519)
+	addSigned(t, chain, signer, "Log 1")
+	allowRedactor(t, chain, signer)
+
+	ref := RedactionRef{TargetIndex: 0, TargetHash: chain.Entries[0].CurrentHash, Fields: []string{FieldMessage}}
+	sig, _ := SignRedaction(signer, ref)
+	ref.Fields = []string{FieldEnvelope}
+	if _, err := chain.Redact(ref, sig, hex.EncodeToString(signer.PublicKey()), AlgEd25519, nil); err == nil {
+		t.Error("Expected error for uncommitted field")
+	}
+	ref.Fields = []string{"metadata"}
+	if _, err := chain.Redact(ref, sig, hex.EncodeToString(signer.PublicKey()), AlgEd25519, nil); err == nil {
+		t.Error("Expected error when there is no metadata to redact")
+	}
+	ref.Fields = []string{FieldMessage}
+	ref.Reason = "changed after signing"
+	if _, err := chain.Redact(ref, sig, hex.EncodeToString(signer.PublicKey()), AlgEd25519, nil); err == nil {
+		t.Error("Expected error for signature over a different statement")
+	}
+	if len(chain.Entries) != 1 || chain.Entries[0].Message != "Log 1" {
+		t.Error("Rejected redactions must not change the chain")
+	}
+}
+
+func TestRedactSealedEntry(t *testing.T) {
+	tempFile := os.TempDir() + "/test_chain_redact_sealed.json"
+	defer os.Remove(tempFile)
+
+	master, _ := GenerateMasterKey()
+	kr, _ := NewKeyring(master)
+	chain := newSealedChain(t, tempFile, kr)
+	signer, _ := GenerateSigner(AlgEd25519)
+	addWithMetadata(t, chain, signer, "patient 1234 admitted", map[string]interface{}{"ward": "B"})
+
+	allowRedactor(t, chain, signer)
+	if _, err := redact(chain, signer, 0, FieldMessage); err != nil {
+		t.Fatalf("Failed to redact sealed entry: %v", err)
+	}
+
+	opened, err := chain.Open(chain.Entries[0])
+	if err != nil || opened.Message != "" || opened.Metadata["ward"] != "B" {
+		t.Fatalf("Expected resealed entry without message, got %+v (%v)", opened, err)
+	}
+	if report := chain.VerifyChainReport(); !report.Valid || len(report.Redacted) != 1 {
+		t.Errorf("Expected redacted but intact chain, got %+v", report)
+	}
+
+	// Without the key the reseal is still checked against the redaction record
+	reloaded, _ := NewLogChain(tempFile)
+	if report := reloaded.VerifyChainReport(); !report.Valid {
+		t.Errorf("Expected chain to verify without key, got %v", report.Errors)
+	}
+	reloaded.Entries[0].Sealed.Ciphertext = "AAAA" + reloaded.Entries[0].Sealed.Ciphertext[4:]
+	if valid, _ := reloaded.VerifyChain(); valid {
+		t.Error("Chain should be invalid after tampering with resealed ciphertext")
+	}
+
+	// Sealed entries cannot be redacted without the key
+	addWithMetadata(t, chain, signer, "patient 5678 admitted", nil)
+	noKey, _ := NewLogChain(tempFile)
+	if _, err := redact(noKey, signer, 2, FieldMessage); err == nil {
+		t.Error("Expected error redacting a sealed entry without the master key")
+	}
+}
+
+func TestRedactionRequiresRedactor(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "chain.json")
+	chain, _ := NewLogChain(path)
+	agent, _ := GenerateSigner(AlgEd25519)
+	admin, _ := GenerateSigner(AlgEd25519)
+	other, _ := GenerateSigner(AlgEd25519)
+	addWithMetadata(t, chain, agent, "alice@example.com logged in", nil)
+
+	if _, err := redact(chain, admin, 0, FieldMessage); !errors.Is(err, ErrNotRedactor) {
+		t.Fatalf("Expected ErrNotRedactor without redactors, got %v", err)
+	}
+	allowRedactor(t, chain, admin)
+	if _, err := redact(chain, other, 0, FieldMessage); !errors.Is(err, ErrNotRedactor) {
+		t.Fatalf("Expected ErrNotRedactor for another key, got %v", err)
+	}
+	if _, err := redact(chain, admin, 0, FieldMessage); err != nil {
+		t.Fatalf("Failed to redact: %v", err)
+	}
+	if err := chain.AllowRedactors([]string{"not hex"}); err == nil {
+		t.Error("Expected an invalid key to be refused")
+	}
+
+	// A rewritten file can list its own redactor, but not against a trusted set
+	reloaded, _ := NewLogChain(path)
+	if valid, errors := reloaded.VerifyChain(); !valid {
+		t.Fatalf("Expected the redaction to verify, got %v", errors)
+	}
+	reloaded.TrustRedactors([]string{hex.EncodeToString(other.PublicKey())})
+	report := reloaded.VerifyChainReport()
+	want := []string{"Entry 0: message redacted without authorization", "Entry 1: redaction signed by a key that may not redact"}
+	if report.Valid || !slices.Equal(report.Errors, want) {
+		t.Errorf("Expected %v, got %v", want, report.Errors)
+	}
+}
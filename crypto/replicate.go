@@ -0,0 +1,192 @@
+package crypto
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// ErrHistoryRewritten is returned when a primary's chain no longer extends
+// the entries a follower already holds
+var ErrHistoryRewritten = errors.New("primary rewrote chain history")
+
+// Replicate appends entries copied from a primary chain, starting at absolute
+// index start, which must be the follower's current length. header is the
+// primary's header, read after the entries; its epochs and checkpoints
+// covering the copied entries are adopted, with its authority and redactors.
+// updates holds the primary's stored form of earlier entries that the new
+// entries redact or disclose, keyed by index; each must keep its hash.
+//
+// Entries are stored verbatim, so the follower's chain file is identical to
+// the primary's up to the new length. Every hash, link and signature is
+// checked before anything is saved, and nothing changes on failure. A primary
+// whose entries or epochs disagree with what the follower already holds
+// fails with ErrHistoryRewritten.
+func (lc *LogChain) Replicate(header ChainHeader, start int, entries []LogEntry, updates map[int]LogEntry) error {
+	unlock, err := lc.lockWrite()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	length := lc.lengthLocked()
+	if start != length {
+		return fmt.Errorf("replication starts at %d, but the chain has %d entries", start, length)
+	}
+	if err := header.validate(); err != nil {
+		return fmt.Errorf("invalid primary header: %w", err)
+	}
+	if first := header.prunedCount(); start < first {
+		return fmt.Errorf("primary has pruned entries before %d that this chain does not have", first)
+	}
+	if length > 0 {
+		if err := lc.checkEpochsLocked(header.Epochs); err != nil {
+			return err
+		}
+	}
+	if len(entries) > 0 && entries[0].PrevHash != lc.headLocked() {
+		return fmt.Errorf("%w: entry %d does not link to entry %d", ErrHistoryRewritten, start, start-1)
+	}
+
+	original := *lc.Header
+	kept := len(lc.Entries)
+	replaced := map[int]LogEntry{}
+	rollback := func() {
+		for pos, entry := range replaced {
+			lc.Entries[pos] = entry
+		}
+		lc.Entries = lc.Entries[:kept]
+		*lc.Header = original
+	}
+
+	newLen := start + len(entries)
+	adopted := lc.Header
+	if length == 0 {
+		adopted.FormatVersion = header.FormatVersion
+		adopted.CreatedAt = header.CreatedAt
+		adopted.CreatedBy = header.CreatedBy
+	}
+	adopted.Epochs = nil
+	for _, epoch := range header.Epochs {
+		if epoch.StartIndex > newLen {
+			break
+		}
+		if epoch.SealIndex >= newLen {
+			epoch.SealIndex, epoch.SealHash = 0, ""
+		}
+		adopted.Epochs = append(adopted.Epochs, epoch)
+	}
+	adopted.Checkpoints = nil
+	for _, cp := range header.Checkpoints {
+		if cp.Length <= newLen {
+			adopted.Checkpoints = append(adopted.Checkpoints, cp)
+		}
+	}
+	// The primary's seals are checked against the authority it recorded,
+	// which must not change once this chain holds it
+	if a := header.Authority; a != nil {
+		if original.Authority != nil && *original.Authority != *a {
+			rollback()
+			return fmt.Errorf("%w: the primary's chain authority changed", ErrHistoryRewritten)
+		}
+		authority := *a
+		adopted.Authority = &authority
+	}
+	// Redactors are only ever added, so one the primary dropped would
+	// unauthorize redactions this chain already holds
+	for _, redactor := range original.Redactors {
+		if !slices.Contains(header.Redactors, redactor) {
+			rollback()
+			return fmt.Errorf("%w: the primary dropped redactor %s", ErrHistoryRewritten, redactor)
+		}
+	}
+	adopted.Redactors = slices.Clone(header.Redactors)
+	adopted.SignatureAlgorithms = slices.Clone(original.SignatureAlgorithms)
+	for _, entry := range entries {
+		lc.Entries = append(lc.Entries, entry)
+		adopted.noteAlgorithm(entry.Algorithm)
+	}
+
+	// Redactions and disclosures rewrite their targets in place, keeping
+	// their hashes; the primary's copy replaces ours. Without it, erased
+	// content would live on in the follower.
+	for _, index := range ReplicationTargets(start, entries) {
+		if _, ok := updates[index]; !ok && index >= lc.Header.liveStart() {
+			rollback()
+			return fmt.Errorf("missing the updated copy of entry %d", index)
+		}
+	}
+	for index, update := range updates {
+		pos, err := lc.livePos(index)
+		if err != nil || index >= start {
+			rollback()
+			return fmt.Errorf("entry %d cannot be updated: not an earlier live entry", index)
+		}
+		current := lc.Entries[pos]
+		if update.CurrentHash != current.CurrentHash {
+			rollback()
+			return fmt.Errorf("%w: entry %d has a different hash", ErrHistoryRewritten, index)
+		}
+		replaced[pos] = current
+		lc.Entries[pos] = update
+	}
+
+	var problems []string
+	problems = append(problems, lc.verifyRangeLocked(start, newLen).Errors...)
+	for index := range updates {
+		problems = append(problems, lc.verifyRangeLocked(index, index+1).Errors...)
+	}
+	// A follower without trusted TSA certificates still checks the tokens
+	checkpointProblems, _ := lc.verifyCheckpointsLocked()
+	problems = append(problems, checkpointProblems...)
+	if len(problems) > 0 {
+		rollback()
+		return fmt.Errorf("replicated entries do not verify: %s", problems[0])
+	}
+
+	if err := lc.Save(); err != nil {
+		rollback()
+		return err
+	}
+	return nil
+}
+
+// checkEpochsLocked checks that a primary's epochs agree with every local
+// epoch that already has entries; callers hold the lock
+func (lc *LogChain) checkEpochsLocked(epochs []ChainEpoch) error {
+	length := lc.lengthLocked()
+	for _, local := range lc.Header.Epochs {
+		if local.StartIndex >= length {
+			continue
+		}
+		if local.Number >= len(epochs) {
+			return fmt.Errorf("%w: epoch %d is missing", ErrHistoryRewritten, local.Number)
+		}
+		remote := epochs[local.Number]
+		if remote.StartIndex != local.StartIndex || remote.HashAlgorithm != local.HashAlgorithm ||
+			remote.PrevHead != local.PrevHead || !remote.StartedAt.Equal(local.StartedAt) ||
+			(local.SealHash != "" && (remote.SealHash != local.SealHash || remote.SealIndex != local.SealIndex)) {
+			return fmt.Errorf("%w: epoch %d differs", ErrHistoryRewritten, local.Number)
+		}
+	}
+	return nil
+}
+
+// ReplicationTargets returns the earlier entries, before index start, that
+// entries redact or disclose. A follower copying entries needs the primary's
+// updated copy of each.
+func ReplicationTargets(start int, entries []LogEntry) []int {
+	var targets []int
+	for _, entry := range entries {
+		target := -1
+		if entry.Redacts != nil {
+			target = entry.Redacts.TargetIndex
+		} else if entry.Discloses != nil {
+			target = entry.Discloses.TargetIndex
+		}
+		if target >= 0 && target < start && !slices.Contains(targets, target) {
+			targets = append(targets, target)
+		}
+	}
+	return targets
+}
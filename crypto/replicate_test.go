@@ -0,0 +1,155 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// replicateFrom copies the entries a follower is missing from primary
+func replicateFrom(follower, primary *LogChain, updates map[int]LogEntry) error {
+	start := follower.Length()
+	entries, _, _ := primary.GetEntriesPage(start, primary.Length())
+	return follower.Replicate(primary.GetHeader(), start, entries, updates)
+}
+
+func TestReplicateCopiesChain(t *testing.T) {
+	dir := t.TempDir()
+	primary := newTestChain(t, filepath.Join(dir, "primary.chain"))
+	follower := newTestChain(t, filepath.Join(dir, "follower.chain"))
+	agent := testSigner(1)
+
+	addSigned(t, primary, agent, "Log 1")
+	addSigned(t, primary, agent, "Log 2")
+	if err := replicateFrom(follower, primary, nil); err != nil {
+		t.Fatalf("Failed to replicate: %v", err)
+	}
+	addSigned(t, primary, agent, "Log 3")
+	if err := replicateFrom(follower, primary, nil); err != nil {
+		t.Fatalf("Failed to replicate: %v", err)
+	}
+
+	want, _ := os.ReadFile(primary.FilePath)
+	got, _ := os.ReadFile(follower.FilePath)
+	if !bytes.Equal(want, got) {
+		t.Error("Expected the follower's chain file to match the primary's")
+	}
+	if valid, errs := follower.VerifyChain(); !valid {
+		t.Errorf("Expected replicated chain to verify, got %v", errs)
+	}
+}
+
+func TestReplicateRejectsTamperedEntries(t *testing.T) {
+	dir := t.TempDir()
+	primary := newTestChain(t, filepath.Join(dir, "primary.chain"))
+	follower := newTestChain(t, filepath.Join(dir, "follower.chain"))
+	agent := testSigner(1)
+
+	addSigned(t, primary, agent, "Log 1")
+	addSigned(t, primary, agent, "Log 2")
+	entries, _, _ := primary.GetEntriesPage(0, 2)
+	entries[1].Message = "Log 2 (edited)"
+	if err := follower.Replicate(primary.GetHeader(), 0, entries, nil); err == nil {
+		t.Fatal("Expected a tampered entry to be rejected")
+	}
+	if follower.Length() != 0 {
+		t.Errorf("Expected nothing replicated, got %d entries", follower.Length())
+	}
+
+	// Nothing was saved, so a clean copy still replicates
+	if err := replicateFrom(follower, primary, nil); err != nil {
+		t.Fatalf("Failed to replicate: %v", err)
+	}
+}
+
+func TestReplicateDetectsRewrittenHistory(t *testing.T) {
+	dir := t.TempDir()
+	primary := newTestChain(t, filepath.Join(dir, "primary.chain"))
+	follower := newTestChain(t, filepath.Join(dir, "follower.chain"))
+	agent := testSigner(1)
+
+	addSigned(t, primary, agent, "Log 1")
+	addSigned(t, primary, agent, "Log 2")
+	if err := replicateFrom(follower, primary, nil); err != nil {
+		t.Fatalf("Failed to replicate: %v", err)
+	}
+
+	// A primary rebuilt with different history, validly signed
+	rewritten := newTestChain(t, filepath.Join(dir, "rewritten.chain"))
+	addSigned(t, rewritten, agent, "Log 1")
+	addSigned(t, rewritten, agent, "Log 2 (rewritten)")
+	addSigned(t, rewritten, agent, "Log 3")
+	err := replicateFrom(follower, rewritten, nil)
+	if !errors.Is(err, ErrHistoryRewritten) {
+		t.Fatalf("Expected ErrHistoryRewritten, got %v", err)
+	}
+	if follower.Length() != 2 || follower.GetLastHash() != primary.GetLastHash() {
+		t.Error("Expected the follower's chain to be unchanged")
+	}
+}
+
+func TestReplicateAppliesRedactions(t *testing.T) {
+	dir := t.TempDir()
+	primary := newTestChain(t, filepath.Join(dir, "primary.chain"))
+	follower := newTestChain(t, filepath.Join(dir, "follower.chain"))
+	agent, admin := testSigner(1), testSigner(2)
+
+	addWithMetadata(t, primary, agent, "alice@example.com logged in", map[string]interface{}{"email": "alice@example.com"})
+	if err := replicateFrom(follower, primary, nil); err != nil {
+		t.Fatalf("Failed to replicate: %v", err)
+	}
+	allowRedactor(t, primary, admin)
+	if _, err := redact(primary, admin, 0, FieldMessage); err != nil {
+		t.Fatalf("Failed to redact: %v", err)
+	}
+
+	// The redaction entry needs its erased target to verify
+	if err := replicateFrom(follower, primary, nil); err == nil {
+		t.Error("Expected a redaction without its updated target to be rejected")
+	}
+	target, _ := primary.GetEntry(0)
+	if err := replicateFrom(follower, primary, map[int]LogEntry{0: *target}); err != nil {
+		t.Fatalf("Failed to replicate redaction: %v", err)
+	}
+	if entry, _ := follower.GetEntry(0); entry.Message != "" {
+		t.Errorf("Expected the follower's copy to be redacted, got %q", entry.Message)
+	}
+	if valid, errs := follower.VerifyChain(); !valid {
+		t.Errorf("Expected replicated chain to verify, got %v", errs)
+	}
+}
+
+func TestReplicateKeepsChainAuthority(t *testing.T) {
+	dir := t.TempDir()
+	primary := newTestChain(t, filepath.Join(dir, "primary.chain"))
+	follower := newTestChain(t, filepath.Join(dir, "follower.chain"))
+	agent, server, attacker := testSigner(1), testSigner(2), testSigner(3)
+
+	addSigned(t, primary, agent, "Log 1")
+	if _, err := primary.SealEpoch(server, ""); err != nil {
+		t.Fatalf("Failed to seal: %v", err)
+	}
+	if err := replicateFrom(follower, primary, nil); err != nil {
+		t.Fatalf("Failed to replicate: %v", err)
+	}
+	want, _ := os.ReadFile(primary.FilePath)
+	got, _ := os.ReadFile(follower.FilePath)
+	if !bytes.Equal(want, got) {
+		t.Error("Expected the follower to adopt the primary's authority")
+	}
+
+	// A primary that changes its recorded authority rewrites history
+	addSigned(t, primary, agent, "Log 2")
+	header := primary.GetHeader()
+	header.Authority = &ChainAuthority{PubKey: hex.EncodeToString(attacker.PublicKey()), Algorithm: attacker.Algorithm()}
+	entries, _, _ := primary.GetEntriesPage(follower.Length(), primary.Length())
+	if err := follower.Replicate(header, follower.Length(), entries, nil); !errors.Is(err, ErrHistoryRewritten) {
+		t.Errorf("Expected ErrHistoryRewritten, got %v", err)
+	}
+	if follower.Length() != 3 {
+		t.Errorf("Expected the follower's chain to be unchanged, got %d entries", follower.Length())
+	}
+}
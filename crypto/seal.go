@@ -0,0 +1,358 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// sealAAD binds sealed payloads and wrapped keys to this format
+const sealAAD = "zcrypt-sealed-v1"
+
+// MasterKeySize is the length of an AES-256 master key
+const MasterKeySize = 32
+
+// ErrNoKey is returned when a sealed entry's master key is not in the keyring
+var ErrNoKey = errors.New("master key not available")
+
+// SealedContent is an entry's confidential fields encrypted with a per-entry
+// data key. Only Nonce and Ciphertext are hashed, so rewrapping the data key
+// under a new master key leaves the chain hashes untouched. Entries with
+// commitments hash the original Digest instead, so redaction can reseal them.
+type SealedContent struct {
+	KeyID      string `json:"key_id"`           // Master key that wraps the data key
+	WrappedKey string `json:"wrapped_key"`      // base64 nonce||AES-GCM(data key)
+	Nonce      string `json:"nonce"`            // base64 content nonce
+	Ciphertext string `json:"ciphertext"`       // base64 AES-GCM(sealedFields)
+	Digest     string `json:"digest,omitempty"` // sealedDigest at first seal, for committed entries
+}
+
+// sealedFields are the entry fields hidden by encryption at rest
+type sealedFields struct {
+	Message  string                 `json:"message"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+	Envelope *Envelope              `json:"envelope,omitempty"`
+	Salts    map[string]string      `json:"salts,omitempty"` // Commitment salts
+}
+
+// sealedDigest identifies the current nonce and ciphertext of sealed content
+func sealedDigest(sealed *SealedContent) string {
+	sum := sha256.Sum256([]byte(sealed.Nonce + "|" + sealed.Ciphertext))
+	return hex.EncodeToString(sum[:])
+}
+
+// Keyring holds the current master key plus older keys kept for decryption
+type Keyring struct {
+	current string
+	keys    map[string][]byte
+}
+
+// NewKeyring creates a keyring whose current key is master; older keys
+// still unwrap existing data keys until the chain is rekeyed.
+func NewKeyring(master []byte, older ...[]byte) (*Keyring, error) {
+	kr := &Keyring{keys: make(map[string][]byte)}
+	for _, key := range append(older, master) {
+		if len(key) != MasterKeySize {
+			return nil, fmt.Errorf("master key must be %d bytes, got %d", MasterKeySize, len(key))
+		}
+		kr.keys[MasterKeyID(key)] = key
+	}
+	kr.current = MasterKeyID(master)
+	return kr, nil
+}
+
+// CurrentKeyID identifies the key new data keys are wrapped with
+func (kr *Keyring) CurrentKeyID() string {
+	return kr.current
+}
+
+// MasterKeyID is a short public fingerprint of a master key
+func MasterKeyID(key []byte) string {
+	sum := sha256.Sum256(append([]byte(sealAAD+"-key-id\x00"), key...))
+	return hex.EncodeToString(sum[:8])
+}
+
+// ParseMasterKey accepts a master key as raw bytes, hex or base64 text
+func ParseMasterKey(data []byte) ([]byte, error) {
+	if len(data) == MasterKeySize {
+		return data, nil
+	}
+	text := strings.TrimSpace(string(data))
+	if key, err := hex.DecodeString(text); err == nil && len(key) == MasterKeySize {
+		return key, nil
+	}
+	if key, err := base64.StdEncoding.DecodeString(text); err == nil && len(key) == MasterKeySize {
+		return key, nil
+	}
+	return nil, fmt.Errorf("master key must be %d bytes (raw, hex or base64)", MasterKeySize)
+}
+
+// LoadMasterKeyFile reads a master key file in any ParseMasterKey format
+func LoadMasterKeyFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read master key: %w", err)
+	}
+	return ParseMasterKey(data)
+}
+
+// GenerateMasterKey returns a random master key
+func GenerateMasterKey() ([]byte, error) {
+	key := make([]byte, MasterKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// IsSealed reports whether the entry's content is encrypted at rest
+func (e *LogEntry) IsSealed() bool {
+	return e.Sealed != nil
+}
+
+// seal moves the entry's message, metadata, envelope and commitment salts
+// into a SealedContent, reading the data key and nonces from random
+func (kr *Keyring) seal(entry *LogEntry, random io.Reader) error {
+	fields := sealedFields{Message: entry.Message, Metadata: entry.Metadata, Envelope: entry.Envelope}
+	if entry.Commitments != nil {
+		fields.Salts = entry.Commitments.Salts
+	}
+	plain, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+
+	dataKey := make([]byte, 32)
+	if _, err := io.ReadFull(random, dataKey); err != nil {
+		return err
+	}
+	nonce, ciphertext, err := aesGCMSeal(dataKey, plain, random)
+	if err != nil {
+		return err
+	}
+	wrapped, err := kr.wrap(kr.current, dataKey, random)
+	if err != nil {
+		return err
+	}
+
+	entry.Sealed = &SealedContent{
+		KeyID:      kr.current,
+		WrappedKey: wrapped,
+		Nonce:      base64.StdEncoding.EncodeToString(nonce),
+		Ciphertext: base64.StdEncoding.EncodeToString(ciphertext),
+	}
+	entry.Message = ""
+	entry.Metadata = nil
+	entry.Envelope = nil
+	if entry.Commitments != nil {
+		commitments := *entry.Commitments
+		commitments.Salts = nil
+		entry.Commitments = &commitments
+		entry.Sealed.Digest = sealedDigest(entry.Sealed)
+	}
+	return nil
+}
+
+// Open returns a copy of a sealed entry with its content decrypted
+func (kr *Keyring) Open(entry LogEntry) (LogEntry, error) {
+	if entry.Sealed == nil {
+		return entry, nil
+	}
+
+	dataKey, err := kr.unwrap(entry.Sealed.KeyID, entry.Sealed.WrappedKey)
+	if err != nil {
+		return entry, err
+	}
+	nonce, err1 := base64.StdEncoding.DecodeString(entry.Sealed.Nonce)
+	ciphertext, err2 := base64.StdEncoding.DecodeString(entry.Sealed.Ciphertext)
+	if err := errors.Join(err1, err2); err != nil {
+		return entry, fmt.Errorf("malformed sealed content: %w", err)
+	}
+
+	plain, err := aesGCMOpen(dataKey, nonce, ciphertext)
+	if err != nil {
+		return entry, fmt.Errorf("failed to decrypt entry: %w", err)
+	}
+	var fields sealedFields
+	if err := json.Unmarshal(plain, &fields); err != nil {
+		return entry, fmt.Errorf("malformed sealed content: %w", err)
+	}
+
+	entry.Message = fields.Message
+	entry.Metadata = fields.Metadata
+	entry.Envelope = fields.Envelope
+	if entry.Commitments != nil {
+		commitments := *entry.Commitments
+		commitments.Salts = fields.Salts
+		entry.Commitments = &commitments
+	}
+	entry.Sealed = nil
+	return entry, nil
+}
+
+// rewrap moves a sealed entry's data key under the current master key
+func (kr *Keyring) rewrap(sealed *SealedContent, random io.Reader) (bool, error) {
+	if sealed.KeyID == kr.current {
+		return false, nil
+	}
+	dataKey, err := kr.unwrap(sealed.KeyID, sealed.WrappedKey)
+	if err != nil {
+		return false, err
+	}
+	wrapped, err := kr.wrap(kr.current, dataKey, random)
+	if err != nil {
+		return false, err
+	}
+	sealed.KeyID = kr.current
+	sealed.WrappedKey = wrapped
+	return true, nil
+}
+
+func (kr *Keyring) wrap(keyID string, dataKey []byte, random io.Reader) (string, error) {
+	master, ok := kr.keys[keyID]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrNoKey, keyID)
+	}
+	nonce, ciphertext, err := aesGCMSeal(master, dataKey, random)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(append(nonce, ciphertext...)), nil
+}
+
+func (kr *Keyring) unwrap(keyID, wrapped string) ([]byte, error) {
+	master, ok := kr.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNoKey, keyID)
+	}
+	data, err := base64.StdEncoding.DecodeString(wrapped)
+	if err != nil || len(data) < 12 {
+		return nil, errors.New("malformed wrapped key")
+	}
+	dataKey, err := aesGCMOpen(master, data[:12], data[12:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+	return dataKey, nil
+}
+
+func aesGCMSeal(key, plaintext []byte, random io.Reader) (nonce, ciphertext []byte, err error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, nil, err
+	}
+	nonce = make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(random, nonce); err != nil {
+		return nil, nil, err
+	}
+	return nonce, gcm.Seal(nil, nonce, plaintext, []byte(sealAAD)), nil
+}
+
+func aesGCMOpen(key, nonce, ciphertext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != gcm.NonceSize() {
+		return nil, errors.New("invalid nonce length")
+	}
+	return gcm.Open(nil, nonce, ciphertext, []byte(sealAAD))
+}
+
+// SetKeyring enables encryption at rest: entries appended from now on are
+// sealed, and sealed entries can be opened for reads and verification
+func (lc *LogChain) SetKeyring(kr *Keyring) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	lc.keyring = kr
+}
+
+// Open returns a decrypted copy of an entry, or the entry itself if it is
+// not sealed. Without a keyring sealed entries cannot be opened.
+func (lc *LogChain) Open(entry LogEntry) (LogEntry, error) {
+	lc.mu.RLock()
+	defer lc.mu.RUnlock()
+	return lc.openLocked(entry)
+}
+
+func (lc *LogChain) openLocked(entry LogEntry) (LogEntry, error) {
+	if entry.Sealed == nil {
+		return entry, nil
+	}
+	if lc.keyring == nil {
+		return entry, ErrNoKey
+	}
+	return lc.keyring.Open(entry)
+}
+
+// plainLocked opens an entry when possible and otherwise returns it sealed
+func (lc *LogChain) plainLocked(entry LogEntry) LogEntry {
+	opened, err := lc.openLocked(entry)
+	if err != nil {
+		return entry
+	}
+	return opened
+}
+
+// Rekey rewraps every data key under the keyring's current master key,
+// including archived entries. The ciphertext is untouched, so entry hashes
+// and the chain stay valid.
+func (lc *LogChain) Rekey() (int, error) {
+	unlock, err := lc.lockWrite()
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	if lc.keyring == nil {
+		return 0, ErrNoKey
+	}
+
+	rewrapped := 0
+	for i := range lc.Entries {
+		sealed := lc.Entries[i].Sealed
+		if sealed == nil {
+			continue
+		}
+		changed, err := lc.keyring.rewrap(sealed, lc.random)
+		if err != nil {
+			return rewrapped, fmt.Errorf("entry %d: %w", i, err)
+		}
+		if changed {
+			rewrapped++
+		}
+	}
+
+	// Archived entries are rewrapped by rewriting their segments
+	archived, replaced, err := lc.rekeySegmentsLocked()
+	rewrapped += archived
+	if rewrapped > 0 {
+		if err := lc.Save(); err != nil {
+			return rewrapped, fmt.Errorf("failed to save chain: %w", err)
+		}
+	}
+	for _, path := range replaced {
+		os.Remove(path)
+	}
+	if err != nil {
+		return rewrapped, err
+	}
+	return rewrapped, nil
+}
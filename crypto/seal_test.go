@@ -0,0 +1,102 @@
+package crypto
+
+import (
+	"os"
+	"testing"
+)
+
+func newSealedChain(t *testing.T, path string, kr *Keyring) *LogChain {
+	t.Helper()
+	chain, err := NewLogChain(path)
+	if err != nil {
+		t.Fatalf("Failed to create chain: %v", err)
+	}
+	chain.SetKeyring(kr)
+	return chain
+}
+
+func TestSealedEntriesVerifyWithoutKey(t *testing.T) {
+	tempFile := os.TempDir() + "/test_chain_sealed.json"
+	defer os.Remove(tempFile)
+
+	master, _ := GenerateMasterKey()
+	kr, _ := NewKeyring(master)
+	chain := newSealedChain(t, tempFile, kr)
+	signer, _ := GenerateSigner(AlgEd25519)
+
+	entry := addSigned(t, chain, signer, "patient record 1234 viewed")
+	if !entry.IsSealed() || entry.Message != "" {
+		t.Fatalf("Expected sealed entry without plaintext, got %+v", entry)
+	}
+
+	// Integrity verifies from the file alone, without the master key
+	reloaded, _ := NewLogChain(tempFile)
+	report := reloaded.VerifyChainReport()
+	if !report.Valid || report.SignaturesSkipped != 1 {
+		t.Errorf("Expected valid chain with one skipped signature, got %+v", report)
+	}
+
+	// With the key, content opens and signatures are checked
+	reloaded.SetKeyring(kr)
+	opened, err := reloaded.Open(reloaded.Entries[0])
+	if err != nil || opened.Message != "patient record 1234 viewed" {
+		t.Fatalf("Failed to open entry: %v", err)
+	}
+	if report := reloaded.VerifyChainReport(); !report.Valid || report.SignaturesSkipped != 0 {
+		t.Errorf("Expected fully verified chain, got %+v", report)
+	}
+
+	// Tampering with ciphertext breaks the hash
+	reloaded.Entries[0].Sealed.Ciphertext = "AAAA" + reloaded.Entries[0].Sealed.Ciphertext[4:]
+	if valid, _ := reloaded.VerifyChain(); valid {
+		t.Error("Chain should be invalid after tampering with ciphertext")
+	}
+}
+
+func TestRekeyKeepsHashes(t *testing.T) {
+	tempFile := os.TempDir() + "/test_chain_rekey.json"
+	defer os.Remove(tempFile)
+
+	oldKey, _ := GenerateMasterKey()
+	newKey, _ := GenerateMasterKey()
+	oldRing, _ := NewKeyring(oldKey)
+	chain := newSealedChain(t, tempFile, oldRing)
+	signer, _ := GenerateSigner(AlgEd25519)
+	addSigned(t, chain, signer, "Log 1")
+	addSigned(t, chain, signer, "Log 2")
+	head := chain.GetLastHash()
+
+	rotating, _ := NewKeyring(newKey, oldKey)
+	chain.SetKeyring(rotating)
+	count, err := chain.Rekey()
+	if err != nil || count != 2 {
+		t.Fatalf("Expected 2 rewrapped entries, got %d (%v)", count, err)
+	}
+
+	// After rotation only the new key is needed
+	newRing, _ := NewKeyring(newKey)
+	reloaded := newSealedChain(t, tempFile, newRing)
+	if reloaded.GetLastHash() != head {
+		t.Error("Rekeying must not change entry hashes")
+	}
+	if report := reloaded.VerifyChainReport(); !report.Valid || report.SignaturesSkipped != 0 {
+		t.Errorf("Expected rekeyed chain to verify with new key, got %+v", report)
+	}
+	if _, err := oldRing.Open(reloaded.Entries[0]); err == nil {
+		t.Error("Old master key should no longer open rekeyed entries")
+	}
+}
+
+func TestParseMasterKey(t *testing.T) {
+	key, _ := GenerateMasterKey()
+	if parsed, err := ParseMasterKey(key); err != nil || string(parsed) != string(key) {
+		t.Errorf("Raw master key not parsed: %v", err)
+	}
+	if _, err := ParseMasterKey([]byte("00ff")); err == nil {
+		t.Error("Short master key accepted")
+	}
+	hexKey := []byte("000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f\n")
+	if parsed, err := ParseMasterKey(hexKey); err != nil || parsed[31] != 0x1f {
+		t.Errorf("Hex master key not parsed: %v", err)
+	}
+}
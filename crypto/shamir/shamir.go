@@ -0,0 +1,194 @@
+// Package shamir implements Shamir secret sharing over GF(256) with a
+// portable, checksummed text encoding for backing up zcrypt keys.
+package shamir
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SharePrefix identifies the text encoding version of a share
+const SharePrefix = "zcrypt-share-v1"
+
+const (
+	setIDLen    = 8 // bytes of random set identifier
+	digestLen   = 8 // bytes of SHA-256 of the secret used to check recovery
+	checksumLen = 4 // bytes of SHA-256 over the share text used to catch typos
+)
+
+var (
+	ErrInvalidParams   = errors.New("invalid share parameters")
+	ErrNotEnoughShares = errors.New("not enough shares to recover the secret")
+	ErrMixedShares     = errors.New("shares belong to different splits")
+	ErrDuplicateShare  = errors.New("duplicate share")
+	ErrChecksum        = errors.New("share checksum mismatch")
+	ErrRecovery        = errors.New("recovered secret does not match its digest")
+)
+
+// Share is one point of the sharing polynomial for every secret byte
+type Share struct {
+	SetID     string // Random identifier shared by all shares of one split
+	Threshold int    // Shares needed to recover
+	Total     int    // Shares produced
+	X         byte   // Evaluation point, 1..Total
+	Data      []byte // Polynomial values, one per secret byte
+	Digest    []byte // Truncated SHA-256 of the secret
+}
+
+// Split divides secret into total shares, any threshold of which recover it
+func Split(secret []byte, total, threshold int) ([]Share, error) {
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("%w: empty secret", ErrInvalidParams)
+	}
+	if threshold < 2 || threshold > total || total > 255 {
+		return nil, fmt.Errorf("%w: need 2 <= threshold <= shares <= 255, got %d of %d", ErrInvalidParams, threshold, total)
+	}
+
+	setID := make([]byte, setIDLen)
+	if _, err := rand.Read(setID); err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(secret)
+
+	shares := make([]Share, total)
+	for i := range shares {
+		shares[i] = Share{
+			SetID:     hex.EncodeToString(setID),
+			Threshold: threshold,
+			Total:     total,
+			X:         byte(i + 1),
+			Data:      make([]byte, len(secret)),
+			Digest:    sum[:digestLen],
+		}
+	}
+
+	// One random polynomial of degree threshold-1 per secret byte
+	coeffs := make([]byte, threshold)
+	for b, s := range secret {
+		coeffs[0] = s
+		if _, err := rand.Read(coeffs[1:]); err != nil {
+			return nil, err
+		}
+		for i := range shares {
+			shares[i].Data[b] = evaluate(coeffs, shares[i].X)
+		}
+	}
+	for i := range coeffs {
+		coeffs[i] = 0
+	}
+
+	return shares, nil
+}
+
+// Combine recovers the secret from at least Threshold shares of one split
+func Combine(shares []Share) ([]byte, error) {
+	if len(shares) == 0 {
+		return nil, ErrNotEnoughShares
+	}
+
+	first := shares[0]
+	seen := make(map[byte]bool)
+	for _, share := range shares {
+		if share.SetID != first.SetID || share.Threshold != first.Threshold ||
+			len(share.Data) != len(first.Data) || subtle.ConstantTimeCompare(share.Digest, first.Digest) != 1 {
+			return nil, ErrMixedShares
+		}
+		if share.X == 0 {
+			return nil, fmt.Errorf("%w: share x must be non-zero", ErrInvalidParams)
+		}
+		if seen[share.X] {
+			return nil, fmt.Errorf("%w: #%d", ErrDuplicateShare, share.X)
+		}
+		seen[share.X] = true
+	}
+	if len(shares) < first.Threshold {
+		return nil, fmt.Errorf("%w: have %d, need %d", ErrNotEnoughShares, len(shares), first.Threshold)
+	}
+
+	// Any Threshold shares define the polynomial; interpolate at x = 0
+	points := shares[:first.Threshold]
+	secret := make([]byte, len(first.Data))
+	for b := range secret {
+		var value byte
+		for i, pi := range points {
+			basis := byte(1)
+			for j, pj := range points {
+				if i != j {
+					basis = mul(basis, div(pj.X, pj.X^pi.X))
+				}
+			}
+			value ^= mul(pi.Data[b], basis)
+		}
+		secret[b] = value
+	}
+
+	sum := sha256.Sum256(secret)
+	if subtle.ConstantTimeCompare(sum[:digestLen], first.Digest) != 1 {
+		return nil, ErrRecovery
+	}
+	return secret, nil
+}
+
+// String encodes the share as a single line of text:
+//
+//	zcrypt-share-v1:<set>:<threshold>:<total>:<x>:<digest>:<data>:<checksum>
+func (s Share) String() string {
+	body := fmt.Sprintf("%s:%s:%d:%d:%d:%s:%s", SharePrefix, s.SetID, s.Threshold, s.Total, s.X,
+		hex.EncodeToString(s.Digest), hex.EncodeToString(s.Data))
+	return body + ":" + checksum(body)
+}
+
+// Parse decodes and checksum-verifies a share produced by String
+func Parse(text string) (Share, error) {
+	text = strings.TrimSpace(text)
+	parts := strings.Split(text, ":")
+	if len(parts) != 8 || parts[0] != SharePrefix {
+		return Share{}, fmt.Errorf("%w: not a %s share", ErrInvalidParams, SharePrefix)
+	}
+
+	body := strings.Join(parts[:7], ":")
+	if subtle.ConstantTimeCompare([]byte(checksum(body)), []byte(strings.ToLower(parts[7]))) != 1 {
+		return Share{}, ErrChecksum
+	}
+
+	threshold, err1 := strconv.Atoi(parts[2])
+	total, err2 := strconv.Atoi(parts[3])
+	x, err3 := strconv.Atoi(parts[4])
+	digest, err4 := hex.DecodeString(parts[5])
+	data, err5 := hex.DecodeString(parts[6])
+	if err := errors.Join(err1, err2, err3, err4, err5); err != nil {
+		return Share{}, fmt.Errorf("%w: %v", ErrInvalidParams, err)
+	}
+	if x < 1 || x > total || threshold < 2 || threshold > total || total > 255 || len(digest) != digestLen || len(data) == 0 {
+		return Share{}, fmt.Errorf("%w: out of range fields", ErrInvalidParams)
+	}
+
+	return Share{
+		SetID:     parts[1],
+		Threshold: threshold,
+		Total:     total,
+		X:         byte(x),
+		Data:      data,
+		Digest:    digest,
+	}, nil
+}
+
+func checksum(body string) string {
+	sum := sha256.Sum256([]byte(body))
+	return hex.EncodeToString(sum[:checksumLen])
+}
+
+// evaluate computes the polynomial at x using Horner's method
+func evaluate(coeffs []byte, x byte) byte {
+	var result byte
+	for i := len(coeffs) - 1; i >= 0; i-- {
+		result = mul(result, x) ^ coeffs[i]
+	}
+	return result
+}
@@ -0,0 +1,138 @@
+package shamir
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestGF256Inverse(t *testing.T) {
+	for a := 1; a < 256; a++ {
+		for b := 1; b < 256; b++ {
+			if got := div(mul(byte(a), byte(b)), byte(b)); got != byte(a) {
+				t.Fatalf("(%d*%d)/%d = %d", a, b, b, got)
+			}
+		}
+	}
+}
+
+func TestSplitCombineAllSubsets(t *testing.T) {
+	_, secret, _ := ed25519.GenerateKey(nil)
+
+	shares, err := Split(secret, 5, 3)
+	if err != nil {
+		t.Fatalf("Split failed: %v", err)
+	}
+	if len(shares) != 5 {
+		t.Fatalf("Expected 5 shares, got %d", len(shares))
+	}
+
+	// Every 3-subset recovers the secret
+	for i := 0; i < 5; i++ {
+		for j := i + 1; j < 5; j++ {
+			for k := j + 1; k < 5; k++ {
+				got, err := Combine([]Share{shares[k], shares[i], shares[j]})
+				if err != nil {
+					t.Fatalf("Combine(%d,%d,%d) failed: %v", i, j, k, err)
+				}
+				if !bytes.Equal(got, secret) {
+					t.Fatalf("Combine(%d,%d,%d) returned wrong secret", i, j, k)
+				}
+			}
+		}
+	}
+
+	// More shares than needed also work
+	if got, err := Combine(shares); err != nil || !bytes.Equal(got, secret) {
+		t.Errorf("Combine with all shares failed: %v", err)
+	}
+}
+
+func TestCombineBelowThreshold(t *testing.T) {
+	shares, _ := Split([]byte("server identity key"), 4, 3)
+
+	_, err := Combine(shares[:2])
+	if !errors.Is(err, ErrNotEnoughShares) {
+		t.Errorf("Expected ErrNotEnoughShares, got %v", err)
+	}
+}
+
+func TestCombineRejectsBadShares(t *testing.T) {
+	shares, _ := Split([]byte("server identity key"), 3, 2)
+	other, _ := Split([]byte("server identity key"), 3, 2)
+
+	if _, err := Combine([]Share{shares[0], other[1]}); !errors.Is(err, ErrMixedShares) {
+		t.Errorf("Expected ErrMixedShares, got %v", err)
+	}
+	if _, err := Combine([]Share{shares[0], shares[0]}); !errors.Is(err, ErrDuplicateShare) {
+		t.Errorf("Expected ErrDuplicateShare, got %v", err)
+	}
+
+	corrupted := shares[1]
+	corrupted.Data = append([]byte(nil), corrupted.Data...)
+	corrupted.Data[0] ^= 0xff
+	if _, err := Combine([]Share{shares[0], corrupted}); !errors.Is(err, ErrRecovery) {
+		t.Errorf("Expected ErrRecovery for corrupted share, got %v", err)
+	}
+}
+
+func TestSplitParams(t *testing.T) {
+	cases := []struct{ total, threshold int }{
+		{3, 1}, {2, 3}, {256, 2}, {0, 0},
+	}
+	for _, c := range cases {
+		if _, err := Split([]byte("secret"), c.total, c.threshold); !errors.Is(err, ErrInvalidParams) {
+			t.Errorf("Split(%d of %d) should fail, got %v", c.threshold, c.total, err)
+		}
+	}
+	if _, err := Split(nil, 3, 2); !errors.Is(err, ErrInvalidParams) {
+		t.Errorf("Split of empty secret should fail, got %v", err)
+	}
+}
+
+func TestShareTextRoundTrip(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	shares, _ := Split(secret, 3, 2)
+
+	var parsed []Share
+	for _, share := range shares {
+		text := share.String()
+		if !strings.HasPrefix(text, SharePrefix+":") {
+			t.Fatalf("Unexpected share text %q", text)
+		}
+		p, err := Parse(text + "\n")
+		if err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		parsed = append(parsed, p)
+	}
+
+	got, err := Combine(parsed[1:])
+	if err != nil || !bytes.Equal(got, secret) {
+		t.Errorf("Combine of parsed shares failed: %v", err)
+	}
+}
+
+func TestParseDetectsTypos(t *testing.T) {
+	shares, _ := Split([]byte("secret key bytes"), 3, 2)
+	text := shares[0].String()
+
+	// Flip one hex digit in the data field
+	parts := strings.Split(text, ":")
+	data := []byte(parts[6])
+	if data[0] == 'a' {
+		data[0] = 'b'
+	} else {
+		data[0] = 'a'
+	}
+	parts[6] = string(data)
+
+	if _, err := Parse(strings.Join(parts, ":")); !errors.Is(err, ErrChecksum) {
+		t.Errorf("Expected ErrChecksum, got %v", err)
+	}
+	if _, err := Parse("not a share"); !errors.Is(err, ErrInvalidParams) {
+		t.Errorf("Expected ErrInvalidParams, got %v", err)
+	}
+}
@@ -0,0 +1,181 @@
+package crypto
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// DefaultSubscriptionBuffer is how many committed entries a subscriber may
+// fall behind before it is dropped
+const DefaultSubscriptionBuffer = 256
+
+// CommittedEntry is an entry delivered to subscribers once it is saved
+type CommittedEntry struct {
+	Index int      `json:"index"`
+	Entry LogEntry `json:"entry"`
+}
+
+// Subscription delivers entries committed to a chain, in index order
+type Subscription struct {
+	lc         *LogChain
+	entries    chan CommittedEntry
+	overflowed atomic.Bool
+}
+
+// Subscribe returns a subscription to every entry committed from now on,
+// including entries other processes appended that this chain saves past. A
+// subscriber that falls more than buffer entries behind is dropped: its
+// channel is closed and Overflowed reports true. It can subscribe again and
+// catch up from the last index it saw.
+func (lc *LogChain) Subscribe(buffer int) *Subscription {
+	if buffer < 1 {
+		buffer = DefaultSubscriptionBuffer
+	}
+	sub := &Subscription{lc: lc, entries: make(chan CommittedEntry, buffer)}
+
+	lc.subsMu.Lock()
+	defer lc.subsMu.Unlock()
+	if lc.subs == nil {
+		lc.subs = make(map[*Subscription]struct{})
+	}
+	lc.subs[sub] = struct{}{}
+	return sub
+}
+
+// Entries returns the channel committed entries arrive on. It is closed when
+// the subscription is closed or dropped.
+func (s *Subscription) Entries() <-chan CommittedEntry {
+	return s.entries
+}
+
+// Overflowed reports whether the subscription was dropped for falling behind
+func (s *Subscription) Overflowed() bool {
+	return s.overflowed.Load()
+}
+
+// Close ends the subscription and closes its channel
+func (s *Subscription) Close() {
+	s.lc.subsMu.Lock()
+	defer s.lc.subsMu.Unlock()
+	if _, ok := s.lc.subs[s]; ok {
+		delete(s.lc.subs, s)
+		close(s.entries)
+	}
+}
+
+// publishLocked delivers entries saved since the last call to subscribers.
+// It never blocks on a slow subscriber. Callers hold the lock.
+func (lc *LogChain) publishLocked() {
+	length := lc.lengthLocked()
+	from := lc.published
+	lc.published = length
+
+	lc.subsMu.Lock()
+	defer lc.subsMu.Unlock()
+	if len(lc.subs) == 0 || from >= length {
+		return
+	}
+	for i := max(from, lc.Header.prunedCount()); i < length; i++ {
+		entry := lc.entryLocked(i)
+		if entry == nil {
+			continue
+		}
+		committed := CommittedEntry{Index: i, Entry: *entry}
+		for sub := range lc.subs {
+			select {
+			case sub.entries <- committed:
+			default:
+				sub.overflowed.Store(true)
+				delete(lc.subs, sub)
+				close(sub.entries)
+			}
+		}
+	}
+}
+
+// StreamVerifier checks entries received one at a time in index order, as
+// from a subscription or a server stream: each entry's hash under its
+// epoch's algorithm, its content and signature when they are readable, and
+// its link to the entry before it when that one was seen too.
+type StreamVerifier struct {
+	header    ChainHeader
+	prevIndex int
+	prevHash  string
+}
+
+// NewStreamVerifier creates a verifier using the epochs of header, which
+// must be valid
+func NewStreamVerifier(header ChainHeader) (*StreamVerifier, error) {
+	if err := header.validate(); err != nil {
+		return nil, err
+	}
+	return &StreamVerifier{header: header, prevIndex: -1}, nil
+}
+
+// SetHeader replaces the header, after the chain started a new epoch. An
+// invalid header is refused and the current one kept.
+func (v *StreamVerifier) SetHeader(header ChainHeader) error {
+	if err := header.validate(); err != nil {
+		return err
+	}
+	v.header = header
+	return nil
+}
+
+// Check verifies the entry at index as stored and returns its problems, if
+// any. Sealed content is not checked; pass its opened form to CheckContent.
+func (v *StreamVerifier) Check(index int, entry LogEntry) []string {
+	var problems []string
+
+	// A genesis entry names its epoch's algorithm, which a header fetched
+	// before the epoch started does not know. An epoch the header knows
+	// keeps the header's algorithm.
+	epoch := v.header.epochAt(index)
+	hashAlg := epoch.HashAlgorithm
+	if opens := entry.Opens; opens != nil {
+		switch {
+		case opens.Epoch > v.header.currentEpoch().Number:
+			hashAlg = opens.HashAlgorithm
+		case opens.Epoch != epoch.Number || opens.HashAlgorithm != epoch.HashAlgorithm:
+			problems = append(problems, fmt.Sprintf("opens epoch %d with %s, but the header has epoch %d with %s",
+				opens.Epoch, opens.HashAlgorithm, epoch.Number, epoch.HashAlgorithm))
+		}
+	}
+	if _, err := NewHash(hashAlg); err != nil {
+		problems = append(problems, err.Error())
+	} else if entry.Hash(hashAlg) != entry.CurrentHash {
+		problems = append(problems, "hash mismatch")
+	}
+	if !entry.IsSealed() {
+		problems = append(problems, v.CheckContent(entry)...)
+	}
+
+	if index == v.prevIndex+1 && v.prevIndex >= 0 && entry.PrevHash != v.prevHash {
+		problems = append(problems, fmt.Sprintf("does not link to entry %d", v.prevIndex))
+	}
+	v.prevIndex, v.prevHash = index, entry.CurrentHash
+	return problems
+}
+
+// CheckContent verifies an unsealed entry's committed content and its
+// signature. Erased content can no longer be checked against its signature.
+func (v *StreamVerifier) CheckContent(entry LogEntry) []string {
+	var problems []string
+	signed := true
+	if c := entry.Commitments; c != nil {
+		problems = append(problems, entry.checkContent()...)
+		signed = !c.isRedacted(FieldMessage) && !c.isRedacted(FieldEnvelope)
+	}
+	if signed {
+		if err := entry.VerifySignature(); err != nil {
+			problems = append(problems, fmt.Sprintf("invalid signature (%s): %v", NormalizeAlgorithm(entry.Algorithm), err))
+		}
+	}
+	return problems
+}
+
+// Hash computes the entry's hash with the given chain hash algorithm, for
+// checking CurrentHash outside a LogChain
+func (e *LogEntry) Hash(hashAlg string) string {
+	return calculateHash(*e, hashAlg)
+}
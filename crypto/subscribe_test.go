@@ -0,0 +1,119 @@
+package crypto
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSubscribe(t *testing.T) {
+	tempFile := os.TempDir() + "/test_chain_subscribe.json"
+	defer os.Remove(tempFile)
+
+	chain := newTestChain(t, tempFile)
+	signer := testSigner(1)
+	addSigned(t, chain, signer, "Before")
+
+	// Only entries committed after subscribing are delivered, in order
+	sub := chain.Subscribe(4)
+	defer sub.Close()
+	addSigned(t, chain, signer, "Log 1")
+	addSigned(t, chain, signer, "Log 2")
+	for i, want := range []string{"Log 1", "Log 2"} {
+		committed := <-sub.Entries()
+		if committed.Index != i+1 || committed.Entry.Message != want {
+			t.Errorf("Expected %q at %d, got %q at %d", want, i+1, committed.Entry.Message, committed.Index)
+		}
+	}
+
+	// A subscriber that falls behind is dropped instead of blocking appends
+	slow := chain.Subscribe(1)
+	addSigned(t, chain, signer, "Log 3")
+	addSigned(t, chain, signer, "Log 4")
+	if _, ok := <-slow.Entries(); !ok {
+		t.Fatal("Expected the buffered entry before the channel closed")
+	}
+	if _, ok := <-slow.Entries(); ok || !slow.Overflowed() {
+		t.Error("Expected slow subscriber to be dropped")
+	}
+	slow.Close()
+	if len(sub.Entries()) != 2 {
+		t.Errorf("Expected other subscribers to keep receiving, got %d queued", len(sub.Entries()))
+	}
+}
+
+func TestStreamVerifier(t *testing.T) {
+	tempFile := os.TempDir() + "/test_chain_stream_verify.json"
+	defer os.Remove(tempFile)
+
+	chain := newTestChain(t, tempFile)
+	signer := testSigner(1)
+	for _, message := range []string{"Log 0", "Log 1", "Log 2"} {
+		addSigned(t, chain, signer, message)
+	}
+
+	verifier, err := NewStreamVerifier(chain.GetHeader())
+	if err != nil {
+		t.Fatalf("Failed to create verifier: %v", err)
+	}
+	for i, entry := range chain.Entries {
+		if problems := verifier.Check(i, entry); len(problems) > 0 {
+			t.Errorf("Expected entry %d to verify, got %v", i, problems)
+		}
+	}
+
+	// An entry that does not link to the one before is caught, even when its
+	// own hash is consistent
+	forged := chain.Entries[2]
+	forged.PrevHash = chain.Entries[0].CurrentHash
+	forged.CurrentHash = forged.Hash(DefaultHashAlgorithm)
+	verifier, _ = NewStreamVerifier(chain.GetHeader())
+	verifier.Check(1, chain.Entries[1])
+	if problems := verifier.Check(2, forged); len(problems) != 1 || problems[0] != "does not link to entry 1" {
+		t.Errorf("Expected broken link, got %v", problems)
+	}
+
+	// Altered content fails its commitment and signature
+	altered := chain.Entries[1]
+	altered.Message = "Altered"
+	verifier, _ = NewStreamVerifier(chain.GetHeader())
+	if problems := verifier.Check(1, altered); len(problems) != 2 {
+		t.Errorf("Expected commitment and signature failures, got %v", problems)
+	}
+}
+
+func TestStreamVerifierChecksAlgorithms(t *testing.T) {
+	chain := newTestChain(t, filepath.Join(t.TempDir(), "chain.json"))
+	addSigned(t, chain, testSigner(1), "Log 0")
+	header := chain.GetHeader()
+
+	invalid := header
+	invalid.Epochs = []ChainEpoch{{HashAlgorithm: "md5"}}
+	if _, err := NewStreamVerifier(invalid); err == nil {
+		t.Error("Expected a header with an unknown algorithm to be refused")
+	}
+	verifier, err := NewStreamVerifier(header)
+	if err != nil {
+		t.Fatalf("Failed to create verifier: %v", err)
+	}
+	if err := verifier.SetHeader(ChainHeader{}); err == nil {
+		t.Error("Expected a header without epochs to be refused")
+	}
+	if problems := verifier.Check(0, chain.Entries[0]); len(problems) > 0 {
+		t.Errorf("Expected the previous header to be kept, got %v", problems)
+	}
+
+	// A genesis entry for a new epoch names an algorithm that is checked
+	// before it is used, and cannot change the algorithm of a known epoch
+	unknown := LogEntry{Opens: &EpochGenesis{Epoch: 1, HashAlgorithm: "md5"}}
+	if problems := verifier.Check(1, unknown); len(problems) == 0 || problems[0] != "unknown hash algorithm: md5" {
+		t.Errorf("Expected an unknown algorithm, got %v", problems)
+	}
+	forged := chain.Entries[0]
+	forged.Opens = &EpochGenesis{Epoch: 0, HashAlgorithm: HashSHA3_256}
+	forged.CurrentHash = forged.Hash(HashSHA3_256)
+	if problems := verifier.Check(0, forged); len(problems) == 0 || !strings.HasPrefix(problems[0], "opens epoch 0 with sha3-256") {
+		t.Errorf("Expected the genesis entry to conflict with the header, got %v", problems)
+	}
+}
@@ -0,0 +1,393 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"time"
+)
+
+// Content types for RFC 3161 time-stamp requests and replies sent over HTTP
+const (
+	TimestampQueryType = "application/timestamp-query"
+	TimestampReplyType = "application/timestamp-reply"
+)
+
+// ErrTimestampMismatch is returned when a time-stamp token covers other data
+var ErrTimestampMismatch = errors.New("timestamp token does not cover the message")
+
+var (
+	oidSHA256 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidSHA384 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 2}
+	oidSHA512 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}
+
+	oidSignedData           = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidTSTInfo              = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 1, 4}
+	oidContentType          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	oidMessageDigest        = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidSigningCertificateV2 = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 2, 47}
+	oidECDSAWithSHA256      = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+	oidTimeStamping         = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 8}
+)
+
+// timestampHashes maps the digest algorithms accepted in tokens to their hashes
+var timestampHashes = map[string]crypto.Hash{
+	oidSHA256.String(): crypto.SHA256,
+	oidSHA384.String(): crypto.SHA384,
+	oidSHA512.String(): crypto.SHA512,
+}
+
+// ASN.1 structures from RFC 3161 and RFC 5652 (CMS)
+
+type messageImprint struct {
+	HashAlgorithm pkix.AlgorithmIdentifier
+	HashedMessage []byte
+}
+
+type timeStampReq struct {
+	Version        int
+	MessageImprint messageImprint
+	ReqPolicy      asn1.ObjectIdentifier `asn1:"optional"`
+	Nonce          *big.Int              `asn1:"optional"`
+	CertReq        bool                  `asn1:"optional"`
+	Extensions     []pkix.Extension      `asn1:"optional,tag:0"`
+}
+
+type pkiStatusInfo struct {
+	Status       int
+	StatusString []string       `asn1:"optional"`
+	FailInfo     asn1.BitString `asn1:"optional"`
+}
+
+type timeStampResp struct {
+	Status         pkiStatusInfo
+	TimeStampToken asn1.RawValue `asn1:"optional"`
+}
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,tag:0"`
+}
+
+type encapsulatedContentInfo struct {
+	EContentType asn1.ObjectIdentifier
+	EContent     []byte `asn1:"explicit,optional,tag:0"`
+}
+
+type signedData struct {
+	Version          int
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	EncapContentInfo encapsulatedContentInfo
+	Certificates     asn1.RawValue `asn1:"optional,tag:0"`
+	CRLs             asn1.RawValue `asn1:"optional,tag:1"`
+	SignerInfos      []signerInfo  `asn1:"set"`
+}
+
+type issuerAndSerial struct {
+	Issuer       asn1.RawValue
+	SerialNumber *big.Int
+}
+
+type signerInfo struct {
+	Version            int
+	SID                asn1.RawValue // IssuerAndSerialNumber or [0] SubjectKeyIdentifier
+	DigestAlgorithm    pkix.AlgorithmIdentifier
+	SignedAttrs        asn1.RawValue `asn1:"optional,tag:0"`
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          []byte
+	UnsignedAttrs      asn1.RawValue `asn1:"optional,tag:1"`
+}
+
+type attribute struct {
+	Type   asn1.ObjectIdentifier
+	Values asn1.RawValue // SET OF values
+}
+
+type essCertIDv2 struct {
+	HashAlgorithm pkix.AlgorithmIdentifier `asn1:"optional"` // SHA-256 when absent
+	CertHash      []byte
+}
+
+type signingCertificateV2 struct {
+	Certs []essCertIDv2
+}
+
+type accuracy struct {
+	Seconds int `asn1:"optional"`
+	Millis  int `asn1:"optional,tag:0"`
+	Micros  int `asn1:"optional,tag:1"`
+}
+
+type tstInfo struct {
+	Version        int
+	Policy         asn1.ObjectIdentifier
+	MessageImprint messageImprint
+	SerialNumber   *big.Int
+	GenTime        time.Time        `asn1:"generalized"`
+	Accuracy       accuracy         `asn1:"optional"`
+	Ordering       bool             `asn1:"optional"`
+	Nonce          *big.Int         `asn1:"optional"`
+	TSA            asn1.RawValue    `asn1:"optional,tag:0"`
+	Extensions     []pkix.Extension `asn1:"optional,tag:1"`
+}
+
+// TimestampRequest is an RFC 3161 request for a token over a SHA-256 digest
+type TimestampRequest struct {
+	Digest []byte
+	Nonce  *big.Int
+}
+
+// NewTimestampRequest prepares a request over the SHA-256 digest of message
+// with a random nonce. The TSA is asked to include its certificate.
+func NewTimestampRequest(message []byte) (*TimestampRequest, error) {
+	nonce, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 64))
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256(message)
+	return &TimestampRequest{Digest: digest[:], Nonce: nonce}, nil
+}
+
+// Marshal returns the DER TimeStampReq to send to a TSA
+func (r *TimestampRequest) Marshal() ([]byte, error) {
+	return asn1.Marshal(timeStampReq{
+		Version: 1,
+		MessageImprint: messageImprint{
+			HashAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidSHA256, Parameters: asn1.NullRawValue},
+			HashedMessage: r.Digest,
+		},
+		Nonce:   r.Nonce,
+		CertReq: true,
+	})
+}
+
+// ParseTimestampResponse extracts the token from a DER TimeStampResp,
+// checking that the TSA granted the request and that the token answers it.
+// The token's signature is checked by VerifyTimestamp.
+func ParseTimestampResponse(resp []byte, req *TimestampRequest) ([]byte, error) {
+	var tsr timeStampResp
+	if rest, err := asn1.Unmarshal(resp, &tsr); err != nil || len(rest) > 0 {
+		return nil, fmt.Errorf("malformed timestamp response: %v", err)
+	}
+	if tsr.Status.Status > 1 {
+		return nil, fmt.Errorf("timestamp request rejected (status %d): %v", tsr.Status.Status, tsr.Status.StatusString)
+	}
+	token := tsr.TimeStampToken.FullBytes
+	info, _, err := parseTimestampToken(token)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(info.Digest, req.Digest) || info.HashAlgorithm != crypto.SHA256 {
+		return nil, ErrTimestampMismatch
+	}
+	if req.Nonce != nil && (info.Nonce == nil || info.Nonce.Cmp(req.Nonce) != 0) {
+		return nil, errors.New("timestamp token nonce does not match the request")
+	}
+	return token, nil
+}
+
+// TimestampInfo is what a verified time-stamp token asserts
+type TimestampInfo struct {
+	GenTime       time.Time
+	SerialNumber  *big.Int
+	Policy        asn1.ObjectIdentifier
+	HashAlgorithm crypto.Hash
+	Digest        []byte
+	Nonce         *big.Int
+	Signer        *x509.Certificate // Nil until the signature is verified
+	Trusted       bool              // The signer chains to a trusted certificate
+}
+
+// parseTimestampToken decodes a TimeStampToken without checking its
+// signature, returning the token's claims and its signed data
+func parseTimestampToken(token []byte) (*TimestampInfo, *signedData, error) {
+	var ci contentInfo
+	if rest, err := asn1.Unmarshal(token, &ci); err != nil || len(rest) > 0 {
+		return nil, nil, fmt.Errorf("malformed timestamp token: %v", err)
+	}
+	if !ci.ContentType.Equal(oidSignedData) {
+		return nil, nil, errors.New("timestamp token is not CMS signed data")
+	}
+	var sd signedData
+	if _, err := asn1.Unmarshal(ci.Content.Bytes, &sd); err != nil {
+		return nil, nil, fmt.Errorf("malformed timestamp signed data: %w", err)
+	}
+	if !sd.EncapContentInfo.EContentType.Equal(oidTSTInfo) || len(sd.SignerInfos) != 1 {
+		return nil, nil, errors.New("timestamp token must hold TSTInfo with one signer")
+	}
+
+	var tst tstInfo
+	if _, err := asn1.Unmarshal(sd.EncapContentInfo.EContent, &tst); err != nil {
+		return nil, nil, fmt.Errorf("malformed TSTInfo: %w", err)
+	}
+	hash, ok := timestampHashes[tst.MessageImprint.HashAlgorithm.Algorithm.String()]
+	if !ok {
+		return nil, nil, fmt.Errorf("unsupported timestamp hash algorithm %s", tst.MessageImprint.HashAlgorithm.Algorithm)
+	}
+	return &TimestampInfo{
+		GenTime:       tst.GenTime.UTC(),
+		SerialNumber:  tst.SerialNumber,
+		Policy:        tst.Policy,
+		HashAlgorithm: hash,
+		Digest:        tst.MessageImprint.HashedMessage,
+		Nonce:         tst.Nonce,
+	}, &sd, nil
+}
+
+// VerifyTimestamp checks offline that token is a valid RFC 3161 token over
+// message. The signer certificate is taken from the token or from trusted,
+// and must allow time stamping. When trusted is non-empty the signer must
+// chain to one of those certificates as of the token's time; otherwise the
+// token is only checked against the certificate it carries.
+func VerifyTimestamp(token, message []byte, trusted []*x509.Certificate) (*TimestampInfo, error) {
+	info, sd, err := parseTimestampToken(token)
+	if err != nil {
+		return nil, err
+	}
+	h := info.HashAlgorithm.New()
+	h.Write(message)
+	if !bytes.Equal(h.Sum(nil), info.Digest) {
+		return nil, ErrTimestampMismatch
+	}
+
+	var embedded []*x509.Certificate
+	if len(sd.Certificates.Bytes) > 0 {
+		if embedded, err = x509.ParseCertificates(sd.Certificates.Bytes); err != nil {
+			return nil, fmt.Errorf("malformed timestamp certificates: %w", err)
+		}
+	}
+	si := sd.SignerInfos[0]
+	signer := findSigner(si.SID, append(embedded, trusted...))
+	if signer == nil {
+		return nil, errors.New("timestamp signer certificate not found")
+	}
+	if !slices.Contains(signer.ExtKeyUsage, x509.ExtKeyUsageTimeStamping) {
+		return nil, errors.New("timestamp signer certificate does not allow time stamping")
+	}
+	if err := checkSignerInfo(si, sd.EncapContentInfo.EContent, signer); err != nil {
+		return nil, err
+	}
+	info.Signer = signer
+
+	if len(trusted) > 0 {
+		roots, intermediates := x509.NewCertPool(), x509.NewCertPool()
+		for _, cert := range trusted {
+			roots.AddCert(cert)
+		}
+		for _, cert := range embedded {
+			intermediates.AddCert(cert)
+		}
+		_, err := signer.Verify(x509.VerifyOptions{
+			Roots:         roots,
+			Intermediates: intermediates,
+			CurrentTime:   info.GenTime,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("timestamp signer is not trusted: %w", err)
+		}
+		info.Trusted = true
+	}
+	return info, nil
+}
+
+// findSigner returns the certificate named by a signer identifier
+func findSigner(sid asn1.RawValue, certs []*x509.Certificate) *x509.Certificate {
+	var ias issuerAndSerial
+	bySerial := sid.Class == asn1.ClassUniversal
+	if bySerial {
+		if _, err := asn1.Unmarshal(sid.FullBytes, &ias); err != nil {
+			return nil
+		}
+	}
+	for _, cert := range certs {
+		if bySerial && bytes.Equal(cert.RawIssuer, ias.Issuer.FullBytes) && cert.SerialNumber.Cmp(ias.SerialNumber) == 0 {
+			return cert
+		}
+		if !bySerial && len(cert.SubjectKeyId) > 0 && bytes.Equal(cert.SubjectKeyId, sid.Bytes) {
+			return cert
+		}
+	}
+	return nil
+}
+
+// checkSignerInfo verifies the signed attributes over content and the
+// signature over the attributes
+func checkSignerInfo(si signerInfo, content []byte, signer *x509.Certificate) error {
+	hash, ok := timestampHashes[si.DigestAlgorithm.Algorithm.String()]
+	if !ok {
+		return fmt.Errorf("unsupported signer digest algorithm %s", si.DigestAlgorithm.Algorithm)
+	}
+	if len(si.SignedAttrs.FullBytes) == 0 {
+		return errors.New("timestamp token has no signed attributes")
+	}
+
+	// Signed attributes are signed as a SET, not with their [0] tag
+	signed := slices.Clone(si.SignedAttrs.FullBytes)
+	signed[0] = 0x31
+	var attrs []attribute
+	if _, err := asn1.UnmarshalWithParams(signed, &attrs, "set"); err != nil {
+		return fmt.Errorf("malformed signed attributes: %w", err)
+	}
+
+	h := hash.New()
+	h.Write(content)
+	var contentType asn1.ObjectIdentifier
+	var digest []byte
+	for _, attr := range attrs {
+		switch {
+		case attr.Type.Equal(oidContentType):
+			asn1.Unmarshal(attr.Values.Bytes, &contentType)
+		case attr.Type.Equal(oidMessageDigest):
+			asn1.Unmarshal(attr.Values.Bytes, &digest)
+		case attr.Type.Equal(oidSigningCertificateV2):
+			var sc signingCertificateV2
+			if _, err := asn1.Unmarshal(attr.Values.Bytes, &sc); err != nil || len(sc.Certs) == 0 {
+				return errors.New("malformed signing certificate attribute")
+			}
+			if id := sc.Certs[0]; id.HashAlgorithm.Algorithm == nil || id.HashAlgorithm.Algorithm.Equal(oidSHA256) {
+				if certHash := sha256.Sum256(signer.Raw); !bytes.Equal(id.CertHash, certHash[:]) {
+					return errors.New("signing certificate attribute names another certificate")
+				}
+			}
+		}
+	}
+	if !contentType.Equal(oidTSTInfo) {
+		return errors.New("signed content type is not TSTInfo")
+	}
+	if !bytes.Equal(digest, h.Sum(nil)) {
+		return errors.New("signed message digest does not match TSTInfo")
+	}
+
+	algorithm, err := signatureAlgorithm(signer, hash)
+	if err != nil {
+		return err
+	}
+	if err := signer.CheckSignature(algorithm, signed, si.Signature); err != nil {
+		return fmt.Errorf("invalid timestamp signature: %w", err)
+	}
+	return nil
+}
+
+// signatureAlgorithm picks the x509 algorithm for a signer key and digest
+func signatureAlgorithm(cert *x509.Certificate, hash crypto.Hash) (x509.SignatureAlgorithm, error) {
+	byHash := map[x509.PublicKeyAlgorithm]map[crypto.Hash]x509.SignatureAlgorithm{
+		x509.RSA:   {crypto.SHA256: x509.SHA256WithRSA, crypto.SHA384: x509.SHA384WithRSA, crypto.SHA512: x509.SHA512WithRSA},
+		x509.ECDSA: {crypto.SHA256: x509.ECDSAWithSHA256, crypto.SHA384: x509.ECDSAWithSHA384, crypto.SHA512: x509.ECDSAWithSHA512},
+	}
+	if cert.PublicKeyAlgorithm == x509.Ed25519 {
+		return x509.PureEd25519, nil
+	}
+	if algorithm, ok := byHash[cert.PublicKeyAlgorithm][hash]; ok {
+		return algorithm, nil
+	}
+	return 0, fmt.Errorf("unsupported timestamp signature with %s key and %s", cert.PublicKeyAlgorithm, hash)
+}
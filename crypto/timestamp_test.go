@@ -0,0 +1,160 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+)
+
+// timestampWith has tsa timestamp message and returns the token
+func timestampWith(t *testing.T, tsa *TSA, message []byte) []byte {
+	t.Helper()
+	req, _ := NewTimestampRequest(message)
+	der, _ := req.Marshal()
+	resp, err := tsa.Respond(der)
+	if err != nil {
+		t.Fatalf("TSA failed to respond: %v", err)
+	}
+	token, err := ParseTimestampResponse(resp, req)
+	if err != nil {
+		t.Fatalf("Failed to parse timestamp response: %v", err)
+	}
+	return token
+}
+
+func TestTimestampVerifiesOffline(t *testing.T) {
+	tsa, err := GenerateTSA("test TSA")
+	if err != nil {
+		t.Fatalf("Failed to generate TSA: %v", err)
+	}
+	message := []byte("chain head")
+	token := timestampWith(t, tsa, message)
+
+	info, err := VerifyTimestamp(token, message, []*x509.Certificate{tsa.Certificate()})
+	if err != nil || !info.Trusted || !info.Policy.Equal(TSAPolicy) {
+		t.Fatalf("Expected trusted token, got %+v (%v)", info, err)
+	}
+	if info, err := VerifyTimestamp(token, message, nil); err != nil || info.Trusted {
+		t.Errorf("Expected token valid but untrusted without certificates, got %v", err)
+	}
+	if _, err := VerifyTimestamp(token, []byte("other head"), nil); !errors.Is(err, ErrTimestampMismatch) {
+		t.Errorf("Expected ErrTimestampMismatch, got %v", err)
+	}
+
+	other, _ := GenerateTSA("other TSA")
+	if _, err := VerifyTimestamp(token, message, []*x509.Certificate{other.Certificate()}); err == nil {
+		t.Error("Expected token from an untrusted TSA to be rejected")
+	}
+
+	// Changing the timestamped content or the signature is detected
+	digest := sha256.Sum256(message)
+	for _, i := range []int{bytes.Index(token, digest[:]) + 4, bytes.Index(token, []byte("Z")) - 1, len(token) - 1} {
+		tampered := bytes.Clone(token)
+		tampered[i] ^= 0x01
+		if _, err := VerifyTimestamp(tampered, message, nil); err == nil {
+			t.Errorf("Expected tampered byte %d to be detected", i)
+		}
+	}
+}
+
+func TestTimestampResponseChecks(t *testing.T) {
+	tsa, _ := GenerateTSA("test TSA")
+
+	resp, err := tsa.Respond([]byte("not a request"))
+	if err != nil {
+		t.Fatalf("Expected a rejection response, got %v", err)
+	}
+	req, _ := NewTimestampRequest([]byte("head"))
+	if _, err := ParseTimestampResponse(resp, req); err == nil {
+		t.Error("Expected rejected request to fail")
+	}
+
+	// A reply to another request is refused
+	der, _ := req.Marshal()
+	resp, _ = tsa.Respond(der)
+	other, _ := NewTimestampRequest([]byte("head"))
+	if _, err := ParseTimestampResponse(resp, other); err == nil {
+		t.Error("Expected nonce mismatch to be detected")
+	}
+}
+
+func TestTSASaveAndLoad(t *testing.T) {
+	keyPath := os.TempDir() + "/test_tsa.key"
+	certPath := os.TempDir() + "/test_tsa.crt"
+	defer os.Remove(keyPath)
+	defer os.Remove(certPath)
+
+	tsa, _ := GenerateTSA("test TSA")
+	if err := tsa.Save(keyPath, certPath); err != nil {
+		t.Fatalf("Failed to save TSA: %v", err)
+	}
+	loaded, err := LoadTSA(keyPath, certPath)
+	if err != nil {
+		t.Fatalf("Failed to load TSA: %v", err)
+	}
+	token := timestampWith(t, loaded, []byte("head"))
+	if _, err := VerifyTimestamp(token, []byte("head"), []*x509.Certificate{tsa.Certificate()}); err != nil {
+		t.Errorf("Expected loaded TSA to sign as the saved one: %v", err)
+	}
+}
+
+func TestChainCheckpoints(t *testing.T) {
+	tempFile := os.TempDir() + "/test_chain_checkpoints.json"
+	defer os.Remove(tempFile)
+
+	chain, _ := NewLogChain(tempFile)
+	signer, _ := GenerateSigner(AlgEd25519)
+	tsa, _ := GenerateTSA("test TSA")
+	for i := 0; i < 3; i++ {
+		addSigned(t, chain, signer, fmt.Sprintf("Log %d", i))
+	}
+
+	length, head := chain.CheckpointHead()
+	token := timestampWith(t, tsa, CheckpointBytes(length, head))
+	addSigned(t, chain, signer, "Log 3")
+
+	// The chain may grow between the request and storing the checkpoint
+	checkpoint, err := chain.AddCheckpoint(Checkpoint{Length: length, Head: head, Token: token})
+	if err != nil {
+		t.Fatalf("Failed to add checkpoint: %v", err)
+	}
+	if checkpoint.GenTime.IsZero() {
+		t.Error("Expected checkpoint time taken from the token")
+	}
+	if _, err := chain.AddCheckpoint(Checkpoint{Length: 4, Head: head, Token: token}); err == nil {
+		t.Error("Expected checkpoint with the wrong head to be rejected")
+	}
+
+	reloaded, _ := NewLogChain(tempFile)
+	checkpoints := reloaded.Checkpoints()
+	if len(checkpoints) != 1 || checkpoints[0].Length != 3 {
+		t.Fatalf("Expected checkpoint to persist, got %+v", checkpoints)
+	}
+	if _, err := checkpoints[0].Verify([]*x509.Certificate{tsa.Certificate()}); err != nil {
+		t.Errorf("Expected stored checkpoint to verify: %v", err)
+	}
+	// Without trusted certificates the checkpoint is intact but untrusted,
+	// and a token from another TSA is rejected once one is trusted
+	if report := reloaded.VerifyChainReport(); !report.Valid || len(report.UntrustedCheckpoints) != 1 {
+		t.Errorf("Expected the checkpoint reported as untrusted, got %+v", report)
+	}
+	reloaded.SetTrustedTSA([]*x509.Certificate{tsa.Certificate()})
+	if report := reloaded.VerifyChainReport(); !report.Valid || len(report.UntrustedCheckpoints) != 0 {
+		t.Errorf("Expected the checkpoint to be trusted, got %+v", report)
+	}
+	other, _ := GenerateTSA("other TSA")
+	reloaded.SetTrustedTSA([]*x509.Certificate{other.Certificate()})
+	if valid, _ := reloaded.VerifyChain(); valid {
+		t.Error("Expected a checkpoint from an untrusted TSA to be rejected")
+	}
+	reloaded.SetTrustedTSA(nil)
+
+	reloaded.Header.Checkpoints[0].Head = reloaded.Entries[3].CurrentHash
+	if valid, _ := reloaded.VerifyChain(); valid {
+		t.Error("Expected altered checkpoint to be detected")
+	}
+}
@@ -0,0 +1,260 @@
+package crypto
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"time"
+)
+
+// TSAPolicy is the policy the built-in TSA states in its tokens. It is a
+// placeholder for local testing, not a registered policy.
+var TSAPolicy = asn1.ObjectIdentifier{1, 2, 3, 4, 1}
+
+// PKIFailureInfo bits reported by the built-in TSA
+const (
+	tsaBadAlg        = 0
+	tsaBadRequest    = 2
+	tsaBadDataFormat = 5
+	tsaSystemFailure = 25
+)
+
+// TSA is a minimal RFC 3161 time-stamp authority for local testing. It signs
+// with an ECDSA P-256 key and a self-signed certificate limited to time
+// stamping, and answers every valid SHA-256, SHA-384 or SHA-512 request.
+type TSA struct {
+	key  *ecdsa.PrivateKey
+	cert *x509.Certificate
+}
+
+// GenerateTSA creates a TSA with a fresh key and a self-signed certificate
+// for name, valid for ten years
+func GenerateTSA(name string) (*TSA, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, err
+	}
+
+	// RFC 3161 requires a critical extended key usage naming only time stamping
+	eku, _ := asn1.Marshal([]asn1.ObjectIdentifier{oidTimeStamping})
+	now := time.Now().UTC()
+	template := &x509.Certificate{
+		SerialNumber:    serial,
+		Subject:         pkix.Name{CommonName: name},
+		NotBefore:       now.Add(-time.Hour),
+		NotAfter:        now.AddDate(10, 0, 0),
+		KeyUsage:        x509.KeyUsageDigitalSignature,
+		ExtraExtensions: []pkix.Extension{{Id: asn1.ObjectIdentifier{2, 5, 29, 37}, Critical: true, Value: eku}},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	return &TSA{key: key, cert: cert}, nil
+}
+
+// LoadTSA reads a TSA key and certificate saved by Save
+func LoadTSA(keyPath, certPath string) (*TSA, error) {
+	keyPEM, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, err
+	}
+	certPEM, err := os.ReadFile(certPath)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, errors.New("TSA key file is not PEM")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TSA key: %w", err)
+	}
+	key, ok := parsed.(*ecdsa.PrivateKey)
+	if !ok || key.Curve != elliptic.P256() {
+		return nil, errors.New("TSA key must be ECDSA P-256")
+	}
+	certs, err := ParseCertificates(certPEM)
+	if err != nil {
+		return nil, err
+	}
+	return &TSA{key: key, cert: certs[0]}, nil
+}
+
+// Save writes the TSA key and certificate as PEM files
+func (t *TSA) Save(keyPath, certPath string) error {
+	der, err := x509.MarshalPKCS8PrivateKey(t.key)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		return err
+	}
+	return os.WriteFile(certPath, t.CertificatePEM(), 0644)
+}
+
+// Certificate returns the TSA's signing certificate
+func (t *TSA) Certificate() *x509.Certificate {
+	return t.cert
+}
+
+// CertificatePEM returns the TSA's certificate in PEM form, for verifiers
+func (t *TSA) CertificatePEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: t.cert.Raw})
+}
+
+// ParseCertificates reads one or more PEM certificates, such as a TSA's
+// certificate chain
+func ParseCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid certificate: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("no PEM certificates found")
+	}
+	return certs, nil
+}
+
+// Respond answers a DER TimeStampReq with a DER TimeStampResp. Malformed or
+// unsupported requests get a rejection response; an error is returned only
+// when no response could be produced.
+func (t *TSA) Respond(req []byte) ([]byte, error) {
+	var tsq timeStampReq
+	if rest, err := asn1.Unmarshal(req, &tsq); err != nil || len(rest) > 0 || tsq.Version != 1 {
+		return rejectTimestamp(tsaBadDataFormat, "malformed request")
+	}
+	hash, ok := timestampHashes[tsq.MessageImprint.HashAlgorithm.Algorithm.String()]
+	if !ok || len(tsq.MessageImprint.HashedMessage) != hash.Size() {
+		return rejectTimestamp(tsaBadAlg, "unsupported hash algorithm")
+	}
+	if tsq.ReqPolicy != nil && !tsq.ReqPolicy.Equal(TSAPolicy) {
+		return rejectTimestamp(tsaBadRequest, "unsupported policy")
+	}
+
+	token, err := t.sign(tsq)
+	if err != nil {
+		return rejectTimestamp(tsaSystemFailure, "signing failed")
+	}
+	return asn1.Marshal(timeStampResp{
+		Status:         pkiStatusInfo{Status: 0},
+		TimeStampToken: asn1.RawValue{FullBytes: token},
+	})
+}
+
+// sign builds the CMS SignedData token for a request
+func (t *TSA) sign(tsq timeStampReq) ([]byte, error) {
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, err
+	}
+	content, err := asn1.Marshal(tstInfo{
+		Version:        1,
+		Policy:         TSAPolicy,
+		MessageImprint: tsq.MessageImprint,
+		SerialNumber:   serial,
+		GenTime:        time.Now().UTC().Truncate(time.Second),
+		Accuracy:       accuracy{Seconds: 1},
+		Nonce:          tsq.Nonce,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	contentDigest := sha256.Sum256(content)
+	certHash := sha256.Sum256(t.cert.Raw)
+	attrs := []attribute{
+		newAttribute(oidContentType, oidTSTInfo),
+		newAttribute(oidMessageDigest, contentDigest[:]),
+		newAttribute(oidSigningCertificateV2, signingCertificateV2{Certs: []essCertIDv2{{CertHash: certHash[:]}}}),
+	}
+	signed, err := asn1.MarshalWithParams(attrs, "set")
+	if err != nil {
+		return nil, err
+	}
+	var signedSet asn1.RawValue
+	asn1.Unmarshal(signed, &signedSet)
+	attrsDigest := sha256.Sum256(signed)
+	signature, err := ecdsa.SignASN1(rand.Reader, t.key, attrsDigest[:])
+	if err != nil {
+		return nil, err
+	}
+
+	sid, _ := asn1.Marshal(issuerAndSerial{Issuer: asn1.RawValue{FullBytes: t.cert.RawIssuer}, SerialNumber: t.cert.SerialNumber})
+	sha256ID := pkix.AlgorithmIdentifier{Algorithm: oidSHA256}
+	sd := signedData{
+		Version:          3,
+		DigestAlgorithms: []pkix.AlgorithmIdentifier{sha256ID},
+		EncapContentInfo: encapsulatedContentInfo{EContentType: oidTSTInfo, EContent: content},
+		SignerInfos: []signerInfo{{
+			Version:            1,
+			SID:                asn1.RawValue{FullBytes: sid},
+			DigestAlgorithm:    sha256ID,
+			SignedAttrs:        asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: signedSet.Bytes},
+			SignatureAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidECDSAWithSHA256},
+			Signature:          signature,
+		}},
+	}
+	if tsq.CertReq {
+		sd.Certificates = asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: t.cert.Raw}
+	}
+	sdDER, err := asn1.Marshal(sd)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(contentInfo{
+		ContentType: oidSignedData,
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: sdDER},
+	})
+}
+
+// newAttribute encodes a CMS attribute with a single value
+func newAttribute(oid asn1.ObjectIdentifier, value interface{}) attribute {
+	der, _ := asn1.Marshal(value)
+	return attribute{
+		Type:   oid,
+		Values: asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true, Bytes: der},
+	}
+}
+
+// rejectTimestamp builds a rejection response with one failure bit
+func rejectTimestamp(bit int, reason string) ([]byte, error) {
+	failInfo := make([]byte, bit/8+1)
+	failInfo[bit/8] = 0x80 >> (bit % 8)
+	return asn1.Marshal(timeStampResp{Status: pkiStatusInfo{
+		Status:       2,
+		StatusString: []string{reason},
+		FailInfo:     asn1.BitString{Bytes: failInfo, BitLength: bit + 1},
+	}})
+}
+
+// randomSerial returns a random positive 127-bit serial number
+func randomSerial() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 127))
+}
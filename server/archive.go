@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/amshithnair/zcrypt/crypto"
+	"github.com/gofiber/fiber/v2"
+)
+
+// List the chain's archive segments
+func (s *Server) getSegments(c *fiber.Ctx) error {
+	segments := s.LogChain.Segments()
+
+	return c.JSON(fiber.Map{
+		"segments": segments,
+		"count":    len(segments),
+	})
+}
+
+// Move old entries into a compressed archive segment
+func (s *Server) archiveChain(c *fiber.Ctx) error {
+	type ArchiveRequest struct {
+		Before      *int   `json:"before,omitempty"` // Archive entries before this index
+		Keep        int    `json:"keep,omitempty"`   // Or keep this many newest entries live
+		Compression string `json:"compression,omitempty"`
+	}
+
+	var req ArchiveRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if req.Before == nil && req.Keep <= 0 {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Missing before or keep",
+		})
+	}
+	before := s.LogChain.Length() - req.Keep
+	if req.Before != nil {
+		before = *req.Before
+	}
+
+	segment, err := s.LogChain.Archive(before, req.Compression)
+	if errors.Is(err, crypto.ErrNothingToArchive) {
+		return c.JSON(fiber.Map{
+			"success":  true,
+			"archived": 0,
+		})
+	}
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(201).JSON(fiber.Map{
+		"success":  true,
+		"archived": segment.Count,
+		"segment":  segment,
+	})
+}
+
+// Move archived entries from an index onward back into the chain file
+func (s *Server) restoreChain(c *fiber.Ctx) error {
+	type RestoreRequest struct {
+		From int `json:"from"`
+	}
+
+	var req RestoreRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+	}
+
+	restored, err := s.LogChain.Restore(req.From)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success":  true,
+		"restored": restored,
+	})
+}
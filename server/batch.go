@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/amshithnair/zcrypt/crypto"
+	"github.com/gofiber/fiber/v2"
+)
+
+// DefaultMaxBatchSize is the most submissions one batch request may carry
+// unless ZCRYPT_MAX_BATCH_SIZE says otherwise
+const DefaultMaxBatchSize = 100
+
+// batchResult reports the outcome of one submission in a batch
+type batchResult struct {
+	Status  int              `json:"status"`          // HTTP status the submission would have had on its own
+	Index   *int             `json:"index,omitempty"` // Chain index of an accepted entry
+	Entry   *crypto.LogEntry `json:"entry,omitempty"`
+	Receipt *crypto.Receipt  `json:"receipt,omitempty"` // Server's signed statement that it appended Entry
+	Error   string           `json:"error,omitempty"`
+}
+
+// Submit several signed log entries at once. Each submission is checked on
+// its own; the accepted ones are appended together with one chain write, in
+// request order. Submissions repeating an earlier request ID report the
+// original entry with status 200. Responds 201 when all were appended or
+// repeats, and 207 otherwise.
+func (s *Server) submitBatch(c *fiber.Ctx) error {
+	var req struct {
+		Entries []LogRequest `json:"entries"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if len(req.Entries) == 0 {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Batch has no entries",
+		})
+	}
+	if len(req.Entries) > s.MaxBatchSize {
+		return c.Status(413).JSON(fiber.Map{
+			"error": fmt.Sprintf("Batch of %d entries exceeds the limit of %d", len(req.Entries), s.MaxBatchSize),
+		})
+	}
+
+	results := make([]batchResult, len(req.Entries))
+	claims := make([]*requestClaim, len(req.Entries))
+	repeats := make(map[int]int) // Position -> earlier position with the same request ID
+	seen := make(map[string]int)
+	var entries []crypto.LogEntry
+	var positions []int
+	for i := range req.Entries {
+		item := &req.Entries[i]
+		if item.RequestID != "" {
+			key := scopedKey(item.PubKey, item.RequestID)
+			if j, ok := seen[key]; ok {
+				if canonicalHex(req.Entries[j].Signature) == canonicalHex(item.Signature) {
+					repeats[i] = j
+				} else {
+					results[i] = batchResult{Status: 422, Error: "request_id was already used for a different submission"}
+				}
+				continue
+			}
+			seen[key] = i
+		}
+
+		claim, rej := s.beginRequest(item)
+		if rej != nil {
+			results[i] = batchResult{Status: rej.Status, Error: rej.Message}
+			continue
+		}
+		if claim != nil && claim.Original != nil {
+			index := claim.Index
+			results[i] = batchResult{Status: 200, Index: &index, Entry: claim.Original, Receipt: s.issueReceipt(index, claim.Original, item.RequestID)}
+			continue
+		}
+		claims[i] = claim
+
+		entry, rej := s.prepareEntry(item)
+		if rej != nil {
+			claim.abort()
+			results[i] = batchResult{Status: rej.Status, Error: rej.Message}
+			continue
+		}
+		entries = append(entries, entry)
+		positions = append(positions, i)
+	}
+
+	if len(entries) > 0 {
+		appended, err := s.LogChain.AddLogs(entries)
+		if err != nil {
+			for j, i := range positions {
+				claims[i].abort()
+				s.releaseNonce(entries[j])
+				results[i] = batchResult{Status: 500, Error: "Failed to add log to chain"}
+			}
+		} else {
+			// A batch is appended contiguously under the chain's write lock
+			first := s.LogChain.IndexOf(appended[0].CurrentHash)
+			for j, entry := range appended {
+				i, index := positions[j], first+j
+				claims[i].complete(entry)
+				results[i] = batchResult{Status: 201, Index: &index, Entry: entry, Receipt: s.issueReceipt(index, entry, req.Entries[i].RequestID)}
+				logClockFlags(index, req.Entries[i].AgentID, entry)
+			}
+		}
+	}
+
+	// Repeats within the batch share the first submission's outcome
+	for i, j := range repeats {
+		results[i] = results[j]
+		if results[j].Status == 201 {
+			results[i].Status = 200
+		}
+	}
+
+	accepted := 0
+	for _, result := range results {
+		if result.Status == 200 || result.Status == 201 {
+			accepted++
+		}
+	}
+	status := 201
+	if accepted < len(results) {
+		status = 207
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"success":      accepted == len(results),
+		"results":      results,
+		"accepted":     accepted,
+		"rejected":     len(results) - accepted,
+		"chain_length": s.LogChain.Length(),
+	})
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/amshithnair/zcrypt/crypto"
+	"github.com/amshithnair/zcrypt/utils"
+)
+
+// batchStatuses returns the per-item statuses of a batch response
+func batchStatuses(result map[string]interface{}) []int {
+	var statuses []int
+	for _, item := range result["results"].([]interface{}) {
+		statuses = append(statuses, int(item.(map[string]interface{})["status"].(float64)))
+	}
+	return statuses
+}
+
+// batchIndex returns the chain index reported for item i of a batch response
+func batchIndex(result map[string]interface{}, i int) interface{} {
+	return result["results"].([]interface{})[i].(map[string]interface{})["index"]
+}
+
+func TestSubmitBatchStatuses(t *testing.T) {
+	s := newTestServer(t)
+	s.MaxBatchSize = 3
+	signer, _ := crypto.GenerateSigner(crypto.AlgEd25519)
+	now := time.Now().UTC()
+
+	// Every submission appended
+	all := []LogRequest{
+		signedRequest(t, s, signer, "Log 0", now),
+		signedRequest(t, s, signer, "Log 1", now),
+	}
+	status, result := post(t, s, "/api/v1/logs/batch", map[string]interface{}{"entries": all})
+	if status != 201 || result["accepted"] != 2.0 || batchIndex(result, 1) != 1.0 {
+		t.Fatalf("Expected 201 with both appended, got %d: %v", status, result)
+	}
+
+	// Valid, forged, incomplete and replayed submissions side by side
+	forged := signedRequest(t, s, signer, "Log 3", now)
+	forged.Message = "Log 3 (edited)"
+	mixed := []LogRequest{signedRequest(t, s, signer, "Log 2", now), forged, {Message: "Log 4"}}
+	status, result = post(t, s, "/api/v1/logs/batch", map[string]interface{}{"entries": mixed})
+	if got, want := batchStatuses(result), []int{201, 401, 400}; status != 207 || !slices.Equal(got, want) {
+		t.Errorf("Expected 207 with statuses %v, got %d: %v", want, status, got)
+	}
+	if result["accepted"] != 1.0 || result["rejected"] != 2.0 || batchIndex(result, 0) != 2.0 {
+		t.Errorf("Expected one entry appended at 2, got %v", result)
+	}
+	status, result = post(t, s, "/api/v1/logs/batch", map[string]interface{}{"entries": all[:1]})
+	if got := batchStatuses(result); status != 207 || got[0] != 409 {
+		t.Errorf("Expected a replay to be rejected with 409, got %d: %v", status, got)
+	}
+
+	// Batches over the limit are refused whole
+	over := []LogRequest{
+		signedRequest(t, s, signer, "Log 5", now),
+		signedRequest(t, s, signer, "Log 6", now),
+		signedRequest(t, s, signer, "Log 7", now),
+		signedRequest(t, s, signer, "Log 8", now),
+	}
+	if status, result := post(t, s, "/api/v1/logs/batch", map[string]interface{}{"entries": over}); status != 413 {
+		t.Errorf("Expected 413, got %d: %v", status, result)
+	}
+	if n := s.LogChain.Length(); n != 3 {
+		t.Errorf("Expected 3 entries, got %d", n)
+	}
+}
+
+func TestSubmitBatchRequestIDs(t *testing.T) {
+	s := newTestServer(t)
+	signer, _ := crypto.GenerateSigner(crypto.AlgEd25519)
+	now := time.Now().UTC()
+	withID := func(message, id string) LogRequest {
+		req := signedRequest(t, s, signer, message, now)
+		req.RequestID = id
+		return req
+	}
+
+	first := withID("Log 0", "id-1")
+	if status, result := post(t, s, "/api/v1/logs/", first); status != 201 {
+		t.Fatalf("Expected 201, got %d: %v", status, result)
+	}
+
+	// A retry of an appended submission reports it; the ID reused for
+	// another submission is refused, in the same request or a later one
+	second := withID("Log 1", "id-2")
+	batch := []LogRequest{first, withID("Log 2", "id-1"), second, second, withID("Log 3", "id-2")}
+	status, result := post(t, s, "/api/v1/logs/batch", map[string]interface{}{"entries": batch})
+	if got, want := batchStatuses(result), []int{200, 422, 201, 200, 422}; status != 207 || !slices.Equal(got, want) {
+		t.Fatalf("Expected 207 with statuses %v, got %d: %v", want, status, got)
+	}
+	if batchIndex(result, 0) != 0.0 || batchIndex(result, 2) != 1.0 || batchIndex(result, 3) != 1.0 {
+		t.Errorf("Expected repeats to report the original entries, got %v", result["results"])
+	}
+	if n := s.LogChain.Length(); n != 2 {
+		t.Errorf("Expected 2 entries, got %d", n)
+	}
+}
+
+// flushRecorder collects the batches a BatchSubmitter sends
+type flushRecorder struct {
+	mu      sync.Mutex
+	sizes   []int
+	flushed chan struct{}
+}
+
+func newFlushRecorder() *flushRecorder {
+	return &flushRecorder{flushed: make(chan struct{}, 16)}
+}
+
+func (r *flushRecorder) onFlush(t *testing.T) func([]utils.LogSubmission, []utils.BatchResult, error) {
+	return func(batch []utils.LogSubmission, results []utils.BatchResult, err error) {
+		if err != nil {
+			t.Errorf("Failed to flush: %v", err)
+		}
+		for _, result := range results {
+			if !result.Accepted() {
+				t.Errorf("Expected every submission to be accepted, got %+v", result)
+			}
+		}
+		r.mu.Lock()
+		r.sizes = append(r.sizes, len(batch))
+		r.mu.Unlock()
+		r.flushed <- struct{}{}
+	}
+}
+
+func (r *flushRecorder) batches() []int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]int(nil), r.sizes...)
+}
+
+// queue signs and queues n submissions
+func queue(t *testing.T, client *utils.LogClient, b *utils.BatchSubmitter, n int) {
+	t.Helper()
+	signer, _ := crypto.GenerateSigner(crypto.AlgEd25519)
+	for i := 0; i < n; i++ {
+		submission, err := client.SignSubmission(signer, "agent-1", fmt.Sprintf("Log %d", i), nil)
+		if err != nil {
+			t.Fatalf("Failed to sign submission: %v", err)
+		}
+		if err := b.Submit(submission); err != nil {
+			t.Fatalf("Failed to submit: %v", err)
+		}
+	}
+}
+
+func TestBatchSubmitterFlushesAtSize(t *testing.T) {
+	s, url := startServer(t, "")
+	client := utils.NewLogClient(url)
+	recorder := newFlushRecorder()
+	b := client.NewBatchSubmitter(3, 0, recorder.onFlush(t))
+
+	queue(t, client, b, 7)
+	if got := recorder.batches(); !slices.Equal(got, []int{3, 3}) {
+		t.Errorf("Expected two full batches sent while queueing, got %v", got)
+	}
+	if err := b.Close(); err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+	if got := recorder.batches(); !slices.Equal(got, []int{3, 3, 1}) {
+		t.Errorf("Expected Close to send the rest, got %v", got)
+	}
+	if n := s.LogChain.Length(); n != 7 {
+		t.Errorf("Expected 7 entries, got %d", n)
+	}
+}
+
+func TestBatchSubmitterFlushesOnInterval(t *testing.T) {
+	s, url := startServer(t, "")
+	client := utils.NewLogClient(url)
+	recorder := newFlushRecorder()
+	b := client.NewBatchSubmitter(100, 50*time.Millisecond, recorder.onFlush(t))
+
+	// Neither submission fills a batch, so only the interval sends them
+	queue(t, client, b, 2)
+	for sent := 0; sent < 2; {
+		select {
+		case <-recorder.flushed:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Expected the queue to be sent when the interval passed")
+		}
+		batches := recorder.batches()
+		sent += batches[len(batches)-1]
+	}
+	if n := s.LogChain.Length(); n != 2 {
+		t.Errorf("Expected 2 entries, got %d", n)
+	}
+
+	// Nothing is left to send on close
+	sent := len(recorder.batches())
+	if err := b.Close(); err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+	if got := recorder.batches(); len(got) != sent {
+		t.Errorf("Expected no more batches, got %v", got)
+	}
+}
@@ -0,0 +1,90 @@
+// server/cosign.go
+package main
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/amshithnair/zcrypt/crypto"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Append a co-signature for an entry that requires approvals
+func (s *Server) coSignLog(c *fiber.Ctx) error {
+	type CoSignRequest struct {
+		Signature string `json:"signature"`
+		PubKey    string `json:"pubkey"`
+		Algorithm string `json:"algorithm,omitempty"`
+		AgentID   string `json:"agent_id,omitempty"`
+	}
+
+	index, err := indexParam(c)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid log ID - must be a number",
+		})
+	}
+
+	var req CoSignRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if req.Signature == "" || req.PubKey == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Missing required fields: signature, pubkey",
+		})
+	}
+
+	entry, err := s.LogChain.AddCoSignature(index, req.Signature, req.PubKey, crypto.NormalizeAlgorithm(req.Algorithm), map[string]interface{}{
+		"agent_id":        req.AgentID,
+		"server_received": time.Now().UTC(),
+	})
+	switch {
+	case errors.Is(err, crypto.ErrNoEntry):
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Log entry not found",
+		})
+	case errors.Is(err, crypto.ErrCoSignRejected):
+		// Policy and signature errors describe the request, not the server
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case err != nil:
+		log.Printf("Failed to record co-signature for entry %d: %v", index, err)
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to record co-signature",
+		})
+	}
+
+	status, _ := s.LogChain.CoSignStatus(index)
+
+	return c.Status(201).JSON(fiber.Map{
+		"success": true,
+		"entry":   entry,
+		"status":  status,
+	})
+}
+
+// Report whether an entry's co-sign threshold is met and by whom
+func (s *Server) getCoSignStatus(c *fiber.Ctx) error {
+	index, err := indexParam(c)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid log ID - must be a number",
+		})
+	}
+
+	status, err := s.LogChain.CoSignStatus(index)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": status,
+	})
+}
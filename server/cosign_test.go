@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/amshithnair/zcrypt/crypto"
+)
+
+// breakChain makes every chain write fail until the returned function runs
+func breakChain(t *testing.T, s *Server) func() {
+	t.Helper()
+	blocker := filepath.Join(t.TempDir(), "not-a-directory")
+	if err := os.WriteFile(blocker, nil, 0600); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	path := s.LogChain.FilePath
+	s.LogChain.FilePath = filepath.Join(blocker, "server_logs.chain")
+	return func() { s.LogChain.FilePath = path }
+}
+
+func TestCoSignStatuses(t *testing.T) {
+	s := newTestServer(t)
+	author, _ := crypto.GenerateSigner(crypto.AlgEd25519)
+	reviewers := make([]crypto.Signer, 2)
+	policy := &crypto.CoSignPolicy{Threshold: 2}
+	for i := range reviewers {
+		reviewers[i], _ = crypto.GenerateSigner(crypto.AlgEd25519)
+		policy.Signers = append(policy.Signers, hex.EncodeToString(reviewers[i].PublicKey()))
+	}
+
+	env, _ := crypto.NewEnvelope("agent-1", s.ChainName, nil)
+	env.CoSign = policy
+	sig, _ := crypto.SignEnvelope(author, "Deploy v2", env)
+	status, result := post(t, s, "/api/v1/logs/", LogRequest{
+		Message:   "Deploy v2",
+		Signature: sig,
+		PubKey:    hex.EncodeToString(author.PublicKey()),
+		AgentID:   "agent-1",
+		Envelope:  env,
+	})
+	if status != 201 {
+		t.Fatalf("Expected 201, got %d: %v", status, result)
+	}
+	hash := result["entry"].(map[string]interface{})["current_hash"].(string)
+
+	cosign := func(signer crypto.Signer, index int) (int, map[string]interface{}) {
+		sig, _ := crypto.SignCoSignature(signer, index, hash)
+		return post(t, s, fmt.Sprintf("/api/v1/logs/%d/cosign", index), map[string]string{
+			"signature": sig,
+			"pubkey":    hex.EncodeToString(signer.PublicKey()),
+		})
+	}
+	if status, result := cosign(reviewers[0], 5); status != 404 {
+		t.Errorf("Expected 404 for a missing entry, got %d: %v", status, result)
+	}
+	if status, result := cosign(author, 0); status != 400 {
+		t.Errorf("Expected 400 for a key outside the policy, got %d: %v", status, result)
+	}
+	if status, result := cosign(reviewers[0], 0); status != 201 {
+		t.Fatalf("Expected 201, got %d: %v", status, result)
+	}
+
+	// A valid co-signature the server cannot save is its own error, and the
+	// cause is not sent to the client
+	repair := breakChain(t, s)
+	status, result = cosign(reviewers[1], 0)
+	if status != 500 || result["error"] != "Failed to record co-signature" {
+		t.Errorf("Expected 500 with a generic error, got %d: %v", status, result)
+	}
+	repair()
+	if status, result := cosign(reviewers[1], 0); status != 201 {
+		t.Errorf("Expected the retry to be accepted, got %d: %v", status, result)
+	}
+}
@@ -0,0 +1,47 @@
+// server/cursor.go
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+
+	"github.com/amshithnair/zcrypt/crypto"
+)
+
+// Page sizes for log listings, the maximum overridden by ZCRYPT_MAX_PAGE_SIZE
+const (
+	DefaultPageSize    = 100
+	DefaultMaxPageSize = 1000
+)
+
+// pageCursor is where a listing continues: the index to read from, or to
+// read back from, and the head the listing was first served from. Clients
+// treat it as opaque.
+type pageCursor struct {
+	Index    int              `json:"i"`
+	Head     crypto.ChainHead `json:"h"`
+	Backward bool             `json:"b,omitempty"` // Read the entries before Index
+}
+
+// encode returns the cursor as a URL-safe token
+func (pc pageCursor) encode() string {
+	data, _ := json.Marshal(pc)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor parses a token made by encode
+func decodeCursor(token string) (pageCursor, error) {
+	var pc pageCursor
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return pc, err
+	}
+	if err := json.Unmarshal(data, &pc); err != nil {
+		return pc, err
+	}
+	if pc.Index < 0 || pc.Head.Length < 0 || pc.Head.Hash == "" {
+		return pc, errors.New("malformed cursor")
+	}
+	return pc, nil
+}
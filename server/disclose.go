@@ -0,0 +1,55 @@
+// server/disclose.go
+package main
+
+import (
+	"time"
+
+	"github.com/amshithnair/zcrypt/crypto"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Reveal the withheld message of a confidential entry. The message and salt
+// must open the entry's commitment, and the entry's own key must consent.
+func (s *Server) discloseLog(c *fiber.Ctx) error {
+	type DiscloseRequest struct {
+		TargetHash string `json:"target_hash"`
+		Message    string `json:"message"`
+		Salt       string `json:"salt"`
+		Signature  string `json:"signature"`
+	}
+
+	index, err := indexParam(c)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid log ID - must be a number",
+		})
+	}
+
+	var req DiscloseRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if req.Salt == "" || req.Signature == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Missing required fields: salt, signature",
+		})
+	}
+
+	ref := crypto.DisclosureRef{TargetIndex: index, TargetHash: req.TargetHash}
+	entry, err := s.LogChain.Disclose(ref, req.Message, req.Salt, req.Signature, map[string]interface{}{
+		"server_received": time.Now().UTC(),
+	})
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(201).JSON(fiber.Map{
+		"success": true,
+		"entry":   entry,
+		"index":   s.LogChain.IndexOf(entry.CurrentHash),
+	})
+}
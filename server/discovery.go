@@ -0,0 +1,58 @@
+// server/discovery.go
+package main
+
+import (
+	"time"
+
+	"github.com/amshithnair/zcrypt/crypto"
+	"github.com/gofiber/fiber/v2"
+)
+
+// apiVersion is the version of the API served under /api/<version>
+const apiVersion = "v1"
+
+// Describe the server in a document signed by its identity key, so clients
+// can learn and pin the key along with the API and algorithms it supports
+func (s *Server) getDiscovery(c *fiber.Ctx) error {
+	doc := crypto.Discovery{
+		APIVersion: apiVersion,
+		APIBase:    "/api/" + apiVersion,
+		Chains: []crypto.DiscoveryChain{
+			{Name: s.ChainName, HashAlgorithm: s.LogChain.HashAlgorithm()},
+		},
+		SignatureAlgorithms: crypto.SupportedAlgorithms(),
+		HashAlgorithms:      crypto.SupportedHashAlgorithms(),
+		Features:            s.serverFeatures(),
+		IssuedAt:            time.Now().UTC(),
+	}
+	if err := doc.Sign(s.Identity); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to sign discovery document",
+		})
+	}
+	return c.JSON(doc)
+}
+
+// serverFeatures lists the optional capabilities this server has enabled
+func (s *Server) serverFeatures() []string {
+	features := []string{"batch", "stream", "receipts", "inclusion-proofs"}
+	if s.Keyring != nil {
+		features = append(features, "encryption-at-rest")
+	}
+	if s.AdminToken != "" {
+		features = append(features, "admin")
+	}
+	if s.TSAURL != "" {
+		features = append(features, "checkpoints")
+	}
+	if s.ArchiveDir != "" {
+		features = append(features, "archive")
+	}
+	if s.TSA != nil {
+		features = append(features, "tsa")
+	}
+	if s.Replicator != nil {
+		features = append(features, "follower")
+	}
+	return features
+}
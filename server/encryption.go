@@ -0,0 +1,110 @@
+// server/encryption.go
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/amshithnair/zcrypt/crypto"
+	"github.com/gofiber/fiber/v2"
+)
+
+// loadKeyring builds the at-rest keyring from ZCRYPT_MASTER_KEY or
+// ZCRYPT_MASTER_KEY_FILE, plus any retired keys in ZCRYPT_OLD_MASTER_KEY_FILES.
+// It returns nil when encryption at rest is not configured.
+func loadKeyring() (*crypto.Keyring, error) {
+	var master []byte
+	var err error
+	switch {
+	case os.Getenv("ZCRYPT_MASTER_KEY") != "":
+		master, err = crypto.ParseMasterKey([]byte(os.Getenv("ZCRYPT_MASTER_KEY")))
+	case os.Getenv("ZCRYPT_MASTER_KEY_FILE") != "":
+		master, err = crypto.LoadMasterKeyFile(os.Getenv("ZCRYPT_MASTER_KEY_FILE"))
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var older [][]byte
+	for _, path := range strings.Split(os.Getenv("ZCRYPT_OLD_MASTER_KEY_FILES"), ",") {
+		if path = strings.TrimSpace(path); path == "" {
+			continue
+		}
+		key, err := crypto.LoadMasterKeyFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("old master key %s: %w", path, err)
+		}
+		older = append(older, key)
+	}
+
+	return crypto.NewKeyring(master, older...)
+}
+
+// canReadPlaintext reports whether the request may see decrypted entries
+func (s *Server) canReadPlaintext(c *fiber.Ctx) bool {
+	return hasBearer(c, s.ReadToken) || hasBearer(c, s.AdminToken)
+}
+
+// presentEntries decrypts entries for authorized readers and leaves them
+// sealed for everyone else
+func (s *Server) presentEntries(c *fiber.Ctx, entries []crypto.LogEntry) []crypto.LogEntry {
+	if !s.canReadPlaintext(c) {
+		return entries
+	}
+
+	result := make([]crypto.LogEntry, len(entries))
+	for i, entry := range entries {
+		result[i] = s.presentEntry(c, entry)
+	}
+	return result
+}
+
+func (s *Server) presentEntry(c *fiber.Ctx, entry crypto.LogEntry) crypto.LogEntry {
+	if !entry.IsSealed() || !s.canReadPlaintext(c) {
+		return entry
+	}
+	opened, err := s.LogChain.Open(entry)
+	if err != nil {
+		return entry
+	}
+	return opened
+}
+
+// openEntries decrypts what it can, for server-internal use
+func openEntries(chain *crypto.LogChain, entries []crypto.LogEntry) []crypto.LogEntry {
+	result := make([]crypto.LogEntry, len(entries))
+	for i, entry := range entries {
+		opened, err := chain.Open(entry)
+		if err != nil {
+			opened = entry
+		}
+		result[i] = opened
+	}
+	return result
+}
+
+// Rewrap all data keys under the current master key
+func (s *Server) rekeyChain(c *fiber.Ctx) error {
+	if s.Keyring == nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Encryption at rest is not enabled",
+		})
+	}
+
+	count, err := s.LogChain.Rekey()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error":     err.Error(),
+			"rewrapped": count,
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success":   true,
+		"rewrapped": count,
+		"key_id":    s.Keyring.CurrentKeyID(),
+	})
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/amshithnair/zcrypt/crypto"
+	"github.com/gofiber/fiber/v2"
+)
+
+// runSealing closes the current epoch every interval, skipping epochs
+// that have no entries yet
+func (s *Server) runSealing(interval time.Duration) {
+	for range time.Tick(interval) {
+		seal, err := s.LogChain.SealEpoch(s.Identity, "")
+		if errors.Is(err, crypto.ErrEmptyEpoch) {
+			continue
+		}
+		if err != nil {
+			log.Printf("⚠️  Epoch sealing failed: %v", err)
+			continue
+		}
+		log.Printf("🔒 Sealed epoch %d (%d entries)", seal.Closes.Epoch, seal.Closes.Count)
+	}
+}
+
+// List every epoch with its entry count and seal
+func (s *Server) getEpochs(c *fiber.Ctx) error {
+	epochs := s.LogChain.Epochs()
+
+	return c.JSON(fiber.Map{
+		"epochs": epochs,
+		"count":  len(epochs),
+	})
+}
+
+// Seal the current epoch with the server identity key and open the next
+func (s *Server) sealEpoch(c *fiber.Ctx) error {
+	type SealRequest struct {
+		HashAlgorithm string `json:"hash_algorithm,omitempty"` // Defaults to the current algorithm
+	}
+
+	var req SealRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+	}
+
+	seal, err := s.LogChain.SealEpoch(s.Identity, req.HashAlgorithm)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	epochs := s.LogChain.Epochs()
+	return c.Status(201).JSON(fiber.Map{
+		"success": true,
+		"seal":    seal,
+		"epoch":   epochs[len(epochs)-1],
+	})
+}
+
+// Verify a single epoch on its own
+func (s *Server) verifyEpoch(c *fiber.Ctx) error {
+	number, err := indexParam(c)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid epoch number - must be a number",
+		})
+	}
+
+	report, err := s.LogChain.VerifyEpoch(number)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(report)
+}
+
+// Export the kept entries of a single epoch
+func (s *Server) getEpochEntries(c *fiber.Ctx) error {
+	number, err := indexParam(c)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid epoch number - must be a number",
+		})
+	}
+
+	entries, err := s.LogChain.EpochEntries(number)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"epoch":   number,
+		"entries": entries,
+		"count":   len(entries),
+	})
+}
@@ -0,0 +1,233 @@
+// server/idempotency.go
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/amshithnair/zcrypt/crypto"
+)
+
+// Defaults for the request ID index, overridden by ZCRYPT_REQUEST_ID_TTL and
+// ZCRYPT_REQUEST_ID_LIMIT
+const (
+	DefaultRequestIDTTL   = 24 * time.Hour
+	DefaultRequestIDLimit = 100000
+)
+
+// MaxRequestIDLength bounds client-supplied request IDs
+const MaxRequestIDLength = 128
+
+// requestState is what the index knows about a request ID
+type requestState int
+
+const (
+	requestNew      requestState = iota // Unseen; now reserved for the caller
+	requestDone                         // Appended before; the original entry's hash is known
+	requestPending                      // Another call with the ID is still being processed
+	requestConflict                     // The ID was used for a different submission
+)
+
+// requestRecord is one remembered request ID
+type requestRecord struct {
+	signature string    // Signature of the submission that used the ID
+	hash      string    // Hash of the appended entry, empty while pending
+	at        time.Time // When the entry was received
+	serial    uint64    // Position in the eviction order
+}
+
+// RequestIndex remembers which entry each agent's request ID produced, so a
+// retried submission returns the original entry instead of appending a
+// duplicate. IDs are scoped to the submitting key. The index keeps at most
+// limit IDs for at most ttl. It is durable because accepted IDs are stored in
+// their entries' metadata and read back by Seed on startup.
+type RequestIndex struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	limit   int
+	records map[string]*requestRecord // pubkey|request ID -> record
+	order   []requestKey              // Oldest first; may hold stale serials
+	serial  uint64
+}
+
+type requestKey struct {
+	key    string
+	serial uint64
+}
+
+// NewRequestIndex creates an index keeping up to limit IDs for ttl
+func NewRequestIndex(ttl time.Duration, limit int) *RequestIndex {
+	return &RequestIndex{
+		ttl:     ttl,
+		limit:   limit,
+		records: make(map[string]*requestRecord),
+	}
+}
+
+// Begin looks up a request ID. An unseen ID is reserved until Complete or
+// Abort; for an ID used before, the original entry's hash is returned.
+func (ri *RequestIndex) Begin(pubKey, requestID, signature string) (string, requestState) {
+	ri.mu.Lock()
+	defer ri.mu.Unlock()
+
+	now := time.Now().UTC()
+	ri.evict(now)
+
+	key := scopedKey(pubKey, requestID)
+	if record, ok := ri.records[key]; ok {
+		switch {
+		case record.signature != canonicalHex(signature):
+			return "", requestConflict
+		case record.hash == "":
+			return "", requestPending
+		default:
+			return record.hash, requestDone
+		}
+	}
+	ri.add(key, &requestRecord{signature: canonicalHex(signature), at: now})
+	ri.evict(now)
+	return "", requestNew
+}
+
+// Complete records the entry a reserved request ID produced
+func (ri *RequestIndex) Complete(pubKey, requestID string, entry *crypto.LogEntry) {
+	ri.mu.Lock()
+	defer ri.mu.Unlock()
+
+	if record, ok := ri.records[scopedKey(pubKey, requestID)]; ok {
+		record.hash = entry.CurrentHash
+		record.at = entry.Timestamp
+	}
+}
+
+// Abort releases a reserved request ID whose submission was not appended, so
+// it can be retried
+func (ri *RequestIndex) Abort(pubKey, requestID string) {
+	ri.mu.Lock()
+	defer ri.mu.Unlock()
+
+	key := scopedKey(pubKey, requestID)
+	if record, ok := ri.records[key]; ok && record.hash == "" {
+		delete(ri.records, key)
+	}
+}
+
+// Seed loads request IDs recorded in recent chain entries, so a restart does
+// not forget them
+func (ri *RequestIndex) Seed(entries []crypto.LogEntry) {
+	ri.mu.Lock()
+	defer ri.mu.Unlock()
+
+	cutoff := time.Now().UTC().Add(-ri.ttl)
+	for _, entry := range entries {
+		requestID, _ := entry.Metadata["request_id"].(string)
+		if requestID == "" || entry.Timestamp.Before(cutoff) {
+			continue
+		}
+		ri.add(scopedKey(entry.PubKey, requestID), &requestRecord{
+			signature: canonicalHex(entry.Signature),
+			hash:      entry.CurrentHash,
+			at:        entry.Timestamp,
+		})
+	}
+	ri.evict(time.Now().UTC())
+}
+
+// Len returns the number of remembered request IDs
+func (ri *RequestIndex) Len() int {
+	ri.mu.Lock()
+	defer ri.mu.Unlock()
+	return len(ri.records)
+}
+
+// add stores a record as the newest; callers hold the lock
+func (ri *RequestIndex) add(key string, record *requestRecord) {
+	ri.serial++
+	record.serial = ri.serial
+	ri.records[key] = record
+	ri.order = append(ri.order, requestKey{key: key, serial: ri.serial})
+}
+
+// evict drops expired IDs, then the oldest ones beyond the limit. Pending
+// IDs expire too, which only costs a retry its deduplication. Callers hold
+// the lock.
+func (ri *RequestIndex) evict(now time.Time) {
+	cutoff := now.Add(-ri.ttl)
+	drop := 0
+	for ; drop < len(ri.order); drop++ {
+		front := ri.order[drop]
+		record, ok := ri.records[front.key]
+		if !ok || record.serial != front.serial {
+			continue // Aborted or re-added since
+		}
+		if len(ri.records) <= ri.limit && !record.at.Before(cutoff) {
+			break
+		}
+		delete(ri.records, front.key)
+	}
+	ri.order = ri.order[drop:]
+
+	// Aborted IDs leave stale slots behind the oldest live one
+	if len(ri.order) > 2*len(ri.records)+64 {
+		live := ri.order[:0]
+		for _, k := range ri.order {
+			if record, ok := ri.records[k.key]; ok && record.serial == k.serial {
+				live = append(live, k)
+			}
+		}
+		ri.order = live
+	}
+}
+
+// requestClaim is a submission's hold on its request ID. Original is set
+// when the ID was used before, and the submission must not be appended again.
+type requestClaim struct {
+	requests  *RequestIndex
+	pubKey    string
+	requestID string
+	Original  *crypto.LogEntry
+	Index     int
+}
+
+// beginRequest checks a submission's request ID against the index. It
+// returns nil when the submission has no request ID.
+func (s *Server) beginRequest(req *LogRequest) (*requestClaim, *rejection) {
+	if req.RequestID == "" || req.PubKey == "" || req.Signature == "" {
+		return nil, nil
+	}
+	if len(req.RequestID) > MaxRequestIDLength {
+		return nil, reject(400, fmt.Sprintf("request_id is longer than %d characters", MaxRequestIDLength))
+	}
+
+	claim := &requestClaim{requests: s.Requests, pubKey: req.PubKey, requestID: req.RequestID}
+	hash, state := s.Requests.Begin(req.PubKey, req.RequestID, req.Signature)
+	switch state {
+	case requestConflict:
+		return nil, reject(422, "request_id was already used for a different submission")
+	case requestPending:
+		return nil, reject(503, "A submission with this request_id is still being processed")
+	case requestDone:
+		claim.Index = s.LogChain.IndexOf(hash)
+		original, err := s.LogChain.GetEntry(claim.Index)
+		if err != nil {
+			return nil, reject(409, "request_id was already processed, but its entry is no longer available")
+		}
+		claim.Original = original
+	}
+	return claim, nil
+}
+
+// complete records the entry appended for the claimed request ID
+func (rc *requestClaim) complete(entry *crypto.LogEntry) {
+	if rc != nil && rc.Original == nil {
+		rc.requests.Complete(rc.pubKey, rc.requestID, entry)
+	}
+}
+
+// abort releases the claimed request ID after the submission failed
+func (rc *requestClaim) abort() {
+	if rc != nil && rc.Original == nil {
+		rc.requests.Abort(rc.pubKey, rc.requestID)
+	}
+}
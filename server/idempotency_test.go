@@ -0,0 +1,253 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/amshithnair/zcrypt/crypto"
+	"github.com/amshithnair/zcrypt/utils"
+)
+
+func TestFailedAppendCanBeRetried(t *testing.T) {
+	s := newTestServer(t)
+	signer, _ := crypto.GenerateSigner(crypto.AlgEd25519)
+	single := signedRequest(t, s, signer, "user alice logged in", time.Now().UTC())
+	single.RequestID = "retry-single"
+	batched := signedRequest(t, s, signer, "user bob logged in", time.Now().UTC())
+	batched.RequestID = "retry-batch"
+	batch := map[string]interface{}{"entries": []LogRequest{batched}}
+
+	repair := breakChain(t, s)
+	if status, _ := post(t, s, "/api/v1/logs/", single); status != 500 {
+		t.Fatalf("Expected 500 while the chain cannot be written, got %d", status)
+	}
+	status, result := post(t, s, "/api/v1/logs/batch", batch)
+	if item := result["results"].([]interface{})[0].(map[string]interface{}); status != 207 || item["status"] != 500.0 {
+		t.Fatalf("Expected 207 with a 500 result, got %d: %v", status, result)
+	}
+	repair()
+
+	// Nothing was appended, so the same submissions are not replays
+	if status, result := post(t, s, "/api/v1/logs/", single); status != 201 {
+		t.Errorf("Expected the retry to be appended with 201, got %d: %v", status, result)
+	}
+	if status, result := post(t, s, "/api/v1/logs/batch", batch); status != 201 {
+		t.Errorf("Expected the batch retry to be appended with 201, got %d: %v", status, result)
+	}
+	if n := s.LogChain.Length(); n != 2 {
+		t.Errorf("Expected 2 entries, got %d", n)
+	}
+}
+
+func TestRequestIndexLifecycle(t *testing.T) {
+	ri := NewRequestIndex(time.Hour, 10)
+
+	if _, state := ri.Begin("abcd", "req-1", "sig-1"); state != requestNew {
+		t.Fatalf("Expected a new request ID, got %v", state)
+	}
+	if _, state := ri.Begin("abcd", "req-1", "sig-1"); state != requestPending {
+		t.Errorf("Expected the reserved ID to be pending, got %v", state)
+	}
+	if _, state := ri.Begin("abcd", "req-1", "sig-2"); state != requestConflict {
+		t.Errorf("Expected another submission with the ID to conflict, got %v", state)
+	}
+	if _, state := ri.Begin("ef01", "req-1", "sig-3"); state != requestNew {
+		t.Errorf("Expected IDs to be scoped to their key, got %v", state)
+	}
+
+	ri.Complete("abcd", "req-1", &crypto.LogEntry{CurrentHash: "hash-1", Timestamp: time.Now().UTC()})
+	if hash, state := ri.Begin("abcd", "req-1", "sig-1"); state != requestDone || hash != "hash-1" {
+		t.Errorf("Expected the completed ID to return its entry hash, got %q %v", hash, state)
+	}
+	ri.Abort("abcd", "req-1")
+	if _, state := ri.Begin("abcd", "req-1", "sig-1"); state != requestDone {
+		t.Errorf("Expected Abort to keep a completed ID, got %v", state)
+	}
+
+	// An aborted ID can be used again
+	ri.Begin("abcd", "req-2", "sig-4")
+	ri.Abort("abcd", "req-2")
+	if _, state := ri.Begin("abcd", "req-2", "sig-5"); state != requestNew {
+		t.Errorf("Expected an aborted ID to be free, got %v", state)
+	}
+}
+
+func TestRequestIndexExpiry(t *testing.T) {
+	ri := NewRequestIndex(time.Minute, 10)
+	ri.Begin("abcd", "old", "sig-1")
+	ri.Complete("abcd", "old", &crypto.LogEntry{CurrentHash: "hash-1", Timestamp: time.Now().UTC().Add(-2 * time.Minute)})
+	ri.Begin("abcd", "recent", "sig-2")
+	ri.Complete("abcd", "recent", &crypto.LogEntry{CurrentHash: "hash-2", Timestamp: time.Now().UTC()})
+
+	if _, state := ri.Begin("abcd", "old", "sig-1"); state != requestNew {
+		t.Errorf("Expected an ID older than the TTL to be forgotten, got %v", state)
+	}
+	if _, state := ri.Begin("abcd", "recent", "sig-2"); state != requestDone {
+		t.Errorf("Expected a recent ID to be remembered, got %v", state)
+	}
+}
+
+func TestRequestIndexLimit(t *testing.T) {
+	ri := NewRequestIndex(time.Hour, 2)
+	for i := 1; i <= 3; i++ {
+		ri.Begin("abcd", fmt.Sprintf("req-%d", i), "sig")
+	}
+	if n := ri.Len(); n != 2 {
+		t.Errorf("Expected the index to hold its limit of 2 IDs, got %d", n)
+	}
+	if _, state := ri.Begin("abcd", "req-1", "other"); state != requestNew {
+		t.Errorf("Expected the oldest ID to be evicted, got %v", state)
+	}
+	if _, state := ri.Begin("abcd", "req-3", "other"); state != requestConflict {
+		t.Errorf("Expected the newest ID to be kept, got %v", state)
+	}
+
+	// Aborted IDs leave slots in the eviction order that are compacted
+	// instead of growing without bound
+	ri = NewRequestIndex(time.Hour, 1000)
+	ri.Begin("abcd", "live", "sig")
+	for i := 0; i < 1000; i++ {
+		id := fmt.Sprintf("aborted-%d", i)
+		ri.Begin("abcd", id, "sig")
+		ri.Abort("abcd", id)
+	}
+	if ri.Len() != 1 || len(ri.order) > 2*ri.Len()+64+1 {
+		t.Errorf("Expected stale slots to be compacted, got %d records and %d slots", ri.Len(), len(ri.order))
+	}
+	if _, state := ri.Begin("abcd", "live", "other"); state != requestConflict {
+		t.Errorf("Expected compaction to keep the live ID, got %v", state)
+	}
+}
+
+func TestRequestIDsSurviveRestart(t *testing.T) {
+	s := newTestServer(t)
+	signer, _ := crypto.GenerateSigner(crypto.AlgEd25519)
+	req := signedRequest(t, s, signer, "user alice logged in", time.Now().UTC())
+	req.RequestID = "restart-1"
+	if status, result := post(t, s, "/api/v1/logs/", req); status != 201 {
+		t.Fatalf("Expected 201, got %d: %v", status, result)
+	}
+	// An ID in the submitted metadata never went through the index
+	claimed := signedRequest(t, s, signer, "user bob logged in", time.Now().UTC())
+	claimed.Metadata = map[string]interface{}{"request_id": "restart-2"}
+	if status, result := post(t, s, "/api/v1/logs/", claimed); status != 201 {
+		t.Fatalf("Expected 201, got %d: %v", status, result)
+	}
+
+	// A new server reads the chain and seeds its index, as main does
+	chain, err := crypto.NewLogChain(s.ChainPath)
+	if err != nil {
+		t.Fatalf("Failed to reload chain: %v", err)
+	}
+	restarted := NewServer(chain, s.Identity)
+	restarted.Requests.Seed(openEntries(chain, chain.Entries))
+
+	status, result := post(t, restarted, "/api/v1/logs/", req)
+	if status != 200 || result["duplicate"] != true || result["index"] != 0.0 {
+		t.Errorf("Expected the original entry as a duplicate, got %d: %v", status, result)
+	}
+	other := signedRequest(t, restarted, signer, "user carol logged in", time.Now().UTC())
+	other.RequestID = "restart-2"
+	if status, result := post(t, restarted, "/api/v1/logs/", other); status != 201 {
+		t.Errorf("Expected an ID only claimed in metadata to be free, got %d: %v", status, result)
+	}
+	if n := chain.Length(); n != 3 {
+		t.Errorf("Expected 3 entries, got %d", n)
+	}
+}
+
+func TestRequestIDConflicts(t *testing.T) {
+	s := newTestServer(t)
+	signer, _ := crypto.GenerateSigner(crypto.AlgEd25519)
+	first := signedRequest(t, s, signer, "user alice logged in", time.Now().UTC())
+	first.RequestID = "conflict-1"
+	if status, result := post(t, s, "/api/v1/logs/", first); status != 201 {
+		t.Fatalf("Expected 201, got %d: %v", status, result)
+	}
+
+	other := signedRequest(t, s, signer, "user bob logged in", time.Now().UTC())
+	other.RequestID = first.RequestID
+	if status, result := post(t, s, "/api/v1/logs/", other); status != 422 {
+		t.Errorf("Expected a reused ID to be rejected with 422, got %d: %v", status, result)
+	}
+
+	// An ID whose first submission is still being processed
+	pending := signedRequest(t, s, signer, "user carol logged in", time.Now().UTC())
+	pending.RequestID = "pending-1"
+	s.Requests.Begin(pending.PubKey, pending.RequestID, pending.Signature)
+	if status, result := post(t, s, "/api/v1/logs/", pending); status != 503 {
+		t.Errorf("Expected a pending ID to be answered with 503, got %d: %v", status, result)
+	}
+	if n := s.LogChain.Length(); n != 1 {
+		t.Errorf("Expected 1 entry, got %d", n)
+	}
+}
+
+func TestRequestIDRepeatedInBatch(t *testing.T) {
+	s := newTestServer(t)
+	signer, _ := crypto.GenerateSigner(crypto.AlgEd25519)
+	req := signedRequest(t, s, signer, "user alice logged in", time.Now().UTC())
+	req.RequestID = "batch-1"
+
+	status, result := post(t, s, "/api/v1/logs/batch", map[string]interface{}{"entries": []LogRequest{req, req}})
+	if status != 201 {
+		t.Fatalf("Expected 201, got %d: %v", status, result)
+	}
+	results := result["results"].([]interface{})
+	first, second := results[0].(map[string]interface{}), results[1].(map[string]interface{})
+	if first["status"] != 201.0 || second["status"] != 200.0 || first["index"] != second["index"] {
+		t.Errorf("Expected the repeat to report the first entry with 200, got %v", results)
+	}
+	if n := s.LogChain.Length(); n != 1 {
+		t.Errorf("Expected 1 entry, got %d", n)
+	}
+}
+
+func TestClientRetryAfterTimeoutIsDuplicate(t *testing.T) {
+	_, serverURL := startServer(t, "")
+	target, _ := url.Parse(serverURL)
+	proxy := httputil.NewSingleHostReverseProxy(target)
+
+	// The first reply is held back until the client has given up on it,
+	// after the server appended the entry
+	var calls atomic.Int32
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reply := httptest.NewRecorder()
+		proxy.ServeHTTP(reply, r)
+		if calls.Add(1) == 1 {
+			time.Sleep(500 * time.Millisecond)
+		}
+		for key, values := range reply.Header() {
+			w.Header()[key] = values
+		}
+		w.WriteHeader(reply.Code)
+		w.Write(reply.Body.Bytes())
+	}))
+	defer slow.Close()
+
+	client := utils.NewLogClient(slow.URL)
+	client.Client.Timeout = 200 * time.Millisecond
+	client.RetryDelay = 10 * time.Millisecond
+	signer, _ := crypto.GenerateSigner(crypto.AlgEd25519)
+	submission, err := client.SignSubmission(signer, "agent-1", "user alice logged in", nil)
+	if err != nil {
+		t.Fatalf("Failed to sign submission: %v", err)
+	}
+
+	resp, err := client.SubmitLog(submission)
+	if err != nil {
+		t.Fatalf("Expected the retry to succeed, got %v", err)
+	}
+	if !resp.Duplicate || resp.Index != 0 || calls.Load() != 2 {
+		t.Errorf("Expected the retry to return the original entry as a duplicate, got %+v after %d calls", resp, calls.Load())
+	}
+	if resp.Receipt == nil {
+		t.Error("Expected a receipt for the original entry")
+	}
+}
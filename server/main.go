@@ -2,11 +2,15 @@
 package main
 
 import (
-	"crypto/ed25519"
+	"crypto/subtle"
 	"encoding/hex"
+	"errors"
+	"fmt"
 	"log"
+	"math"
+	"os"
+	"strconv"
 	"time"
-	"fmt"
 
 	"github.com/amshithnair/zcrypt/crypto"
 	"github.com/gofiber/fiber/v2"
@@ -15,31 +19,234 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/recover"
 )
 
-type ServerConfig struct {
+// Server holds one zcrypt server's chain, identity and settings. Every
+// handler is a method on it, so several servers can run in one process.
+type Server struct {
 	Port       string
 	ChainPath  string
 	LogChain   *crypto.LogChain
-	PubKeyRepo map[string]ed25519.PublicKey // agent_id -> public key
+	PubKeyRepo map[string]AgentKey // agent_id -> registered key
+	AdminToken string              // Bearer token for admin endpoints, disabled when empty
+
+	// Replay protection for signed envelopes
+	ChainName        string        // Target chain name agents must sign for
+	FreshnessWindow  time.Duration // Maximum envelope clock difference
+	AllowUnenveloped bool          // Accept legacy raw-message signatures
+	Nonces           *NonceCache
+	Requests         *RequestIndex // Request IDs of recent submissions, for safe retries
+	MaxBatchSize     int           // Most submissions accepted in one batch request
+	MaxPageSize      int           // Most entries returned by one log listing
+
+	// Encryption at rest
+	Keyring   *crypto.Keyring // Nil when disabled
+	ReadToken string          // Bearer token that may read decrypted entries
+
+	// Retention
+	Identity   crypto.Signer // Server key that signs prune anchors
+	ArchiveDir string        // Where pruned entries are archived, disabled when empty
+
+	// Trusted timestamping
+	TSAURL string      // RFC 3161 TSA that timestamps chain heads, disabled when empty
+	TSA    *crypto.TSA // Built-in TSA for local testing, nil unless enabled
+
+	// Replication
+	Replicator *Replicator // Pulls from the primary, nil on a primary
 }
 
-var config *ServerConfig
+// AgentKey is a registered agent's public key and signature algorithm
+type AgentKey struct {
+	PubKey    string `json:"pubkey"` // hex encoded
+	Algorithm string `json:"algorithm"`
+	Name      string `json:"name,omitempty"`
+	EncPubKey string `json:"enc_pubkey,omitempty"` // hex X25519 key for encrypted messages
+}
 
-func main() {
-	// Initialize server config
-	config = &ServerConfig{
-		Port:       ":8080",
-		ChainPath:  "./server_logs.chain",
-		PubKeyRepo: make(map[string]ed25519.PublicKey),
+// NewServer returns a server for chain with default settings, signing
+// with identity
+func NewServer(chain *crypto.LogChain, identity crypto.Signer) *Server {
+	s := &Server{
+		Port:            ":8080",
+		ChainPath:       chain.FilePath,
+		LogChain:        chain,
+		PubKeyRepo:      make(map[string]AgentKey),
+		ChainName:       crypto.DefaultServerChain,
+		FreshnessWindow: 5 * time.Minute,
+		Requests:        NewRequestIndex(DefaultRequestIDTTL, DefaultRequestIDLimit),
+		MaxBatchSize:    DefaultMaxBatchSize,
+		MaxPageSize:     DefaultMaxPageSize,
+		Identity:        identity,
 	}
+	s.Nonces = NewNonceCache(s.FreshnessWindow)
+	return s
+}
 
-	// Initialize server-side log chain
-	chain, err := crypto.NewLogChain(config.ChainPath)
+func main() {
+	// Initialize server-side log chain. A configured hash algorithm applies
+	// to new chains; existing chains switch algorithms through an explicit
+	// epoch change.
+	chainPath := "./server_logs.chain"
+	hashAlg := getEnv("ZCRYPT_HASH_ALGORITHM", crypto.DefaultHashAlgorithm)
+	chain, err := crypto.NewLogChain(chainPath, crypto.WithHashAlgorithm(hashAlg))
 	if err != nil {
 		log.Fatal("Failed to initialize chain:", err)
 	}
-	config.LogChain = chain
 
-	// Create Fiber app
+	identity, err := loadServerIdentity()
+	if err != nil {
+		log.Fatal("Failed to load server identity key:", err)
+	}
+
+	srv := NewServer(chain, identity)
+	srv.Port = ":" + getEnv("ZCRYPT_PORT", "8080")
+	srv.AdminToken = os.Getenv("ZCRYPT_ADMIN_TOKEN")
+	srv.ChainName = getEnv("ZCRYPT_CHAIN_NAME", crypto.DefaultServerChain)
+	srv.AllowUnenveloped = os.Getenv("ZCRYPT_ALLOW_UNENVELOPED") == "true"
+	srv.ReadToken = os.Getenv("ZCRYPT_READ_TOKEN")
+	srv.ArchiveDir = os.Getenv("ZCRYPT_ARCHIVE_DIR")
+	srv.TSAURL = os.Getenv("ZCRYPT_TSA_URL")
+
+	if window := os.Getenv("ZCRYPT_FRESHNESS_WINDOW"); window != "" {
+		d, err := time.ParseDuration(window)
+		if err != nil {
+			log.Fatal("Invalid ZCRYPT_FRESHNESS_WINDOW:", err)
+		}
+		srv.FreshnessWindow = d
+		srv.Nonces = NewNonceCache(d)
+	}
+	requestTTL, requestLimit := DefaultRequestIDTTL, DefaultRequestIDLimit
+	if ttl := os.Getenv("ZCRYPT_REQUEST_ID_TTL"); ttl != "" {
+		d, err := time.ParseDuration(ttl)
+		if err != nil {
+			log.Fatal("Invalid ZCRYPT_REQUEST_ID_TTL:", err)
+		}
+		requestTTL = d
+	}
+	if limit := os.Getenv("ZCRYPT_REQUEST_ID_LIMIT"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			log.Fatal("Invalid ZCRYPT_REQUEST_ID_LIMIT:", limit)
+		}
+		requestLimit = n
+	}
+	srv.Requests = NewRequestIndex(requestTTL, requestLimit)
+	if size := os.Getenv("ZCRYPT_MAX_BATCH_SIZE"); size != "" {
+		n, err := strconv.Atoi(size)
+		if err != nil || n < 1 {
+			log.Fatal("Invalid ZCRYPT_MAX_BATCH_SIZE:", size)
+		}
+		srv.MaxBatchSize = n
+	}
+	if size := os.Getenv("ZCRYPT_MAX_PAGE_SIZE"); size != "" {
+		n, err := strconv.Atoi(size)
+		if err != nil || n < 1 {
+			log.Fatal("Invalid ZCRYPT_MAX_PAGE_SIZE:", size)
+		}
+		srv.MaxPageSize = n
+	}
+
+	keyring, err := loadKeyring()
+	if err != nil {
+		log.Fatal("Failed to load master key:", err)
+	}
+	if keyring != nil {
+		chain.SetKeyring(keyring)
+		srv.Keyring = keyring
+		log.Printf("🔒 Encryption at rest enabled (key %s)", keyring.CurrentKeyID())
+	}
+
+	recent := openEntries(chain, chain.Entries)
+	srv.Nonces.Seed(recent)
+	srv.Requests.Seed(recent)
+
+	if version := chain.FormatVersion(); version < crypto.FormatVersion {
+		log.Printf("⚠️  Chain file is format version %d; run 'zcrypt chain migrate --file %s' to upgrade", version, chainPath)
+	}
+
+	if primary := os.Getenv("ZCRYPT_PRIMARY_URL"); primary != "" {
+		interval := DefaultReplicationInterval
+		if value := os.Getenv("ZCRYPT_REPLICATION_INTERVAL"); value != "" {
+			if interval, err = time.ParseDuration(value); err != nil || interval <= 0 {
+				log.Fatal("Invalid ZCRYPT_REPLICATION_INTERVAL:", value)
+			}
+		}
+		srv.Replicator = NewReplicator(srv, primary, interval)
+		go srv.Replicator.Run()
+		log.Printf("🔁 Following primary %s every %s (read-only)", primary, interval)
+	}
+
+	// A primary signs its anchors and seals with its identity key, so any
+	// signed by another key fail verification
+	if srv.Replicator == nil {
+		chain.SetAuthority(identity.PublicKey())
+	}
+
+	// Keys in ZCRYPT_REDACTORS may sign redactions from now on; keys already
+	// recorded stay allowed, so earlier redactions keep verifying
+	if redactors := redactorsFromEnv(); len(redactors) > 0 && srv.Replicator == nil {
+		if err := chain.AllowRedactors(redactors); err != nil {
+			log.Fatal("Invalid ZCRYPT_REDACTORS:", err)
+		}
+	}
+
+	// Retention settings from the environment replace the stored policy
+	policy, err := retentionFromEnv()
+	if err != nil {
+		log.Fatal("Invalid retention policy:", err)
+	}
+	if policy != nil && srv.Replicator == nil {
+		if err := chain.SetRetention(*policy); err != nil {
+			log.Fatal("Failed to set retention policy:", err)
+		}
+	}
+	interval := time.Hour
+	if value := os.Getenv("ZCRYPT_PRUNE_INTERVAL"); value != "" {
+		if interval, err = time.ParseDuration(value); err != nil || interval <= 0 {
+			log.Fatal("Invalid ZCRYPT_PRUNE_INTERVAL:", value)
+		}
+	}
+	// A follower's chain changes only by replication, so the maintenance
+	// loops run on the primary alone
+	if srv.Replicator == nil {
+		go srv.runRetention(interval)
+	}
+	if value := os.Getenv("ZCRYPT_EPOCH_SEAL_INTERVAL"); value != "" {
+		sealInterval, err := time.ParseDuration(value)
+		if err != nil || sealInterval <= 0 {
+			log.Fatal("Invalid ZCRYPT_EPOCH_SEAL_INTERVAL:", value)
+		}
+		if srv.Replicator == nil {
+			go srv.runSealing(sealInterval)
+		}
+	}
+
+	if srv.TSA, err = loadBuiltinTSA(); err != nil {
+		log.Fatal("Failed to load built-in TSA:", err)
+	}
+	trusted, err := loadTrustedTSA()
+	if err != nil {
+		log.Fatal("Failed to load trusted TSA certificates:", err)
+	}
+	chain.SetTrustedTSA(trusted)
+	if srv.TSAURL != "" {
+		timestampInterval := time.Hour
+		if value := os.Getenv("ZCRYPT_TIMESTAMP_INTERVAL"); value != "" {
+			if timestampInterval, err = time.ParseDuration(value); err != nil || timestampInterval <= 0 {
+				log.Fatal("Invalid ZCRYPT_TIMESTAMP_INTERVAL:", value)
+			}
+		}
+		if srv.Replicator == nil {
+			go srv.runTimestamping(timestampInterval)
+		}
+	}
+
+	// Start server
+	app := srv.App()
+	log.Printf("🚀 Zcrypt Server starting on http://localhost%s", srv.Port)
+	log.Fatal(app.Listen(srv.Port))
+}
+
+// App returns the Fiber app serving s's API
+func (s *Server) App() *fiber.App {
 	app := fiber.New(fiber.Config{
 		AppName:      "Zcrypt Log Server v1.0",
 		ServerHeader: "Zcrypt",
@@ -56,38 +263,103 @@ func main() {
 	}))
 
 	// Routes
-	setupRoutes(app)
+	s.setupRoutes(app)
+	return app
+}
 
-	// Start server
-	log.Printf("🚀 Zcrypt Server starting on http://localhost%s", config.Port)
-	log.Fatal(app.Listen(config.Port))
+// getEnv reads an environment variable with a fallback
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
 }
 
-func setupRoutes(app *fiber.App) {
-	api := app.Group("/api/v1")
+func (s *Server) setupRoutes(app *fiber.App) {
+	// Signed discovery document
+	app.Get(crypto.DiscoveryPath, s.getDiscovery)
+
+	// Followers serve reads only
+	api := app.Group("/api/"+apiVersion, s.readOnly)
 
 	// Health check
 	api.Get("/health", healthCheck)
 
 	// Log management
 	logs := api.Group("/logs")
-	logs.Post("/", submitLog)
-	logs.Get("/", getLogs)
-	logs.Get("/:id", getLogById)
-	logs.Get("/range", getLogsByRange)
+	logs.Post("/", s.submitLog)
+	logs.Post("/batch", s.submitBatch)
+	logs.Get("/", s.getLogs)
+	logs.Get("/stream", s.streamLogsSSE)
+	logs.Get("/ws", s.streamLogsWS)
+	logs.Get("/range", s.getLogsByRange)
+	logs.Get("/:id", s.getLogById)
+	logs.Post("/:id/cosign", s.coSignLog)
+	logs.Get("/:id/cosign", s.getCoSignStatus)
+	logs.Get("/:id/proof", s.getInclusionProof)
+	logs.Post("/:id/redact", s.requireAdmin, s.redactLog)
+	logs.Post("/:id/disclose", s.discloseLog)
 
 	// Verification
 	verify := api.Group("/verify")
 	verify.Post("/signature", verifySignature)
-	verify.Post("/chain", verifyChain)
+	verify.Post("/chain", s.verifyChain)
 
 	// Agent management
 	agents := api.Group("/agents")
-	agents.Post("/register", registerAgent)
-	agents.Get("/", listAgents)
+	agents.Post("/register", s.registerAgent)
+	agents.Get("/", s.listAgents)
+
+	// Chain parameters
+	chainGroup := api.Group("/chain")
+	chainGroup.Get("/", s.getChainHeader)
+	chainGroup.Get("/epochs", s.getEpochs)
+	chainGroup.Post("/epochs", s.requireAdmin, s.startEpoch)
+	chainGroup.Post("/epochs/seal", s.requireAdmin, s.sealEpoch)
+	chainGroup.Get("/epochs/:id/verify", s.verifyEpoch)
+	chainGroup.Get("/epochs/:id/entries", s.getEpochEntries)
+	chainGroup.Post("/rekey", s.requireAdmin, s.rekeyChain)
+	chainGroup.Get("/retention", s.getRetention)
+	chainGroup.Put("/retention", s.requireAdmin, s.setRetention)
+	chainGroup.Post("/prune", s.requireAdmin, s.pruneChain)
+	chainGroup.Post("/holds", s.requireAdmin, s.addLegalHold)
+	chainGroup.Delete("/holds/:id", s.requireAdmin, s.releaseLegalHold)
+	chainGroup.Get("/segments", s.getSegments)
+	chainGroup.Post("/archive", s.requireAdmin, s.archiveChain)
+	chainGroup.Post("/restore", s.requireAdmin, s.restoreChain)
+	chainGroup.Get("/checkpoints", s.getCheckpoints)
+	chainGroup.Post("/checkpoints", s.requireAdmin, s.createCheckpoint)
+
+	// Built-in time-stamp authority
+	if s.TSA != nil {
+		api.Post("/tsa", s.timestampQuery)
+		api.Get("/tsa/certificate", s.getTSACertificate)
+	}
 
 	// Stats
-	api.Get("/stats", getStats)
+	api.Get("/stats", s.getStats)
+	api.Get("/replication", s.getReplication)
+}
+
+// requireAdmin only lets requests carrying the admin bearer token through
+func (s *Server) requireAdmin(c *fiber.Ctx) error {
+	if s.AdminToken == "" {
+		return c.Status(403).JSON(fiber.Map{
+			"error": "Admin API disabled - set ZCRYPT_ADMIN_TOKEN",
+		})
+	}
+	if !hasBearer(c, s.AdminToken) {
+		return c.Status(401).JSON(fiber.Map{
+			"error": "Invalid admin token",
+		})
+	}
+	return c.Next()
+}
+
+// hasBearer reports whether the request carries token as its bearer token,
+// comparing in constant time so the token cannot be guessed byte by byte
+func hasBearer(c *fiber.Ctx, token string) bool {
+	return token != "" && subtle.ConstantTimeCompare([]byte(c.Get("Authorization")), []byte("Bearer "+token)) == 1
 }
 
 // Health check endpoint
@@ -99,118 +371,278 @@ func healthCheck(c *fiber.Ctx) error {
 	})
 }
 
-// Submit a new log entry
-func submitLog(c *fiber.Ctx) error {
-	type LogRequest struct {
-		Message   string                 `json:"message"`
-		Signature string                 `json:"signature"`
-		PubKey    string                 `json:"pubkey"`
-		AgentID   string                 `json:"agent_id"`
-		Metadata  map[string]interface{} `json:"metadata,omitempty"`
-	}
+// LogRequest is a signed submission from an agent
+type LogRequest struct {
+	Message   string                 `json:"message"`
+	Signature string                 `json:"signature"`
+	PubKey    string                 `json:"pubkey"`
+	Algorithm string                 `json:"algorithm,omitempty"`
+	AgentID   string                 `json:"agent_id"`
+	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+	Envelope  *crypto.Envelope       `json:"envelope,omitempty"`
+	RequestID string                 `json:"request_id,omitempty"` // Idempotency key; repeats return the original entry
+}
+
+// rejection is a submission refused with an HTTP status
+type rejection struct {
+	Status  int
+	Message string
+}
+
+func reject(status int, message string) *rejection {
+	return &rejection{Status: status, Message: message}
+}
 
+// Submit a new log entry
+func (s *Server) submitLog(c *fiber.Ctx) error {
 	var req LogRequest
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(400).JSON(fiber.Map{
 			"error": "Invalid request body",
 		})
 	}
-
-	// Validate required fields
-	if req.Message == "" || req.Signature == "" || req.PubKey == "" {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "Missing required fields: message, signature, pubkey",
-		})
+	if req.RequestID == "" {
+		req.RequestID = c.Get("Idempotency-Key")
 	}
 
-	// Verify signature
-	pubKeyBytes, err := hex.DecodeString(req.PubKey)
-	if err != nil {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "Invalid public key format",
+	// A retried request gets the entry it created the first time
+	claim, rej := s.beginRequest(&req)
+	if rej != nil {
+		return c.Status(rej.Status).JSON(fiber.Map{
+			"error": rej.Message,
 		})
 	}
-
-	sigBytes, err := hex.DecodeString(req.Signature)
-	if err != nil {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "Invalid signature format",
+	if claim != nil && claim.Original != nil {
+		return c.Status(200).JSON(fiber.Map{
+			"success":      true,
+			"duplicate":    true,
+			"entry":        claim.Original,
+			"index":        claim.Index,
+			"chain_length": s.LogChain.Length(),
+			"receipt":      s.issueReceipt(claim.Index, claim.Original, req.RequestID),
 		})
 	}
 
-	valid := ed25519.Verify(ed25519.PublicKey(pubKeyBytes), []byte(req.Message), sigBytes)
-	if !valid {
-		return c.Status(401).JSON(fiber.Map{
-			"error": "Invalid signature - verification failed",
+	entry, rej := s.prepareEntry(&req)
+	if rej != nil {
+		claim.abort()
+		return c.Status(rej.Status).JSON(fiber.Map{
+			"error": rej.Message,
 		})
 	}
 
-	// Add metadata
-	if req.Metadata == nil {
-		req.Metadata = make(map[string]interface{})
-	}
-	req.Metadata["agent_id"] = req.AgentID
-	req.Metadata["server_received"] = time.Now().UTC()
-
 	// Add to chain
-	entry, err := config.LogChain.AddLog(req.Message, req.Signature, req.PubKey, req.Metadata)
+	appended, err := s.LogChain.AddEntry(entry)
 	if err != nil {
+		claim.abort()
+		s.releaseNonce(entry)
 		return c.Status(500).JSON(fiber.Map{
 			"error": "Failed to add log to chain",
 		})
 	}
+	claim.complete(appended)
+
+	index := s.LogChain.IndexOf(appended.CurrentHash)
+	logClockFlags(index, req.AgentID, appended)
 
 	return c.Status(201).JSON(fiber.Map{
 		"success":      true,
-		"entry":        entry,
-		"chain_length": len(config.LogChain.Entries),
+		"entry":        appended,
+		"index":        index,
+		"chain_length": s.LogChain.Length(),
+		"receipt":      s.issueReceipt(index, appended, req.RequestID),
 	})
 }
 
-// Get all logs
-func getLogs(c *fiber.Ctx) error {
-	limit := c.QueryInt("limit", 100)
-	offset := c.QueryInt("offset", 0)
-
-	entries := config.LogChain.Entries
-	total := len(entries)
-
-	// Pagination
-	if offset >= total {
-		return c.JSON(fiber.Map{
-			"entries": []crypto.LogEntry{},
-			"total":   total,
-			"limit":   limit,
-			"offset":  offset,
+// prepareEntry validates a submission, verifies its signature and consumes
+// its envelope nonce, returning the entry to append
+func (s *Server) prepareEntry(req *LogRequest) (crypto.LogEntry, *rejection) {
+	// Validate required fields. Confidential submissions carry a
+	// commitment in the envelope instead of the message.
+	confidential := req.Envelope != nil && req.Envelope.Commitment != ""
+	if (req.Message == "" && !confidential) || req.Signature == "" || req.PubKey == "" {
+		return crypto.LogEntry{}, reject(400, "Missing required fields: message, signature, pubkey")
+	}
+	if confidential {
+		if req.Message != "" {
+			return crypto.LogEntry{}, reject(400, "Confidential submissions must not include the message")
+		}
+		if commitment, err := hex.DecodeString(req.Envelope.Commitment); err != nil || len(commitment) != 32 {
+			return crypto.LogEntry{}, reject(400, "Invalid message commitment")
+		}
+	}
+
+	// Verify signature with the declared algorithm
+	algorithm := crypto.NormalizeAlgorithm(req.Algorithm)
+	if _, err := crypto.LookupVerifier(algorithm); err != nil {
+		return crypto.LogEntry{}, reject(400, "Unsupported signature algorithm: "+algorithm)
+	}
+
+	// Envelopes bind the signature to agent, time, nonce and this chain
+	env := req.Envelope
+	if env == nil && !s.AllowUnenveloped {
+		return crypto.LogEntry{}, reject(400, "Missing signed envelope")
+	}
+	if env != nil {
+		if env.Chain != s.ChainName {
+			return crypto.LogEntry{}, reject(400, fmt.Sprintf("Envelope targets chain %q, this server is %q", env.Chain, s.ChainName))
+		}
+		if env.Nonce == "" {
+			return crypto.LogEntry{}, reject(400, "Envelope nonce is required")
+		}
+		if err := env.CheckFreshness(time.Now().UTC(), s.FreshnessWindow); err != nil {
+			return crypto.LogEntry{}, reject(400, err.Error())
+		}
+		if env.CoSign != nil {
+			if err := env.CoSign.Validate(); err != nil {
+				return crypto.LogEntry{}, reject(400, err.Error())
+			}
+		}
+		if req.AgentID == "" {
+			req.AgentID = env.AgentID
+		} else if req.AgentID != env.AgentID {
+			return crypto.LogEntry{}, reject(400, "agent_id does not match signed envelope")
+		}
+	}
+
+	probe := crypto.LogEntry{Message: req.Message, Envelope: env}
+	if err := crypto.VerifyWithAlgorithm(algorithm, req.PubKey, probe.SigningPayload(), req.Signature); err != nil {
+		return crypto.LogEntry{}, reject(401, "Invalid signature - verification failed")
+	}
+
+	// Only a verified envelope may consume its nonce
+	if env != nil && !s.Nonces.Use(req.PubKey, env.Nonce, env.Timestamp) {
+		return crypto.LogEntry{}, reject(409, "Replay detected - nonce already used")
+	}
+
+	// Add metadata without touching the signed envelope's map. Signed
+	// values win over unsigned request metadata.
+	metadata := make(map[string]interface{})
+	for k, v := range req.Metadata {
+		metadata[k] = v
+	}
+	if env != nil {
+		for k, v := range env.Metadata {
+			metadata[k] = v
+		}
+	}
+	metadata["agent_id"] = req.AgentID
+	metadata["server_received"] = time.Now().UTC()
+	// The request index is seeded from this key on restart, so only an ID
+	// that went through it may set it
+	delete(metadata, "request_id")
+	if req.RequestID != "" {
+		metadata["request_id"] = req.RequestID
+	}
+
+	return crypto.LogEntry{
+		Message:   req.Message,
+		Signature: req.Signature,
+		PubKey:    req.PubKey,
+		Algorithm: algorithm,
+		Metadata:  metadata,
+		Envelope:  env,
+	}, nil
+}
+
+// releaseNonce frees the nonce prepareEntry consumed for an entry that was
+// not appended, so a retry of the same submission is not taken for a replay
+func (s *Server) releaseNonce(entry crypto.LogEntry) {
+	if entry.Envelope != nil {
+		s.Nonces.Release(entry.PubKey, entry.Envelope.Nonce)
+	}
+}
+
+// logClockFlags notes entries received with clock problems
+func logClockFlags(index int, agentID string, entry *crypto.LogEntry) {
+	if len(entry.ClockFlags) > 0 {
+		log.Printf("⏱️  Entry %d from %s flagged: %v", index, agentID, entry.ClockFlags)
+	}
+}
+
+// List logs a page at a time, against the head the listing started from
+func (s *Server) getLogs(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit", DefaultPageSize)
+	if limit < 1 {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "limit must be at least 1",
 		})
 	}
+	limit = min(limit, s.MaxPageSize)
+
+	// A cursor carries the position, direction and head of the listing it
+	// came from. Without one, offset is the first index, or backward paging
+	// starts at the head.
+	var cursor pageCursor
+	if token := c.Query("cursor"); token != "" {
+		var err error
+		if cursor, err = decodeCursor(token); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid cursor",
+			})
+		}
+	} else {
+		switch c.Query("direction", "forward") {
+		case "forward":
+			cursor.Index = max(c.QueryInt("offset", 0), 0)
+		case "backward":
+			cursor.Index, cursor.Backward = math.MaxInt, true
+		default:
+			return c.Status(400).JSON(fiber.Map{
+				"error": "direction must be forward or backward",
+			})
+		}
+	}
 
-	end := offset + limit
-	if end > total {
-		end = total
+	var page *crypto.Page
+	var err error
+	if cursor.Backward {
+		page, err = s.LogChain.ReadPageBefore(cursor.Head, cursor.Index, limit)
+	} else {
+		page, err = s.LogChain.ReadPage(cursor.Head, cursor.Index, limit)
+	}
+	if errors.Is(err, crypto.ErrUnknownHead) {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Cursor does not match this chain",
+		})
+	} else if err != nil {
+		return c.Status(410).JSON(fiber.Map{
+			"error": "Cursor refers to pruned entries: " + err.Error(),
+		})
 	}
 
-	return c.JSON(fiber.Map{
-		"entries": entries[offset:end],
-		"total":   total,
-		"limit":   limit,
-		"offset":  offset,
-	})
+	response := fiber.Map{
+		"entries":     s.presentEntries(c, page.Entries),
+		"total":       page.Head.Length,
+		"first_index": page.First,
+		"limit":       limit,
+		"offset":      page.Start,
+		"head":        page.Head,
+	}
+	if page.End() < page.Head.Length {
+		response["next_cursor"] = pageCursor{Index: page.End(), Head: page.Head}.encode()
+	}
+	if page.Start > page.First {
+		response["prev_cursor"] = pageCursor{Index: page.Start, Head: page.Head, Backward: true}.encode()
+	}
+	return c.JSON(response)
 }
 
 // Get log by index
-func getLogById(c *fiber.Ctx) error {
-	id := c.Params("id")
-	
-	// Convert string to int manually
-	index := 0
-	if _, err := fmt.Sscanf(id, "%d", &index); err != nil {
+func (s *Server) getLogById(c *fiber.Ctx) error {
+	index, err := indexParam(c)
+	if err != nil {
 		return c.Status(400).JSON(fiber.Map{
 			"error": "Invalid log ID - must be a number",
 		})
 	}
 
-	entry, err := config.LogChain.GetEntry(index)
+	if index >= 0 && index < s.LogChain.FirstIndex() {
+		return c.Status(410).JSON(fiber.Map{
+			"error": "Log entry has been pruned",
+		})
+	}
+	entry, err := s.LogChain.GetEntry(index)
 	if err != nil {
 		return c.Status(404).JSON(fiber.Map{
 			"error": "Log entry not found",
@@ -218,12 +650,19 @@ func getLogById(c *fiber.Ctx) error {
 	}
 
 	return c.JSON(fiber.Map{
-		"entry": entry,
+		"entry": s.presentEntry(c, *entry),
 	})
 }
 
+// indexParam parses the :id route parameter as an entry index
+func indexParam(c *fiber.Ctx) (int, error) {
+	index := 0
+	_, err := fmt.Sscanf(c.Params("id"), "%d", &index)
+	return index, err
+}
+
 // Get logs by time range
-func getLogsByRange(c *fiber.Ctx) error {
+func (s *Server) getLogsByRange(c *fiber.Ctx) error {
 	startStr := c.Query("start")
 	endStr := c.Query("end")
 
@@ -247,10 +686,10 @@ func getLogsByRange(c *fiber.Ctx) error {
 		})
 	}
 
-	entries := config.LogChain.GetEntriesRange(start, end)
+	entries := s.LogChain.GetEntriesRange(start, end)
 
 	return c.JSON(fiber.Map{
-		"entries": entries,
+		"entries": s.presentEntries(c, entries),
 		"count":   len(entries),
 		"start":   start,
 		"end":     end,
@@ -263,6 +702,7 @@ func verifySignature(c *fiber.Ctx) error {
 		Message   string `json:"message"`
 		Signature string `json:"signature"`
 		PubKey    string `json:"pubkey"`
+		Algorithm string `json:"algorithm,omitempty"`
 	}
 
 	var req VerifyRequest
@@ -272,46 +712,38 @@ func verifySignature(c *fiber.Ctx) error {
 		})
 	}
 
-	pubKeyBytes, err := hex.DecodeString(req.PubKey)
-	if err != nil {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "Invalid public key format",
-		})
-	}
-
-	sigBytes, err := hex.DecodeString(req.Signature)
-	if err != nil {
+	algorithm := crypto.NormalizeAlgorithm(req.Algorithm)
+	if _, err := crypto.LookupVerifier(algorithm); err != nil {
 		return c.Status(400).JSON(fiber.Map{
-			"error": "Invalid signature format",
+			"error": "Unsupported signature algorithm: " + algorithm,
 		})
 	}
 
-	valid := ed25519.Verify(ed25519.PublicKey(pubKeyBytes), []byte(req.Message), sigBytes)
+	valid := crypto.VerifyWithAlgorithm(algorithm, req.PubKey, []byte(req.Message), req.Signature) == nil
 
 	return c.JSON(fiber.Map{
-		"valid":   valid,
-		"message": req.Message,
-		"pubkey":  req.PubKey,
+		"valid":     valid,
+		"message":   req.Message,
+		"pubkey":    req.PubKey,
+		"algorithm": algorithm,
 	})
 }
 
 // Verify chain integrity
-func verifyChain(c *fiber.Ctx) error {
-	valid, errors := config.LogChain.VerifyChain()
+func (s *Server) verifyChain(c *fiber.Ctx) error {
+	report := s.LogChain.VerifyChainReport()
 
-	return c.JSON(fiber.Map{
-		"valid":  valid,
-		"errors": errors,
-		"total":  len(config.LogChain.Entries),
-	})
+	return c.JSON(report)
 }
 
 // Register an agent
-func registerAgent(c *fiber.Ctx) error {
+func (s *Server) registerAgent(c *fiber.Ctx) error {
 	type RegisterRequest struct {
-		AgentID string `json:"agent_id"`
-		PubKey  string `json:"pubkey"`
-		Name    string `json:"name,omitempty"`
+		AgentID   string `json:"agent_id"`
+		PubKey    string `json:"pubkey"`
+		Algorithm string `json:"algorithm,omitempty"`
+		Name      string `json:"name,omitempty"`
+		EncPubKey string `json:"enc_pubkey,omitempty"`
 	}
 
 	var req RegisterRequest
@@ -327,14 +759,39 @@ func registerAgent(c *fiber.Ctx) error {
 		})
 	}
 
-	pubKeyBytes, err := hex.DecodeString(req.PubKey)
+	pubKey, err := hex.DecodeString(req.PubKey)
 	if err != nil {
 		return c.Status(400).JSON(fiber.Map{
 			"error": "Invalid public key format",
 		})
 	}
 
-	config.PubKeyRepo[req.AgentID] = ed25519.PublicKey(pubKeyBytes)
+	algorithm := crypto.NormalizeAlgorithm(req.Algorithm)
+	if _, err := crypto.LookupVerifier(algorithm); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Unsupported signature algorithm: " + algorithm,
+		})
+	}
+	if err := crypto.CheckPublicKey(algorithm, pubKey); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Public key does not match algorithm " + algorithm,
+		})
+	}
+
+	if req.EncPubKey != "" {
+		if _, err := crypto.ParseEncryptionPublicKey([]byte(req.EncPubKey)); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid encryption public key",
+			})
+		}
+	}
+
+	s.PubKeyRepo[req.AgentID] = AgentKey{
+		PubKey:    req.PubKey,
+		Algorithm: algorithm,
+		Name:      req.Name,
+		EncPubKey: req.EncPubKey,
+	}
 
 	return c.Status(201).JSON(fiber.Map{
 		"success":  true,
@@ -344,12 +801,15 @@ func registerAgent(c *fiber.Ctx) error {
 }
 
 // List all registered agents
-func listAgents(c *fiber.Ctx) error {
-	agents := make([]fiber.Map, 0, len(config.PubKeyRepo))
-	for agentID, pubKey := range config.PubKeyRepo {
+func (s *Server) listAgents(c *fiber.Ctx) error {
+	agents := make([]fiber.Map, 0, len(s.PubKeyRepo))
+	for agentID, key := range s.PubKeyRepo {
 		agents = append(agents, fiber.Map{
-			"agent_id": agentID,
-			"pubkey":   hex.EncodeToString(pubKey),
+			"agent_id":   agentID,
+			"pubkey":     key.PubKey,
+			"algorithm":  key.Algorithm,
+			"name":       key.Name,
+			"enc_pubkey": key.EncPubKey,
 		})
 	}
 
@@ -360,9 +820,52 @@ func listAgents(c *fiber.Ctx) error {
 }
 
 // Get server statistics
-func getStats(c *fiber.Ctx) error {
-	stats := config.LogChain.Stats()
-	stats["registered_agents"] = len(config.PubKeyRepo)
+func (s *Server) getStats(c *fiber.Ctx) error {
+	stats := s.LogChain.Stats()
+	stats["registered_agents"] = len(s.PubKeyRepo)
+	stats["encryption_at_rest"] = s.Keyring != nil
+	stats["role"] = "primary"
+	if s.Replicator != nil {
+		stats["role"] = "follower"
+		stats["replication"] = s.Replicator.Status()
+	}
 
 	return c.JSON(stats)
-}
\ No newline at end of file
+}
+
+// Get chain header with hash epochs
+func (s *Server) getChainHeader(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{
+		"header":           s.LogChain.GetHeader(),
+		"hash_algorithm":   s.LogChain.HashAlgorithm(),
+		"supported_hashes": crypto.SupportedHashAlgorithms(),
+		"server_pubkey":    hex.EncodeToString(s.Identity.PublicKey()),
+		"server_algorithm": s.Identity.Algorithm(),
+	})
+}
+
+// Start a new hash epoch linked to the current head
+func (s *Server) startEpoch(c *fiber.Ctx) error {
+	type EpochRequest struct {
+		HashAlgorithm string `json:"hash_algorithm"`
+	}
+
+	var req EpochRequest
+	if err := c.BodyParser(&req); err != nil || req.HashAlgorithm == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Missing hash_algorithm",
+		})
+	}
+
+	epoch, err := s.LogChain.StartEpoch(req.HashAlgorithm)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(201).JSON(fiber.Map{
+		"success": true,
+		"epoch":   epoch,
+	})
+}
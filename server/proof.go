@@ -0,0 +1,99 @@
+// server/proof.go
+package main
+
+import (
+	"errors"
+	"log"
+	"strconv"
+
+	"github.com/amshithnair/zcrypt/crypto"
+	"github.com/gofiber/fiber/v2"
+)
+
+// issueReceipt signs a receipt for the entry at index with the server
+// identity, countersigning the submission accepted under requestID. The head
+// is the chain state right after the entry's append, so the same entry always
+// gets the same statement. It returns nil if signing fails; the entry is
+// appended either way.
+func (s *Server) issueReceipt(index int, entry *crypto.LogEntry, requestID string) *crypto.Receipt {
+	head := crypto.ChainHead{Length: index + 1, Hash: entry.CurrentHash}
+	receipt, err := crypto.NewReceipt(s.Identity, index, entry, head, requestID)
+	if err != nil {
+		log.Printf("Failed to sign receipt for entry %d: %v", index, err)
+		return nil
+	}
+	return receipt
+}
+
+// Prove that an entry is part of the chain, against the latest checkpoint
+// by default, a checkpoint by number, or the current head. The tree head is
+// signed by the server; a checkpoint adds the TSA's token over the same head.
+func (s *Server) getInclusionProof(c *fiber.Ctx) error {
+	index, err := indexParam(c)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid log ID - must be a number",
+		})
+	}
+	if index >= 0 && index < s.LogChain.FirstIndex() {
+		return c.Status(410).JSON(fiber.Map{
+			"error": "Log entry has been pruned",
+		})
+	}
+	if index < 0 || index >= s.LogChain.Length() {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Log entry not found",
+		})
+	}
+
+	var checkpoint *crypto.Checkpoint
+	head := s.LogChain.Head()
+	checkpoints := s.LogChain.Checkpoints()
+	switch selector := c.Query("checkpoint", "latest"); selector {
+	case "head":
+	case "latest":
+		if len(checkpoints) == 0 {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "No checkpoints yet - use checkpoint=head",
+			})
+		}
+		checkpoint = &checkpoints[len(checkpoints)-1]
+	default:
+		n, err := strconv.Atoi(selector)
+		if err != nil || n < 0 || n >= len(checkpoints) {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Checkpoint not found",
+			})
+		}
+		checkpoint = &checkpoints[n]
+	}
+	if checkpoint != nil {
+		if index >= checkpoint.Length {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Entry is not covered by this checkpoint yet - use a later one or checkpoint=head",
+			})
+		}
+		head = crypto.ChainHead{Length: checkpoint.Length, Hash: checkpoint.Head}
+	}
+
+	proof, err := s.LogChain.InclusionProof(index, head)
+	if errors.Is(err, crypto.ErrUnknownHead) {
+		return c.Status(409).JSON(fiber.Map{
+			"error": "Checkpoint does not match the chain",
+		})
+	} else if err != nil {
+		return c.Status(410).JSON(fiber.Map{
+			"error": "Cannot prove against this checkpoint: " + err.Error(),
+		})
+	}
+	if err := proof.TreeHead.Sign(s.Identity); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to sign tree head",
+		})
+	}
+	proof.Checkpoint = checkpoint
+
+	return c.JSON(fiber.Map{
+		"proof": proof,
+	})
+}
@@ -0,0 +1,93 @@
+// server/redact.go
+package main
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/amshithnair/zcrypt/crypto"
+	"github.com/gofiber/fiber/v2"
+)
+
+// redactorsFromEnv reads the hex public keys in ZCRYPT_REDACTORS, separated
+// by commas, that may sign redactions
+func redactorsFromEnv() []string {
+	var keys []string
+	for _, key := range strings.Split(os.Getenv("ZCRYPT_REDACTORS"), ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// Erase committed fields from an entry and record the signed redaction.
+// Requires the admin token and a signature from a registered agent key that
+// the chain allows to redact.
+func (s *Server) redactLog(c *fiber.Ctx) error {
+	type RedactRequest struct {
+		TargetHash string   `json:"target_hash"`
+		Fields     []string `json:"fields"`
+		Reason     string   `json:"reason"`
+		Signature  string   `json:"signature"`
+		PubKey     string   `json:"pubkey"`
+		Algorithm  string   `json:"algorithm,omitempty"`
+		AgentID    string   `json:"agent_id"`
+	}
+
+	index, err := indexParam(c)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid log ID - must be a number",
+		})
+	}
+
+	var req RedactRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if len(req.Fields) == 0 || req.Reason == "" || req.Signature == "" || req.PubKey == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Missing required fields: fields, reason, signature, pubkey",
+		})
+	}
+
+	// Redactions are attributable to a registered key
+	key, ok := s.PubKeyRepo[req.AgentID]
+	if !ok || key.PubKey != req.PubKey {
+		return c.Status(403).JSON(fiber.Map{
+			"error": "Redaction must be signed by a registered agent key",
+		})
+	}
+
+	ref := crypto.RedactionRef{
+		TargetIndex: index,
+		TargetHash:  req.TargetHash,
+		Fields:      req.Fields,
+		Reason:      req.Reason,
+	}
+	entry, err := s.LogChain.Redact(ref, req.Signature, req.PubKey, crypto.NormalizeAlgorithm(req.Algorithm), map[string]interface{}{
+		"agent_id":        req.AgentID,
+		"server_received": time.Now().UTC(),
+	})
+	if errors.Is(err, crypto.ErrNotRedactor) {
+		return c.Status(403).JSON(fiber.Map{
+			"error": "Key may not sign redactions - add it to ZCRYPT_REDACTORS",
+		})
+	}
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(201).JSON(fiber.Map{
+		"success": true,
+		"entry":   entry,
+		"index":   s.LogChain.IndexOf(entry.CurrentHash),
+	})
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/amshithnair/zcrypt/crypto"
+)
+
+func TestRedactionRequiresRedactor(t *testing.T) {
+	s := newTestServer(t)
+	s.AdminToken = "admin-token"
+	agent, _ := crypto.GenerateSigner(crypto.AlgEd25519)
+	admin, _ := crypto.GenerateSigner(crypto.AlgEd25519)
+	adminKey := hex.EncodeToString(admin.PublicKey())
+	s.PubKeyRepo["admin"] = AgentKey{PubKey: adminKey, Algorithm: admin.Algorithm()}
+
+	status, result := post(t, s, "/api/v1/logs/", signedRequest(t, s, agent, "user alice logged in", time.Now().UTC()))
+	if status != 201 {
+		t.Fatalf("Expected 201, got %d: %v", status, result)
+	}
+	ref := crypto.RedactionRef{
+		TargetIndex: 0,
+		TargetHash:  result["entry"].(map[string]interface{})["current_hash"].(string),
+		Fields:      []string{crypto.FieldMessage},
+		Reason:      "erasure request",
+	}
+	sig, _ := crypto.SignRedaction(admin, ref)
+	redact := func() int {
+		body, _ := json.Marshal(map[string]interface{}{
+			"target_hash": ref.TargetHash,
+			"fields":      ref.Fields,
+			"reason":      ref.Reason,
+			"signature":   sig,
+			"pubkey":      adminKey,
+			"agent_id":    "admin",
+		})
+		req := httptest.NewRequest("POST", "/api/v1/logs/0/redact", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+s.AdminToken)
+		resp, err := s.App().Test(req, -1)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// A registered key is not enough; the chain must allow it to redact
+	if status := redact(); status != 403 {
+		t.Errorf("Expected 403 for a key that may not redact, got %d", status)
+	}
+	if err := s.LogChain.AllowRedactors([]string{adminKey}); err != nil {
+		t.Fatalf("Failed to allow redactor: %v", err)
+	}
+	if status := redact(); status != 201 {
+		t.Errorf("Expected 201, got %d", status)
+	}
+	if valid, errors := s.LogChain.VerifyChain(); !valid {
+		t.Errorf("Expected the redacted chain to verify, got %v", errors)
+	}
+}
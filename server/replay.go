@@ -0,0 +1,97 @@
+// server/replay.go
+package main
+
+import (
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/amshithnair/zcrypt/crypto"
+)
+
+// NonceCache remembers envelope nonces for the freshness window. Envelopes
+// older than the window are rejected outright, so nonces never need to be
+// kept longer than that.
+type NonceCache struct {
+	mu         sync.Mutex
+	window     time.Duration
+	seen       map[string]time.Time // pubkey|nonce -> envelope timestamp
+	lastExpiry time.Time
+}
+
+// NewNonceCache creates a cache for the given freshness window
+func NewNonceCache(window time.Duration) *NonceCache {
+	return &NonceCache{
+		window: window,
+		seen:   make(map[string]time.Time),
+	}
+}
+
+// Use records a nonce and reports false if it was already used by this key
+func (nc *NonceCache) Use(pubKey, nonce string, ts time.Time) bool {
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+
+	nc.expire(time.Now().UTC())
+
+	key := scopedKey(pubKey, nonce)
+	if _, ok := nc.seen[key]; ok {
+		return false
+	}
+	nc.seen[key] = ts
+	return true
+}
+
+// Release forgets a nonce whose submission was not appended, so the same
+// signed submission can be retried
+func (nc *NonceCache) Release(pubKey, nonce string) {
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+	delete(nc.seen, scopedKey(pubKey, nonce))
+}
+
+// Seed loads nonces from recent chain entries so a restart does not reopen
+// the replay window
+func (nc *NonceCache) Seed(entries []crypto.LogEntry) {
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+
+	cutoff := time.Now().UTC().Add(-nc.window)
+	for _, entry := range entries {
+		if entry.Envelope != nil && entry.Envelope.Timestamp.After(cutoff) {
+			nc.seen[scopedKey(entry.PubKey, entry.Envelope.Nonce)] = entry.Envelope.Timestamp
+		}
+	}
+}
+
+// expire drops nonces whose envelopes can no longer pass the freshness check.
+// It sweeps at most a few times per window to keep Use cheap.
+func (nc *NonceCache) expire(now time.Time) {
+	if now.Sub(nc.lastExpiry) < nc.window/4 {
+		return
+	}
+	nc.lastExpiry = now
+
+	cutoff := now.Add(-nc.window)
+	for key, ts := range nc.seen {
+		if ts.Before(cutoff) {
+			delete(nc.seen, key)
+		}
+	}
+}
+
+// canonicalHex re-encodes hex in lowercase. Keys and signatures decode the
+// same in either case, so replay and request ID lookups must not tell them
+// apart. Invalid hex is returned unchanged; it never verifies.
+func canonicalHex(s string) string {
+	data, err := hex.DecodeString(s)
+	if err != nil {
+		return s
+	}
+	return hex.EncodeToString(data)
+}
+
+// scopedKey keys a nonce or request ID by the public key that used it
+func scopedKey(pubKey, id string) string {
+	return canonicalHex(pubKey) + "|" + id
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amshithnair/zcrypt/crypto"
+)
+
+// newTestServer returns a server for a new chain in a temporary directory
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	chain, err := crypto.NewLogChain(filepath.Join(t.TempDir(), "server_logs.chain"))
+	if err != nil {
+		t.Fatalf("Failed to create chain: %v", err)
+	}
+	identity, err := crypto.GenerateSigner(crypto.AlgEd25519)
+	if err != nil {
+		t.Fatalf("Failed to generate identity: %v", err)
+	}
+	return NewServer(chain, identity)
+}
+
+// signedRequest builds a submission with an envelope stamped at the given time
+func signedRequest(t *testing.T, s *Server, signer crypto.Signer, message string, at time.Time) LogRequest {
+	t.Helper()
+	env, err := crypto.NewEnvelope("agent-1", s.ChainName, nil)
+	if err != nil {
+		t.Fatalf("Failed to create envelope: %v", err)
+	}
+	env.Timestamp = at
+	sig, err := crypto.SignEnvelope(signer, message, env)
+	if err != nil {
+		t.Fatalf("Failed to sign envelope: %v", err)
+	}
+	return LogRequest{
+		Message:   message,
+		Signature: sig,
+		PubKey:    hex.EncodeToString(signer.PublicKey()),
+		Algorithm: signer.Algorithm(),
+		AgentID:   "agent-1",
+		Envelope:  env,
+	}
+}
+
+// post sends body as JSON to the server's path and returns the status and
+// decoded response
+func post(t *testing.T, s *Server, path string, body interface{}) (int, map[string]interface{}) {
+	t.Helper()
+	data, err := json.Marshal(body)
+	if err != nil {
+		t.Fatalf("Failed to marshal request: %v", err)
+	}
+	req := httptest.NewRequest("POST", path, bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.App().Test(req, -1)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	var result map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return resp.StatusCode, result
+}
+
+func TestReplayedSubmissionsRejected(t *testing.T) {
+	s := newTestServer(t)
+	signer, _ := crypto.GenerateSigner(crypto.AlgEd25519)
+	req := signedRequest(t, s, signer, "user alice logged in", time.Now().UTC())
+
+	if status, result := post(t, s, "/api/v1/logs/", req); status != 201 {
+		t.Fatalf("Expected 201, got %d: %v", status, result)
+	}
+	if status, _ := post(t, s, "/api/v1/logs/", req); status != 409 {
+		t.Errorf("Expected a replay to be rejected with 409, got %d", status)
+	}
+
+	// The key and signature decode the same in upper case
+	changed := req
+	changed.PubKey = strings.ToUpper(req.PubKey)
+	changed.Signature = strings.ToUpper(req.Signature)
+	if status, _ := post(t, s, "/api/v1/logs/", changed); status != 409 {
+		t.Errorf("Expected a case-changed replay to be rejected with 409, got %d", status)
+	}
+	if n := s.LogChain.Length(); n != 1 {
+		t.Errorf("Expected 1 entry, got %d", n)
+	}
+}
+
+func TestStaleEnvelopesRejected(t *testing.T) {
+	s := newTestServer(t)
+	signer, _ := crypto.GenerateSigner(crypto.AlgEd25519)
+
+	for _, at := range []time.Time{
+		time.Now().UTC().Add(-2 * s.FreshnessWindow),
+		time.Now().UTC().Add(2 * s.FreshnessWindow),
+	} {
+		req := signedRequest(t, s, signer, "user alice logged in", at)
+		if status, result := post(t, s, "/api/v1/logs/", req); status != 400 {
+			t.Errorf("Expected an envelope stamped %s to be rejected with 400, got %d: %v", at, status, result)
+		}
+	}
+	if n := s.LogChain.Length(); n != 0 {
+		t.Errorf("Expected no entries, got %d", n)
+	}
+}
+
+func TestNonceCacheKeysIgnoreHexCase(t *testing.T) {
+	nc := NewNonceCache(time.Minute)
+	now := time.Now().UTC()
+	if !nc.Use("abcdef", "nonce-1", now) {
+		t.Fatal("Expected a new nonce to be accepted")
+	}
+	if nc.Use("ABCDEF", "nonce-1", now) {
+		t.Error("Expected the nonce to be used for the key in any case")
+	}
+	if !nc.Use("abcdef", "nonce-2", now) {
+		t.Error("Expected another nonce to be accepted")
+	}
+}
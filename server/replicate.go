@@ -0,0 +1,235 @@
+// server/replicate.go
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/amshithnair/zcrypt/crypto"
+	"github.com/amshithnair/zcrypt/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// DefaultReplicationInterval is how often a follower polls its primary
+const DefaultReplicationInterval = 5 * time.Second
+
+// replicationPageSize is how many entries a follower requests at a time
+const replicationPageSize = 500
+
+// Replicator keeps a follower's chain identical to its primary's. It pulls
+// entries over the primary's HTTP API and appends them only after every
+// hash, link and signature checks out. Once the primary is caught rewriting
+// entries the follower already holds, the replicator halts for good.
+type Replicator struct {
+	server   *Server
+	client   *utils.LogClient
+	primary  string
+	interval time.Duration
+
+	mu      sync.Mutex
+	status  ReplicationStatus
+	started time.Time
+	halted  error // The rewrite that halted replication, nil while running
+}
+
+// ReplicationStatus reports how far a follower is behind its primary.
+// LagSeconds is the time since the follower last held every entry the
+// primary had, or since it started if it never has.
+type ReplicationStatus struct {
+	Primary       string    `json:"primary"`
+	Length        int       `json:"length"`
+	PrimaryLength int       `json:"primary_length"`
+	LagEntries    int       `json:"lag_entries"`
+	LagSeconds    float64   `json:"lag_seconds"`
+	LastSync      time.Time `json:"last_sync,omitzero"`  // Last poll that succeeded
+	InSyncAt      time.Time `json:"in_sync_at,omitzero"` // Last time the follower matched the primary
+	Halted        bool      `json:"halted"`
+	Error         string    `json:"error,omitempty"` // Why the last poll failed
+}
+
+// NewReplicator returns a replicator that follows the primary at primaryURL
+// into s's chain every interval
+func NewReplicator(s *Server, primaryURL string, interval time.Duration) *Replicator {
+	primaryURL = strings.TrimSuffix(primaryURL, "/")
+	return &Replicator{
+		server:   s,
+		client:   utils.NewLogClient(primaryURL),
+		primary:  primaryURL,
+		interval: interval,
+		status:   ReplicationStatus{Primary: primaryURL},
+		started:  time.Now().UTC(),
+	}
+}
+
+// Run polls the primary until replication halts
+func (r *Replicator) Run() {
+	for {
+		err := r.SyncOnce()
+		if errors.Is(err, crypto.ErrHistoryRewritten) {
+			log.Printf("🚨 REPLICATION HALTED: %v", err)
+			log.Printf("🚨 The primary at %s no longer extends this follower's chain. The follower keeps serving its verified copy; investigate the primary before replicating again.", r.primary)
+			return
+		}
+		if err != nil {
+			log.Printf("⚠️  Replication from %s failed: %v", r.primary, err)
+		}
+		time.Sleep(r.interval)
+	}
+}
+
+// SyncOnce copies every entry the primary has that the follower lacks. It
+// returns an error wrapping crypto.ErrHistoryRewritten, now and on every
+// later call, once the primary has rewritten replicated history.
+func (r *Replicator) SyncOnce() error {
+	r.mu.Lock()
+	halted := r.halted
+	r.mu.Unlock()
+	if halted != nil {
+		return halted
+	}
+
+	primaryLength, err := r.sync()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now().UTC()
+	r.status.Length = r.server.LogChain.Length()
+	if primaryLength >= 0 {
+		r.status.PrimaryLength = primaryLength
+	}
+	switch {
+	case errors.Is(err, crypto.ErrHistoryRewritten):
+		r.halted = err
+		r.status.Halted = true
+		r.status.Error = err.Error()
+	case err != nil:
+		r.status.Error = err.Error()
+	default:
+		r.status.Error = ""
+		r.status.LastSync = now
+		if r.status.Length >= primaryLength {
+			r.status.InSyncAt = now
+		}
+	}
+	return err
+}
+
+// sync pulls the primary's entries from the follower's head to the head the
+// first page was served from, and returns that head's length, or -1 if the
+// primary could not be read
+func (r *Replicator) sync() (int, error) {
+	chain := r.server.LogChain
+	length := chain.Length()
+
+	// The first page starts one entry back, so it shows whether the primary
+	// still has the entry the follower ends with
+	page, err := r.client.ListLogsFrom(max(length-1, 0), replicationPageSize)
+	if err != nil {
+		return -1, err
+	}
+	primaryLength := page.Head.Length
+	if primaryLength < length {
+		return primaryLength, fmt.Errorf("%w: primary has %d entries, this follower has %d", crypto.ErrHistoryRewritten, primaryLength, length)
+	}
+	entries := page.Entries
+	if length > 0 && page.Start == length-1 {
+		if len(entries) == 0 || entries[0].CurrentHash != chain.GetLastHash() {
+			return primaryLength, fmt.Errorf("%w: entry %d differs from the replicated copy", crypto.ErrHistoryRewritten, length-1)
+		}
+		entries = entries[1:]
+	}
+
+	// The header is read after the entries, so it covers all of them
+	header, err := r.client.ChainHeader()
+	if err != nil {
+		return primaryLength, err
+	}
+	for {
+		if err := r.replicate(*header, entries); err != nil {
+			return primaryLength, err
+		}
+		if page.NextCursor == "" {
+			return primaryLength, nil
+		}
+		if page, err = r.client.ListLogs(page.NextCursor, replicationPageSize); err != nil {
+			return primaryLength, err
+		}
+		entries = page.Entries
+	}
+}
+
+// replicate appends entries at the follower's head, with the primary's copy
+// of any earlier entries they redact or disclose
+func (r *Replicator) replicate(header crypto.ChainHeader, entries []crypto.LogEntry) error {
+	chain := r.server.LogChain
+	start := chain.Length()
+	if len(entries) == 0 {
+		return nil
+	}
+	updates := map[int]crypto.LogEntry{}
+	for _, index := range crypto.ReplicationTargets(start, entries) {
+		if index < chain.FirstIndex() {
+			continue
+		}
+		entry, err := r.client.GetEntry(index)
+		if err != nil {
+			return fmt.Errorf("failed to fetch updated entry %d: %w", index, err)
+		}
+		updates[index] = *entry
+	}
+	return chain.Replicate(header, start, entries, updates)
+}
+
+// Status reports the follower's replication progress and lag
+func (r *Replicator) Status() ReplicationStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	status := r.status
+	status.Length = r.server.LogChain.Length()
+	status.LagEntries = max(status.PrimaryLength-status.Length, 0)
+	since := r.started
+	if !status.InSyncAt.IsZero() {
+		since = status.InSyncAt
+	}
+	status.LagSeconds = time.Since(since).Seconds()
+	return status
+}
+
+// readOnly refuses writes on a follower, whose chain changes only by
+// replication. Verification and timestamp queries do not write and pass.
+func (s *Server) readOnly(c *fiber.Ctx) error {
+	if s.Replicator == nil {
+		return c.Next()
+	}
+	switch c.Method() {
+	case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+		return c.Next()
+	}
+	path := strings.TrimPrefix(c.Path(), "/api/"+apiVersion)
+	if strings.HasPrefix(path, "/verify") || strings.HasPrefix(path, "/tsa") {
+		return c.Next()
+	}
+	return c.Status(403).JSON(fiber.Map{
+		"error":   "This server is a read-only follower; send writes to the primary",
+		"primary": s.Replicator.primary,
+	})
+}
+
+// Report whether this server is a primary or a follower, and how far a
+// follower lags behind its primary
+func (s *Server) getReplication(c *fiber.Ctx) error {
+	if s.Replicator == nil {
+		return c.JSON(fiber.Map{
+			"role":   "primary",
+			"length": s.LogChain.Length(),
+		})
+	}
+	return c.JSON(fiber.Map{
+		"role":        "follower",
+		"replication": s.Replicator.Status(),
+	})
+}
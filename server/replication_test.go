@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/amshithnair/zcrypt/crypto"
+	"github.com/amshithnair/zcrypt/utils"
+)
+
+// startServer runs a server for a new chain on a free local port and
+// returns it with its base URL. A non-empty primary makes it a follower
+// whose replication the test drives with SyncOnce.
+func startServer(t *testing.T, primary string) (*Server, string) {
+	t.Helper()
+	s := newTestServer(t)
+	if primary != "" {
+		s.Replicator = NewReplicator(s, primary, time.Hour)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	app := s.App()
+	go app.Listener(ln)
+	// Open streams only end at their next heartbeat, so shutdown does not
+	// wait for them
+	t.Cleanup(func() { app.ShutdownWithTimeout(100 * time.Millisecond) })
+	return s, "http://" + ln.Addr().String()
+}
+
+// syncOnce runs one replication round and fails the test on error
+func syncOnce(t *testing.T, s *Server) {
+	t.Helper()
+	if err := s.Replicator.SyncOnce(); err != nil {
+		t.Fatalf("Failed to replicate: %v", err)
+	}
+}
+
+// sameChain reports whether two servers' chain files are byte-identical
+func sameChain(t *testing.T, a, b *Server) bool {
+	t.Helper()
+	want, err := os.ReadFile(a.ChainPath)
+	if err != nil {
+		t.Fatalf("Failed to read chain: %v", err)
+	}
+	got, err := os.ReadFile(b.ChainPath)
+	if err != nil {
+		t.Fatalf("Failed to read chain: %v", err)
+	}
+	return bytes.Equal(want, got)
+}
+
+func TestFollowersReplicateChain(t *testing.T) {
+	primary, primaryURL := startServer(t, "")
+	follower, followerURL := startServer(t, primaryURL)
+	// A follower may follow another follower
+	second, secondURL := startServer(t, followerURL)
+
+	agent, _ := crypto.GenerateSigner(crypto.AlgEd25519)
+	admin, _ := crypto.GenerateSigner(crypto.AlgEd25519)
+	primary.AdminToken = "admin-token"
+	primary.PubKeyRepo["admin"] = AgentKey{PubKey: hex.EncodeToString(admin.PublicKey()), Algorithm: admin.Algorithm()}
+	if err := primary.LogChain.AllowRedactors([]string{hex.EncodeToString(admin.PublicKey())}); err != nil {
+		t.Fatalf("Failed to allow redactor: %v", err)
+	}
+
+	submit(t, primaryURL, agent, "user alice logged in")
+	submit(t, primaryURL, agent, "user bob logged in")
+	syncOnce(t, follower)
+	syncOnce(t, second)
+	if !sameChain(t, primary, follower) || !sameChain(t, primary, second) {
+		t.Fatal("Expected followers to hold the primary's chain")
+	}
+
+	// New entries and a redaction of an already replicated entry
+	submit(t, primaryURL, agent, "user carol logged in")
+	client := utils.NewLogClient(primaryURL)
+	client.Token = primary.AdminToken
+	if _, err := client.Redact(admin, "admin", 0, []string{crypto.FieldMessage}, "erasure request"); err != nil {
+		t.Fatalf("Failed to redact: %v", err)
+	}
+	syncOnce(t, follower)
+	syncOnce(t, second)
+	if !sameChain(t, primary, follower) || !sameChain(t, primary, second) {
+		t.Fatal("Expected followers to hold the primary's chain after new entries")
+	}
+	if entry, _ := second.LogChain.GetEntry(0); entry.Message != "" {
+		t.Errorf("Expected the redaction to reach the second follower, got %q", entry.Message)
+	}
+	if valid, errs := second.LogChain.VerifyChain(); !valid {
+		t.Errorf("Expected the replicated chain to verify, got %v", errs)
+	}
+
+	// Followers serve reads and refuse writes
+	page, err := utils.NewLogClient(secondURL).ListLogs("", 10)
+	if err != nil || len(page.Entries) != 4 {
+		t.Fatalf("Expected 4 entries from the follower, got %v (%v)", page, err)
+	}
+	resp, err := http.Post(followerURL+"/api/v1/logs/", "application/json", bytes.NewBufferString("{}"))
+	if err != nil {
+		t.Fatalf("Failed to post: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected a follower to refuse writes with 403, got %d", resp.StatusCode)
+	}
+
+	// Lag is reported
+	resp, err = http.Get(secondURL + "/api/v1/replication")
+	if err != nil {
+		t.Fatalf("Failed to get replication status: %v", err)
+	}
+	defer resp.Body.Close()
+	var result struct {
+		Role        string            `json:"role"`
+		Replication ReplicationStatus `json:"replication"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatalf("Failed to decode replication status: %v", err)
+	}
+	status := result.Replication
+	if result.Role != "follower" || status.Primary != followerURL || status.Length != 4 || status.LagEntries != 0 || status.InSyncAt.IsZero() {
+		t.Errorf("Unexpected replication status %+v", result)
+	}
+}
+
+func TestFollowerRefusesRewrittenHistory(t *testing.T) {
+	primary, primaryURL := startServer(t, "")
+	follower, _ := startServer(t, primaryURL)
+	agent, _ := crypto.GenerateSigner(crypto.AlgEd25519)
+
+	for _, message := range []string{"Log 1", "Log 2", "Log 3"} {
+		submit(t, primaryURL, agent, message)
+	}
+	syncOnce(t, follower)
+	before, _ := os.ReadFile(follower.ChainPath)
+
+	// The primary's chain is replaced by a longer, validly signed chain
+	// with different history
+	rewritten, err := crypto.NewLogChain(filepath.Join(t.TempDir(), "rewritten.chain"))
+	if err != nil {
+		t.Fatalf("Failed to create chain: %v", err)
+	}
+	primary.LogChain = rewritten
+	for _, message := range []string{"Log 1", "Log 2 (rewritten)", "Log 3", "Log 4", "Log 5"} {
+		submit(t, primaryURL, agent, message)
+	}
+
+	err = follower.Replicator.SyncOnce()
+	if !errors.Is(err, crypto.ErrHistoryRewritten) {
+		t.Fatalf("Expected ErrHistoryRewritten, got %v", err)
+	}
+	if err := follower.Replicator.SyncOnce(); !errors.Is(err, crypto.ErrHistoryRewritten) {
+		t.Errorf("Expected replication to stay halted, got %v", err)
+	}
+	after, _ := os.ReadFile(follower.ChainPath)
+	if !bytes.Equal(before, after) {
+		t.Error("Expected the follower's chain to be unchanged")
+	}
+	status := follower.Replicator.Status()
+	if !status.Halted || status.PrimaryLength != 5 || status.LagEntries != 2 {
+		t.Errorf("Unexpected replication status %+v", status)
+	}
+}
+
+func TestFollowerRetriesUnreachablePrimary(t *testing.T) {
+	follower, _ := startServer(t, "http://127.0.0.1:1")
+	follower.Replicator.client.Retries = 0
+
+	err := follower.Replicator.SyncOnce()
+	if err == nil || errors.Is(err, crypto.ErrHistoryRewritten) {
+		t.Fatalf("Expected a connection error, got %v", err)
+	}
+	if status := follower.Replicator.Status(); status.Halted || status.Error == "" {
+		t.Errorf("Expected replication to keep running and report the error, got %+v", status)
+	}
+}
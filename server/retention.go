@@ -0,0 +1,234 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/amshithnair/zcrypt/crypto"
+	"github.com/gofiber/fiber/v2"
+)
+
+// loadServerIdentity loads the server's signing key from
+// ZCRYPT_SERVER_KEY_FILE, generating an Ed25519 key on first start
+func loadServerIdentity() (crypto.Signer, error) {
+	path := getEnv("ZCRYPT_SERVER_KEY_FILE", "./server_identity.key")
+	if _, err := os.Stat(path); err == nil {
+		return crypto.LoadSigner(path)
+	}
+
+	signer, err := crypto.GenerateSigner(crypto.AlgEd25519)
+	if err != nil {
+		return nil, err
+	}
+	pubPath := strings.TrimSuffix(path, ".key") + ".pub"
+	if err := crypto.SaveSigner(signer, path, pubPath); err != nil {
+		return nil, err
+	}
+	log.Printf("🔑 Generated server identity key %s", path)
+	return signer, nil
+}
+
+// retentionFromEnv reads ZCRYPT_RETENTION_MAX_AGE, ZCRYPT_RETENTION_MAX_ENTRIES,
+// ZCRYPT_RETENTION_MAX_BYTES and ZCRYPT_RETENTION_WHOLE_EPOCHS. It returns nil
+// when no limit is set.
+func retentionFromEnv() (*crypto.RetentionPolicy, error) {
+	age := os.Getenv("ZCRYPT_RETENTION_MAX_AGE")
+	entries := os.Getenv("ZCRYPT_RETENTION_MAX_ENTRIES")
+	size := os.Getenv("ZCRYPT_RETENTION_MAX_BYTES")
+	if age == "" && entries == "" && size == "" {
+		return nil, nil
+	}
+
+	var policy crypto.RetentionPolicy
+	var err error
+	if age != "" {
+		if policy.MaxAge, err = crypto.ParseRetentionAge(age); err != nil {
+			return nil, err
+		}
+	}
+	if entries != "" {
+		if policy.MaxEntries, err = strconv.Atoi(entries); err != nil {
+			return nil, fmt.Errorf("invalid ZCRYPT_RETENTION_MAX_ENTRIES: %w", err)
+		}
+	}
+	if size != "" {
+		if policy.MaxBytes, err = strconv.ParseInt(size, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid ZCRYPT_RETENTION_MAX_BYTES: %w", err)
+		}
+	}
+	if whole := os.Getenv("ZCRYPT_RETENTION_WHOLE_EPOCHS"); whole != "" {
+		if policy.WholeEpochs, err = strconv.ParseBool(whole); err != nil {
+			return nil, fmt.Errorf("invalid ZCRYPT_RETENTION_WHOLE_EPOCHS: %w", err)
+		}
+	}
+	return &policy, nil
+}
+
+// runRetention prunes the chain by its stored retention policy every interval
+func (s *Server) runRetention(interval time.Duration) {
+	for range time.Tick(interval) {
+		policy := s.LogChain.Retention()
+		if policy == nil {
+			continue
+		}
+		anchor, err := s.LogChain.Prune(*policy, s.Identity, s.ArchiveDir)
+		if errors.Is(err, crypto.ErrNothingToPrune) {
+			continue
+		}
+		if err != nil {
+			log.Printf("⚠️  Retention pruning failed: %v", err)
+			continue
+		}
+		log.Printf("✂️  Pruned entries %d-%d", anchor.StartIndex, anchor.StartIndex+anchor.Count-1)
+	}
+}
+
+// Get the retention policy, legal holds and prune anchors
+func (s *Server) getRetention(c *fiber.Ctx) error {
+	header := s.LogChain.GetHeader()
+	prunable := 0
+	var blocked *crypto.LegalHold
+	if header.Retention != nil {
+		prunable, blocked = s.LogChain.Prunable(*header.Retention, time.Now().UTC())
+	}
+
+	return c.JSON(fiber.Map{
+		"retention":   header.Retention,
+		"legal_holds": header.LegalHolds,
+		"anchors":     header.Anchors,
+		"first_index": s.LogChain.FirstIndex(),
+		"prunable":    prunable,
+		"blocked_by":  blocked,
+	})
+}
+
+// Replace the chain's retention policy; an empty policy keeps everything
+func (s *Server) setRetention(c *fiber.Ctx) error {
+	var policy crypto.RetentionPolicy
+	if err := c.BodyParser(&policy); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid retention policy: " + err.Error(),
+		})
+	}
+
+	if err := s.LogChain.SetRetention(policy); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success":   true,
+		"retention": s.LogChain.Retention(),
+	})
+}
+
+// Prune the chain now, by the stored policy or one given in the body
+func (s *Server) pruneChain(c *fiber.Ctx) error {
+	type PruneRequest struct {
+		Policy *crypto.RetentionPolicy `json:"policy,omitempty"`
+		DryRun bool                    `json:"dry_run,omitempty"`
+	}
+
+	var req PruneRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid request body: " + err.Error(),
+			})
+		}
+	}
+	policy := req.Policy
+	if policy == nil {
+		policy = s.LogChain.Retention()
+	}
+	if policy == nil || policy.IsZero() {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "No retention policy configured",
+		})
+	}
+
+	if req.DryRun {
+		prunable, blocked := s.LogChain.Prunable(*policy, time.Now().UTC())
+		return c.JSON(fiber.Map{
+			"prunable":   prunable,
+			"blocked_by": blocked,
+		})
+	}
+
+	anchor, err := s.LogChain.Prune(*policy, s.Identity, s.ArchiveDir)
+	if errors.Is(err, crypto.ErrNothingToPrune) {
+		return c.JSON(fiber.Map{
+			"success": true,
+			"pruned":  0,
+		})
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"pruned":  anchor.Count,
+		"anchor":  anchor,
+	})
+}
+
+// Place a legal hold on a range of entries
+func (s *Server) addLegalHold(c *fiber.Ctx) error {
+	type HoldRequest struct {
+		StartIndex int    `json:"start_index"`
+		EndIndex   *int   `json:"end_index,omitempty"` // Defaults to the start index
+		Reason     string `json:"reason"`
+	}
+
+	var req HoldRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	end := req.StartIndex
+	if req.EndIndex != nil {
+		end = *req.EndIndex
+	}
+
+	hold, err := s.LogChain.AddLegalHold(req.StartIndex, end, req.Reason)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(201).JSON(fiber.Map{
+		"success": true,
+		"hold":    hold,
+	})
+}
+
+// Release a legal hold
+func (s *Server) releaseLegalHold(c *fiber.Ctx) error {
+	id, err := indexParam(c)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid hold ID - must be a number",
+		})
+	}
+
+	if err := s.LogChain.ReleaseLegalHold(id); err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+	})
+}
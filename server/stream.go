@@ -0,0 +1,238 @@
+// server/stream.go
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/amshithnair/zcrypt/crypto"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Stream tuning: how often idle streams send a keep-alive, and how many
+// entries are read at a time while catching up
+const (
+	streamHeartbeat = 15 * time.Second
+	streamPageSize  = 500
+)
+
+// streamEvent is one committed entry sent to a stream. Entry is the entry as
+// stored, so clients can check its hash and link; Opened is its decrypted
+// form, for callers allowed to read sealed entries.
+type streamEvent struct {
+	Index  int              `json:"index"`
+	Entry  crypto.LogEntry  `json:"entry"`
+	Opened *crypto.LogEntry `json:"opened,omitempty"`
+}
+
+// entryStream follows the chain from an index for one client. Filters match
+// the entry as the client may see it, so sealed metadata is never matched
+// for callers who cannot read it.
+type entryStream struct {
+	chain    *crypto.LogChain
+	from     int
+	agentID  string
+	kind     string
+	pubKey   string
+	readable bool
+}
+
+// newEntryStream reads the stream's position and filters from the request.
+// from resumes at an index; SSE clients reconnecting send Last-Event-ID
+// instead. Without either, only entries committed from now on are sent.
+func (s *Server) newEntryStream(c *fiber.Ctx) (*entryStream, *rejection) {
+	stream := &entryStream{
+		chain:    s.LogChain,
+		from:     s.LogChain.Length(),
+		agentID:  c.Query("agent"),
+		kind:     c.Query("kind"),
+		pubKey:   c.Query("pubkey"),
+		readable: s.canReadPlaintext(c),
+	}
+	if lastID := c.Get("Last-Event-ID"); lastID != "" {
+		index, err := strconv.Atoi(lastID)
+		if err != nil || index < -1 {
+			return nil, reject(400, "Invalid Last-Event-ID")
+		}
+		stream.from = index + 1
+	}
+	if from := c.Query("from"); from != "" {
+		index, err := strconv.Atoi(from)
+		if err != nil || index < 0 {
+			return nil, reject(400, "Invalid from index")
+		}
+		stream.from = index
+	}
+	return stream, nil
+}
+
+// event prepares an entry for the client, or returns nil when the stream's
+// filters exclude it
+func (s *entryStream) event(index int, entry crypto.LogEntry) *streamEvent {
+	event := &streamEvent{Index: index, Entry: entry}
+	visible := entry
+	if entry.IsSealed() && s.readable {
+		if opened, err := s.chain.Open(entry); err == nil {
+			event.Opened, visible = &opened, opened
+		}
+	}
+
+	if s.pubKey != "" && visible.PubKey != s.pubKey {
+		return nil
+	}
+	if s.kind != "" && visible.Kind != s.kind {
+		return nil
+	}
+	if s.agentID != "" {
+		if agentID, _ := visible.Metadata["agent_id"].(string); agentID != s.agentID {
+			return nil
+		}
+	}
+	return event
+}
+
+// run sends committed entries in index order until send or ping fails or
+// done is closed. It subscribes before catching up on stored entries, so
+// nothing committed in between is missed, and subscribes again after falling
+// behind.
+func (s *entryStream) run(send func(*streamEvent) error, ping func() error, done <-chan struct{}) error {
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	next := s.from
+	for {
+		sub := s.chain.Subscribe(crypto.DefaultSubscriptionBuffer)
+
+		// Catch up on entries stored before the subscription
+		for {
+			entries, first, total := s.chain.GetEntriesPage(next, streamPageSize)
+			next = max(next, first)
+			for _, entry := range entries {
+				if event := s.event(next, entry); event != nil {
+					if err := send(event); err != nil {
+						sub.Close()
+						return err
+					}
+				}
+				next++
+			}
+			if len(entries) == 0 || next >= total {
+				break
+			}
+		}
+
+		// Follow the chain until the subscription falls behind
+		for live := true; live; {
+			select {
+			case <-done:
+				sub.Close()
+				return nil
+			case <-heartbeat.C:
+				if err := ping(); err != nil {
+					sub.Close()
+					return err
+				}
+			case committed, ok := <-sub.Entries():
+				if !ok {
+					live = false
+					break
+				}
+				if committed.Index < next {
+					continue // Already sent while catching up
+				}
+				next = committed.Index + 1
+				if event := s.event(committed.Index, committed.Entry); event != nil {
+					if err := send(event); err != nil {
+						sub.Close()
+						return err
+					}
+				}
+			}
+		}
+	}
+}
+
+// Stream committed entries as server-sent events. Each event's id is the
+// entry's index, so a reconnecting client resumes after the last one seen.
+func (s *Server) streamLogsSSE(c *fiber.Ctx) error {
+	stream, rej := s.newEntryStream(c)
+	if rej != nil {
+		return c.Status(rej.Status).JSON(fiber.Map{
+			"error": rej.Message,
+		})
+	}
+
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		send := func(event *streamEvent) error {
+			data, err := json.Marshal(event)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(w, "id: %d\nevent: entry\ndata: %s\n\n", event.Index, data)
+			return w.Flush()
+		}
+		ping := func() error {
+			w.WriteString(": ping\n\n")
+			return w.Flush()
+		}
+
+		// Tell the client where the stream starts, even before any entry
+		fmt.Fprintf(w, "retry: 3000\n: streaming from index %d\n\n", stream.from)
+		if err := w.Flush(); err != nil {
+			return
+		}
+		stream.run(send, ping, nil)
+	})
+	return nil
+}
+
+// Stream committed entries over a WebSocket, one JSON text message per entry
+func (s *Server) streamLogsWS(c *fiber.Ctx) error {
+	stream, rej := s.newEntryStream(c)
+	if rej != nil {
+		return c.Status(rej.Status).JSON(fiber.Map{
+			"error": rej.Message,
+		})
+	}
+	accept, rej := upgradeWebSocket(c)
+	if rej != nil {
+		return c.Status(rej.Status).JSON(fiber.Map{
+			"error": rej.Message,
+		})
+	}
+
+	// The handshake response is written on the hijacked connection
+	c.Context().HijackSetNoResponse(true)
+	c.Context().Hijack(func(conn net.Conn) {
+		ws, err := acceptWebSocket(conn, accept)
+		if err != nil {
+			return
+		}
+
+		done := make(chan struct{})
+		go func() {
+			ws.readLoop()
+			close(done)
+		}()
+		send := func(event *streamEvent) error {
+			data, err := json.Marshal(event)
+			if err != nil {
+				return err
+			}
+			return ws.WriteText(data)
+		}
+		if stream.run(send, ws.Ping, done) == nil {
+			ws.Close(1000)
+		}
+	})
+	return nil
+}
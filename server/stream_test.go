@@ -0,0 +1,307 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amshithnair/zcrypt/crypto"
+	"github.com/amshithnair/zcrypt/utils"
+)
+
+// submit signs and sends a message to the server at url
+func submit(t *testing.T, url string, signer crypto.Signer, message string) {
+	t.Helper()
+	client := utils.NewLogClient(url)
+	submission, err := client.SignSubmission(signer, "agent-1", message, map[string]interface{}{"host": "web-1"})
+	if err != nil {
+		t.Fatalf("Failed to sign submission: %v", err)
+	}
+	if _, err := client.SubmitLog(submission); err != nil {
+		t.Fatalf("Failed to submit %q: %v", message, err)
+	}
+}
+
+// openSSE opens the server-sent event stream at url with the given query
+// and headers. The stream is closed when the test ends.
+func openSSE(t *testing.T, url, query string, header http.Header) *bufio.Reader {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	req, err := http.NewRequestWithContext(ctx, "GET", url+"/api/v1/logs/stream"+query, nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to open stream: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	if resp.StatusCode != 200 {
+		t.Fatalf("Expected 200, got %d", resp.StatusCode)
+	}
+	return bufio.NewReader(resp.Body)
+}
+
+// nextEvent reads the stream up to the next entry event and checks that its
+// id is the entry's index
+func nextEvent(t *testing.T, r *bufio.Reader) streamEvent {
+	t.Helper()
+	id := ""
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Failed to read event: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if value, ok := strings.CutPrefix(line, "id: "); ok {
+			id = value
+		}
+		if data, ok := strings.CutPrefix(line, "data: "); ok {
+			var event streamEvent
+			if err := json.Unmarshal([]byte(data), &event); err != nil {
+				t.Fatalf("Failed to decode event: %v", err)
+			}
+			if id != strconv.Itoa(event.Index) {
+				t.Errorf("Expected event id %d, got %q", event.Index, id)
+			}
+			return event
+		}
+	}
+}
+
+func TestStreamResumes(t *testing.T) {
+	_, url := startServer(t, "")
+	agent, _ := crypto.GenerateSigner(crypto.AlgEd25519)
+	for _, message := range []string{"Log 0", "Log 1", "Log 2"} {
+		submit(t, url, agent, message)
+	}
+
+	// from starts at an index, and entries committed later follow
+	stream := openSSE(t, url, "?from=1", nil)
+	for _, want := range []string{"Log 1", "Log 2"} {
+		if event := nextEvent(t, stream); event.Entry.Message != want {
+			t.Errorf("Expected %q, got %q at %d", want, event.Entry.Message, event.Index)
+		}
+	}
+	submit(t, url, agent, "Log 3")
+	if event := nextEvent(t, stream); event.Index != 3 || event.Entry.Message != "Log 3" {
+		t.Errorf("Expected the new entry at 3, got %q at %d", event.Entry.Message, event.Index)
+	}
+
+	// A reconnecting client resumes after the last event it saw
+	resumed := openSSE(t, url, "", http.Header{"Last-Event-ID": {"1"}})
+	for _, want := range []int{2, 3} {
+		if event := nextEvent(t, resumed); event.Index != want {
+			t.Errorf("Expected entry %d, got %d", want, event.Index)
+		}
+	}
+}
+
+func TestStreamRejectsInvalidPosition(t *testing.T) {
+	s := newTestServer(t)
+	for _, tc := range []struct {
+		path   string
+		header string
+	}{
+		{"/api/v1/logs/stream?from=-1", ""},
+		{"/api/v1/logs/stream?from=abc", ""},
+		{"/api/v1/logs/stream", "abc"},
+		{"/api/v1/logs/ws?from=-1", ""},
+	} {
+		req := httptest.NewRequest("GET", tc.path, nil)
+		if tc.header != "" {
+			req.Header.Set("Last-Event-ID", tc.header)
+		}
+		resp, err := s.App().Test(req, -1)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != 400 {
+			t.Errorf("Expected 400 for %s (Last-Event-ID %q), got %d", tc.path, tc.header, resp.StatusCode)
+		}
+	}
+}
+
+func TestStreamFiltersSealedMetadata(t *testing.T) {
+	s, url := startServer(t, "")
+	master, _ := crypto.GenerateMasterKey()
+	keyring, err := crypto.NewKeyring(master)
+	if err != nil {
+		t.Fatalf("Failed to create keyring: %v", err)
+	}
+	s.LogChain.SetKeyring(keyring)
+	s.Keyring = keyring
+	s.ReadToken = "read-token"
+
+	agent, _ := crypto.GenerateSigner(crypto.AlgEd25519)
+	client := utils.NewLogClient(url)
+	for _, agentID := range []string{"agent-1", "agent-2"} {
+		submission, err := client.SignSubmission(agent, agentID, "user alice logged in", nil)
+		if err != nil {
+			t.Fatalf("Failed to sign submission: %v", err)
+		}
+		if _, err := client.SubmitLog(submission); err != nil {
+			t.Fatalf("Failed to submit: %v", err)
+		}
+	}
+
+	// The agent ID is sealed, so only readers can match it
+	stored, _, _ := s.LogChain.GetEntriesPage(0, 2)
+	for readable, want := range map[bool][]int{false: nil, true: {1}} {
+		stream := &entryStream{chain: s.LogChain, agentID: "agent-2", readable: readable}
+		var matched []int
+		for i, entry := range stored {
+			if stream.event(i, entry) != nil {
+				matched = append(matched, i)
+			}
+		}
+		if !slices.Equal(matched, want) {
+			t.Errorf("Expected matches %v when readable is %v, got %v", want, readable, matched)
+		}
+	}
+
+	stream := openSSE(t, url, "?from=0&agent=agent-2", http.Header{"Authorization": {"Bearer read-token"}})
+	event := nextEvent(t, stream)
+	if event.Index != 1 || event.Opened == nil || event.Opened.Metadata["agent_id"] != "agent-2" {
+		t.Errorf("Expected entry 1 opened for the reader, got %+v", event)
+	}
+	if event.Entry.Metadata != nil {
+		t.Error("Expected the stored entry to stay sealed")
+	}
+}
+
+// dialWebSocket opens a WebSocket to the server at url and returns the
+// connection and a reader for it after checking the handshake
+func dialWebSocket(t *testing.T, url, query string) (net.Conn, *bufio.Reader) {
+	t.Helper()
+	conn, err := net.Dial("tcp", strings.TrimPrefix(url, "http://"))
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	// The key and accept value from RFC 6455, section 1.3
+	io.WriteString(conn, "GET /api/v1/logs/ws"+query+" HTTP/1.1\r\n"+
+		"Host: "+conn.RemoteAddr().String()+"\r\n"+
+		"Upgrade: websocket\r\n"+
+		"Connection: keep-alive, Upgrade\r\n"+
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n"+
+		"Sec-WebSocket-Version: 13\r\n\r\n")
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, nil)
+	if err != nil {
+		t.Fatalf("Failed to read handshake: %v", err)
+	}
+	if resp.StatusCode != 101 || resp.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("Unexpected handshake response %d %v", resp.StatusCode, resp.Header)
+	}
+	return conn, r
+}
+
+// clientFrame builds a masked client frame with a short payload
+func clientFrame(opcode byte, payload []byte) []byte {
+	var mask [4]byte
+	rand.Read(mask[:])
+	frame := append([]byte{0x80 | opcode, 0x80 | byte(len(payload))}, mask[:]...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	return frame
+}
+
+// readServerFrame reads one unmasked frame from the server
+func readServerFrame(t *testing.T, r *bufio.Reader) (byte, []byte) {
+	t.Helper()
+	var head [2]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		t.Fatalf("Failed to read frame: %v", err)
+	}
+	if head[0]&0x80 == 0 || head[1]&0x80 != 0 {
+		t.Fatalf("Expected a final, unmasked frame, got %x", head)
+	}
+	length := uint64(head[1] & 0x7F)
+	switch length {
+	case 126:
+		var ext [2]byte
+		io.ReadFull(r, ext[:])
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		io.ReadFull(r, ext[:])
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		t.Fatalf("Failed to read frame payload: %v", err)
+	}
+	return head[0] & 0x0F, payload
+}
+
+func TestWebSocketStream(t *testing.T) {
+	_, url := startServer(t, "")
+	agent, _ := crypto.GenerateSigner(crypto.AlgEd25519)
+	submit(t, url, agent, "Log 0")
+	conn, r := dialWebSocket(t, url, "?from=0")
+
+	// Each entry is a text message
+	opcode, payload := readServerFrame(t, r)
+	var event streamEvent
+	if err := json.Unmarshal(payload, &event); opcode != wsText || err != nil || event.Index != 0 || event.Entry.Message != "Log 0" {
+		t.Fatalf("Expected entry 0 as a text message, got opcode %x: %s", opcode, payload)
+	}
+	submit(t, url, agent, "Log 1")
+	if _, payload := readServerFrame(t, r); !strings.Contains(string(payload), `"Log 1"`) {
+		t.Errorf("Expected the new entry, got %s", payload)
+	}
+
+	// Masked pings are answered with their payload, and close is echoed
+	conn.Write(clientFrame(wsPing, []byte("hello")))
+	if opcode, payload := readServerFrame(t, r); opcode != wsPong || string(payload) != "hello" {
+		t.Errorf("Expected a pong with the ping's payload, got opcode %x: %q", opcode, payload)
+	}
+	conn.Write(clientFrame(wsClose, binary.BigEndian.AppendUint16(nil, 1000)))
+	if opcode, payload := readServerFrame(t, r); opcode != wsClose || binary.BigEndian.Uint16(payload) != 1000 {
+		t.Errorf("Expected the close to be echoed with 1000, got opcode %x: %v", opcode, payload)
+	}
+}
+
+func TestWebSocketRejectsUnmaskedFrames(t *testing.T) {
+	_, url := startServer(t, "")
+	conn, r := dialWebSocket(t, url, "")
+
+	// An unmasked frame ends the stream
+	conn.Write([]byte{0x80 | wsPing, 0})
+	if opcode, _ := readServerFrame(t, r); opcode != wsClose {
+		t.Errorf("Expected the server to close, got opcode %x", opcode)
+	}
+	if _, err := r.ReadByte(); err != io.EOF {
+		t.Errorf("Expected the connection to be closed, got %v", err)
+	}
+
+	// A plain request is not upgraded
+	resp, err := http.Get(url + "/api/v1/logs/ws")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 426 {
+		t.Errorf("Expected 426, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"crypto/x509"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/amshithnair/zcrypt/crypto"
+	"github.com/amshithnair/zcrypt/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// loadBuiltinTSA loads the built-in time-stamp authority when
+// ZCRYPT_TSA_SERVE is true, generating its key and self-signed certificate
+// at ZCRYPT_TSA_KEY_FILE and ZCRYPT_TSA_CERT_FILE on first start. It is
+// meant for local testing; auditors should rely on an independent TSA.
+func loadBuiltinTSA() (*crypto.TSA, error) {
+	if os.Getenv("ZCRYPT_TSA_SERVE") != "true" {
+		return nil, nil
+	}
+	keyPath := getEnv("ZCRYPT_TSA_KEY_FILE", "./tsa.key")
+	certPath := getEnv("ZCRYPT_TSA_CERT_FILE", "./tsa.crt")
+	if _, err := os.Stat(keyPath); err == nil {
+		return crypto.LoadTSA(keyPath, certPath)
+	}
+
+	tsa, err := crypto.GenerateTSA("zcrypt built-in TSA")
+	if err != nil {
+		return nil, err
+	}
+	if err := tsa.Save(keyPath, certPath); err != nil {
+		return nil, err
+	}
+	log.Printf("🕰️  Generated built-in TSA certificate %s", certPath)
+	return tsa, nil
+}
+
+// loadTrustedTSA reads the PEM certificates at ZCRYPT_TSA_TRUST_FILE that
+// checkpoint tokens must chain to. Without them verification reports every
+// checkpoint as untrusted.
+func loadTrustedTSA() ([]*x509.Certificate, error) {
+	path := os.Getenv("ZCRYPT_TSA_TRUST_FILE")
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.ParseCertificates(data)
+}
+
+// runTimestamping timestamps the chain head every interval, skipping heads
+// that already have a checkpoint
+func (s *Server) runTimestamping(interval time.Duration) {
+	for range time.Tick(interval) {
+		length, _ := s.LogChain.CheckpointHead()
+		checkpoints := s.LogChain.Checkpoints()
+		if length == 0 || (len(checkpoints) > 0 && checkpoints[len(checkpoints)-1].Length == length) {
+			continue
+		}
+		checkpoint, err := utils.TimestampChain(s.LogChain, s.TSAURL)
+		if err != nil {
+			log.Printf("⚠️  Timestamping failed: %v", err)
+			continue
+		}
+		log.Printf("🕰️  Timestamped head of %d entries at %s", checkpoint.Length, checkpoint.GenTime.Format(time.RFC3339))
+	}
+}
+
+// List the chain's timestamped checkpoints
+func (s *Server) getCheckpoints(c *fiber.Ctx) error {
+	checkpoints := s.LogChain.Checkpoints()
+
+	return c.JSON(fiber.Map{
+		"checkpoints": checkpoints,
+		"count":       len(checkpoints),
+		"tsa":         s.TSAURL,
+	})
+}
+
+// Timestamp the current chain head now
+func (s *Server) createCheckpoint(c *fiber.Ctx) error {
+	if s.TSAURL == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "No TSA configured - set ZCRYPT_TSA_URL",
+		})
+	}
+
+	checkpoint, err := utils.TimestampChain(s.LogChain, s.TSAURL)
+	if err != nil {
+		return c.Status(502).JSON(fiber.Map{
+			"error": "Timestamping failed: " + err.Error(),
+		})
+	}
+
+	return c.Status(201).JSON(fiber.Map{
+		"success":    true,
+		"checkpoint": checkpoint,
+	})
+}
+
+// Answer an RFC 3161 time-stamp query with the built-in TSA
+func (s *Server) timestampQuery(c *fiber.Ctx) error {
+	if !strings.HasPrefix(c.Get(fiber.HeaderContentType), crypto.TimestampQueryType) {
+		return c.Status(415).SendString("expected " + crypto.TimestampQueryType)
+	}
+
+	reply, err := s.TSA.Respond(c.Body())
+	if err != nil {
+		return c.Status(500).SendString(err.Error())
+	}
+	c.Set(fiber.HeaderContentType, crypto.TimestampReplyType)
+	return c.Send(reply)
+}
+
+// Serve the built-in TSA's certificate for offline verification
+func (s *Server) getTSACertificate(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderContentType, "application/x-pem-file")
+	return c.Send(s.TSA.CertificatePEM())
+}
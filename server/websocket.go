@@ -0,0 +1,183 @@
+// server/websocket.go
+package main
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// websocketGUID is appended to the client's key to compute the handshake
+// accept value (RFC 6455, section 1.3)
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// maxClientFrame bounds frames read from clients, which only send control
+// frames to a log stream
+const maxClientFrame = 64 << 10
+
+// WebSocket opcodes used by the server
+const (
+	wsText  = 0x1
+	wsClose = 0x8
+	wsPing  = 0x9
+	wsPong  = 0xA
+)
+
+// wsConn is a server-side WebSocket connection. It writes unfragmented,
+// unmasked frames and reads only what a streaming endpoint needs: close,
+// ping and pong.
+type wsConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	mu     sync.Mutex // Serializes frame writes
+}
+
+// upgradeWebSocket checks a request's WebSocket handshake and returns the
+// Sec-WebSocket-Accept value, or a rejection for a plain HTTP request
+func upgradeWebSocket(c *fiber.Ctx) (string, *rejection) {
+	if !strings.EqualFold(c.Get("Upgrade"), "websocket") || !headerHasToken(c.Get("Connection"), "upgrade") {
+		return "", reject(426, "Expected a WebSocket upgrade request")
+	}
+	if c.Get("Sec-WebSocket-Version") != "13" {
+		return "", reject(426, "Unsupported WebSocket version (use 13)")
+	}
+	key := c.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		return "", reject(400, "Invalid Sec-WebSocket-Key")
+	}
+
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(sum[:]), nil
+}
+
+// headerHasToken reports whether a comma-separated header contains token
+func headerHasToken(header, token string) bool {
+	for _, part := range strings.Split(header, ",") {
+		if strings.EqualFold(strings.TrimSpace(part), token) {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptWebSocket completes the handshake on a hijacked connection
+func acceptWebSocket(conn net.Conn, accept string) (*wsConn, error) {
+	response := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + accept + "\r\n\r\n"
+	if _, err := io.WriteString(conn, response); err != nil {
+		return nil, err
+	}
+	return &wsConn{conn: conn, reader: bufio.NewReader(conn)}, nil
+}
+
+// WriteText sends a text message
+func (ws *wsConn) WriteText(data []byte) error {
+	return ws.writeFrame(wsText, data)
+}
+
+// Ping sends a ping, which also detects a vanished client
+func (ws *wsConn) Ping() error {
+	return ws.writeFrame(wsPing, nil)
+}
+
+// Close sends a close frame with the given status code
+func (ws *wsConn) Close(code uint16) error {
+	payload := binary.BigEndian.AppendUint16(nil, code)
+	return ws.writeFrame(wsClose, payload)
+}
+
+func (ws *wsConn) writeFrame(opcode byte, payload []byte) error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	header := []byte{0x80 | opcode}
+	switch n := len(payload); {
+	case n < 126:
+		header = append(header, byte(n))
+	case n <= 0xFFFF:
+		header = append(header, 126)
+		header = binary.BigEndian.AppendUint16(header, uint16(n))
+	default:
+		header = append(header, 127)
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+	if _, err := ws.conn.Write(append(header, payload...)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// readLoop reads client frames until the client closes the connection or
+// the connection fails, answering pings and close frames. Data frames are
+// ignored.
+func (ws *wsConn) readLoop() error {
+	for {
+		opcode, payload, err := ws.readFrame()
+		if err != nil {
+			return err
+		}
+		switch opcode {
+		case wsClose:
+			ws.writeFrame(wsClose, payload[:min(len(payload), 2)])
+			return nil
+		case wsPing:
+			if err := ws.writeFrame(wsPong, payload); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (ws *wsConn) readFrame() (byte, []byte, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(ws.reader, head[:]); err != nil {
+		return 0, nil, err
+	}
+	opcode := head[0] & 0x0F
+	if head[1]&0x80 == 0 {
+		return 0, nil, errors.New("client frame is not masked")
+	}
+
+	length := uint64(head[1] & 0x7F)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(ws.reader, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(ws.reader, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	if length > maxClientFrame {
+		return 0, nil, fmt.Errorf("client frame of %d bytes is too large", length)
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(ws.reader, mask[:]); err != nil {
+		return 0, nil, err
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(ws.reader, payload); err != nil {
+		return 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return opcode, payload, nil
+}
@@ -0,0 +1,190 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/amshithnair/zcrypt/crypto"
+)
+
+// DefaultBatchSize matches the server's default limit on entries per batch
+const DefaultBatchSize = 100
+
+// BatchResult is the outcome of one submission in a batch
+type BatchResult struct {
+	Status  int              `json:"status"`          // HTTP status the submission would have had on its own
+	Index   *int             `json:"index,omitempty"` // Chain index of an accepted entry
+	Entry   *crypto.LogEntry `json:"entry,omitempty"`
+	Receipt *crypto.Receipt  `json:"receipt,omitempty"` // Server's signed statement that it appended Entry
+	Error   string           `json:"error,omitempty"`
+}
+
+// Accepted reports whether the server appended the submission, now or in
+// an earlier request with the same request ID
+func (r BatchResult) Accepted() bool {
+	return r.Status == http.StatusCreated || r.Status == http.StatusOK
+}
+
+// Duplicate reports whether the submission's request ID was seen before, so
+// Entry is the one appended then
+func (r BatchResult) Duplicate() bool {
+	return r.Status == http.StatusOK
+}
+
+// SubmitLogs sends several submissions in one request and returns one result
+// per submission, in order. Some may be rejected while others are appended;
+// an error means the batch as a whole was not processed. When every
+// submission has a request ID, the batch is retried like SubmitLog. Receipts
+// of accepted submissions are verified; one that does not hold is dropped and
+// Error says why.
+func (lc *LogClient) SubmitLogs(submissions []LogSubmission) ([]BatchResult, error) {
+	url := fmt.Sprintf("%s/api/v1/logs/batch", lc.BaseURL)
+
+	jsonData, err := json.Marshal(map[string]interface{}{"entries": submissions})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	retry := true
+	for _, submission := range submissions {
+		retry = retry && submission.RequestID != ""
+	}
+	status, body, err := lc.postSubmission(url, jsonData, retry)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Results []BatchResult `json:"results"`
+		Error   string        `json:"error"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if status != http.StatusCreated && status != http.StatusMultiStatus {
+		return nil, fmt.Errorf("server error: %s", result.Error)
+	}
+	if len(result.Results) != len(submissions) {
+		return nil, fmt.Errorf("server returned %d results for %d submissions", len(result.Results), len(submissions))
+	}
+	for i := range result.Results {
+		r := &result.Results[i]
+		if !r.Accepted() {
+			continue
+		}
+		if r.Index == nil || r.Entry == nil {
+			return nil, fmt.Errorf("server returned no entry for accepted submission %d", i)
+		}
+		if err := lc.checkReceipt(r.Receipt, submissions[i], *r.Index, r.Entry); err != nil {
+			r.Receipt, r.Error = nil, err.Error()
+		}
+	}
+	return result.Results, nil
+}
+
+// BatchSubmitter queues signed submissions and sends them in batches, when
+// enough are queued or when the flush interval passes. Submissions are signed
+// when queued, so the interval must stay well inside the server's freshness
+// window.
+type BatchSubmitter struct {
+	client  *LogClient
+	size    int
+	onFlush func(batch []LogSubmission, results []BatchResult, err error)
+
+	mu      sync.Mutex
+	pending []LogSubmission
+	flushMu sync.Mutex // Keeps batches in submission order
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewBatchSubmitter starts a submitter that sends up to size submissions per
+// request, and flushes whatever is queued every interval. onFlush, when not
+// nil, receives every batch sent with its results or error. Close it to send
+// what is left.
+func (lc *LogClient) NewBatchSubmitter(size int, interval time.Duration, onFlush func(batch []LogSubmission, results []BatchResult, err error)) *BatchSubmitter {
+	if size < 1 {
+		size = DefaultBatchSize
+	}
+	b := &BatchSubmitter{
+		client:  lc,
+		size:    size,
+		onFlush: onFlush,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go b.run(interval)
+	return b
+}
+
+// Submit queues a submission, sending the queue at once when it is full
+func (b *BatchSubmitter) Submit(submission LogSubmission) error {
+	b.mu.Lock()
+	b.pending = append(b.pending, submission)
+	full := len(b.pending) >= b.size
+	b.mu.Unlock()
+
+	if full {
+		return b.Flush()
+	}
+	return nil
+}
+
+// Flush sends everything queued, in batches of at most the submitter's size,
+// and returns the first error. Results go to the onFlush callback.
+func (b *BatchSubmitter) Flush() error {
+	b.flushMu.Lock()
+	defer b.flushMu.Unlock()
+
+	b.mu.Lock()
+	pending := b.pending
+	b.pending = nil
+	b.mu.Unlock()
+
+	var firstErr error
+	for len(pending) > 0 {
+		n := min(len(pending), b.size)
+		batch := pending[:n]
+		pending = pending[n:]
+
+		results, err := b.client.SubmitLogs(batch)
+		if b.onFlush != nil {
+			b.onFlush(batch, results, err)
+		}
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Close stops the interval flushes and sends what is still queued
+func (b *BatchSubmitter) Close() error {
+	close(b.stop)
+	<-b.done
+	return b.Flush()
+}
+
+// run flushes the queue every interval until the submitter is closed.
+// Errors are reported through onFlush.
+func (b *BatchSubmitter) run(interval time.Duration) {
+	defer close(b.done)
+	if interval <= 0 {
+		<-b.stop
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-b.stop:
+			return
+		case <-ticker.C:
+			b.Flush()
+		}
+	}
+}
@@ -3,45 +3,157 @@ package utils
 
 import (
 	"bytes"
+	"crypto/ecdh"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
 	"time"
+
+	"github.com/amshithnair/zcrypt/crypto"
+)
+
+// Retry defaults for submissions that carry a request ID
+const (
+	DefaultRetries    = 3
+	DefaultRetryDelay = 500 * time.Millisecond
 )
 
 type LogClient struct {
-	BaseURL string
-	Client  *http.Client
+	BaseURL    string
+	Chain      string // Target chain name signed into every envelope
+	Token      string // Optional bearer token for admin calls and decrypted reads
+	Client     *http.Client
+	Retries    int           // Extra attempts for submissions with a request ID
+	ServerKey  string        // Hex key receipts must be signed with; any key when empty
+	RetryDelay time.Duration // Wait before the first retry, doubled after each
 }
 
 type LogSubmission struct {
 	Message   string                 `json:"message"`
 	Signature string                 `json:"signature"`
 	PubKey    string                 `json:"pubkey"`
+	Algorithm string                 `json:"algorithm,omitempty"`
 	AgentID   string                 `json:"agent_id"`
 	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+	Envelope  *crypto.Envelope       `json:"envelope,omitempty"`
+	RequestID string                 `json:"request_id,omitempty"` // Idempotency key, kept across retries
+}
+
+type AgentRegistration struct {
+	AgentID   string `json:"agent_id"`
+	PubKey    string `json:"pubkey"`
+	Algorithm string `json:"algorithm,omitempty"`
+	Name      string `json:"name,omitempty"`
+	EncPubKey string `json:"enc_pubkey,omitempty"` // hex X25519 key for receiving encrypted messages
 }
 
 type ServerResponse struct {
 	Success     bool                   `json:"success"`
 	Entry       interface{}            `json:"entry,omitempty"`
+	Index       int                    `json:"index,omitempty"`
 	ChainLength int                    `json:"chain_length,omitempty"`
 	Error       string                 `json:"error,omitempty"`
 	Data        map[string]interface{} `json:"data,omitempty"`
+	Duplicate   bool                   `json:"duplicate,omitempty"` // The request ID was seen before; Entry is the original
+	Receipt     *crypto.Receipt        `json:"receipt,omitempty"`   // Server's signed statement that it appended Entry
 }
 
 // NewLogClient creates a new client for the Zcrypt server
 func NewLogClient(baseURL string) *LogClient {
 	return &LogClient{
 		BaseURL: baseURL,
+		Chain:   crypto.DefaultServerChain,
 		Client: &http.Client{
 			Timeout: 10 * time.Second,
 		},
+		Retries:    DefaultRetries,
+		RetryDelay: DefaultRetryDelay,
+	}
+}
+
+// SubmissionOption adjusts an envelope before it is signed
+type SubmissionOption func(env *crypto.Envelope)
+
+// WithCoSigners requires threshold of the given hex public keys to co-sign the entry
+func WithCoSigners(signers []string, threshold int) SubmissionOption {
+	return func(env *crypto.Envelope) {
+		env.CoSign = &crypto.CoSignPolicy{Signers: signers, Threshold: threshold}
+	}
+}
+
+// SignSubmission wraps a message in a fresh envelope for the client's chain
+// and signs it, producing a submission the server will accept exactly once.
+// It carries a random request ID, so resending it is always safe.
+func (lc *LogClient) SignSubmission(signer crypto.Signer, agentID, message string, metadata map[string]interface{}, opts ...SubmissionOption) (LogSubmission, error) {
+	env, err := crypto.NewEnvelope(agentID, lc.Chain, metadata)
+	if err != nil {
+		return LogSubmission{}, err
+	}
+	for _, opt := range opts {
+		opt(env)
+	}
+	if env.CoSign != nil {
+		if err := env.CoSign.Validate(); err != nil {
+			return LogSubmission{}, err
+		}
+	}
+
+	sigHex, err := crypto.SignEnvelope(signer, message, env)
+	if err != nil {
+		return LogSubmission{}, fmt.Errorf("failed to sign envelope: %w", err)
 	}
+
+	requestID, err := crypto.NewNonce()
+	if err != nil {
+		return LogSubmission{}, err
+	}
+
+	return LogSubmission{
+		Message:   message,
+		Signature: sigHex,
+		PubKey:    hex.EncodeToString(signer.PublicKey()),
+		Algorithm: signer.Algorithm(),
+		AgentID:   agentID,
+		Envelope:  env,
+		RequestID: requestID,
+	}, nil
+}
+
+// SignConfidentialSubmission signs a commitment to message instead of the
+// message itself. The returned salt must be kept to disclose the message later.
+func (lc *LogClient) SignConfidentialSubmission(signer crypto.Signer, agentID, message string, metadata map[string]interface{}, opts ...SubmissionOption) (LogSubmission, string, error) {
+	commitment, salt, err := crypto.CommitMessage(message)
+	if err != nil {
+		return LogSubmission{}, "", err
+	}
+	opts = append(opts, func(env *crypto.Envelope) { env.Commitment = commitment })
+
+	submission, err := lc.SignSubmission(signer, agentID, "", metadata, opts...)
+	if err != nil {
+		return LogSubmission{}, "", err
+	}
+	return submission, salt, nil
 }
 
-// SubmitLog sends a log entry to the server
+// SignEncryptedSubmission seals message to the recipients' X25519 keys and
+// signs the ciphertext, so the server chains a message it cannot read
+func (lc *LogClient) SignEncryptedSubmission(signer crypto.Signer, agentID, message string, recipients []*ecdh.PublicKey, metadata map[string]interface{}, opts ...SubmissionOption) (LogSubmission, error) {
+	armored, err := crypto.EncryptForRecipients([]byte(message), recipients)
+	if err != nil {
+		return LogSubmission{}, fmt.Errorf("failed to encrypt message: %w", err)
+	}
+	return lc.SignSubmission(signer, agentID, armored, metadata, opts...)
+}
+
+// SubmitLog sends a log entry to the server. Submissions with a request ID
+// are retried after timeouts and server errors; if the first attempt was
+// appended, the server returns that entry with Duplicate set. The receipt is
+// verified before returning: if it does not hold, the response is returned
+// with an error wrapping ErrInvalidReceipt, since the entry was appended.
 func (lc *LogClient) SubmitLog(submission LogSubmission) (*ServerResponse, error) {
 	url := fmt.Sprintf("%s/api/v1/logs", lc.BaseURL)
 
@@ -50,27 +162,332 @@ func (lc *LogClient) SubmitLog(submission LogSubmission) (*ServerResponse, error
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	resp, err := lc.Client.Post(url, "application/json", bytes.NewBuffer(jsonData))
+	status, body, err := lc.postSubmission(url, jsonData, submission.RequestID != "")
+	if err != nil {
+		return nil, err
+	}
+
+	var serverResp ServerResponse
+	if err := json.Unmarshal(body, &serverResp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	if status != http.StatusCreated && !(status == http.StatusOK && serverResp.Duplicate) {
+		return &serverResp, fmt.Errorf("server error: %s", serverResp.Error)
+	}
+
+	entry, err := serverResp.AppendedEntry()
+	if err != nil {
+		return &serverResp, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if err := lc.checkReceipt(serverResp.Receipt, submission, serverResp.Index, entry); err != nil {
+		return &serverResp, fmt.Errorf("entry %d was appended, but %w", serverResp.Index, err)
+	}
+	return &serverResp, nil
+}
+
+// postSubmission posts JSON to url and returns the reply's status and body.
+// When retry is set, failed sends and 5xx replies are retried up to
+// lc.Retries times with a doubling delay.
+func (lc *LogClient) postSubmission(url string, jsonData []byte, retry bool) (int, []byte, error) {
+	attempts := 1
+	if retry {
+		attempts += max(lc.Retries, 0)
+	}
+	delay := lc.RetryDelay
+
+	var lastErr error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(delay)
+			delay *= 2
+		}
+
+		resp, err := lc.Client.Post(url, "application/json", bytes.NewBuffer(jsonData))
+		if err != nil {
+			lastErr = fmt.Errorf("failed to send request: %w", err)
+			continue
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			lastErr = fmt.Errorf("failed to read response: %w", err)
+			continue
+		}
+		if resp.StatusCode >= 500 && attempt < attempts-1 {
+			lastErr = fmt.Errorf("server error: status %d", resp.StatusCode)
+			continue
+		}
+		return resp.StatusCode, body, nil
+	}
+	return 0, nil, lastErr
+}
+
+// GetEntry fetches a single entry from the server chain
+func (lc *LogClient) GetEntry(index int) (*crypto.LogEntry, error) {
+	url := fmt.Sprintf("%s/api/v1/logs/%d", lc.BaseURL, index)
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	lc.authorize(req)
+
+	resp, err := lc.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	return decodeEntry(resp, http.StatusOK)
+}
+
+// LogPage is one page of the server chain, read against a fixed head
+type LogPage struct {
+	Entries    []crypto.LogEntry `json:"entries"`
+	Start      int               `json:"offset"`      // Index of the first entry
+	FirstIndex int               `json:"first_index"` // Oldest kept index
+	Head       crypto.ChainHead  `json:"head"`        // Chain state the listing is served from
+	NextCursor string            `json:"next_cursor,omitempty"`
+	PrevCursor string            `json:"prev_cursor,omitempty"`
+}
+
+// ListLogs fetches a page of up to limit entries from the server chain. An
+// empty cursor starts at the first entry; a page's NextCursor or PrevCursor
+// continues the same listing against the same head. The page's entries are
+// checked to link to each other and, when it ends at the head, to the head.
+func (lc *LogClient) ListLogs(cursor string, limit int) (*LogPage, error) {
+	query := url.Values{}
+	query.Set("limit", strconv.Itoa(limit))
+	if cursor != "" {
+		query.Set("cursor", cursor)
+	}
+	return lc.listLogs(query)
+}
+
+// ListLogsFrom fetches a page of up to limit entries starting at index
+// offset. Its NextCursor continues against the same head, like ListLogs.
+func (lc *LogClient) ListLogsFrom(offset, limit int) (*LogPage, error) {
+	query := url.Values{}
+	query.Set("limit", strconv.Itoa(limit))
+	query.Set("offset", strconv.Itoa(offset))
+	return lc.listLogs(query)
+}
+
+// listLogs fetches and checks one page of the log listing
+func (lc *LogClient) listLogs(query url.Values) (*LogPage, error) {
+	req, err := http.NewRequest(http.MethodGet, lc.BaseURL+"/api/v1/logs?"+query.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	lc.authorize(req)
+
+	resp, err := lc.Client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
 	defer resp.Body.Close()
 
+	var result struct {
+		LogPage
+		Error string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("server error: %s", result.Error)
+	}
+
+	page := &result.LogPage
+	for i := 1; i < len(page.Entries); i++ {
+		if page.Entries[i].PrevHash != page.Entries[i-1].CurrentHash {
+			return nil, fmt.Errorf("entry %d does not link to entry %d", page.Start+i, page.Start+i-1)
+		}
+	}
+	if n := len(page.Entries); n > 0 && page.Start+n == page.Head.Length && page.Entries[n-1].CurrentHash != page.Head.Hash {
+		return nil, fmt.Errorf("page does not end at the head it was served from")
+	}
+	return page, nil
+}
+
+// authorize adds the client's bearer token, if any
+func (lc *LogClient) authorize(req *http.Request) {
+	if lc.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+lc.Token)
+	}
+}
+
+func decodeEntry(resp *http.Response, wantStatus int) (*crypto.LogEntry, error) {
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response: %w", err)
 	}
 
-	var serverResp ServerResponse
-	if err := json.Unmarshal(body, &serverResp); err != nil {
+	var result struct {
+		Entry *crypto.LogEntry `json:"entry"`
+		Error string           `json:"error"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
 		return nil, fmt.Errorf("failed to parse response: %w", err)
 	}
+	if resp.StatusCode != wantStatus || result.Entry == nil {
+		return nil, fmt.Errorf("server error: %s", result.Error)
+	}
 
-	if resp.StatusCode != http.StatusCreated {
-		return &serverResp, fmt.Errorf("server error: %s", serverResp.Error)
+	return result.Entry, nil
+}
+
+// Disclose reveals the withheld message of a confidential entry. The
+// message and salt are checked against the server's commitment first.
+func (lc *LogClient) Disclose(signer crypto.Signer, index int, message, salt string) (*crypto.LogEntry, error) {
+	target, err := lc.GetEntry(index)
+	if err != nil {
+		return nil, err
+	}
+	if err := crypto.CheckDisclosure(target, message, salt); err != nil {
+		return nil, err
 	}
 
-	return &serverResp, nil
+	sigHex, err := crypto.SignDisclosure(signer, crypto.DisclosureRef{TargetIndex: index, TargetHash: target.CurrentHash})
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign: %w", err)
+	}
+
+	jsonData, err := json.Marshal(map[string]string{
+		"target_hash": target.CurrentHash,
+		"message":     message,
+		"salt":        salt,
+		"signature":   sigHex,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/api/v1/logs/%d/disclose", lc.BaseURL, index)
+	resp, err := lc.Client.Post(url, "application/json", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	return decodeEntry(resp, http.StatusCreated)
+}
+
+// Redact erases fields of the entry at index on the server. It needs the
+// admin token in lc.Token and a key registered under agentID. The signed
+// statement binds the entry's current hash, so it is fetched first.
+func (lc *LogClient) Redact(signer crypto.Signer, agentID string, index int, fields []string, reason string) (*crypto.LogEntry, error) {
+	target, err := lc.GetEntry(index)
+	if err != nil {
+		return nil, err
+	}
+
+	ref := crypto.RedactionRef{
+		TargetIndex: index,
+		TargetHash:  target.CurrentHash,
+		Fields:      fields,
+		Reason:      reason,
+	}
+	sigHex, err := crypto.SignRedaction(signer, ref)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign: %w", err)
+	}
+
+	jsonData, err := json.Marshal(map[string]interface{}{
+		"target_hash": ref.TargetHash,
+		"fields":      ref.Fields,
+		"reason":      ref.Reason,
+		"signature":   sigHex,
+		"pubkey":      hex.EncodeToString(signer.PublicKey()),
+		"algorithm":   signer.Algorithm(),
+		"agent_id":    agentID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/api/v1/logs/%d/redact", lc.BaseURL, index)
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	lc.authorize(req)
+
+	resp, err := lc.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	return decodeEntry(resp, http.StatusCreated)
+}
+
+// CoSign approves the entry at index. The co-signature binds the entry's
+// current hash, so it is fetched from the server first.
+func (lc *LogClient) CoSign(signer crypto.Signer, agentID string, index int) (*crypto.CoSignStatus, error) {
+	target, err := lc.GetEntry(index)
+	if err != nil {
+		return nil, err
+	}
+
+	sigHex, err := crypto.SignCoSignature(signer, index, target.CurrentHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign: %w", err)
+	}
+
+	jsonData, err := json.Marshal(map[string]string{
+		"signature": sigHex,
+		"pubkey":    hex.EncodeToString(signer.PublicKey()),
+		"algorithm": signer.Algorithm(),
+		"agent_id":  agentID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/api/v1/logs/%d/cosign", lc.BaseURL, index)
+	resp, err := lc.Client.Post(url, "application/json", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	return decodeCoSignStatus(resp, http.StatusCreated)
+}
+
+// CoSignStatus reports whether an entry's co-sign threshold is met
+func (lc *LogClient) CoSignStatus(index int) (*crypto.CoSignStatus, error) {
+	url := fmt.Sprintf("%s/api/v1/logs/%d/cosign", lc.BaseURL, index)
+
+	resp, err := lc.Client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	return decodeCoSignStatus(resp, http.StatusOK)
+}
+
+func decodeCoSignStatus(resp *http.Response, wantStatus int) (*crypto.CoSignStatus, error) {
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var result struct {
+		Status *crypto.CoSignStatus `json:"status"`
+		Error  string               `json:"error"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if resp.StatusCode != wantStatus {
+		return nil, fmt.Errorf("server error: %s", result.Error)
+	}
+
+	return result.Status, nil
 }
 
 // VerifyChain asks the server to verify its chain
@@ -96,6 +513,30 @@ func (lc *LogClient) VerifyChain() (*ServerResponse, error) {
 	return &serverResp, nil
 }
 
+// VerifyChainReport asks the server to verify its chain and returns the full
+// report, including co-signature status
+func (lc *LogClient) VerifyChainReport() (*crypto.VerifyReport, error) {
+	url := fmt.Sprintf("%s/api/v1/verify/chain", lc.BaseURL)
+
+	resp, err := lc.Client.Post(url, "application/json", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var report crypto.VerifyReport
+	if err := json.Unmarshal(body, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return &report, nil
+}
+
 // GetStats retrieves server statistics
 func (lc *LogClient) GetStats() (map[string]interface{}, error) {
 	url := fmt.Sprintf("%s/api/v1/stats", lc.BaseURL)
@@ -120,16 +561,10 @@ func (lc *LogClient) GetStats() (map[string]interface{}, error) {
 }
 
 // RegisterAgent registers an agent with the server
-func (lc *LogClient) RegisterAgent(agentID, pubKey, name string) error {
+func (lc *LogClient) RegisterAgent(registration AgentRegistration) error {
 	url := fmt.Sprintf("%s/api/v1/agents/register", lc.BaseURL)
 
-	data := map[string]string{
-		"agent_id": agentID,
-		"pubkey":   pubKey,
-		"name":     name,
-	}
-
-	jsonData, err := json.Marshal(data)
+	jsonData, err := json.Marshal(registration)
 	if err != nil {
 		return fmt.Errorf("failed to marshal request: %w", err)
 	}
@@ -148,6 +583,26 @@ func (lc *LogClient) RegisterAgent(agentID, pubKey, name string) error {
 	return nil
 }
 
+// ListAgents returns the agents registered with the server
+func (lc *LogClient) ListAgents() ([]AgentRegistration, error) {
+	url := fmt.Sprintf("%s/api/v1/agents", lc.BaseURL)
+
+	resp, err := lc.Client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Agents []AgentRegistration `json:"agents"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return result.Agents, nil
+}
+
 // HealthCheck checks if the server is running
 func (lc *LogClient) HealthCheck() (bool, error) {
 	url := fmt.Sprintf("%s/api/v1/health", lc.BaseURL)
@@ -159,4 +614,219 @@ func (lc *LogClient) HealthCheck() (bool, error) {
 	defer resp.Body.Close()
 
 	return resp.StatusCode == http.StatusOK, nil
-}
\ No newline at end of file
+}
+
+// PruneResult is the server's answer to a prune request
+type PruneResult struct {
+	Pruned    int                 `json:"pruned"`
+	Prunable  int                 `json:"prunable"`
+	Anchor    *crypto.PruneAnchor `json:"anchor,omitempty"`
+	BlockedBy *crypto.LegalHold   `json:"blocked_by,omitempty"`
+}
+
+// Prune asks the server to prune its chain by policy, or by its stored
+// policy when nil. It needs the admin token in lc.Token. A dry run only
+// reports how many entries would be pruned.
+func (lc *LogClient) Prune(policy *crypto.RetentionPolicy, dryRun bool) (*PruneResult, error) {
+	var result struct {
+		PruneResult
+		Error string `json:"error"`
+	}
+	status, err := lc.postAdmin("/api/v1/chain/prune", map[string]interface{}{
+		"policy":  policy,
+		"dry_run": dryRun,
+	}, &result)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("server error: %s", result.Error)
+	}
+	return &result.PruneResult, nil
+}
+
+// ArchiveChain asks the server to move all but the newest keep entries into
+// a compressed archive segment. It needs the admin token in lc.Token.
+func (lc *LogClient) ArchiveChain(keep int, compression string) (*crypto.ArchiveSegment, error) {
+	var result struct {
+		Archived int                    `json:"archived"`
+		Segment  *crypto.ArchiveSegment `json:"segment"`
+		Error    string                 `json:"error"`
+	}
+	status, err := lc.postAdmin("/api/v1/chain/archive", map[string]interface{}{
+		"keep":        keep,
+		"compression": compression,
+	}, &result)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusCreated && status != http.StatusOK {
+		return nil, fmt.Errorf("server error: %s", result.Error)
+	}
+	return result.Segment, nil
+}
+
+// RestoreChain asks the server to move archived entries from index from
+// onward back into its chain file. It needs the admin token in lc.Token.
+func (lc *LogClient) RestoreChain(from int) (int, error) {
+	var result struct {
+		Restored int    `json:"restored"`
+		Error    string `json:"error"`
+	}
+	status, err := lc.postAdmin("/api/v1/chain/restore", map[string]interface{}{"from": from}, &result)
+	if err != nil {
+		return 0, err
+	}
+	if status != http.StatusOK {
+		return 0, fmt.Errorf("server error: %s", result.Error)
+	}
+	return result.Restored, nil
+}
+
+// Epochs lists the server chain's epochs
+func (lc *LogClient) Epochs() ([]crypto.EpochSummary, error) {
+	var result struct {
+		Epochs []crypto.EpochSummary `json:"epochs"`
+		Error  string                `json:"error"`
+	}
+	status, err := lc.getJSON("/api/v1/chain/epochs", &result)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("server error: %s", result.Error)
+	}
+	return result.Epochs, nil
+}
+
+// SealEpoch asks the server to seal its current epoch and open the next with
+// hashAlg, or the current algorithm when empty. It needs the admin token in
+// lc.Token and returns the seal entry.
+func (lc *LogClient) SealEpoch(hashAlg string) (*crypto.LogEntry, error) {
+	var result struct {
+		Seal  *crypto.LogEntry `json:"seal"`
+		Error string           `json:"error"`
+	}
+	status, err := lc.postAdmin("/api/v1/chain/epochs/seal", map[string]interface{}{"hash_algorithm": hashAlg}, &result)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusCreated {
+		return nil, fmt.Errorf("server error: %s", result.Error)
+	}
+	return result.Seal, nil
+}
+
+// VerifyEpoch asks the server to verify one epoch on its own
+func (lc *LogClient) VerifyEpoch(number int) (*crypto.EpochReport, error) {
+	var result struct {
+		crypto.EpochReport
+		Error string `json:"error"`
+	}
+	status, err := lc.getJSON(fmt.Sprintf("/api/v1/chain/epochs/%d/verify", number), &result)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("server error: %s", result.Error)
+	}
+	return &result.EpochReport, nil
+}
+
+// EpochEntries retrieves the kept entries of one epoch
+func (lc *LogClient) EpochEntries(number int) ([]crypto.LogEntry, error) {
+	var result struct {
+		Entries []crypto.LogEntry `json:"entries"`
+		Error   string            `json:"error"`
+	}
+	status, err := lc.getJSON(fmt.Sprintf("/api/v1/chain/epochs/%d/entries", number), &result)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("server error: %s", result.Error)
+	}
+	return result.Entries, nil
+}
+
+// getJSON fetches path and decodes the reply into result, returning the HTTP status
+func (lc *LogClient) getJSON(path string, result interface{}) (int, error) {
+	resp, err := lc.Client.Get(lc.BaseURL + path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read response: %w", err)
+	}
+	if err := json.Unmarshal(data, result); err != nil {
+		return 0, fmt.Errorf("failed to parse response: %w", err)
+	}
+	return resp.StatusCode, nil
+}
+
+// postAdmin posts a JSON body with the client's token and decodes the reply
+// into result, returning the HTTP status
+func (lc *LogClient) postAdmin(path string, body interface{}, result interface{}) (int, error) {
+	jsonData, err := json.Marshal(body)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, lc.BaseURL+path, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	lc.authorize(req)
+
+	resp, err := lc.Client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read response: %w", err)
+	}
+	if err := json.Unmarshal(data, result); err != nil {
+		return 0, fmt.Errorf("failed to parse response: %w", err)
+	}
+	return resp.StatusCode, nil
+}
+
+// Checkpoints retrieves the server chain's timestamped checkpoints
+func (lc *LogClient) Checkpoints() ([]crypto.Checkpoint, error) {
+	var result struct {
+		Checkpoints []crypto.Checkpoint `json:"checkpoints"`
+		Error       string              `json:"error"`
+	}
+	status, err := lc.getJSON("/api/v1/chain/checkpoints", &result)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("server error: %s", result.Error)
+	}
+	return result.Checkpoints, nil
+}
+
+// TimestampServer asks the server to timestamp its current head now; needs
+// the admin token
+func (lc *LogClient) TimestampServer() (*crypto.Checkpoint, error) {
+	var result struct {
+		Checkpoint *crypto.Checkpoint `json:"checkpoint"`
+		Error      string             `json:"error"`
+	}
+	status, err := lc.postAdmin("/api/v1/chain/checkpoints", struct{}{}, &result)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusCreated {
+		return nil, fmt.Errorf("server error: %s", result.Error)
+	}
+	return result.Checkpoint, nil
+}
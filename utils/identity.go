@@ -0,0 +1,130 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/amshithnair/zcrypt/crypto"
+)
+
+// IdentityChangedError means a server presented an identity key other than
+// the one pinned for it. Either the server's key was replaced or something
+// is impersonating it.
+type IdentityChangedError struct {
+	Server    string
+	Pinned    string
+	Presented string
+}
+
+func (e *IdentityChangedError) Error() string {
+	return fmt.Sprintf("server identity of %s changed: pinned %s, presented %s", e.Server, e.Pinned, e.Presented)
+}
+
+// KnownServer is the identity key pinned for a server
+type KnownServer struct {
+	PubKey    string    `json:"pubkey"`
+	Algorithm string    `json:"algorithm"`
+	PinnedAt  time.Time `json:"pinned_at"`
+}
+
+// KnownServers holds the identity keys pinned per server URL, kept in a
+// JSON file like SSH's known_hosts
+type KnownServers struct {
+	Servers map[string]KnownServer `json:"servers"`
+	path    string
+}
+
+// LoadKnownServers reads the pins kept at path. A missing file has none.
+func LoadKnownServers(path string) (*KnownServers, error) {
+	known := &KnownServers{Servers: map[string]KnownServer{}, path: path}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return known, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, known); err != nil {
+		return nil, fmt.Errorf("malformed known servers file: %w", err)
+	}
+	if known.Servers == nil {
+		known.Servers = map[string]KnownServer{}
+	}
+	return known, nil
+}
+
+// Lookup returns the pin for a server URL
+func (ks *KnownServers) Lookup(server string) (KnownServer, bool) {
+	pin, ok := ks.Servers[serverKey(server)]
+	return pin, ok
+}
+
+// Pin records key as the identity of a server URL and saves the file,
+// replacing any earlier pin
+func (ks *KnownServers) Pin(server, pubKey, algorithm string) error {
+	ks.Servers[serverKey(server)] = KnownServer{PubKey: pubKey, Algorithm: algorithm, PinnedAt: time.Now().UTC()}
+	data, err := json.MarshalIndent(ks, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(ks.path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(ks.path, data, 0600)
+}
+
+// serverKey normalizes a server URL for use as a pin key
+func serverKey(server string) string {
+	return strings.TrimRight(server, "/")
+}
+
+// Discover fetches the server's discovery document and checks that it is
+// signed by the key it names
+func (lc *LogClient) Discover() (*crypto.Discovery, error) {
+	var doc crypto.Discovery
+	status, err := lc.getJSON(crypto.DiscoveryPath, &doc)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("server error: status %d", status)
+	}
+	if err := doc.Verify(); err != nil {
+		return nil, fmt.Errorf("discovery document signature: %w", err)
+	}
+	return &doc, nil
+}
+
+// PinIdentity checks the server's identity key against the expected one: the
+// client's ServerKey when set, as from configuration, or else the key pinned
+// in known. A server seen for the first time is pinned on first use. On
+// success ServerKey is the server's key, so receipts are checked against it.
+// A different key returns an *IdentityChangedError. If the document cannot
+// be fetched, ServerKey is still set from any pin and the error returned.
+func (lc *LogClient) PinIdentity(known *KnownServers) (*crypto.Discovery, error) {
+	expected := lc.ServerKey
+	pin, pinned := known.Lookup(lc.BaseURL)
+	if expected == "" && pinned {
+		expected = pin.PubKey
+	}
+
+	doc, err := lc.Discover()
+	if err != nil {
+		lc.ServerKey = expected
+		return nil, err
+	}
+	if expected != "" && doc.PubKey != expected {
+		return doc, &IdentityChangedError{Server: lc.BaseURL, Pinned: expected, Presented: doc.PubKey}
+	}
+	if !pinned || pin.PubKey != doc.PubKey {
+		if err := known.Pin(lc.BaseURL, doc.PubKey, doc.Algorithm); err != nil {
+			return doc, fmt.Errorf("failed to save pin: %w", err)
+		}
+	}
+	lc.ServerKey = doc.PubKey
+	return doc, nil
+}
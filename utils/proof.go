@@ -0,0 +1,30 @@
+package utils
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/amshithnair/zcrypt/crypto"
+)
+
+// InclusionProof fetches a proof that the server entry at index is in the
+// chain. checkpoint is "latest", a checkpoint number, or "head" for the
+// current head; empty means "latest". The proof is returned unverified.
+func (lc *LogClient) InclusionProof(index int, checkpoint string) (*crypto.InclusionProof, error) {
+	if checkpoint == "" {
+		checkpoint = "latest"
+	}
+	var result struct {
+		Proof *crypto.InclusionProof `json:"proof"`
+		Error string                 `json:"error"`
+	}
+	status, err := lc.getJSON(fmt.Sprintf("/api/v1/logs/%d/proof?checkpoint=%s", index, url.QueryEscape(checkpoint)), &result)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK || result.Proof == nil {
+		return nil, fmt.Errorf("server error: %s", result.Error)
+	}
+	return result.Proof, nil
+}
@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/amshithnair/zcrypt/crypto"
+)
+
+// ErrInvalidReceipt means the server appended a submission but returned no
+// receipt, or one that does not hold for it
+var ErrInvalidReceipt = errors.New("invalid receipt")
+
+// AppendedEntry decodes the entry the server returned
+func (r *ServerResponse) AppendedEntry() (*crypto.LogEntry, error) {
+	data, err := json.Marshal(r.Entry)
+	if err != nil {
+		return nil, err
+	}
+	var entry crypto.LogEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("malformed entry: %w", err)
+	}
+	return &entry, nil
+}
+
+// checkReceipt verifies the receipt returned for submission, appended at
+// index as entry: it is signed by the expected server key, and countersigns
+// this submission at this index with the entry's hash and received time
+func (lc *LogClient) checkReceipt(receipt *crypto.Receipt, submission LogSubmission, index int, entry *crypto.LogEntry) error {
+	if receipt == nil {
+		return fmt.Errorf("%w: server returned none", ErrInvalidReceipt)
+	}
+	if err := receipt.Verify(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidReceipt, err)
+	}
+	switch {
+	case lc.ServerKey != "" && receipt.PubKey != lc.ServerKey:
+		return fmt.Errorf("%w: signed by %s, not the server key", ErrInvalidReceipt, receipt.PubKey)
+	case receipt.Index != index:
+		return fmt.Errorf("%w: for index %d, not %d", ErrInvalidReceipt, receipt.Index, index)
+	case receipt.Submitter != submission.PubKey || receipt.Countersigns != submission.Signature:
+		return fmt.Errorf("%w: countersigns another submission", ErrInvalidReceipt)
+	case receipt.RequestID != submission.RequestID:
+		return fmt.Errorf("%w: for request %q", ErrInvalidReceipt, receipt.RequestID)
+	case receipt.EntryHash != entry.CurrentHash || !receipt.ReceivedAt.Equal(entry.Timestamp):
+		return fmt.Errorf("%w: names another entry", ErrInvalidReceipt)
+	}
+	return nil
+}
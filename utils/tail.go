@@ -0,0 +1,164 @@
+package utils
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/amshithnair/zcrypt/crypto"
+)
+
+// maxEventSize bounds one server-sent event, which carries a single entry
+const maxEventSize = 4 << 20
+
+// TailEvent is an entry the server committed, as received from its stream.
+// Entry is stored form, for checking hashes and links; Opened is the
+// decrypted form of a sealed entry when the client's token may read it.
+type TailEvent struct {
+	Index  int              `json:"index"`
+	Entry  crypto.LogEntry  `json:"entry"`
+	Opened *crypto.LogEntry `json:"opened,omitempty"`
+}
+
+// TailFilter limits a stream to matching entries. Empty fields match all.
+type TailFilter struct {
+	AgentID string
+	Kind    string
+	PubKey  string
+}
+
+// ChainHeader retrieves the server chain's header
+func (lc *LogClient) ChainHeader() (*crypto.ChainHeader, error) {
+	var result struct {
+		Header *crypto.ChainHeader `json:"header"`
+		Error  string              `json:"error"`
+	}
+	status, err := lc.getJSON("/api/v1/chain/", &result)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK || result.Header == nil {
+		return nil, fmt.Errorf("server error: %s", result.Error)
+	}
+	return result.Header, nil
+}
+
+// Tail streams entries as the server commits them, starting at index from,
+// or with the next entry committed when from is negative, and calls fn for
+// each in index order. A dropped connection is resumed after the last event
+// received; Tail gives up after the client's retry count of failed attempts
+// in a row. It returns fn's error when fn fails.
+func (lc *LogClient) Tail(from int, filter TailFilter, fn func(TailEvent) error) error {
+	// The client timeout would end the stream, so the stream has none
+	client := *lc.Client
+	client.Timeout = 0
+
+	failures := 0
+	delay := lc.RetryDelay
+	for {
+		received := false
+		err := lc.tailOnce(&client, from, filter, func(event TailEvent) error {
+			received = true
+			from = event.Index + 1
+			return fn(event)
+		})
+		var fnErr *tailCallbackError
+		if errors.As(err, &fnErr) {
+			return fnErr.err
+		}
+		if received {
+			failures, delay = 0, lc.RetryDelay
+		}
+		if failures++; failures > lc.Retries {
+			return err
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// tailCallbackError marks an error returned by Tail's callback, which ends
+// the stream instead of reconnecting
+type tailCallbackError struct {
+	err error
+}
+
+func (e *tailCallbackError) Error() string {
+	return e.err.Error()
+}
+
+// tailOnce reads one stream connection until it ends
+func (lc *LogClient) tailOnce(client *http.Client, from int, filter TailFilter, fn func(TailEvent) error) error {
+	query := url.Values{}
+	if from >= 0 {
+		query.Set("from", strconv.Itoa(from))
+	}
+	if filter.AgentID != "" {
+		query.Set("agent", filter.AgentID)
+	}
+	if filter.Kind != "" {
+		query.Set("kind", filter.Kind)
+	}
+	if filter.PubKey != "" {
+		query.Set("pubkey", filter.PubKey)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, lc.BaseURL+"/api/v1/logs/stream?"+query.Encode(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	lc.authorize(req)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var result struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&result)
+		return fmt.Errorf("server error: %s", result.Error)
+	}
+
+	// Events are blocks of "field: value" lines ended by a blank line
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64<<10), maxEventSize)
+	var eventType, data string
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			if eventType == "entry" && data != "" {
+				var event TailEvent
+				if err := json.Unmarshal([]byte(data), &event); err != nil {
+					return fmt.Errorf("failed to parse event: %w", err)
+				}
+				if err := fn(event); err != nil {
+					return &tailCallbackError{err: err}
+				}
+			}
+			eventType, data = "", ""
+			continue
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			eventType = value
+		case "data":
+			data += value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("stream error: %w", err)
+	}
+	return errors.New("stream closed by server")
+}
@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/amshithnair/zcrypt/crypto"
+)
+
+// maxTimestampReply bounds the size of a TSA reply
+const maxTimestampReply = 1 << 20
+
+// RequestTimestamp asks the RFC 3161 time-stamp authority at tsaURL for a
+// token over message. The reply must answer this request and nonce; the
+// token's signature is checked with crypto.VerifyTimestamp.
+func RequestTimestamp(client *http.Client, tsaURL string, message []byte) ([]byte, error) {
+	req, err := crypto.NewTimestampRequest(message)
+	if err != nil {
+		return nil, err
+	}
+	der, err := req.Marshal()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Post(tsaURL, crypto.TimestampQueryType, bytes.NewReader(der))
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach TSA: %w", err)
+	}
+	defer resp.Body.Close()
+	reply, err := io.ReadAll(io.LimitReader(resp.Body, maxTimestampReply))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read TSA reply: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("TSA returned HTTP %d", resp.StatusCode)
+	}
+	return crypto.ParseTimestampResponse(reply, req)
+}
+
+// TimestampChain timestamps the chain's current head at tsaURL and stores
+// the resulting checkpoint in the chain
+func TimestampChain(chain *crypto.LogChain, tsaURL string) (*crypto.Checkpoint, error) {
+	length, head := chain.CheckpointHead()
+	if length == 0 {
+		return nil, fmt.Errorf("chain is empty")
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	token, err := RequestTimestamp(client, tsaURL, crypto.CheckpointBytes(length, head))
+	if err != nil {
+		return nil, err
+	}
+	return chain.AddCheckpoint(crypto.Checkpoint{Length: length, Head: head, TSA: tsaURL, Token: token})
+}